@@ -19,6 +19,7 @@ import (
 
 	"github.com/troikatech/calling-agent/internal/api/handlers"
 	"github.com/troikatech/calling-agent/pkg/ai"
+	"github.com/troikatech/calling-agent/pkg/circuitbreaker"
 	"github.com/troikatech/calling-agent/pkg/env"
 	"github.com/troikatech/calling-agent/pkg/exotel"
 	"github.com/troikatech/calling-agent/pkg/logger"
@@ -26,6 +27,7 @@ import (
 	"github.com/troikatech/calling-agent/pkg/mongo"
 	"github.com/troikatech/calling-agent/pkg/otel"
 	"github.com/troikatech/calling-agent/pkg/storage"
+	"github.com/troikatech/calling-agent/pkg/stt"
 	"github.com/troikatech/calling-agent/pkg/utils"
 	"github.com/troikatech/calling-agent/pkg/webhook"
 )
@@ -99,7 +101,7 @@ func main() {
 		cfg.ExotelAccountSID,
 		cfg.ExotelAPIKey,
 		cfg.ExotelAPIToken,
-	)
+	).WithBaseURL(cfg.ExotelAPIBaseURL)
 
 	// Initialize storage driver
 	storageDriver, err := storage.NewDriver(
@@ -114,7 +116,9 @@ func main() {
 	// Initialize AI services if enabled
 	var aiManager *ai.Manager
 	var ttsService *ai.TTSService
+	var ttsManager *ai.TTSManager
 	var sttService *ai.STTService
+	var sttManager *stt.Manager
 	var personaLoader *ai.PersonaLoader
 
 	if cfg.FeatureAI {
@@ -193,6 +197,29 @@ func main() {
 			}
 		}
 
+		// Initialize voicebot TTS providers in the configured fallback order
+		ttsProviders := []ai.TTSProvider{}
+		for _, name := range strings.Split(cfg.VoicebotTTSProviders, ",") {
+			switch strings.TrimSpace(name) {
+			case "openai":
+				if cfg.OpenAIApiKey != "" {
+					ttsProviders = append(ttsProviders, ai.NewOpenAITTSService(cfg.OpenAIApiKey, 10*time.Second, logger.Log))
+				}
+			case "elevenlabs":
+				if ttsService != nil && ttsService.IsAvailable() {
+					ttsProviders = append(ttsProviders, ttsService)
+				}
+			case "fake":
+				ttsProviders = append(ttsProviders, ai.NewFakeTTSProvider(16000, nil))
+			}
+		}
+		if len(ttsProviders) > 0 {
+			ttsManager = ai.NewTTSManager(ttsProviders, logger.Log)
+			logger.Log.Info("Voicebot TTS initialized", zap.Strings("providers", ttsManager.Providers()))
+		} else {
+			logger.Log.Warn("No TTS providers available - voicebot will fall back to text")
+		}
+
 		// Initialize STT service (OpenAI Whisper)
 		if cfg.OpenAIApiKey != "" {
 			sttService = ai.NewSTTService(
@@ -207,20 +234,52 @@ func main() {
 			}
 		}
 
+		// Initialize voicebot STT providers in the configured fallback order
+		sttProviders := []stt.Provider{}
+		for _, name := range strings.Split(cfg.VoicebotSTTProviders, ",") {
+			switch strings.TrimSpace(name) {
+			case "deepgram":
+				if cfg.DeepgramApiKey != "" {
+					sttProviders = append(sttProviders, stt.NewDeepgramClient(cfg.DeepgramApiKey, 10*time.Second, logger.Log))
+				}
+			case "whisper":
+				if sttService != nil && sttService.IsAvailable() {
+					sttProviders = append(sttProviders, stt.NewWhisperProvider(sttService))
+				}
+			case "fake":
+				sttProviders = append(sttProviders, stt.NewFakeProvider("Hello", "Tell me more", "Thank you, goodbye"))
+			}
+		}
+		if len(sttProviders) > 0 {
+			sttManager = stt.NewManager(sttProviders, circuitbreaker.DefaultConfig(), logger.Log)
+			logger.Log.Info("Voicebot STT initialized", zap.Strings("providers", sttManager.Providers()))
+		} else {
+			logger.Log.Warn("No STT providers available - voicebot will not understand callers")
+		}
+
 		// Initialize document loader
 		docLoader := ai.NewDocumentLoader(cfg.LocalStoragePath, logger.Log)
 
 		// Initialize persona loader
 		if mongoClient != nil {
-			personaLoader = ai.NewPersonaLoader(mongoClient, docLoader, logger.Log)
-			logger.Log.Info("Persona loader initialized")
+			personaLoader = ai.NewPersonaLoader(mongoClient, docLoader, logger.Log).
+				WithCache(cfg.PersonaCacheSize, time.Duration(cfg.PersonaCacheTTLSec)*time.Second)
+			knowledge := personaLoader.Knowledge().WithTopK(cfg.RAGTopK)
+			if cfg.RAGEmbeddings && cfg.OpenAIApiKey != "" {
+				knowledge.WithEmbedder(ai.NewOpenAIEmbedder(cfg.OpenAIApiKey, cfg.RAGEmbeddingModel, 10*time.Second, logger.Log))
+			}
+			logger.Log.Info("Persona loader initialized",
+				zap.Int("rag_top_k", cfg.RAGTopK),
+				zap.Int("persona_cache_size", cfg.PersonaCacheSize),
+				zap.Bool("rag_embeddings", cfg.RAGEmbeddings && cfg.OpenAIApiKey != ""),
+			)
 		}
 	} else {
 		logger.Log.Info("AI features are disabled")
 	}
 
 	// Initialize API Gateway handler
-	apiHandler := handlers.NewHandler(cfg, redisClient, mongoClient, aiManager, ttsService, sttService, personaLoader)
+	apiHandler := handlers.NewHandler(cfg, redisClient, mongoClient, aiManager, ttsService, ttsManager, sttService, sttManager, personaLoader, storageDriver)
 
 	// Create unified server
 	server := &UnifiedServer{
@@ -238,6 +297,16 @@ func main() {
 	// Start Jobs background worker
 	go server.startJobsWorker()
 
+	// Heartbeat live voicebot calls and take commands for them from other instances
+	registryCtx, stopRegistry := context.WithCancel(context.Background())
+	defer stopRegistry()
+	go apiHandler.RunSessionRegistry(registryCtx)
+
+	// Extract, chunk and index uploaded documents
+	ingestCtx, stopIngest := context.WithCancel(context.Background())
+	defer stopIngest()
+	go apiHandler.RunDocumentIngestion(ingestCtx)
+
 	// Start HTTP server
 	srv := &http.Server{
 		Addr:         ":" + cfg.AppPort,
@@ -263,6 +332,8 @@ func main() {
 	ctx, cancel = context.WithTimeout(context.Background(), 10*time.Second)
 	defer cancel()
 
+	stopRegistry()
+	stopIngest()
 	if err := srv.Shutdown(ctx); err != nil {
 		logger.Log.Fatal("Server forced to shutdown", zap.Error(err))
 	}
@@ -348,6 +419,7 @@ func (s *UnifiedServer) setupRouter() *gin.Engine {
 		{
 			calls.POST("", s.CreateCall) // Use unified dialer directly
 			calls.GET("/:call_sid", s.handler.GetCall)
+			calls.GET("/:call_sid/transcript", s.handler.GetCallTranscript)
 		}
 
 		campaigns := api.Group("/campaigns")
@@ -366,6 +438,13 @@ func (s *UnifiedServer) setupRouter() *gin.Engine {
 			recordings.GET("/:call_sid", s.handler.GetRecording)
 		}
 
+		voicebot := api.Group("/voicebot")
+		{
+			voicebot.GET("/sessions", s.handler.ListVoiceSessions)
+			voicebot.GET("/sessions/:call_sid", s.handler.GetVoiceSession)
+			voicebot.POST("/sessions/:call_sid/commands", middleware.RoleMiddleware("admin", "manager"), s.handler.SendVoiceSessionCommand)
+		}
+
 		analytics := api.Group("/analytics")
 		{
 			analytics.GET("/overview", s.handler.GetAnalyticsOverview)
@@ -378,6 +457,9 @@ func (s *UnifiedServer) setupRouter() *gin.Engine {
 			personas.GET("/:id", middleware.ValidateIDParam("id"), s.handler.GetPersona)
 			personas.POST("", middleware.RoleMiddleware("admin"), s.handler.CreatePersona)
 			personas.PUT("/:id", middleware.ValidateIDParam("id"), middleware.RoleMiddleware("admin"), s.handler.UpdatePersona)
+			personas.GET("/:id/versions", middleware.ValidateIDParam("id"), s.handler.ListPersonaVersions)
+			personas.GET("/:id/versions/:version", middleware.ValidateIDParam("id"), s.handler.GetPersonaVersion)
+			personas.POST("/:id/tts/prewarm", middleware.ValidateIDParam("id"), middleware.RoleMiddleware("admin"), s.handler.PrewarmPersonaTTS)
 			personas.POST("/:id/documents", middleware.ValidateIDParam("id"), middleware.RoleMiddleware("admin"), s.handler.LinkDocumentToPersona)
 		}
 
@@ -386,6 +468,9 @@ func (s *UnifiedServer) setupRouter() *gin.Engine {
 			documents.POST("", middleware.RoleMiddleware("admin", "manager"), s.handler.UploadDocument)
 			documents.GET("", s.handler.ListDocuments)
 			documents.GET("/:id", middleware.ValidateIDParam("id"), s.handler.GetDocument)
+			documents.GET("/:id/status", middleware.ValidateIDParam("id"), s.handler.GetDocumentStatus)
+			documents.GET("/:id/events", middleware.ValidateIDParam("id"), s.handler.StreamDocumentStatus)
+			documents.POST("/:id/reingest", middleware.ValidateIDParam("id"), middleware.RoleMiddleware("admin", "manager"), s.handler.ReingestDocument)
 			documents.DELETE("/:id", middleware.ValidateIDParam("id"), middleware.RoleMiddleware("admin", "manager"), s.handler.DeleteDocument)
 			documents.GET("/download/:filename", s.handler.DownloadDocument)
 		}
@@ -434,6 +519,10 @@ func (s *UnifiedServer) setupRouter() *gin.Engine {
 	router.POST("/voicebot/init", s.handler.ExotelVoicebotEndpoint)
 	router.GET("/voicebot/ws", s.handler.VoicebotWebSocket)
 
+	// Connect applet dynamic URL: where to route a call the bot handed over to a human
+	router.GET("/voicebot/transfer", s.handler.ExotelTransferEndpoint)
+	router.POST("/voicebot/transfer", s.handler.ExotelTransferEndpoint)
+
 	// Also support /was endpoint as per original requirements (exactly /was)
 	// Exotel will connect via WebSocket GET request to this endpoint
 	router.GET("/was", s.handler.VoicebotWebSocket)
@@ -1246,7 +1335,13 @@ func (s *UnifiedServer) processWebhookPayload(ctx context.Context, payload *Exot
 		s.mongoClient.NewQuery("campaign_contacts").
 			Eq("call_sid", payload.CallSid).
 			UpdateOne(ctx, map[string]interface{}{
-				"status":      "completed",
+				"status": "completed",
+			})
+		// Keep a disposition the voicebot already set (voicemail, opt_out, transferred)
+		s.mongoClient.NewQuery("campaign_contacts").
+			Eq("call_sid", payload.CallSid).
+			IsNull("disposition").
+			UpdateOne(ctx, map[string]interface{}{
 				"disposition": "answered",
 			})
 	} else if payload.Status == "no-answer" || payload.Status == "busy" || payload.Status == "failed" {
@@ -51,7 +51,7 @@ func (h *Handler) GenerateScript(c *gin.Context) {
 	var ragContext map[string]interface{}
 	if h.personaLoader != nil {
 		personaID := req.PersonaID
-		ctx, err := h.personaLoader.BuildRAGContext(c.Request.Context(), &personaID)
+		ctx, err := h.personaLoader.BuildRAGContext(c.Request.Context(), &personaID, industry+" "+valueProp)
 		if err == nil && ctx != nil {
 			ragContext = ctx
 		}
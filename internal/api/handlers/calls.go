@@ -82,6 +82,41 @@ func (h *Handler) GetCall(c *gin.Context) {
 	c.JSON(http.StatusOK, call)
 }
 
+// GetCallTranscript returns the call's turns from call_turns in order, with timing and latency per turn
+func (h *Handler) GetCallTranscript(c *gin.Context) {
+	callSID := c.Param("call_sid")
+
+	ctx, cancel := context.WithTimeout(c.Request.Context(), 5*time.Second)
+	defer cancel()
+
+	turns, err := h.mongoClient.NewQuery("call_turns").
+		Select("*").
+		Eq("call_sid", callSID).
+		Sort("seq", true).
+		Find(ctx)
+
+	if err != nil {
+		errors.InternalError(c, err, h.logger)
+		return
+	}
+	if len(turns) == 0 {
+		errors.NotFound(c, "transcript not found")
+		return
+	}
+
+	for _, turn := range turns {
+		delete(turn, "_id")
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"call_sid": callSID,
+		"turns":    turns,
+		"count":    len(turns),
+	})
+}
+
+// GetRecording serves the voicebot's own stereo recording (caller left, bot right) with range support,
+// falling back to a redirect to Exotel's recording. ?source=exotel skips the voicebot recording.
 func (h *Handler) GetRecording(c *gin.Context) {
 	callSID := c.Param("call_sid")
 
@@ -89,7 +124,7 @@ func (h *Handler) GetRecording(c *gin.Context) {
 	defer cancel()
 
 	call, err := h.mongoClient.NewQuery("calls").
-		Select("recording_url").
+		Select("recording_url", "voicebot_recording_key").
 		Eq("call_sid", callSID).
 		FindOne(ctx)
 
@@ -98,6 +133,18 @@ func (h *Handler) GetRecording(c *gin.Context) {
 		return
 	}
 
+	if key, _ := call["voicebot_recording_key"].(string); key != "" && h.storage != nil && c.Query("source") != "exotel" {
+		recording, err := h.storage.OpenRecording(key)
+		if err == nil {
+			defer recording.Content.Close()
+			c.Header("Content-Type", "audio/wav")
+			c.Header("Content-Disposition", fmt.Sprintf("inline; filename=%q", callSID+".wav"))
+			http.ServeContent(c.Writer, c.Request, callSID+".wav", recording.ModTime, recording.Content)
+			return
+		}
+		h.logger.Warn("Voicebot recording unavailable", zap.String("call_sid", callSID), zap.Error(err))
+	}
+
 	recordingURL, ok := call["recording_url"].(string)
 	if !ok || recordingURL == "" {
 		errors.NotFound(c, "recording not available")
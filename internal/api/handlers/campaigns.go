@@ -17,12 +17,13 @@ import (
 )
 
 type CreateCampaignRequest struct {
-	Name      string         `json:"name" binding:"required"`
-	Contacts  []ContactRef   `json:"contacts" binding:"required"`
-	Window    CampaignWindow `json:"window"`
-	Retries   RetryConfig    `json:"retries"`
-	FlowID    string         `json:"flow_id" binding:"required"`
-	PersonaID int64          `json:"persona_id"`
+	Name           string         `json:"name" binding:"required"`
+	Contacts       []ContactRef   `json:"contacts" binding:"required"`
+	Window         CampaignWindow `json:"window"`
+	Retries        RetryConfig    `json:"retries"`
+	FlowID         string         `json:"flow_id" binding:"required"`
+	PersonaID      int64          `json:"persona_id"`
+	PersonaVersion int64          `json:"persona_version"` // 0 pins the persona's current version
 }
 
 type ContactRef struct {
@@ -70,21 +71,34 @@ func (h *Handler) CreateCampaign(c *gin.Context) {
 		return
 	}
 
-	campaignData := map[string]interface{}{
-		"name":          req.Name,
-		"status":        "draft",
-		"window_start":  req.Window.Start,
-		"window_end":    req.Window.End,
-		"max_retries":   req.Retries.Max,
-		"retry_gap_min": req.Retries.GapMin,
-		"flow_id":       req.FlowID,
-		"persona_id":    req.PersonaID,
-		"created_by":    userID,
-	}
-
 	ctx, cancel := context.WithTimeout(c.Request.Context(), 5*time.Second)
 	defer cancel()
 
+	// The persona is pinned to a version so later edits never change a running campaign
+	var personaVersion int64
+	if req.PersonaID != 0 {
+		if h.refuseFailedPersonaDocuments(ctx, c, req.PersonaID) {
+			return
+		}
+		var ok bool
+		if personaVersion, ok = h.pinPersonaVersion(ctx, c, req.PersonaID, req.PersonaVersion); !ok {
+			return
+		}
+	}
+
+	campaignData := map[string]interface{}{
+		"name":            req.Name,
+		"status":          "draft",
+		"window_start":    req.Window.Start,
+		"window_end":      req.Window.End,
+		"max_retries":     req.Retries.Max,
+		"retry_gap_min":   req.Retries.GapMin,
+		"flow_id":         req.FlowID,
+		"persona_id":      req.PersonaID,
+		"persona_version": personaVersion,
+		"created_by":      userID,
+	}
+
 	campaignData["created_at"] = time.Now().Format(time.RFC3339)
 	campaignID, err := h.mongoClient.NewQuery("campaigns").Insert(ctx, campaignData)
 	if err != nil {
@@ -256,7 +270,21 @@ func (h *Handler) ResumeCampaign(c *gin.Context) {
 	ctx, cancel := context.WithTimeout(c.Request.Context(), 5*time.Second)
 	defer cancel()
 
-	_, err := h.mongoClient.NewQuery("campaigns").
+	campaign, err := h.mongoClient.NewQuery("campaigns").
+		Select("persona_id").
+		Eq("id", fmt.Sprintf("%d", id.(int64))).
+		FindOne(ctx)
+	if err != nil || campaign == nil {
+		errors.NotFound(c, "campaign not found")
+		return
+	}
+	if personaID := campaign["persona_id"]; personaID != nil && fmt.Sprint(personaID) != "0" {
+		if h.refuseFailedPersonaDocuments(ctx, c, personaID) {
+			return
+		}
+	}
+
+	_, err = h.mongoClient.NewQuery("campaigns").
 		Eq("id", fmt.Sprintf("%d", id.(int64))).
 		UpdateOne(ctx, map[string]interface{}{"status": "running"})
 
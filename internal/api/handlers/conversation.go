@@ -37,7 +37,7 @@ func (h *Handler) Conversation(c *gin.Context) {
 	// Build RAG context if persona loader is available
 	var ragContext map[string]interface{}
 	if h.personaLoader != nil && req.PersonaID != nil {
-		ctx, err := h.personaLoader.BuildRAGContext(c.Request.Context(), req.PersonaID)
+		ctx, err := h.personaLoader.BuildRAGContext(c.Request.Context(), req.PersonaID, req.UserText)
 		if err == nil && ctx != nil {
 			ragContext = ctx
 		}
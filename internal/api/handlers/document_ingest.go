@@ -0,0 +1,434 @@
+package handlers
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+
+	"github.com/troikatech/calling-agent/pkg/ai"
+	"github.com/troikatech/calling-agent/pkg/audit"
+	"github.com/troikatech/calling-agent/pkg/errors"
+	"github.com/troikatech/calling-agent/pkg/extract"
+)
+
+// Document ingestion states, stored in documents.status
+const (
+	documentPending    = "pending"    // Queued for extraction and indexing
+	documentProcessing = "processing" // Claimed by a worker
+	documentReady      = "ready"      // Chunks are searchable
+	documentFailed     = "failed"     // See ingest_error; re-ingest to retry
+)
+
+// Ingestion stages within processing, with the progress reported for each
+const (
+	stageQueued     = "queued"
+	stageExtracting = "extracting"
+	stageIndexing   = "indexing"
+	stageDone       = "done"
+)
+
+var stageProgress = map[string]int{stageQueued: 0, stageExtracting: 20, stageIndexing: 60, stageDone: 100}
+
+const (
+	ingestPollInterval  = 10 * time.Second // Picks up uploads queued on other instances or before a restart
+	ingestBatchSize     = 10
+	ingestTimeout       = 5 * time.Minute
+	ingestStaleAfter    = 15 * time.Minute // A claim this old belongs to a worker that died
+	ingestEventInterval = time.Second      // How often the SSE endpoint checks for progress
+	ingestEventTimeout  = 10 * time.Minute
+)
+
+// ingestionFields are the documents fields that describe ingestion progress
+var ingestionFields = []string{
+	"id", "name", "status", "ingest_stage", "ingest_progress", "ingest_error", "ingest_attempts",
+	"ingest_started_at", "ingest_finished_at", "chunk_count", "language", "page_count", "text_length",
+}
+
+// queueIngestion wakes the ingestion worker; the document itself is queued by its pending status
+func (h *Handler) queueIngestion() {
+	select {
+	case h.ingestWake <- struct{}{}:
+	default:
+	}
+}
+
+// RunDocumentIngestion extracts, chunks and indexes pending documents until ctx is cancelled.
+// Documents are claimed with a conditional update, so several instances can run it at once.
+func (h *Handler) RunDocumentIngestion(ctx context.Context) {
+	if h.mongoClient == nil || h.personaLoader == nil {
+		h.logger.Warn("Document ingestion disabled: MongoDB or persona loader not available")
+		return
+	}
+	h.logger.Info("Document ingestion worker started")
+
+	ticker := time.NewTicker(ingestPollInterval)
+	defer ticker.Stop()
+
+	for {
+		h.processPendingDocuments(ctx)
+
+		select {
+		case <-ctx.Done():
+			h.logger.Info("Document ingestion worker stopped")
+			return
+		case <-ticker.C:
+		case <-h.ingestWake:
+		}
+	}
+}
+
+// processPendingDocuments ingests queued documents, oldest first, until none are left.
+// A batch in which nothing could be claimed ends the pass; the next tick or upload starts another,
+// so a persistent claim failure does not spin against MongoDB.
+func (h *Handler) processPendingDocuments(ctx context.Context) {
+	h.requeueStaleDocuments(ctx)
+
+	for ctx.Err() == nil {
+		pending, err := h.mongoClient.NewQuery("documents").
+			Select("name", "file_path", "persona_id", "ingest_attempts").
+			Eq("status", documentPending).
+			Sort("created_at", true).
+			Limit(ingestBatchSize).
+			Find(ctx)
+		if err != nil {
+			h.logger.Error("Failed to fetch pending documents", zap.Error(err))
+			return
+		}
+		if len(pending) == 0 {
+			return
+		}
+
+		claimed := 0
+		for _, doc := range pending {
+			if ctx.Err() != nil {
+				return
+			}
+			if h.claimDocument(ctx, doc) {
+				claimed++
+				h.ingestDocument(ctx, doc)
+			}
+		}
+		if claimed == 0 || len(pending) < ingestBatchSize {
+			return
+		}
+	}
+}
+
+// requeueStaleDocuments returns documents whose worker stopped mid-way to the queue
+func (h *Handler) requeueStaleDocuments(ctx context.Context) {
+	cutoff := time.Now().Add(-ingestStaleAfter).Format(time.RFC3339)
+	result, err := h.mongoClient.NewQuery("documents").
+		Eq("status", documentProcessing).
+		Lte("ingest_started_at", cutoff).
+		Update(ctx, map[string]interface{}{
+			"status":          documentPending,
+			"ingest_stage":    stageQueued,
+			"ingest_progress": stageProgress[stageQueued],
+		})
+	if err != nil {
+		h.logger.Warn("Failed to requeue stale documents", zap.Error(err))
+		return
+	}
+	if result != nil && result.ModifiedCount > 0 {
+		h.logger.Warn("Requeued documents abandoned during ingestion", zap.Int64("count", result.ModifiedCount))
+	}
+}
+
+// claimDocument moves a pending document to processing; false means another worker took it first
+func (h *Handler) claimDocument(ctx context.Context, doc map[string]interface{}) bool {
+	result, err := h.mongoClient.NewQuery("documents").
+		Eq("_id", doc["_id"]).
+		Eq("status", documentPending).
+		UpdateOne(ctx, map[string]interface{}{
+			"status":            documentProcessing,
+			"ingest_stage":      stageExtracting,
+			"ingest_progress":   stageProgress[stageExtracting],
+			"ingest_error":      "",
+			"ingest_attempts":   ingestAttempts(doc) + 1,
+			"ingest_started_at": time.Now().Format(time.RFC3339),
+		})
+	if err != nil {
+		h.logger.Warn("Failed to claim document", zap.String("document_id", ai.DocumentID(doc)), zap.Error(err))
+		return false
+	}
+	return result.ModifiedCount == 1
+}
+
+// ingestAttempts reads how often a document has been claimed
+func ingestAttempts(doc map[string]interface{}) int {
+	switch v := doc["ingest_attempts"].(type) {
+	case int32:
+		return int(v)
+	case int64:
+		return int(v)
+	case float64:
+		return int(v)
+	}
+	return 0
+}
+
+// ingestDocument extracts and indexes a claimed document, recording the outcome on its record
+func (h *Handler) ingestDocument(parent context.Context, doc map[string]interface{}) {
+	ctx, cancel := context.WithTimeout(parent, ingestTimeout)
+	defer cancel()
+
+	docID := ai.DocumentID(doc)
+	start := time.Now()
+	knowledge := h.personaLoader.Knowledge()
+
+	extracted, err := knowledge.ExtractDocument(ctx, doc)
+	if err != nil {
+		h.failIngestion(parent, doc, ingestionErrorMessage(err))
+		return
+	}
+	if strings.TrimSpace(extracted.Text) == "" {
+		h.failIngestion(parent, doc, "no text found in document; scanned PDFs and images need a text layer")
+		return
+	}
+
+	h.setIngestionStage(ctx, doc, stageIndexing)
+	for k, v := range ai.ExtractionFields(extracted) {
+		doc[k] = v
+	}
+	chunks, err := knowledge.IndexDocument(ctx, doc)
+	if err != nil {
+		h.failIngestion(parent, doc, ingestionErrorMessage(err))
+		return
+	}
+
+	if _, err := h.mongoClient.NewQuery("documents").Eq("_id", doc["_id"]).UpdateOne(parent, map[string]interface{}{
+		"status":             documentReady,
+		"ingest_stage":       stageDone,
+		"ingest_progress":    stageProgress[stageDone],
+		"ingest_finished_at": time.Now().Format(time.RFC3339),
+	}); err != nil {
+		h.logger.Error("Failed to mark document ready", zap.String("document_id", docID), zap.Error(err))
+		return
+	}
+	h.invalidatePersonaContext(doc["persona_id"])
+
+	h.logger.Info("Document ingested",
+		zap.String("document_id", docID),
+		zap.Int("chunks", chunks),
+		zap.String("language", extracted.Language),
+		zap.Duration("duration", time.Since(start)),
+	)
+}
+
+// setIngestionStage records progress on a document being processed
+func (h *Handler) setIngestionStage(ctx context.Context, doc map[string]interface{}, stage string) {
+	if _, err := h.mongoClient.NewQuery("documents").Eq("_id", doc["_id"]).UpdateOne(ctx, map[string]interface{}{
+		"ingest_stage":    stage,
+		"ingest_progress": stageProgress[stage],
+	}); err != nil {
+		h.logger.Warn("Failed to update ingestion stage", zap.String("document_id", ai.DocumentID(doc)), zap.Error(err))
+	}
+}
+
+// failIngestion marks a document failed with a message for the uploader
+func (h *Handler) failIngestion(ctx context.Context, doc map[string]interface{}, message string) {
+	h.logger.Warn("Document ingestion failed",
+		zap.String("document_id", ai.DocumentID(doc)),
+		zap.Any("name", doc["name"]),
+		zap.String("error", message),
+	)
+	if _, err := h.mongoClient.NewQuery("documents").Eq("_id", doc["_id"]).UpdateOne(ctx, map[string]interface{}{
+		"status":             documentFailed,
+		"ingest_stage":       stageDone,
+		"ingest_progress":    stageProgress[stageDone],
+		"ingest_error":       message,
+		"ingest_finished_at": time.Now().Format(time.RFC3339),
+	}); err != nil {
+		h.logger.Error("Failed to mark document failed", zap.String("document_id", ai.DocumentID(doc)), zap.Error(err))
+	}
+	h.invalidatePersonaContext(doc["persona_id"])
+}
+
+// ingestionErrorMessage explains an ingestion error in terms the uploader can act on
+func ingestionErrorMessage(err error) string {
+	switch {
+	case err == nil:
+		return ""
+	case strings.Contains(err.Error(), extract.ErrEncrypted.Error()):
+		return "password-protected PDFs cannot be read; upload an unprotected copy"
+	case strings.Contains(err.Error(), extract.ErrUnsupported.Error()):
+		return fmt.Sprintf("file type not supported; supported: %s", strings.Join(extract.Formats, ", "))
+	}
+	return err.Error()
+}
+
+// ingestionStatus is the progress view of a documents record
+func ingestionStatus(doc map[string]interface{}) gin.H {
+	status := gin.H{}
+	for _, field := range ingestionFields {
+		if v, ok := doc[field]; ok {
+			status[field] = v
+		}
+	}
+	if status["status"] == nil {
+		// Uploaded before the ingestion queue; indexed on first use
+		status["status"] = documentReady
+	}
+	return status
+}
+
+// findDocument loads a document by its numeric ID with the given fields
+func (h *Handler) findDocument(ctx context.Context, idStr string, fields ...string) (map[string]interface{}, error) {
+	return h.mongoClient.NewQuery("documents").
+		Select(fields...).
+		Eq("id", idStr).
+		FindOne(ctx)
+}
+
+// GetDocumentStatus returns a document's ingestion status and progress
+func (h *Handler) GetDocumentStatus(c *gin.Context) {
+	id, _ := c.Get("id_int")
+	idStr := fmt.Sprintf("%d", id.(int64))
+
+	ctx, cancel := context.WithTimeout(c.Request.Context(), 5*time.Second)
+	defer cancel()
+
+	document, err := h.findDocument(ctx, idStr, ingestionFields...)
+	if err != nil || document == nil {
+		errors.NotFound(c, "document not found")
+		return
+	}
+
+	c.JSON(http.StatusOK, ingestionStatus(document))
+}
+
+// StreamDocumentStatus sends the ingestion status as server-sent events whenever it changes,
+// ending once the document is ready or failed
+func (h *Handler) StreamDocumentStatus(c *gin.Context) {
+	id, _ := c.Get("id_int")
+	idStr := fmt.Sprintf("%d", id.(int64))
+
+	ctx, cancel := context.WithTimeout(c.Request.Context(), ingestEventTimeout)
+	defer cancel()
+
+	document, err := h.findDocument(ctx, idStr, ingestionFields...)
+	if err != nil || document == nil {
+		errors.NotFound(c, "document not found")
+		return
+	}
+
+	// The server's write timeout is meant for ordinary requests; this one stays open until ingestion ends
+	if err := http.NewResponseController(c.Writer).SetWriteDeadline(time.Now().Add(ingestEventTimeout)); err != nil {
+		h.logger.Debug("Failed to extend write deadline for status stream", zap.Error(err))
+	}
+	c.Header("Cache-Control", "no-cache")
+	c.Header("Connection", "keep-alive")
+	c.Header("X-Accel-Buffering", "no")
+
+	ticker := time.NewTicker(ingestEventInterval)
+	defer ticker.Stop()
+
+	last := ""
+	c.Stream(func(w io.Writer) bool {
+		status := ingestionStatus(document)
+		key := fmt.Sprint(status["status"], status["ingest_stage"], status["ingest_attempts"])
+		if key != last {
+			c.SSEvent("status", status)
+			last = key
+		}
+		if s := status["status"]; s == documentReady || s == documentFailed {
+			return false
+		}
+
+		select {
+		case <-ctx.Done():
+			return false
+		case <-ticker.C:
+		}
+		if next, err := h.findDocument(ctx, idStr, ingestionFields...); err == nil && next != nil {
+			document = next
+		}
+		return true
+	})
+}
+
+// ReingestDocument queues a document for extraction and indexing again, e.g. after a failure
+func (h *Handler) ReingestDocument(c *gin.Context) {
+	id, _ := c.Get("id_int")
+	idStr := fmt.Sprintf("%d", id.(int64))
+	userID, _ := c.Get("user_id")
+	userIDStr := fmt.Sprintf("%v", userID)
+
+	ctx, cancel := context.WithTimeout(c.Request.Context(), 5*time.Second)
+	defer cancel()
+
+	document, err := h.findDocument(ctx, idStr, "name", "status")
+	if err != nil || document == nil {
+		errors.NotFound(c, "document not found")
+		return
+	}
+	if document["status"] == documentProcessing {
+		errors.Conflict(c, "document is being ingested")
+		return
+	}
+
+	if _, err := h.mongoClient.NewQuery("documents").Eq("_id", document["_id"]).UpdateOne(ctx, map[string]interface{}{
+		"status":          documentPending,
+		"ingest_stage":    stageQueued,
+		"ingest_progress": stageProgress[stageQueued],
+		"ingest_error":    "",
+		"updated_at":      time.Now().Format(time.RFC3339),
+	}); err != nil {
+		h.logger.Error("Failed to queue document for re-ingestion", zap.Error(err))
+		errors.InternalError(c, err, h.logger)
+		return
+	}
+	h.queueIngestion()
+
+	audit.Log(h.mongoClient, userIDStr, string(audit.ActionUpdate), "document", idStr, map[string]interface{}{
+		"name":   document["name"],
+		"action": "reingest",
+	})
+
+	c.JSON(http.StatusAccepted, gin.H{"id": idStr, "status": documentPending})
+}
+
+// failedPersonaDocuments returns the names of a persona's documents whose ingestion failed
+func (h *Handler) failedPersonaDocuments(ctx context.Context, personaID interface{}) ([]string, error) {
+	ids := []interface{}{fmt.Sprint(personaID)}
+	if n, err := strconv.ParseInt(fmt.Sprint(personaID), 10, 64); err == nil {
+		ids = append(ids, n)
+	}
+
+	docs, err := h.mongoClient.NewQuery("documents").
+		Select("name").
+		In("persona_id", ids).
+		Eq("status", documentFailed).
+		Find(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to check persona documents: %w", err)
+	}
+	names := make([]string, 0, len(docs))
+	for _, doc := range docs {
+		names = append(names, fmt.Sprint(doc["name"]))
+	}
+	return names, nil
+}
+
+// refuseFailedPersonaDocuments writes a 409 and returns true when a persona that is about to take
+// live calls has documents that could not be ingested
+func (h *Handler) refuseFailedPersonaDocuments(ctx context.Context, c *gin.Context, personaID interface{}) bool {
+	failed, err := h.failedPersonaDocuments(ctx, personaID)
+	if err != nil {
+		errors.InternalError(c, err, h.logger)
+		return true
+	}
+	if len(failed) == 0 {
+		return false
+	}
+	errors.Conflict(c, fmt.Sprintf("persona %v has documents that failed ingestion (%s); re-ingest or remove them first",
+		personaID, strings.Join(failed, ", ")))
+	return true
+}
@@ -0,0 +1,174 @@
+package handlers
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+
+	"github.com/troikatech/calling-agent/pkg/env"
+	"github.com/troikatech/calling-agent/pkg/logger"
+	"github.com/troikatech/calling-agent/pkg/middleware"
+	"github.com/troikatech/calling-agent/pkg/mongo"
+)
+
+// MongoDB is probed once; when it is unreachable later tests skip without waiting for it again
+var (
+	mongoTestOnce        sync.Once
+	mongoTestUnavailable error
+)
+
+// newMongoTestHandler returns a handler on a throwaway database of the MongoDB at MONGO_TEST_URI
+// (default localhost), skipping the test when none is reachable. Uploads go to a temporary directory.
+func newMongoTestHandler(t *testing.T) *Handler {
+	t.Helper()
+	if logger.Log == nil {
+		logger.Log = zap.NewNop()
+	}
+	uri := os.Getenv("MONGO_TEST_URI")
+	if uri == "" {
+		uri = "mongodb://localhost:27017"
+	}
+	mongoTestOnce.Do(func() {
+		probe, err := mongo.NewClient(uri, "admin")
+		if err != nil {
+			mongoTestUnavailable = err
+			return
+		}
+		probe.Disconnect(context.Background())
+	})
+	if mongoTestUnavailable != nil {
+		t.Skipf("MongoDB not available: %v", mongoTestUnavailable)
+	}
+	client, err := mongo.NewClient(uri, fmt.Sprintf("calling_agent_test_%d", time.Now().UnixNano()))
+	if err != nil {
+		t.Skipf("MongoDB not available: %v", err)
+	}
+	t.Cleanup(func() {
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		client.Database().Drop(ctx)
+		client.Disconnect(ctx)
+	})
+
+	wd, err := os.Getwd()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Chdir(t.TempDir()); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { os.Chdir(wd) })
+
+	return &Handler{
+		cfg:         &env.Config{},
+		mongoClient: client,
+		logger:      zap.NewNop(),
+		ingestWake:  make(chan struct{}, 1),
+	}
+}
+
+// documentRouter serves the document routes as the given user, without JWT auth
+func documentRouter(h *Handler) *gin.Engine {
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	r.Use(func(c *gin.Context) {
+		c.Set("user_id", "1")
+		c.Set("user_role", "admin")
+	})
+	r.POST("/api/documents", h.UploadDocument)
+	r.GET("/api/documents/:id/status", middleware.ValidateIDParam("id"), h.GetDocumentStatus)
+	r.POST("/api/documents/:id/reingest", middleware.ValidateIDParam("id"), h.ReingestDocument)
+	return r
+}
+
+func TestDocumentUploadStatusReingest(t *testing.T) {
+	h := newMongoTestHandler(t)
+	r := documentRouter(h)
+
+	var form bytes.Buffer
+	mw := multipart.NewWriter(&form)
+	mw.WriteField("name", "Pricing FAQ")
+	fw, _ := mw.CreateFormFile("file", "faq.txt")
+	fw.Write([]byte("Plans start at 499 a month."))
+	mw.Close()
+
+	req := httptest.NewRequest(http.MethodPost, "/api/documents", &form)
+	req.Header.Set("Content-Type", mw.FormDataContentType())
+	code, uploaded := serve(t, r, req)
+	if code != http.StatusCreated {
+		t.Fatalf("upload = %d %v", code, uploaded)
+	}
+	id, _ := uploaded["id"].(string)
+	if id == "" {
+		t.Fatalf("upload returned no numeric id: %v", uploaded)
+	}
+	select {
+	case <-h.ingestWake:
+	default:
+		t.Error("upload did not wake the ingestion worker")
+	}
+
+	code, status := serve(t, r, httptest.NewRequest(http.MethodGet, "/api/documents/"+id+"/status", nil))
+	if code != http.StatusOK || status["status"] != documentPending || status["id"] != id {
+		t.Fatalf("status = %d %v", code, status)
+	}
+
+	// A failed document can be queued again
+	ctx := context.Background()
+	if _, err := h.mongoClient.NewQuery("documents").Eq("id", id).UpdateOne(ctx, map[string]interface{}{
+		"status":       documentFailed,
+		"ingest_error": "no text found",
+	}); err != nil {
+		t.Fatal(err)
+	}
+	code, body := serve(t, r, httptest.NewRequest(http.MethodPost, "/api/documents/"+id+"/reingest", nil))
+	if code != http.StatusAccepted {
+		t.Fatalf("reingest = %d %v", code, body)
+	}
+	_, status = serve(t, r, httptest.NewRequest(http.MethodGet, "/api/documents/"+id+"/status", nil))
+	if status["status"] != documentPending || status["ingest_error"] != "" {
+		t.Errorf("status after reingest = %v", status)
+	}
+
+	// A document being ingested cannot be queued twice
+	h.mongoClient.NewQuery("documents").Eq("id", id).UpdateOne(ctx, map[string]interface{}{"status": documentProcessing})
+	if code, _ := serve(t, r, httptest.NewRequest(http.MethodPost, "/api/documents/"+id+"/reingest", nil)); code != http.StatusConflict {
+		t.Errorf("reingest while processing = %d, want 409", code)
+	}
+
+	if code, _ := serve(t, r, httptest.NewRequest(http.MethodGet, "/api/documents/999999/status", nil)); code != http.StatusNotFound {
+		t.Errorf("unknown document = %d, want 404", code)
+	}
+}
+
+func TestNextID_SkipsExistingIDs(t *testing.T) {
+	h := newMongoTestHandler(t)
+	ctx := context.Background()
+
+	// A document created before the counter existed keeps its ID
+	if _, err := h.mongoClient.NewQuery("documents").Insert(ctx, map[string]interface{}{"id": "1", "name": "legacy"}); err != nil {
+		t.Fatal(err)
+	}
+
+	seen := map[int64]bool{}
+	for i := 0; i < 5; i++ {
+		id, err := h.nextID(ctx, "documents")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if id == 1 || seen[id] {
+			t.Fatalf("nextID returned %d twice", id)
+		}
+		seen[id] = true
+	}
+}
@@ -7,14 +7,17 @@ import (
 	"net/http"
 	"os"
 	"path/filepath"
+	"strconv"
 	"strings"
 	"time"
 
 	"github.com/gin-gonic/gin"
 	"go.uber.org/zap"
 
+	"github.com/troikatech/calling-agent/pkg/ai"
 	"github.com/troikatech/calling-agent/pkg/audit"
 	"github.com/troikatech/calling-agent/pkg/errors"
+	"github.com/troikatech/calling-agent/pkg/extract"
 	"github.com/troikatech/calling-agent/pkg/utils"
 )
 
@@ -54,18 +57,10 @@ func (h *Handler) UploadDocument(c *gin.Context) {
 		return
 	}
 
-	// Validate file type
-	allowedTypes := []string{".pdf", ".docx", ".doc", ".txt", ".md"}
+	// Validate file type: only formats whose text can be extracted are useful to the agent
 	ext := strings.ToLower(filepath.Ext(file.Filename))
-	allowed := false
-	for _, t := range allowedTypes {
-		if ext == t {
-			allowed = true
-			break
-		}
-	}
-	if !allowed {
-		errors.BadRequest(c, fmt.Sprintf("file type not allowed. allowed: %v", allowedTypes))
+	if !extract.Supported(ext) {
+		errors.BadRequest(c, fmt.Sprintf("file type not allowed. allowed: %v", extract.Formats))
 		return
 	}
 
@@ -102,8 +97,12 @@ func (h *Handler) UploadDocument(c *gin.Context) {
 			mimeType = "application/pdf"
 		case ".docx":
 			mimeType = "application/vnd.openxmlformats-officedocument.wordprocessingml.document"
-		case ".doc":
-			mimeType = "application/msword"
+		case ".xlsx":
+			mimeType = "application/vnd.openxmlformats-officedocument.spreadsheetml.sheet"
+		case ".csv":
+			mimeType = "text/csv"
+		case ".html", ".htm":
+			mimeType = "text/html"
 		case ".txt", ".md":
 			mimeType = "text/plain"
 		default:
@@ -115,7 +114,17 @@ func (h *Handler) UploadDocument(c *gin.Context) {
 	ctx, cancel := context.WithTimeout(c.Request.Context(), 5*time.Second)
 	defer cancel()
 
+	docID, err := h.nextID(ctx, "documents")
+	if err != nil {
+		os.Remove(filePath)
+		h.logger.Error("Failed to allocate document ID", zap.Error(err))
+		errors.InternalError(c, err, h.logger)
+		return
+	}
+	idStr := strconv.FormatInt(docID, 10)
+
 	docData := map[string]interface{}{
+		"id":          idStr,
 		"name":        req.Name,
 		"type":        req.Type,
 		"persona_id":  req.PersonaID,
@@ -126,10 +135,13 @@ func (h *Handler) UploadDocument(c *gin.Context) {
 		"uploaded_by": userIDStr,
 		"created_at":  time.Now().Format(time.RFC3339),
 		"updated_at":  time.Now().Format(time.RFC3339),
+		// Text is extracted, chunked and indexed by the ingestion worker; see GetDocumentStatus
+		"status":          documentPending,
+		"ingest_stage":    stageQueued,
+		"ingest_progress": stageProgress[stageQueued],
 	}
 
-	docID, err := h.mongoClient.NewQuery("documents").Insert(ctx, docData)
-	if err != nil {
+	if _, err := h.mongoClient.NewQuery("documents").Insert(ctx, docData); err != nil {
 		// Clean up uploaded file on error
 		os.Remove(filePath)
 		h.logger.Error("Failed to save document metadata", zap.Error(err))
@@ -137,10 +149,10 @@ func (h *Handler) UploadDocument(c *gin.Context) {
 		return
 	}
 
-	docData["id"] = docID
+	h.queueIngestion()
 
 	// Audit log
-	audit.Log(h.mongoClient, userIDStr, string(audit.ActionCreate), "document", fmt.Sprintf("%v", docID), map[string]interface{}{
+	audit.Log(h.mongoClient, userIDStr, string(audit.ActionCreate), "document", idStr, map[string]interface{}{
 		"name": req.Name,
 		"type": req.Type,
 	})
@@ -158,7 +170,7 @@ func (h *Handler) ListDocuments(c *gin.Context) {
 	defer cancel()
 
 	query := h.mongoClient.NewQuery("documents").
-		Select("id", "name", "type", "persona_id", "file_url", "file_size", "mime_type", "created_at", "updated_at").
+		Select("id", "name", "type", "persona_id", "file_url", "file_size", "mime_type", "status", "ingest_error", "created_at", "updated_at").
 		Limit(int64(pagination.Limit))
 
 	if personaID != "" {
@@ -279,7 +291,7 @@ func (h *Handler) DeleteDocument(c *gin.Context) {
 
 	// Get document to find file path
 	document, err := h.mongoClient.NewQuery("documents").
-		Select("file_path", "name").
+		Select("file_path", "name", "persona_id").
 		Eq("id", idStr).
 		FindOne(ctx)
 
@@ -288,6 +300,12 @@ func (h *Handler) DeleteDocument(c *gin.Context) {
 		return
 	}
 
+	if h.personaLoader != nil {
+		if err := h.personaLoader.Knowledge().DeleteDocument(ctx, ai.DocumentID(document)); err != nil {
+			h.logger.Warn("Failed to delete document chunks", zap.String("id", idStr), zap.Error(err))
+		}
+	}
+
 	// Delete file
 	if filePath, ok := document["file_path"].(string); ok && filePath != "" {
 		if err := os.Remove(filePath); err != nil && !os.IsNotExist(err) {
@@ -305,6 +323,7 @@ func (h *Handler) DeleteDocument(c *gin.Context) {
 		errors.InternalError(c, err, h.logger)
 		return
 	}
+	h.invalidatePersonaContext(document["persona_id"])
 
 	// Audit log
 	audit.Log(h.mongoClient, userIDStr, string(audit.ActionDelete), "document", idStr, map[string]interface{}{
@@ -331,8 +350,17 @@ func (h *Handler) LinkDocumentToPersona(c *gin.Context) {
 	ctx, cancel := context.WithTimeout(c.Request.Context(), 5*time.Second)
 	defer cancel()
 
+	document, err := h.mongoClient.NewQuery("documents").
+		Select("persona_id").
+		Eq("id", fmt.Sprintf("%d", req.DocumentID)).
+		FindOne(ctx)
+	if err != nil || document == nil {
+		errors.NotFound(c, "document not found")
+		return
+	}
+
 	// Update document with persona_id
-	_, err := h.mongoClient.NewQuery("documents").
+	_, err = h.mongoClient.NewQuery("documents").
 		Eq("id", fmt.Sprintf("%d", req.DocumentID)).
 		UpdateOne(ctx, map[string]interface{}{
 			"persona_id": personaIDInt,
@@ -345,5 +373,9 @@ func (h *Handler) LinkDocumentToPersona(c *gin.Context) {
 		return
 	}
 
+	// The document leaves its old persona's knowledge and joins the new one's
+	h.invalidatePersonaContext(document["persona_id"])
+	h.invalidatePersonaContext(personaIDInt)
+
 	c.JSON(http.StatusOK, gin.H{"message": "document linked to persona"})
 }
@@ -1,6 +1,11 @@
 package handlers
 
 import (
+	"context"
+	"fmt"
+	"strconv"
+	"time"
+
 	"github.com/redis/go-redis/v9"
 	"go.uber.org/zap"
 
@@ -8,6 +13,8 @@ import (
 	"github.com/troikatech/calling-agent/pkg/env"
 	"github.com/troikatech/calling-agent/pkg/logger"
 	"github.com/troikatech/calling-agent/pkg/mongo"
+	"github.com/troikatech/calling-agent/pkg/storage"
+	"github.com/troikatech/calling-agent/pkg/stt"
 )
 
 type Handler struct {
@@ -17,8 +24,14 @@ type Handler struct {
 	logger        *zap.Logger
 	aiManager     *ai.Manager
 	ttsService    *ai.TTSService
+	ttsManager    *ai.TTSManager
+	ttsCache      *ai.TTSCache
 	sttService    *ai.STTService
+	sttManager    *stt.Manager
 	personaLoader *ai.PersonaLoader
+	storage       storage.Driver
+	registry      *sessionRegistry // Live voicebot calls across instances (nil without Redis)
+	ingestWake    chan struct{}    // Signals the document ingestion worker that uploads are queued
 }
 
 func NewHandler(
@@ -27,9 +40,24 @@ func NewHandler(
 	mongoClient *mongo.Client,
 	aiManager *ai.Manager,
 	ttsService *ai.TTSService,
+	ttsManager *ai.TTSManager,
 	sttService *ai.STTService,
+	sttManager *stt.Manager,
 	personaLoader *ai.PersonaLoader,
+	storageDriver storage.Driver,
 ) *Handler {
+	// Rendered prompt audio is cached in Redis; a negative TTL disables the cache
+	var ttsCache *ai.TTSCache
+	if redisClient != nil && cfg.VoicebotTTSCacheHours >= 0 {
+		ttsCache = ai.NewTTSCache(redisClient, time.Duration(cfg.VoicebotTTSCacheHours)*time.Hour, logger.Log)
+	}
+
+	// Live calls are registered in Redis so any instance can find and control them
+	var registry *sessionRegistry
+	if redisClient != nil {
+		registry = newSessionRegistry(redisClient, cfg.VoicebotInstanceID, logger.Log)
+	}
+
 	return &Handler{
 		cfg:           cfg,
 		redisClient:   redisClient,
@@ -37,7 +65,57 @@ func NewHandler(
 		logger:        logger.Log,
 		aiManager:     aiManager,
 		ttsService:    ttsService,
+		ttsManager:    ttsManager,
+		ttsCache:      ttsCache,
 		sttService:    sttService,
+		sttManager:    sttManager,
 		personaLoader: personaLoader,
+		storage:       storageDriver,
+		registry:      registry,
+		ingestWake:    make(chan struct{}, 1),
+	}
+}
+
+// nextID allocates the next numeric ID of a collection from its counter. IDs are stored as strings.
+// A counter that runs into an ID assigned before it existed is first moved past the collection's highest ID.
+func (h *Handler) nextID(ctx context.Context, collection string) (int64, error) {
+	for attempt := 0; attempt < 3; attempt++ {
+		id, err := h.mongoClient.NextSequence(ctx, collection)
+		if err != nil {
+			return 0, err
+		}
+		taken, err := h.mongoClient.NewQuery(collection).
+			In("id", []interface{}{strconv.FormatInt(id, 10), id}).
+			Count(ctx)
+		if err != nil {
+			return 0, fmt.Errorf("failed to check %s ID: %w", collection, err)
+		}
+		if taken == 0 {
+			return id, nil
+		}
+
+		highest, err := h.highestID(ctx, collection)
+		if err != nil {
+			return 0, err
+		}
+		if err := h.mongoClient.RaiseSequence(ctx, collection, highest); err != nil {
+			return 0, err
+		}
+	}
+	return 0, fmt.Errorf("failed to allocate %s ID", collection)
+}
+
+// highestID returns the highest numeric ID in a collection; IDs are stored as strings or numbers
+func (h *Handler) highestID(ctx context.Context, collection string) (int64, error) {
+	records, err := h.mongoClient.NewQuery(collection).Select("id").Find(ctx)
+	if err != nil {
+		return 0, fmt.Errorf("failed to list %s IDs: %w", collection, err)
+	}
+	var highest int64
+	for _, record := range records {
+		if n, err := strconv.ParseInt(fmt.Sprint(record["id"]), 10, 64); err == nil && n > highest {
+			highest = n
+		}
 	}
+	return highest, nil
 }
@@ -2,17 +2,24 @@ package handlers
 
 import (
 	"context"
+	"encoding/json"
 	"fmt"
 	"net/http"
+	"strconv"
 	"time"
 
 	"github.com/gin-gonic/gin"
 	"go.uber.org/zap"
 
+	"github.com/troikatech/calling-agent/pkg/ai"
+	"github.com/troikatech/calling-agent/pkg/audit"
 	"github.com/troikatech/calling-agent/pkg/errors"
 	"github.com/troikatech/calling-agent/pkg/utils"
 )
 
+// personaServerFields are written by the server; clients may send them back with an edited persona
+var personaServerFields = []string{"_id", "id", "version", "created_at", "created_by", "updated_at", "updated_by"}
+
 func (h *Handler) ListPersonas(c *gin.Context) {
 	// Parse pagination
 	pagination := utils.ParsePagination(c)
@@ -59,9 +66,18 @@ func (h *Handler) GetPersona(c *gin.Context) {
 	c.JSON(http.StatusOK, persona)
 }
 
+// CreatePersona validates a new persona and stores it as version 1
 func (h *Handler) CreatePersona(c *gin.Context) {
-	var req map[string]interface{}
-	if err := c.ShouldBindJSON(&req); err != nil {
+	userID, _ := c.Get("user_id")
+	userIDStr := fmt.Sprintf("%v", userID)
+
+	var persona ai.Persona
+	body, err := bindPersona(c, &persona)
+	if err != nil {
+		errors.BadRequest(c, err.Error())
+		return
+	}
+	if err := persona.Validate(); err != nil {
 		errors.BadRequest(c, err.Error())
 		return
 	}
@@ -69,40 +85,355 @@ func (h *Handler) CreatePersona(c *gin.Context) {
 	ctx, cancel := context.WithTimeout(c.Request.Context(), 5*time.Second)
 	defer cancel()
 
-	req["created_at"] = time.Now().Format(time.RFC3339)
-	personaID, err := h.mongoClient.NewQuery("personas").Insert(ctx, req)
+	// Clients may choose the numeric ID; otherwise one is allocated from the personas counter
+	if id, ok := body["id"]; ok && id != nil {
+		persona.ID, err = strconv.ParseInt(fmt.Sprint(id), 10, 64)
+		if err != nil || persona.ID <= 0 {
+			errors.BadRequest(c, "id must be a positive integer")
+			return
+		}
+		existing, err := h.mongoClient.NewQuery("personas").
+			Select("id").
+			In("id", []interface{}{fmt.Sprintf("%d", persona.ID), persona.ID}).
+			FindOne(ctx)
+		if err != nil {
+			errors.InternalError(c, err, h.logger)
+			return
+		}
+		if existing != nil {
+			errors.Conflict(c, fmt.Sprintf("persona %d already exists", persona.ID))
+			return
+		}
+	} else if persona.ID, err = h.nextID(ctx, "personas"); err != nil {
+		errors.InternalError(c, err, h.logger)
+		return
+	}
+
+	persona.Version = 1
+	now := time.Now().Format(time.RFC3339)
+	versionID, err := h.savePersonaVersion(ctx, &persona, userIDStr, now)
 	if err != nil {
 		h.logger.Error("Failed to create persona", zap.Error(err))
 		errors.InternalError(c, err, h.logger)
 		return
 	}
 
-	req["id"] = personaID
-	c.JSON(http.StatusCreated, req)
+	record := persona.Fields()
+	record["id"] = fmt.Sprintf("%d", persona.ID)
+	record["version"] = persona.Version
+	record["created_at"] = now
+	record["created_by"] = userIDStr
+	record["updated_at"] = now
+	if _, err := h.mongoClient.NewQuery("personas").Insert(ctx, record); err != nil {
+		h.mongoClient.NewQuery("persona_versions").Eq("_id", versionID).DeleteOne(ctx)
+		h.logger.Error("Failed to create persona", zap.Error(err))
+		errors.InternalError(c, err, h.logger)
+		return
+	}
+
+	audit.Log(h.mongoClient, userIDStr, string(audit.ActionCreate), "persona", record["id"].(string), map[string]interface{}{
+		"name":    persona.Name,
+		"version": persona.Version,
+	})
+	h.prewarmPersonaTTSAsync(&persona)
+
+	c.JSON(http.StatusCreated, record)
 }
 
+// UpdatePersona applies the fields in the body over the current persona, validates the result and
+// stores it as a new version. Campaigns keep the version they pinned, so live calls are unaffected.
 func (h *Handler) UpdatePersona(c *gin.Context) {
 	id, _ := c.Get("id_int")
-	idStr := fmt.Sprintf("%d", id.(int64))
+	personaID := id.(int64)
+	idStr := fmt.Sprintf("%d", personaID)
+	userID, _ := c.Get("user_id")
+	userIDStr := fmt.Sprintf("%v", userID)
+
+	ctx, cancel := context.WithTimeout(c.Request.Context(), 5*time.Second)
+	defer cancel()
+
+	record, err := h.mongoClient.NewQuery("personas").
+		Select("*").
+		Eq("id", idStr).
+		FindOne(ctx)
+	if err != nil || record == nil {
+		errors.NotFound(c, "persona not found")
+		return
+	}
+	current := ai.PersonaFromRecord(record)
 
-	var req map[string]interface{}
-	if err := c.ShouldBindJSON(&req); err != nil {
+	// Fields the body leaves out keep their stored values
+	updated := ai.PersonaFromRecord(record)
+	if _, err := bindPersona(c, updated); err != nil {
+		errors.BadRequest(c, err.Error())
+		return
+	}
+	if err := updated.Validate(); err != nil {
 		errors.BadRequest(c, err.Error())
 		return
 	}
 
+	// A persona answering from documents that could not be read would improvise; keep it offline
+	if updated.Status != current.Status && (updated.Status == "active" || updated.Status == "live") {
+		if h.refuseFailedPersonaDocuments(ctx, c, idStr) {
+			return
+		}
+	}
+
+	updated.ID = personaID
+	updated.Version = current.Version + 1
+	now := time.Now().Format(time.RFC3339)
+	versionID, err := h.savePersonaVersion(ctx, updated, userIDStr, now)
+	if err != nil {
+		h.logger.Error("Failed to update persona", zap.Error(err))
+		errors.InternalError(c, err, h.logger)
+		return
+	}
+
+	fields := updated.Fields()
+	fields["version"] = updated.Version
+	fields["updated_at"] = now
+	fields["updated_by"] = userIDStr
+
+	// Only the version this edit started from may be replaced, so concurrent edits cannot both win
+	query := h.mongoClient.NewQuery("personas").Eq("id", idStr)
+	if current.Version == 0 {
+		query = query.IsNull("version")
+	} else {
+		query = query.Eq("version", current.Version)
+	}
+	result, err := query.UpdateOne(ctx, fields)
+	if err != nil || result.MatchedCount == 0 {
+		// The copy must not outlive a version that never became current
+		h.mongoClient.NewQuery("persona_versions").Eq("_id", versionID).DeleteOne(ctx)
+		if err != nil {
+			h.logger.Error("Failed to update persona", zap.Error(err))
+			errors.InternalError(c, err, h.logger)
+			return
+		}
+		errors.Conflict(c, "persona was changed by another request; reload it and try again")
+		return
+	}
+	h.invalidatePersonaContext(personaID)
+
+	audit.Log(h.mongoClient, userIDStr, string(audit.ActionUpdate), "persona", idStr, map[string]interface{}{
+		"version": updated.Version,
+	})
+
+	// Greeting or voice may have changed; render the new audio before the next call needs it
+	h.prewarmPersonaTTSAsync(updated)
+
+	c.JSON(http.StatusOK, gin.H{"message": "persona updated", "version": updated.Version})
+}
+
+// ListPersonaVersions lists the versions of a persona, newest first
+func (h *Handler) ListPersonaVersions(c *gin.Context) {
+	id, _ := c.Get("id_int")
+	pagination := utils.ParsePagination(c)
+
+	ctx, cancel := context.WithTimeout(c.Request.Context(), 5*time.Second)
+	defer cancel()
+
+	versions, err := h.mongoClient.NewQuery("persona_versions").
+		Select("persona_id", "version", "name", "status", "created_at", "created_by").
+		Eq("persona_id", id.(int64)).
+		Sort("version", false).
+		Limit(int64(pagination.Limit)).
+		Find(ctx)
+	if err != nil {
+		h.logger.Error("Failed to fetch persona versions", zap.Error(err))
+		errors.InternalError(c, err, h.logger)
+		return
+	}
+
+	c.JSON(http.StatusOK, utils.PaginatedResponse{
+		Data:  versions,
+		Page:  pagination.Page,
+		Limit: pagination.Limit,
+		Count: len(versions),
+	})
+}
+
+// GetPersonaVersion returns one immutable version of a persona
+func (h *Handler) GetPersonaVersion(c *gin.Context) {
+	id, _ := c.Get("id_int")
+	version, err := strconv.ParseInt(c.Param("version"), 10, 64)
+	if err != nil || version <= 0 {
+		errors.BadRequest(c, "version must be a positive integer")
+		return
+	}
+
 	ctx, cancel := context.WithTimeout(c.Request.Context(), 5*time.Second)
 	defer cancel()
 
-	_, err := h.mongoClient.NewQuery("personas").
+	snapshot, err := h.mongoClient.NewQuery("persona_versions").
+		Select("*").
+		Eq("persona_id", id.(int64)).
+		Eq("version", version).
+		FindOne(ctx)
+	if err != nil || snapshot == nil {
+		errors.NotFound(c, "persona version not found")
+		return
+	}
+
+	c.JSON(http.StatusOK, snapshot)
+}
+
+// PrewarmPersonaTTS renders the persona's greeting, reprompt and closing lines into the TTS cache
+// so calls start speaking without waiting for synthesis
+func (h *Handler) PrewarmPersonaTTS(c *gin.Context) {
+	id, _ := c.Get("id_int")
+	idStr := fmt.Sprintf("%d", id.(int64))
+
+	if !h.ttsAvailable() {
+		errors.ErrorResponse(c, http.StatusServiceUnavailable, "Service Unavailable", "TTS is not configured")
+		return
+	}
+	if h.ttsCache == nil {
+		errors.ErrorResponse(c, http.StatusServiceUnavailable, "Service Unavailable", "TTS cache is disabled")
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(c.Request.Context(), 60*time.Second)
+	defer cancel()
+
+	record, err := h.mongoClient.NewQuery("personas").
+		Select("*").
 		Eq("id", idStr).
-		UpdateOne(ctx, req)
+		FindOne(ctx)
+
+	if err != nil || record == nil {
+		errors.NotFound(c, "persona not found")
+		return
+	}
+
+	results := h.prewarmPersonaTTS(ctx, ai.PersonaFromRecord(record))
+	rendered := 0
+	for _, result := range results {
+		if _, failed := result["error"]; !failed {
+			rendered++
+		}
+	}
 
+	status := http.StatusOK
+	if rendered < len(results) {
+		status = http.StatusBadGateway
+	}
+	c.JSON(status, gin.H{
+		"persona_id": idStr,
+		"prompts":    results,
+	})
+}
+
+// invalidatePersonaContext drops the cached context of a persona so new calls load the change;
+// calls already in progress keep the context they started with
+func (h *Handler) invalidatePersonaContext(personaID interface{}) {
+	if h.personaLoader == nil || personaID == nil {
+		return
+	}
+	if id, err := strconv.ParseInt(fmt.Sprint(personaID), 10, 64); err == nil {
+		h.personaLoader.InvalidatePersona(id)
+	}
+}
+
+// bindPersona decodes the request body onto persona, rejecting fields the persona model does not have.
+// It returns the body as sent, including any server fields.
+func bindPersona(c *gin.Context, persona *ai.Persona) (map[string]interface{}, error) {
+	var body map[string]interface{}
+	if err := c.ShouldBindJSON(&body); err != nil {
+		return nil, err
+	}
+	content := make(map[string]interface{}, len(body))
+	for field, value := range body {
+		content[field] = value
+	}
+	for _, field := range personaServerFields {
+		delete(content, field)
+	}
+	data, err := json.Marshal(content)
+	if err != nil {
+		return nil, err
+	}
+	return body, ai.DecodePersona(data, persona)
+}
+
+// savePersonaVersion stores the immutable copy of a persona version and returns its document ID
+func (h *Handler) savePersonaVersion(ctx context.Context, persona *ai.Persona, userID, createdAt string) (interface{}, error) {
+	snapshot := persona.Fields()
+	snapshot["persona_id"] = persona.ID
+	snapshot["version"] = persona.Version
+	snapshot["created_at"] = createdAt
+	snapshot["created_by"] = userID
+
+	versionID, err := h.mongoClient.NewQuery("persona_versions").Insert(ctx, snapshot)
+	if err != nil {
+		return nil, fmt.Errorf("failed to save persona version: %w", err)
+	}
+	return versionID, nil
+}
+
+// pinPersonaVersion returns the persona version a campaign should run with, writing the HTTP error and
+// returning false when there is none. requested must exist; 0 pins the persona's current version,
+// giving a persona written before versioning its first version.
+func (h *Handler) pinPersonaVersion(ctx context.Context, c *gin.Context, personaID, requested int64) (int64, bool) {
+	idStr := fmt.Sprintf("%d", personaID)
+	record, err := h.mongoClient.NewQuery("personas").
+		Select("*").
+		In("id", []interface{}{idStr, personaID}).
+		FindOne(ctx)
 	if err != nil {
-		h.logger.Error("Failed to update persona", zap.Error(err))
 		errors.InternalError(c, err, h.logger)
-		return
+		return 0, false
+	}
+	if record == nil {
+		errors.BadRequest(c, fmt.Sprintf("persona %d not found", personaID))
+		return 0, false
+	}
+	current := ai.PersonaFromRecord(record)
+
+	if requested > 0 {
+		if requested == current.Version {
+			return requested, true
+		}
+		count, err := h.mongoClient.NewQuery("persona_versions").
+			Eq("persona_id", personaID).
+			Eq("version", requested).
+			Count(ctx)
+		if err != nil {
+			errors.InternalError(c, err, h.logger)
+			return 0, false
+		}
+		if count == 0 {
+			errors.BadRequest(c, fmt.Sprintf("persona %d has no version %d", personaID, requested))
+			return 0, false
+		}
+		return requested, true
+	}
+	if current.Version > 0 {
+		return current.Version, true
 	}
 
-	c.JSON(http.StatusOK, gin.H{"message": "persona updated"})
+	// Unversioned persona: its current content becomes version 1
+	current.ID = personaID
+	current.Version = 1
+	userID, _ := c.Get("user_id")
+	versionID, err := h.savePersonaVersion(ctx, current, fmt.Sprintf("%v", userID), time.Now().Format(time.RFC3339))
+	if err != nil {
+		errors.InternalError(c, err, h.logger)
+		return 0, false
+	}
+	result, err := h.mongoClient.NewQuery("personas").
+		Eq("_id", record["_id"]).
+		IsNull("version").
+		UpdateOne(ctx, map[string]interface{}{"version": current.Version})
+	if err != nil || result.MatchedCount == 0 {
+		// Versioned concurrently; retry against the version that won
+		h.mongoClient.NewQuery("persona_versions").Eq("_id", versionID).DeleteOne(ctx)
+		if err != nil {
+			errors.InternalError(c, err, h.logger)
+			return 0, false
+		}
+		return h.pinPersonaVersion(ctx, c, personaID, 0)
+	}
+	return current.Version, true
 }
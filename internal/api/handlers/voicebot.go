@@ -1,11 +1,9 @@
 package handlers
 
 import (
-	"bytes"
 	"context"
 	"encoding/json"
 	"fmt"
-	"io"
 	"net/http"
 	"net/url"
 	"strconv"
@@ -35,13 +33,73 @@ type VoiceSession struct {
 	Conn                *websocket.Conn
 	ConversationHistory []map[string]interface{}
 	AudioBuffer         *AudioBuffer
+	VAD                 *audio.VAD // Voice activity detector deciding when an utterance has ended
 	GreetingSent        bool
 	IsActive            bool
 	Mu                  sync.RWMutex
 	CancelCtx           context.CancelFunc
-	ProcessingMu        sync.Mutex             // Prevents concurrent STT→AI→TTS processing
-	SampleRate          int                    // Audio sample rate (default 16000)
-	CustomParameters    map[string]interface{} // Custom parameters from start event
+	ProcessingMu        sync.Mutex                // Prevents concurrent STT→AI→TTS processing
+	Format              audio.Format              // Negotiated media stream codec and sample rate
+	Inbound             *audio.Resampler          // Converts caller audio to 16kHz, keeping filter state across media frames
+	CustomParameters    map[string]interface{}    // Custom parameters from start event
+	STTStream           stt.Stream                // Live STT stream (nil when falling back to batch STT)
+	sttGeneration       int                       // Bumped when the live stream is stopped, so one still opening is discarded
+	InterimTranscript   string                    // Latest interim transcript from the live stream
+	PendingTranscript   []string                  // Final transcript segments of the utterance in progress
+	PendingConfidence   []float64                 // STT confidence of each pending transcript segment
+	Playback            *playback                 // Outbound audio currently being played (nil when the bot is silent)
+	Outbound            *audio.OutboundScheduler  // Paces outbound frames and tracks what the caller has heard
+	PendingMarks        map[string]chan time.Time // Marks sent to Exotel and not yet echoed back, by name
+	markSeq             int                       // Makes mark names unique within the call
+	Digits              string                    // DTMF digits pressed during the stream, in order
+	KeypressMap         map[string]string         // Digit → keypress action, resolved on the first keypress
+	TransferNumbers     []string                  // Agent numbers for warm transfer, resolved on first use
+	Transferring        bool                      // Set once the call is being handed to a human agent
+	AMD                 *amdState                 // Answering-machine detection (nil when disabled or not outbound); set once by startAMD
+	LastActivity        time.Time                 // Last caller speech, keypress or bot activity, for the inactivity timer
+	Reprompts           int                       // Silence reprompts since the caller last spoke
+	Inactivity          *inactivitySettings       // Silence reprompt settings, resolved when the greeting starts
+	Voice               *voiceSettings            // TTS provider and voice, resolved on first synthesis
+	Persona             *ai.PersonaContext        // Persona data and document index, loaded once per call (nil when the call has none)
+	CallContext         map[string]interface{}    // Campaign, contact and pinned persona of the call, loaded with Persona
+	Recorder            *audio.CallRecorder       // Caller (left) and bot (right) audio at the stream rate (nil when not recording)
+	StartedAt           time.Time                 // When the media stream started; call_turns offsets are relative to it
+	SpeechStartedAt     time.Time                 // VAD start of the caller utterance in progress
+	SpeechEndedAt       time.Time                 // VAD end of the caller utterance awaiting its transcript
+	turnSeq             int                       // Next call_turns sequence number
+	contextLoad         sync.Once                 // Loads CallContext and Persona; callers block until the load is done
+	WriteMu             sync.Mutex                // Serialises writes to Conn (gorilla/websocket allows one concurrent writer)
+}
+
+// playback tracks outbound audio being streamed to the caller so it can be interrupted
+type playback struct {
+	cancel context.CancelFunc
+}
+
+// playbackResult describes how much of an outbound clip the caller heard
+type playbackResult struct {
+	PlayedMs    int       // Audio actually played before completion or interruption
+	TotalMs     int       // Length of the clip
+	Interrupted bool      // True if the caller barged in before the clip finished
+	StartedAt   time.Time // When the first frame started playing (zero if nothing was sent)
+	PlayedAt    time.Time // When Exotel confirmed the clip finished playing (zero if interrupted)
+}
+
+// markAckGrace is how long to wait for a mark echo beyond the audio still buffered at Exotel
+const markAckGrace = 2 * time.Second
+
+// writeJSON sends a JSON event to Exotel on the session's socket
+func (s *VoiceSession) writeJSON(v interface{}) error {
+	s.Mu.RLock()
+	conn := s.Conn
+	s.Mu.RUnlock()
+	if conn == nil {
+		return fmt.Errorf("no connection for session %s", s.CallSid)
+	}
+
+	s.WriteMu.Lock()
+	defer s.WriteMu.Unlock()
+	return conn.WriteJSON(v)
 }
 
 // ExotelEvent represents the base structure for Exotel WebSocket events
@@ -55,6 +113,10 @@ type StartEvent struct {
 	Event            string                 `json:"event"`
 	StreamSid        string                 `json:"stream_sid"`
 	CustomParameters map[string]interface{} `json:"custom_parameters,omitempty"`
+	MediaFormat      map[string]interface{} `json:"media_format,omitempty"` // encoding, sample_rate, bit_rate
+	Start            struct {
+		MediaFormat map[string]interface{} `json:"media_format,omitempty"`
+	} `json:"start"`
 }
 
 // MediaEvent represents Exotel "media" event with base64-encoded PCM audio
@@ -66,6 +128,15 @@ type MediaEvent struct {
 	} `json:"media"`
 }
 
+// MarkEvent represents Exotel "mark" event, echoed back once all audio sent before the mark has played
+type MarkEvent struct {
+	Event     string `json:"event"`
+	StreamSid string `json:"stream_sid"`
+	Mark      struct {
+		Name string `json:"name"`
+	} `json:"mark"`
+}
+
 // StopEvent represents Exotel "stop" event
 type StopEvent struct {
 	Event     string `json:"event"`
@@ -77,7 +148,7 @@ type AudioBuffer struct {
 	mu          sync.Mutex
 	chunks      [][]byte
 	totalSize   int
-	maxSize     int // Maximum buffer size before processing (e.g., 1 second of audio)
+	maxSize     int // Maximum buffer size before processing is forced (longest utterance we accept)
 	lastProcess time.Time
 	sampleRate  int // Sample rate for this buffer (default 16000)
 }
@@ -114,13 +185,29 @@ func (ab *AudioBuffer) GetData() []byte {
 	return result
 }
 
-// IsReady checks if buffer is ready for processing
-// Uses silence detection: process if buffer is full OR 1.5 seconds of silence (no new data)
+// IsReady checks if buffer is full and must be processed even though the caller is still talking
+// End of utterance is decided by the session VAD, not by this buffer
 func (ab *AudioBuffer) IsReady() bool {
 	ab.mu.Lock()
 	defer ab.mu.Unlock()
-	// Process if buffer is full or 1.5 seconds have passed since last process
-	return ab.totalSize >= ab.maxSize || time.Since(ab.lastProcess) >= 1500*time.Millisecond
+	return ab.totalSize >= ab.maxSize
+}
+
+// TrimTo drops the oldest audio so that at most maxBytes remain
+// Used to keep a short pre-roll while no speech is detected
+func (ab *AudioBuffer) TrimTo(maxBytes int) {
+	ab.mu.Lock()
+	defer ab.mu.Unlock()
+	if ab.totalSize <= maxBytes {
+		return
+	}
+	data := make([]byte, 0, ab.totalSize)
+	for _, chunk := range ab.chunks {
+		data = append(data, chunk...)
+	}
+	tail := data[len(data)-maxBytes:]
+	ab.chunks = [][]byte{tail}
+	ab.totalSize = len(tail)
 }
 
 // Clear clears the buffer
@@ -132,12 +219,27 @@ func (ab *AudioBuffer) Clear() {
 	ab.lastProcess = time.Now()
 }
 
+// maxUtteranceBytes caps a single buffered utterance (15 seconds of 16kHz PCM16)
+const maxUtteranceBytes = 15 * 16000 * 2
+
+// bytesPerMs16k is the size of one millisecond of 16kHz PCM16 mono audio
+const bytesPerMs16k = 32
+
+// frameDuration is the length of one outbound media frame
+const frameDuration = 20 * time.Millisecond
+
+// playbackLeadFrames is the default for how far outbound audio may run ahead of playback (200ms)
+const playbackLeadFrames = 10
+
+// vadPreRollBytes is the audio kept before detected speech so word onsets are not clipped (300ms at 16kHz)
+const vadPreRollBytes = 300 * 16 * 2
+
 // sessions stores active voice sessions per call_sid
 var sessions = make(map[string]*VoiceSession)
 var sessionsMu sync.RWMutex
 
 // getOrCreateSession gets or creates a voice session for call_sid
-func getOrCreateSession(callSid, streamSid, from, to string, conn *websocket.Conn, sampleRate int) *VoiceSession {
+func getOrCreateSession(callSid, streamSid, from, to string, conn *websocket.Conn, format audio.Format) *VoiceSession {
 	sessionsMu.Lock()
 	defer sessionsMu.Unlock()
 
@@ -145,15 +247,12 @@ func getOrCreateSession(callSid, streamSid, from, to string, conn *websocket.Con
 		session.Mu.Lock()
 		session.StreamSid = streamSid
 		session.Conn = conn
-		session.SampleRate = sampleRate
+		session.Format = format
 		session.Mu.Unlock()
 		return session
 	}
 
 	_, cancel := context.WithCancel(context.Background())
-	if sampleRate == 0 {
-		sampleRate = 16000 // Default 16kHz
-	}
 	session := &VoiceSession{
 		CallSid:             callSid,
 		StreamSid:           streamSid,
@@ -161,11 +260,14 @@ func getOrCreateSession(callSid, streamSid, from, to string, conn *websocket.Con
 		To:                  to,
 		Conn:                conn,
 		ConversationHistory: make([]map[string]interface{}, 0),
-		AudioBuffer:         NewAudioBuffer(32*1024, sampleRate), // 32KB buffer (~1 second at 16kHz, 2 bytes per sample)
+		AudioBuffer:         NewAudioBuffer(maxUtteranceBytes, 16000), // Media is resampled to 16kHz on arrival
+		VAD:                 audio.NewVAD(audio.DefaultVADConfig(), 16000),
+		Outbound:            audio.NewOutboundScheduler(playbackLeadFrames * frameDuration),
 		GreetingSent:        false,
 		IsActive:            true,
 		CancelCtx:           cancel,
-		SampleRate:          sampleRate,
+		Format:              format,
+		StartedAt:           time.Now(),
 		CustomParameters:    make(map[string]interface{}),
 	}
 
@@ -188,6 +290,14 @@ func removeSession(callSid string) {
 		if session.CancelCtx != nil {
 			session.CancelCtx()
 		}
+		session.Mu.Lock()
+		sttStream := session.STTStream
+		session.STTStream = nil
+		session.sttGeneration++
+		session.Mu.Unlock()
+		if sttStream != nil {
+			go sttStream.Close()
+		}
 		delete(sessions, callSid)
 	}
 }
@@ -753,25 +863,14 @@ func (h *Handler) VoicebotWebSocket(c *gin.Context) {
 	from := c.Query("from")
 	to := c.Query("to")
 
-	// Get sample-rate from query parameter - 16kHz is mandatory
-	sampleRateStr := c.Query("sample-rate")
-	sampleRate := 16000 // Default 16kHz
-	if sampleRateStr != "" {
-		if sr, err := strconv.Atoi(sampleRateStr); err == nil && sr > 0 {
-			sampleRate = sr
-			h.logger.Info("Sample rate from query parameter",
-				zap.Int("sample_rate", sampleRate),
-				zap.String("call_sid", callSid),
-			)
-		}
-	}
-	// Enforce 16kHz as mandatory requirement
-	if sampleRate != 16000 {
-		h.logger.Warn("Invalid sample rate, enforcing 16kHz",
-			zap.Int("requested", sampleRate),
+	// Stream format from the sample-rate (and optional encoding) query parameters;
+	// the start event's media_format overrides it once it arrives
+	format, err := streamFormatFromQuery(c.Query("sample-rate"), c.Query("encoding"))
+	if err != nil {
+		h.logger.Warn("Ignoring invalid stream format in query",
+			zap.Error(err),
 			zap.String("call_sid", callSid),
 		)
-		sampleRate = 16000
 	}
 
 	// Allow WebSocket connection even without call_sid
@@ -806,7 +905,7 @@ func (h *Handler) VoicebotWebSocket(c *gin.Context) {
 		zap.String("call_sid", callSid),
 		logger.MaskPhoneIfPresent("from", from),
 		logger.MaskPhoneIfPresent("to", to),
-		zap.Int("sample_rate", sampleRate),
+		zap.Stringer("format", format),
 		zap.Bool("call_sid_from_query", callSid != "" && !strings.HasPrefix(callSid, "pending-")),
 	)
 
@@ -817,7 +916,7 @@ func (h *Handler) VoicebotWebSocket(c *gin.Context) {
 
 	// Handle WebSocket messages - Exotel sends JSON events, not binary
 	// Pass a pointer to callSid so it can be updated when we get it from start event
-	h.handleVoicebotConnection(conn, &callSid, from, to, sampleRate)
+	h.handleVoicebotConnection(conn, &callSid, from, to, format)
 }
 
 // initializeCallRecord creates or updates call record when Voicebot session starts
@@ -854,7 +953,7 @@ func (h *Handler) initializeCallRecord(callSid, from, to string) {
 
 // handleVoicebotConnection manages the WebSocket connection lifecycle
 // callSidPtr is a pointer so we can update it when we get the real call_sid from start event
-func (h *Handler) handleVoicebotConnection(conn *websocket.Conn, callSidPtr *string, from, to string, sampleRate int) {
+func (h *Handler) handleVoicebotConnection(conn *websocket.Conn, callSidPtr *string, from, to string, format audio.Format) {
 	// Set read deadline to detect connection closure
 	conn.SetReadDeadline(time.Now().Add(60 * time.Second))
 	conn.SetPongHandler(func(string) error {
@@ -883,7 +982,7 @@ func (h *Handler) handleVoicebotConnection(conn *websocket.Conn, callSidPtr *str
 
 			// Exotel sends JSON events as text messages
 			if messageType == websocket.TextMessage {
-				h.handleExotelEvent(conn, callSidPtr, from, to, message, sampleRate)
+				h.handleExotelEvent(conn, callSidPtr, from, to, message, format)
 			} else if messageType == websocket.PingMessage {
 				conn.WriteMessage(websocket.PongMessage, nil)
 			}
@@ -897,15 +996,20 @@ func (h *Handler) handleVoicebotConnection(conn *websocket.Conn, callSidPtr *str
 			h.logger.Info("Voicebot WebSocket connection closed",
 				zap.String("call_sid", *callSidPtr),
 			)
-			// Clean up session
+			// Clean up session; the recording is saved here if the stream ended without a stop event
+			if session := getSession(*callSidPtr); session != nil {
+				go h.saveRecording(session)
+			}
 			removeSession(*callSidPtr)
+			h.unregisterSession(*callSidPtr)
 			if *callSidPtr != "" && !strings.HasPrefix(*callSidPtr, "pending-") {
 				h.finalizeCallRecord(*callSidPtr)
 			}
 			return
 
 		case <-pingTicker.C:
-			if err := conn.WriteMessage(websocket.PingMessage, nil); err != nil {
+			// WriteControl is safe to call concurrently with the audio writers
+			if err := conn.WriteControl(websocket.PingMessage, nil, time.Now().Add(10*time.Second)); err != nil {
 				h.logger.Error("Failed to send ping", zap.Error(err))
 				return
 			}
@@ -913,9 +1017,9 @@ func (h *Handler) handleVoicebotConnection(conn *websocket.Conn, callSidPtr *str
 	}
 }
 
-// handleExotelEvent processes Exotel JSON events (start, media, stop, clear)
+// handleExotelEvent processes Exotel JSON events (start, media, stop, clear, mark, dtmf)
 // callSidPtr is a pointer so we can update it when we get the real call_sid from start event
-func (h *Handler) handleExotelEvent(conn *websocket.Conn, callSidPtr *string, from, to string, message []byte, sampleRate int) {
+func (h *Handler) handleExotelEvent(conn *websocket.Conn, callSidPtr *string, from, to string, message []byte, format audio.Format) {
 	var event ExotelEvent
 	if err := json.Unmarshal(message, &event); err != nil {
 		h.logger.Warn("Failed to parse Exotel event", zap.Error(err), zap.String("raw", string(message)))
@@ -930,13 +1034,17 @@ func (h *Handler) handleExotelEvent(conn *websocket.Conn, callSidPtr *string, fr
 
 	switch event.Event {
 	case "start":
-		h.handleStartEvent(conn, callSidPtr, from, to, message, sampleRate)
+		h.handleStartEvent(conn, callSidPtr, from, to, message, format)
 	case "media":
 		h.handleMediaEvent(conn, *callSidPtr, message)
 	case "stop":
 		h.handleStopEvent(*callSidPtr, message)
 	case "clear":
 		h.handleClearEvent(*callSidPtr, message)
+	case "mark":
+		h.handleMarkEvent(*callSidPtr, message)
+	case "dtmf":
+		h.handleDTMFEvent(*callSidPtr, message)
 	default:
 		h.logger.Debug("Unknown Exotel event", zap.String("event", event.Event))
 	}
@@ -945,7 +1053,8 @@ func (h *Handler) handleExotelEvent(conn *websocket.Conn, callSidPtr *string, fr
 // handleStartEvent processes Exotel "start" event
 // On start: create session, extract custom_parameters, trigger greeting TTS
 // callSidPtr is a pointer so we can update it if we get call_sid from custom_parameters
-func (h *Handler) handleStartEvent(conn *websocket.Conn, callSidPtr *string, from, to string, message []byte, sampleRate int) {
+// format is the stream format from the connection's query; the start event's media_format wins
+func (h *Handler) handleStartEvent(conn *websocket.Conn, callSidPtr *string, from, to string, message []byte, format audio.Format) {
 	var startEvent StartEvent
 	if err := json.Unmarshal(message, &startEvent); err != nil {
 		h.logger.Warn("Failed to parse start event", zap.Error(err))
@@ -976,8 +1085,10 @@ func (h *Handler) handleStartEvent(conn *websocket.Conn, callSidPtr *string, fro
 		}
 	}
 
+	format = startEvent.streamFormat(format)
+
 	// Create or get session
-	session := getOrCreateSession(*callSidPtr, startEvent.StreamSid, from, to, conn, sampleRate)
+	session := getOrCreateSession(*callSidPtr, startEvent.StreamSid, from, to, conn, format)
 
 	// Store custom_parameters in session
 	session.Mu.Lock()
@@ -989,12 +1100,28 @@ func (h *Handler) handleStartEvent(conn *websocket.Conn, callSidPtr *string, fro
 	// Extract and store custom_parameters
 	if startEvent.CustomParameters != nil {
 		session.CustomParameters = startEvent.CustomParameters
-		session.SampleRate = sampleRate
 	}
 
+	session.Inbound = audio.NewResampler(format.SampleRate, 16000)
+	session.Recorder = h.newCallRecorder(format)
+
+	// Endpointing thresholds are tunable per campaign through custom_parameters
+	session.VAD = audio.NewVAD(vadConfigFromParams(session.CustomParameters), 16000)
+	if h.cfg.VoicebotPlaybackLeadMs > 0 {
+		session.Outbound = audio.NewOutboundScheduler(time.Duration(h.cfg.VoicebotPlaybackLeadMs) * time.Millisecond)
+	}
+	amdRequested := getBoolFromMap(session.CustomParameters, "amd_enabled", h.cfg.VoicebotAMDEnabled)
+
 	session.GreetingSent = true
 	session.Mu.Unlock()
 
+	// Other instances reach the call through the registry from here on
+	h.registerSession(session)
+
+	// Call context, persona and documents are loaded once, off the reader goroutine so media keeps
+	// flowing; the greeting and the first turn wait for the load, later turns only search the loaded index
+	go h.loadSessionContext(session)
+
 	// Log custom_parameters for debugging
 	if len(startEvent.CustomParameters) > 0 {
 		customParamsBytes, _ := json.Marshal(startEvent.CustomParameters)
@@ -1014,24 +1141,46 @@ func (h *Handler) handleStartEvent(conn *websocket.Conn, callSidPtr *string, fro
 		zap.String("call_sid", *callSidPtr),
 		zap.String("stream_sid", startEvent.StreamSid),
 		zap.Any("custom_parameters", startEvent.CustomParameters),
-		zap.Int("sample_rate", sampleRate),
+		zap.Stringer("format", format),
 	)
 
+	// Open the live STT stream off the reader goroutine; until it is up, media is buffered for batch STT
+	// and the buffered audio is forwarded once it opens. Batch STT is used if opening fails
+	go h.startSTTStream(session)
+
+	// Outbound calls with answering-machine detection only greet once a person has answered;
+	// the direction comes with the call context, so startAMD decides whether detection applies
+	if amdRequested {
+		go h.startAMD(session)
+		return
+	}
+
 	// Send greeting TTS in a goroutine
 	go h.sendGreeting(session)
 }
 
+// greetingText returns the call's greeting: custom_parameters greeting_text, then the persona's, then the default
+func (h *Handler) greetingText(session *VoiceSession) string {
+	greetingText := defaultGreetingText
+	if persona := h.sessionPersona(session); persona != nil && persona.GreetingText != "" {
+		greetingText = persona.GreetingText
+	}
+	session.Mu.RLock()
+	defer session.Mu.RUnlock()
+	if gt := getStringFromMap(session.CustomParameters, "greeting_text", ""); gt != "" {
+		greetingText = gt
+	}
+	return greetingText
+}
+
 // sendGreeting sends TTS greeting to Exotel in chunked PCM format
 func (h *Handler) sendGreeting(session *VoiceSession) {
-	// Get greeting text from custom_parameters or use default
-	greetingText := "Hello! How can I help you today?"
-	if session.CustomParameters != nil {
-		if gt, ok := session.CustomParameters["greeting_text"].(string); ok && gt != "" {
-			greetingText = gt
-		}
-	}
+	// The conversation starts here, and so does listening for a silent caller
+	go h.watchInactivity(session)
+
+	greetingText := h.greetingText(session)
 
-	if !h.cfg.FeatureAI || h.cfg.OpenAIApiKey == "" {
+	if !h.ttsAvailable() {
 		// Fallback: send text response
 		h.sendTextResponse(session, greetingText)
 		return
@@ -1040,41 +1189,23 @@ func (h *Handler) sendGreeting(session *VoiceSession) {
 	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
 	defer cancel()
 
-	// Get voice from custom_parameters or use default
-	voice := "shimmer" // Default: female, super natural Hindi
-	if session.CustomParameters != nil {
-		if v, ok := session.CustomParameters["voice_id"].(string); ok && v != "" {
-			voice = v
-		}
-	}
-
-	// CRITICAL FIX: Use OpenAI TTS with PCM16 format
-	openAITTS := ai.NewOpenAITTSService(h.cfg.OpenAIApiKey, 10*time.Second, h.logger)
-	ttsReq := &ai.OpenAITTSRequest{
-		Text:   greetingText,
-		Model:  "tts-1-hd", // Best quality
-		Voice:  voice,
-		Format: "pcm", // Request raw PCM16 format (24kHz output)
-		Speed:  1.0,
-	}
-
-	// Get PCM16 audio (24kHz from OpenAI)
-	pcm24k, err := openAITTS.TextToSpeechPCM(ctx, ttsReq)
+	pcm16k, err := h.synthesizePrompt(ctx, session, greetingText)
 	if err != nil {
-		h.logger.Warn("OpenAI TTS greeting failed", zap.Error(err))
+		h.logger.Warn("TTS greeting failed", zap.Error(err))
 		h.sendTextResponse(session, greetingText)
 		return
 	}
 
-	// Resample 24kHz → 16kHz for Exotel
-	pcm16k := h.resample24kTo16k(pcm24k)
-
-	// Stream PCM in 640-byte chunks (20ms frames at 16kHz)
-	h.streamPCMAudio(session, pcm16k, "greeting_done")
+	// Stream PCM in 20ms frames in the negotiated format
+	result := h.streamPCMAudio(session, pcm16k, "greeting_done")
+	heard := greetingText
+	if result.Interrupted {
+		heard = truncateAtFraction(greetingText, result.playedFraction())
+	}
+	h.recordTurn(session, botTurn(turnGreeting, heard, greetingText, result, turnLatency{}))
 }
 
-// handleMediaEvent processes Exotel "media" event with base64-encoded G.711 μ-law audio
-// CRITICAL FIX: Exotel sends G.711 μ-law (8kHz), NOT PCM16
+// handleMediaEvent processes Exotel "media" event with base64-encoded audio in the negotiated format
 func (h *Handler) handleMediaEvent(conn *websocket.Conn, callSid string, message []byte) {
 	var mediaEvent MediaEvent
 	if err := json.Unmarshal(message, &mediaEvent); err != nil {
@@ -1088,25 +1219,102 @@ func (h *Handler) handleMediaEvent(conn *websocket.Conn, callSid string, message
 		return
 	}
 
-	// CRITICAL FIX: Exotel sends G.711 μ-law (8-bit, 8kHz), NOT PCM16
-	// Step 1: Decode base64 to get μ-law bytes
-	muLawData, err := audio.DecodeBase64PCM(mediaEvent.Media.Payload)
+	// Step 1: Decode base64 to get the encoded frame
+	payload, err := audio.DecodeBase64PCM(mediaEvent.Media.Payload)
 	if err != nil {
-		h.logger.Warn("Failed to decode base64 μ-law", zap.Error(err))
+		h.logger.Warn("Failed to decode base64 media payload", zap.Error(err))
+		return
+	}
+
+	// Step 2: Decode μ-law/A-law to PCM16 at the stream rate
+	format := session.streamFormat()
+	pcm := format.Decode(payload)
+
+	session.Mu.RLock()
+	recorder := session.Recorder
+	session.Mu.RUnlock()
+	if recorder != nil {
+		recorder.WriteCaller(pcm)
+	}
+
+	// Step 3: Resample to 16kHz (VAD, AMD and STT all run at 16kHz)
+	var pcm16k []byte
+	if session.Inbound != nil {
+		pcm16k = session.Inbound.Process(pcm)
+	} else {
+		pcm16k = audio.Resample(pcm, format.SampleRate, 16000)
+	}
+
+	// Until answering-machine detection says a person picked up, audio only goes to the detector
+	if session.amd() != nil && h.runAMD(session, pcm16k) {
 		return
 	}
 
-	// Step 2: Decode μ-law to PCM16 (8kHz)
-	pcm8k := audio.DecodeMuLawToPCM16(muLawData)
+	// While a non-interruptible message is playing the caller is not listened to,
+	// until Exotel confirms playback actually finished
+	if !bargeInEnabled(session) && session.awaitingPlayback() {
+		session.VAD.Reset()
+		return
+	}
 
-	// Step 3: Resample 8kHz → 16kHz (Deepgram requires 16kHz)
-	pcm16k := audio.Resample8kTo16k(pcm8k)
+	// Step 4: Run voice activity detection to find where the utterance ends
+	// Media events are handled sequentially by the connection reader, so the VAD needs no locking
+	utteranceEnded := false
+	for _, event := range session.VAD.Process(pcm16k) {
+		switch event {
+		case audio.VADSpeechStart:
+			session.markSpeechStart(time.Now())
+			// Caller started talking over the bot - stop playback so they are not talked over
+			if bargeInEnabled(session) {
+				h.interruptPlayback(session, "caller_speech")
+			}
+		case audio.VADSpeechEnd:
+			session.markSpeechEnd(time.Now())
+			utteranceEnded = true
+		case audio.VADSpeechDiscarded:
+			// Too short to be speech - drop it instead of sending noise to STT
+			session.AudioBuffer.Clear()
+		}
+	}
+	if session.VAD.Speaking() {
+		session.noteActivity(true)
+	}
+
+	// Forward the frame straight to the live STT stream when one is open
+	session.Mu.RLock()
+	sttStream := session.STTStream
+	session.Mu.RUnlock()
+	if sttStream != nil {
+		// Audio buffered while the stream was opening goes first, so the utterance reaches it whole
+		if backlog := session.AudioBuffer.GetData(); len(backlog) > 0 {
+			session.AudioBuffer.Clear()
+			pcm16k = append(backlog, pcm16k...)
+		}
+		err := sttStream.Send(pcm16k)
+		if err == nil {
+			if utteranceEnded {
+				// Flush Deepgram now; the finalized transcript triggers the response
+				if err := sttStream.Finalize(); err != nil {
+					h.logger.Warn("Failed to finalize STT stream", zap.String("call_sid", callSid), zap.Error(err))
+				}
+			}
+			return
+		}
+		h.logger.Warn("Failed to forward audio to STT stream, falling back to batch STT",
+			zap.String("call_sid", callSid),
+			zap.Error(err),
+		)
+		h.stopSTTStream(session)
+	}
 
-	// Append resampled PCM16 (16kHz) to audio buffer
+	// Append resampled PCM16 (16kHz) to audio buffer, keeping only a short pre-roll while nobody speaks
 	session.AudioBuffer.Append(pcm16k)
+	if !session.VAD.InUtterance() && !utteranceEnded {
+		session.AudioBuffer.TrimTo(vadPreRollBytes)
+	}
 
-	// Process if buffer is ready (utterance detection)
-	if session.AudioBuffer.IsReady() {
+	// Process once the VAD reports end of utterance, or if the caller talks past the buffer cap
+	if utteranceEnded || session.AudioBuffer.IsReady() {
 		// Prevent concurrent processing
 		if !session.ProcessingMu.TryLock() {
 			return
@@ -1118,6 +1326,21 @@ func (h *Handler) handleMediaEvent(conn *websocket.Conn, callSid string, message
 	}
 }
 
+// vadConfigFromParams builds VAD thresholds from campaign custom_parameters
+// Supported keys: vad_energy_threshold, vad_max_zcr, vad_speech_start_ms, vad_hangover_ms, vad_min_utterance_ms
+func vadConfigFromParams(params map[string]interface{}) audio.VADConfig {
+	cfg := audio.DefaultVADConfig()
+	if params == nil {
+		return cfg
+	}
+	cfg.EnergyThreshold = getFloatFromMap(params, "vad_energy_threshold", cfg.EnergyThreshold)
+	cfg.MaxZeroCrossRate = getFloatFromMap(params, "vad_max_zcr", cfg.MaxZeroCrossRate)
+	cfg.SpeechStartMs = int(getFloatFromMap(params, "vad_speech_start_ms", float64(cfg.SpeechStartMs)))
+	cfg.HangoverMs = int(getFloatFromMap(params, "vad_hangover_ms", float64(cfg.HangoverMs)))
+	cfg.MinUtteranceMs = int(getFloatFromMap(params, "vad_min_utterance_ms", float64(cfg.MinUtteranceMs)))
+	return cfg
+}
+
 // processAudioBuffer processes buffered audio through STT → AI → TTS pipeline
 func (h *Handler) processAudioBuffer(session *VoiceSession) {
 	audioData := session.AudioBuffer.GetData()
@@ -1128,17 +1351,34 @@ func (h *Handler) processAudioBuffer(session *VoiceSession) {
 	}
 
 	// Step 1: Convert audio to text using STT
-	transcribedText := h.callSTTService(session, audioData)
-	if transcribedText == "" {
+	sttResp := h.callSTTService(session, audioData)
+	if sttResp == nil {
 		h.logger.Warn("STT returned empty text", zap.String("call_sid", session.CallSid))
 		return
 	}
 
 	h.logger.Info("STT transcription",
 		zap.String("call_sid", session.CallSid),
-		zap.String("text", transcribedText),
+		zap.String("text", sttResp.Text),
 	)
 
+	h.respondToTranscript(session, session.speechUtterance(sttResp.Text, sttResp.Confidence, sttResp.Provider))
+}
+
+// respondToTranscript runs the AI → TTS half of the pipeline for a finished user utterance
+// Both the caller turn and the reply are appended to call_turns as they happen
+func (h *Handler) respondToTranscript(session *VoiceSession, utterance callerUtterance) {
+	turnStart := time.Now()
+	transcribedText := utterance.Text
+	h.recordTurn(session, utterance.callerTurn())
+	latency := turnLatency{STT: utterance.sttLatency()}
+
+	// First-frame latency runs from the end of speech when the VAD saw it
+	responseStart := utterance.SpeechEnd
+	if responseStart.IsZero() {
+		responseStart = turnStart
+	}
+
 	// Step 2: Update conversation history
 	session.Mu.Lock()
 	session.ConversationHistory = append(session.ConversationHistory, map[string]interface{}{
@@ -1149,8 +1389,14 @@ func (h *Handler) processAudioBuffer(session *VoiceSession) {
 	copy(conversationHistory, session.ConversationHistory)
 	session.Mu.Unlock()
 
+	// Rule-based hand-off: configured keywords skip the LLM entirely
+	if transferKeywordMatched(session, transcribedText) {
+		h.transferToAgent(session, "keyword", true)
+		return
+	}
+
 	// Step 3: Get call context and generate AI response
-	callContext := h.getCallContext(session.CallSid)
+	callContext := h.sessionCallContext(session)
 	callContext["conversation_history"] = conversationHistory
 	// Add custom_parameters to call context
 	session.Mu.RLock()
@@ -1159,7 +1405,54 @@ func (h *Handler) processAudioBuffer(session *VoiceSession) {
 	if customParams != nil {
 		callContext["custom_parameters"] = customParams
 	}
-	aiResponse := h.generateAIResponse(session, transcribedText, callContext)
+
+	// Stream tokens into sentence-sized TTS segments so audio starts before the reply is complete;
+	// ai.Manager fails over between providers within the AI timeout
+	if h.cfg.FeatureAI && h.aiManager != nil {
+		// Only the passages relevant to this utterance go into the prompt
+		ragStart := time.Now()
+		ragContext := h.loadRAGContext(session, transcribedText)
+		latency.RAG = time.Since(ragStart)
+
+		aiResponse, heard, result, err := h.streamAIResponse(session, transcribedText, callContext, ragContext, turnStart, &latency)
+		if err == nil {
+			aiResponse, transfer := extractTransferMarker(aiResponse)
+			session.Mu.Lock()
+			session.ConversationHistory = append(session.ConversationHistory, map[string]interface{}{
+				"role":    "assistant",
+				"content": aiResponse,
+			})
+			turnIndex := len(session.ConversationHistory) - 1
+			session.Mu.Unlock()
+
+			markTurnPlayed(session, turnIndex, result)
+			if result.Interrupted {
+				markTurnTruncated(session, turnIndex, heard, result.PlayedMs)
+			} else {
+				heard = aiResponse
+			}
+			if !result.StartedAt.IsZero() {
+				latency.FirstFrame = result.StartedAt.Sub(responseStart)
+			}
+			turn := botTurn(turnReply, heard, aiResponse, result, latency)
+			turn.Sources = ragSources(ragContext)
+			h.recordTurn(session, turn)
+			if transfer {
+				h.transferToAgent(session, "llm", false)
+			}
+			return
+		}
+		if result.Interrupted {
+			// The caller spoke before any reply was ready; their new utterance gets the answer
+			return
+		}
+		h.logger.Warn("No AI provider answered the turn, using fallback reply",
+			zap.String("call_sid", session.CallSid),
+			zap.Error(err),
+		)
+	}
+
+	aiResponse := fallbackReply(h.cfg.FeatureAI && h.aiManager != nil, transcribedText)
 
 	// Step 4: Update conversation history with AI response
 	session.Mu.Lock()
@@ -1167,270 +1460,654 @@ func (h *Handler) processAudioBuffer(session *VoiceSession) {
 		"role":    "assistant",
 		"content": aiResponse,
 	})
+	turnIndex := len(session.ConversationHistory) - 1
 	session.Mu.Unlock()
 
 	// Step 5: Convert AI response to speech and stream
-	h.sendTTSResponse(session, aiResponse)
+	result := h.sendTTSResponse(session, aiResponse)
+	markTurnPlayed(session, turnIndex, result)
+	heard := aiResponse
+	if result.Interrupted {
+		heard = truncateAtFraction(aiResponse, result.playedFraction())
+		markTurnTruncated(session, turnIndex, heard, result.PlayedMs)
+	}
+	if !result.StartedAt.IsZero() {
+		latency.FirstFrame = result.StartedAt.Sub(responseStart)
+	}
+	h.recordTurn(session, botTurn(turnReply, heard, aiResponse, result, latency))
 }
 
-// callSTTService calls Deepgram to convert audio to text
-// CRITICAL FIX: Use Deepgram STT with raw PCM16 (16kHz, mono, little-endian)
-func (h *Handler) callSTTService(session *VoiceSession, audioData []byte) string {
-	if !h.cfg.FeatureAI || h.cfg.DeepgramApiKey == "" {
-		// Fallback to OpenAI Whisper if Deepgram not configured
-		if h.cfg.OpenAIApiKey == "" {
-			return ""
+// streamAIResponse streams the LLM reply into sentence-sized TTS segments and plays each one as soon as it is synthesised
+// Returns the generated text, the part the caller heard, and how playback ended; LLM and TTS latency are added to latency
+// An error means no provider generated anything (or the caller barged in before the first segment)
+func (h *Handler) streamAIResponse(session *VoiceSession, userText string, callContext, ragContext map[string]interface{}, turnStart time.Time, latency *turnLatency) (string, string, playbackResult, error) {
+	req := h.buildConversationRequest(session, userText, callContext, ragContext)
+	llmStart := time.Now()
+
+	// The whole turn is one playback so a barge-in stops generation, synthesis and audio together
+	playCtx, endPlayback := h.beginPlayback(session)
+	defer endPlayback()
+
+	llmCtx, cancelLLM := context.WithTimeout(playCtx, 30*time.Second)
+	defer cancelLLM()
+
+	// Stage 1: LLM tokens → segments
+	segments := make(chan string, 8)
+	var fullText string
+	var llmErr error
+	var firstToken time.Duration
+	llmDone := make(chan struct{})
+	go func() {
+		defer close(llmDone)
+		defer close(segments)
+		segmenter := ai.NewSegmenter()
+		send := func(seg string) error {
+			select {
+			case segments <- seg:
+				return nil
+			case <-llmCtx.Done():
+				return llmCtx.Err()
+			}
 		}
-		return h.callSTTServiceWhisper(session, audioData)
+		// The first token must arrive within the AI timeout, failing over between providers inside it
+		fullText, llmErr = h.aiManager.StreamConversationResponse(llmCtx, req, time.Duration(h.cfg.AITimeoutMs)*time.Millisecond, func(delta string) error {
+			if firstToken == 0 {
+				firstToken = time.Since(llmStart)
+			}
+			for _, seg := range segmenter.Push(delta) {
+				if err := send(seg); err != nil {
+					return err
+				}
+			}
+			return nil
+		})
+		if rest := segmenter.Flush(); rest != "" {
+			send(rest)
+		}
+	}()
+
+	// Stage 2: segments → PCM, synthesising the next segment while the current one plays
+	type spokenSegment struct {
+		text string
+		pcm  []byte
 	}
+	clips := make(chan spokenSegment, 1)
+	var firstSynthesis time.Duration
+	go func() {
+		defer close(clips)
+		for seg := range segments {
+			// The transfer marker is an instruction to us, not something to say
+			if seg, _ = extractTransferMarker(seg); seg == "" {
+				continue
+			}
+			ttsCtx, cancel := context.WithTimeout(playCtx, 10*time.Second)
+			synthStart := time.Now()
+			pcm, err := h.synthesizeSpeech(ttsCtx, session, seg)
+			cancel()
+			if firstSynthesis == 0 && err == nil {
+				firstSynthesis = time.Since(synthStart)
+			}
+			if err != nil {
+				if playCtx.Err() == nil {
+					h.logger.Warn("TTS failed for response segment", zap.String("call_sid", session.CallSid), zap.Error(err))
+				}
+				continue
+			}
+			select {
+			case clips <- spokenSegment{text: seg, pcm: pcm}:
+			case <-playCtx.Done():
+				return
+			}
+		}
+	}()
 
-	// Use Deepgram STT with raw PCM16 (16kHz, mono, little-endian)
-	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
-	defer cancel()
+	// Stage 3: queue segments back to back with a mark after each one; the scheduler keeps
+	// one continuous clock, so the next segment starts as soon as the previous one ends
+	sched := session.Outbound
+	turnStartPos := sched.Sent()
+	var queued []queuedClip
+	var startedAt time.Time
+	interrupted := false
+	for clip := range clips {
+		if len(queued) == 0 {
+			// The first frame plays once whatever is still queued at Exotel has played
+			startedAt = time.Now().Add(sched.Buffered())
+			h.logger.Info("Time to first audio",
+				zap.String("call_sid", session.CallSid),
+				zap.Int64("latency_ms", time.Since(turnStart).Milliseconds()),
+				zap.String("segment", clip.text),
+			)
+		}
+		clipStart := sched.Sent()
+		completed := h.playPCM(playCtx, session, clip.pcm)
+		queued = append(queued, queuedClip{text: clip.text, start: clipStart, end: clipStart + pcmDuration(clip.pcm)})
+		if !completed {
+			interrupted = true
+			break
+		}
+		h.sendMark(session, "response_segment")
+	}
+	count := len(queued)
 
-	// Import Deepgram client
-	deepgramClient := stt.NewDeepgramClient(h.cfg.DeepgramApiKey, 10*time.Second, h.logger)
+	// Wait until Exotel confirms the tail has played; a barge-in here (or while the next
+	// segment was still being synthesised) still counts as an interruption
+	var playedAt time.Time
+	if !interrupted && count > 0 {
+		var ok bool
+		playedAt, ok = h.waitForPlayback(playCtx, session, h.sendMark(session, "response_done"))
+		interrupted = !ok
+	}
+	if !interrupted && playCtx.Err() != nil {
+		interrupted = true
+	}
 
-	// Get language from custom_parameters or use auto-detect
-	language := ""
-	if session.CustomParameters != nil {
-		if lang, ok := session.CustomParameters["language"].(string); ok && lang != "" {
-			language = lang
+	position := sched.Position()
+	total := playbackResult{
+		PlayedMs:    int((position - turnStartPos) / time.Millisecond),
+		Interrupted: interrupted,
+		StartedAt:   startedAt,
+		PlayedAt:    playedAt,
+	}
+	for _, q := range queued {
+		total.TotalMs += int((q.end - q.start) / time.Millisecond)
+	}
+	if total.PlayedMs < 0 {
+		total.PlayedMs = 0
+	}
+	heard := heardText(queued, position)
+
+	// Let the producers wind down before reading their results
+	cancelLLM()
+	for range clips {
+	}
+	<-llmDone
+	latency.LLM = firstToken
+	latency.TTS = firstSynthesis
+
+	if fullText == "" {
+		if llmErr == nil {
+			llmErr = fmt.Errorf("empty response from AI stream")
 		}
+		return "", "", total, llmErr
+	}
+	if llmErr != nil && !total.Interrupted {
+		h.logger.Warn("AI stream ended early", zap.String("call_sid", session.CallSid), zap.Error(llmErr))
 	}
 
-	sttReq := &stt.STTRequest{
-		AudioData:   audioData, // Raw PCM16, 16kHz, mono, little-endian
-		SampleRate:  16000,     // 16kHz (required by Deepgram)
-		Language:    language,  // Auto-detect if empty
-		Model:       "nova-2",  // Best accuracy model
-		Punctuate:   true,      // Add punctuation
-		Interim:     false,     // Final results only
-		Endpointing: true,      // Enable endpointing
+	if count == 0 && !total.Interrupted {
+		// Every TTS segment failed - at least get the text across
+		h.sendTextResponse(session, fullText)
 	}
 
-	sttResp, err := deepgramClient.SpeechToText(ctx, sttReq)
-	if err != nil {
-		h.logger.Warn("Deepgram STT failed", zap.Error(err))
-		// Fallback to Whisper if Deepgram fails
-		if h.cfg.OpenAIApiKey != "" {
-			return h.callSTTServiceWhisper(session, audioData)
+	h.logger.Info("Streamed AI response",
+		zap.String("call_sid", session.CallSid),
+		zap.Int("segments", count),
+		zap.Int("played_ms", total.PlayedMs),
+		zap.Bool("interrupted", total.Interrupted),
+		zap.Int64("turn_ms", time.Since(turnStart).Milliseconds()),
+	)
+
+	return fullText, heard, total, nil
+}
+
+// queuedClip is a TTS segment placed on the outbound scheduler timeline
+type queuedClip struct {
+	text       string
+	start, end time.Duration
+}
+
+// heardText returns the text the caller heard given the playback position on the scheduler timeline
+func heardText(clips []queuedClip, position time.Duration) string {
+	var heard []string
+	for _, c := range clips {
+		if position >= c.end {
+			heard = append(heard, c.text)
+			continue
 		}
-		return ""
+		if position > c.start {
+			fraction := float64(position-c.start) / float64(c.end-c.start)
+			if partial := truncateAtFraction(c.text, fraction); partial != "" {
+				heard = append(heard, partial)
+			}
+		}
+		break
 	}
+	return strings.Join(heard, " ")
+}
 
-	if sttResp == nil || sttResp.Text == "" {
-		h.logger.Warn("Deepgram STT returned empty text")
+// markTurnPlayed records when the caller finished hearing an assistant turn
+func markTurnPlayed(session *VoiceSession, turnIndex int, result playbackResult) {
+	if result.PlayedAt.IsZero() {
+		return
+	}
+	session.Mu.Lock()
+	defer session.Mu.Unlock()
+	if turnIndex >= 0 && turnIndex < len(session.ConversationHistory) {
+		session.ConversationHistory[turnIndex]["played_at"] = result.PlayedAt.Format(time.RFC3339Nano)
+	}
+}
+
+// markTurnTruncated records that the caller barged in and only heard the start of an assistant turn
+// The content is cut to the heard part so the model does not assume the caller heard the rest
+func markTurnTruncated(session *VoiceSession, turnIndex int, heard string, playedMs int) {
+	session.Mu.Lock()
+	defer session.Mu.Unlock()
+
+	if turnIndex < 0 || turnIndex >= len(session.ConversationHistory) {
+		return
+	}
+	turn := session.ConversationHistory[turnIndex]
+	fullContent, _ := turn["content"].(string)
+
+	turn["truncated"] = true
+	turn["truncated_at_ms"] = playedMs
+	turn["full_content"] = fullContent
+	turn["content"] = heard + "..."
+}
+
+// truncateAtFraction returns roughly the first fraction of text, cut back to a word boundary
+func truncateAtFraction(text string, fraction float64) string {
+	runes := []rune(text)
+	if fraction <= 0 || len(runes) == 0 {
 		return ""
 	}
+	if fraction >= 1 {
+		return text
+	}
+	cut := int(float64(len(runes)) * fraction)
+	for cut > 0 && runes[cut] != ' ' {
+		cut--
+	}
+	return strings.TrimSpace(string(runes[:cut]))
+}
 
-	return sttResp.Text
+// callSTTService converts a buffered utterance to text with the configured STT providers
+// Audio is raw PCM16 (16kHz, mono, little-endian). Returns nil if nothing was transcribed.
+func (h *Handler) callSTTService(session *VoiceSession, audioData []byte) *stt.STTResponse {
+	if !h.cfg.FeatureAI || h.sttManager == nil {
+		return nil
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	language, hints := sttLanguage(session)
+	sttResp, err := h.sttManager.Transcribe(ctx, &stt.TranscribeRequest{
+		Audio:         audioData,
+		SampleRate:    16000,
+		Language:      language, // Auto-detect if empty
+		LanguageHints: hints,
+	})
+	if err != nil {
+		h.logger.Warn("STT failed", zap.String("call_sid", session.CallSid), zap.Error(err))
+		return nil
+	}
+
+	if sttResp.Text == "" {
+		h.logger.Warn("STT returned empty text", zap.String("provider", sttResp.Provider))
+		return nil
+	}
+
+	return sttResp
 }
 
-// callSTTServiceWhisper fallback to OpenAI Whisper
-func (h *Handler) callSTTServiceWhisper(session *VoiceSession, audioData []byte) string {
-	// Convert PCM to WAV format for Whisper
+// sttLanguage returns the call's language and language hints from custom_parameters
+// (language, language_hints as a comma separated list)
+func sttLanguage(session *VoiceSession) (string, []string) {
 	session.Mu.RLock()
-	sampleRate := session.SampleRate
+	defer session.Mu.RUnlock()
+
+	var hints []string
+	for _, hint := range strings.Split(getStringFromMap(session.CustomParameters, "language_hints", ""), ",") {
+		if hint = strings.TrimSpace(hint); hint != "" {
+			hints = append(hints, hint)
+		}
+	}
+	return getStringFromMap(session.CustomParameters, "language", ""), hints
+}
+
+// startSTTStream opens a live-transcription stream for the session
+// Each 20ms frame is forwarded as it arrives instead of being batched for HTTP STT
+// Opening dials the provider, so callers on the media reader run it in a goroutine
+func (h *Handler) startSTTStream(session *VoiceSession) {
+	if !h.cfg.FeatureAI || h.sttManager == nil || !h.sttManager.CanStream() {
+		return
+	}
+
+	language, hints := sttLanguage(session)
+	session.Mu.RLock()
+	alreadyOpen := session.STTStream != nil
+	hangoverMs := session.VAD.Config().HangoverMs
+	generation := session.sttGeneration
 	session.Mu.RUnlock()
-	if sampleRate == 0 {
-		sampleRate = 16000 // Default 16kHz
+	if alreadyOpen {
+		return
 	}
-	wavData := h.convertPCMToWAV(audioData, sampleRate)
 
-	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	// Our VAD decides the end of the utterance; Deepgram's UtteranceEnd is only a backstop
+	// for speech too quiet for the VAD (Deepgram requires at least 1000ms)
+	utteranceEndMs := hangoverMs + 500
+	if utteranceEndMs < 1000 {
+		utteranceEndMs = 1000
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
 	defer cancel()
 
-	sttService := ai.NewSTTService(h.cfg.OpenAIApiKey, "whisper-1", "", 10*time.Second, h.logger)
-	sttReq := &ai.STTRequest{
-		AudioData:   wavData,
-		AudioFormat: "wav",
-		Language:    "",
-		Prompt:      "",
+	stream, err := h.sttManager.OpenStream(ctx, &stt.StreamOptions{
+		SampleRate:     16000,    // Media is resampled to 16kHz before forwarding
+		Language:       language, // Falls back to the first hint
+		LanguageHints:  hints,
+		Model:          "nova-2", // Best accuracy model
+		Punctuate:      true,
+		Interim:        true,
+		EndpointingMs:  hangoverMs,
+		UtteranceEndMs: utteranceEndMs,
+	})
+	if err != nil {
+		h.logger.Warn("Failed to open STT stream, using batch STT",
+			zap.String("call_sid", session.CallSid),
+			zap.Error(err),
+		)
+		return
 	}
 
-	sttResp, err := sttService.SpeechToText(ctx, sttReq)
-	if err != nil {
-		h.logger.Warn("OpenAI Whisper failed", zap.Error(err))
-		return ""
+	session.Mu.Lock()
+	if session.sttGeneration != generation || session.STTStream != nil {
+		// Stopped (call ended, language switched) or opened elsewhere while dialing
+		session.Mu.Unlock()
+		go stream.Close()
+		return
 	}
+	session.STTStream = stream
+	session.Mu.Unlock()
 
-	if sttResp == nil || sttResp.Text == "" {
-		h.logger.Warn("OpenAI Whisper returned empty text")
-		return ""
+	h.logger.Info("STT live stream opened", zap.String("call_sid", session.CallSid))
+
+	go h.consumeSTTStream(session, stream)
+}
+
+// stopSTTStream detaches and closes the session's live STT stream
+func (h *Handler) stopSTTStream(session *VoiceSession) {
+	session.Mu.Lock()
+	stream := session.STTStream
+	session.STTStream = nil
+	session.sttGeneration++
+	session.Mu.Unlock()
+
+	if stream != nil {
+		go stream.Close()
+	}
+}
+
+// consumeSTTStream delivers interim and final transcripts from the live stream to the session
+// Final segments are accumulated until the VAD-triggered Finalize (or Deepgram's UtteranceEnd backstop) arrives
+func (h *Handler) consumeSTTStream(session *VoiceSession, stream stt.Stream) {
+	provider := ""
+	for result := range stream.Results() {
+		if result.Provider != "" {
+			provider = result.Provider
+		}
+		h.checkAMDTranscript(session, result.Text)
+
+		if !result.IsFinal {
+			session.Mu.Lock()
+			session.InterimTranscript = result.Text
+			session.Mu.Unlock()
+			if result.Text != "" {
+				h.logger.Debug("STT interim transcript",
+					zap.String("call_sid", session.CallSid),
+					zap.String("text", result.Text),
+				)
+			}
+			continue
+		}
+
+		session.Mu.Lock()
+		session.InterimTranscript = ""
+		if text := strings.TrimSpace(result.Text); text != "" {
+			session.PendingTranscript = append(session.PendingTranscript, text)
+			session.PendingConfidence = append(session.PendingConfidence, result.Confidence)
+		}
+		session.Mu.Unlock()
+
+		// speech_final alone is ignored: it fires on short pauses and would cut callers off mid-sentence
+		if result.FromFinalize || result.UtteranceEnd {
+			h.flushPendingTranscript(session, provider)
+		}
 	}
 
-	return sttResp.Text
+	// Stream ended: if it was not replaced or stopped deliberately, fall back to batch STT
+	session.Mu.Lock()
+	if session.STTStream == stream {
+		session.STTStream = nil
+		h.logger.Warn("STT stream ended unexpectedly, using batch STT", zap.String("call_sid", session.CallSid))
+	}
+	session.Mu.Unlock()
+	h.flushPendingTranscript(session, provider)
 }
 
-// convertPCMToWAV converts raw PCM (16-bit, 16kHz, mono) to WAV format
-func (h *Handler) convertPCMToWAV(pcmData []byte, sampleRate int) []byte {
-	// WAV header for 16-bit PCM, 16kHz, mono
-	// This is a simplified WAV header - in production, use a proper WAV library
-	if sampleRate == 0 {
-		sampleRate = 16000 // Default 16kHz
+// flushPendingTranscript hands the accumulated utterance to the AI → TTS pipeline
+func (h *Handler) flushPendingTranscript(session *VoiceSession, provider string) {
+	session.Mu.Lock()
+	text := strings.Join(session.PendingTranscript, " ")
+	var confidence float64
+	for _, c := range session.PendingConfidence {
+		confidence += c / float64(len(session.PendingConfidence))
+	}
+	session.PendingTranscript = nil
+	session.PendingConfidence = nil
+	session.Mu.Unlock()
+
+	if text == "" {
+		return
+	}
+	if amdHoldsTranscripts(session) {
+		h.logger.Debug("Dropping transcript heard during answering machine detection",
+			zap.String("call_sid", session.CallSid),
+			zap.String("text", text),
+		)
+		return
 	}
-	bitsPerSample := 16
-	channels := 1
-	dataSize := len(pcmData)
-
-	// WAV header (44 bytes)
-	header := make([]byte, 44)
-	copy(header[0:4], "RIFF")
-	// File size - 8
-	fileSize := uint32(36 + dataSize)
-	header[4] = byte(fileSize)
-	header[5] = byte(fileSize >> 8)
-	header[6] = byte(fileSize >> 16)
-	header[7] = byte(fileSize >> 24)
-	copy(header[8:12], "WAVE")
-	copy(header[12:16], "fmt ")
-	// fmt chunk size
-	header[16] = 16
-	header[17] = 0
-	header[18] = 0
-	header[19] = 0
-	// Audio format (1 = PCM)
-	header[20] = 1
-	header[21] = 0
-	// Number of channels
-	header[22] = byte(channels)
-	header[23] = 0
-	// Sample rate
-	header[24] = byte(sampleRate)
-	header[25] = byte(sampleRate >> 8)
-	header[26] = byte(sampleRate >> 16)
-	header[27] = byte(sampleRate >> 24)
-	// Byte rate
-	byteRate := uint32(sampleRate * channels * bitsPerSample / 8)
-	header[28] = byte(byteRate)
-	header[29] = byte(byteRate >> 8)
-	header[30] = byte(byteRate >> 16)
-	header[31] = byte(byteRate >> 24)
-	// Block align
-	blockAlign := uint16(channels * bitsPerSample / 8)
-	header[32] = byte(blockAlign)
-	header[33] = byte(blockAlign >> 8)
-	// Bits per sample
-	header[34] = byte(bitsPerSample)
-	header[35] = 0
-	copy(header[36:40], "data")
-	// Data size
-	header[40] = byte(dataSize)
-	header[41] = byte(dataSize >> 8)
-	header[42] = byte(dataSize >> 16)
-	header[43] = byte(dataSize >> 24)
-
-	// Combine header + PCM data
-	wavData := make([]byte, 44+dataSize)
-	copy(wavData[0:44], header)
-	copy(wavData[44:], pcmData)
-
-	return wavData
+
+	h.logger.Info("STT transcription",
+		zap.String("call_sid", session.CallSid),
+		zap.String("text", text),
+		zap.Bool("streaming", true),
+	)
+
+	// Utterances are answered in order; a new one waits for the previous reply to finish
+	utterance := session.speechUtterance(text, confidence, provider)
+	go func() {
+		session.ProcessingMu.Lock()
+		defer session.ProcessingMu.Unlock()
+		h.respondToTranscript(session, utterance)
+	}()
 }
 
 // sendTTSResponse converts text to speech and streams audio back to Exotel
-func (h *Handler) sendTTSResponse(session *VoiceSession, text string) {
-	if !h.cfg.FeatureAI || h.cfg.OpenAIApiKey == "" {
+func (h *Handler) sendTTSResponse(session *VoiceSession, text string) playbackResult {
+	return h.speak(session, text, false)
+}
+
+// sendPrompt is sendTTSResponse for fixed lines (reprompts, keypress and transfer messages), served from the TTS cache
+func (h *Handler) sendPrompt(session *VoiceSession, text string) playbackResult {
+	return h.speak(session, text, true)
+}
+
+// speak synthesizes text (through the TTS cache for fixed lines) and plays it, falling back to a text event
+func (h *Handler) speak(session *VoiceSession, text string, fixed bool) playbackResult {
+	if !h.ttsAvailable() {
 		h.sendTextResponse(session, text)
-		return
+		return playbackResult{}
 	}
 
 	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
 	defer cancel()
 
-	// Get voice from custom_parameters or use default
-	voice := "shimmer" // Default: female, super natural Hindi
-	if session.CustomParameters != nil {
-		if v, ok := session.CustomParameters["voice_id"].(string); ok && v != "" {
-			voice = v
-		}
+	synthesize := h.synthesizeSpeech
+	if fixed {
+		synthesize = h.synthesizePrompt
 	}
-
-	// CRITICAL FIX: Use OpenAI TTS with PCM16 format (not MP3)
-	// OpenAI TTS supports "pcm" format which returns raw PCM16, 24kHz
-	// We need to resample to 16kHz for Exotel
-	openAITTS := ai.NewOpenAITTSService(h.cfg.OpenAIApiKey, 10*time.Second, h.logger)
-	ttsReq := &ai.OpenAITTSRequest{
-		Text:   text,
-		Model:  "tts-1-hd", // Best quality model
-		Voice:  voice,
-		Format: "pcm", // Request raw PCM16 format (24kHz output)
-		Speed:  1.0,
-	}
-
-	// Get PCM16 audio (24kHz from OpenAI)
-	pcm24k, err := openAITTS.TextToSpeechPCM(ctx, ttsReq)
+	pcm16k, err := synthesize(ctx, session, text)
 	if err != nil {
-		h.logger.Warn("OpenAI TTS service failed", zap.Error(err))
+		h.logger.Warn("TTS service failed", zap.Error(err))
 		h.sendTextResponse(session, text)
-		return
+		return playbackResult{}
+	}
+
+	// Stream PCM in 20ms frames in the negotiated format
+	return h.streamPCMAudio(session, pcm16k, "response_done")
+}
+
+// streamPCMAudio streams 16-bit 16kHz PCM to Exotel as 20ms frames in the negotiated format
+// and returns once the caller has heard it or barged in
+func (h *Handler) streamPCMAudio(session *VoiceSession, pcmData []byte, markName string) playbackResult {
+	ctx, endPlayback := h.beginPlayback(session)
+	defer endPlayback()
+
+	sched := session.Outbound
+	clipStart := sched.Sent()
+	total := pcmDuration(pcmData)
+
+	// The last frames are still playing out of Exotel's buffer after playPCM returns;
+	// stay interruptible until Exotel echoes the mark
+	var playedAt time.Time
+	startedAt := time.Now().Add(sched.Buffered())
+	completed := h.playPCM(ctx, session, pcmData)
+	if completed {
+		playedAt, completed = h.waitForPlayback(ctx, session, h.sendMark(session, markName))
+	}
+
+	played := total
+	if !completed {
+		played = sched.Position() - clipStart
+		if played < 0 {
+			played = 0
+		} else if played > total {
+			played = total
+		}
 	}
 
-	// CRITICAL FIX: Resample 24kHz → 16kHz for Exotel
-	// OpenAI TTS returns 24kHz PCM, but Exotel expects 16kHz
-	pcm16k := h.resample24kTo16k(pcm24k)
+	h.logger.Info("Streamed PCM audio",
+		zap.String("call_sid", session.CallSid),
+		zap.Int("total_bytes", len(pcmData)),
+		zap.String("mark", markName),
+		zap.Int64("played_ms", played.Milliseconds()),
+		zap.Bool("interrupted", !completed),
+	)
 
-	// Stream PCM in 640-byte chunks (20ms frames at 16kHz)
-	h.streamPCMAudio(session, pcm16k, "response_done")
+	return playbackResult{
+		PlayedMs:    int(played / time.Millisecond),
+		TotalMs:     int(total / time.Millisecond),
+		Interrupted: !completed,
+		StartedAt:   startedAt,
+		PlayedAt:    playedAt,
+	}
 }
 
-// streamPCMAudio streams raw 16-bit 16kHz PCM in 640-byte chunks (20ms frames) to Exotel
-// CRITICAL FIX: Exotel expects 20ms frames = 640 bytes at 16kHz (16-bit, mono)
-// Frame size = sampleRate * 2 bytes * 0.02 sec = 16000 * 2 * 0.02 = 640 bytes
-func (h *Handler) streamPCMAudio(session *VoiceSession, pcmData []byte, markName string) {
-	session.Mu.RLock()
-	conn := session.Conn
-	streamSid := session.StreamSid
-	session.Mu.RUnlock()
+// beginPlayback registers a new outbound playback on the session, replacing anything still playing
+// The returned context is cancelled on barge-in; call the returned func when playback is over
+func (h *Handler) beginPlayback(session *VoiceSession) (context.Context, func()) {
+	ctx, cancel := context.WithCancel(context.Background())
+	current := &playback{cancel: cancel}
 
-	if conn == nil {
-		h.logger.Warn("No connection for streaming audio", zap.String("call_sid", session.CallSid))
-		return
+	session.Mu.Lock()
+	if session.Playback != nil {
+		session.Playback.cancel()
 	}
+	session.Playback = current
+	session.Mu.Unlock()
 
-	// CRITICAL FIX: Chunk into exactly 640-byte frames (20ms at 16kHz)
-	// Frame size = sampleRate * 2 bytes * 0.02 sec = 16000 * 2 * 0.02 = 640 bytes
-	chunkSize := 640
-	chunks := make([][]byte, 0)
-	for i := 0; i < len(pcmData); i += chunkSize {
-		end := i + chunkSize
-		if end > len(pcmData) {
-			end = len(pcmData)
+	return ctx, func() {
+		cancel()
+		session.Mu.Lock()
+		if session.Playback == current {
+			session.Playback = nil
 		}
-		chunks = append(chunks, pcmData[i:end])
+		session.Mu.Unlock()
 	}
+}
+
+// playPCM queues one 16kHz PCM16 clip as 20ms media frames, encoded in the session's stream format
+// e.g. 160 bytes per frame for 8kHz μ-law, 640 bytes for 16kHz PCM16
+// Frames are released by the session's outbound scheduler at real-time rate, so it returns
+// roughly when the clip has played minus the scheduler lead. Returns false if interrupted.
+func (h *Handler) playPCM(ctx context.Context, session *VoiceSession, pcmData []byte) bool {
+	sched := session.Outbound
+
+	// Convert the whole clip up front so resampling has no seams at frame boundaries
+	format := session.streamFormat()
+	encoded := format.Encode(audio.Resample(pcmData, 16000, format.SampleRate))
 
 	// Send each chunk as Exotel media event with base64-encoded payload
 	// Format: {"event": "media", "media": {"payload": "<base64>", "track": "outbound"}}
-	for _, chunk := range chunks {
-		// Base64 encode the PCM chunk
-		base64Payload := audio.EncodePCMChunkToBase64(chunk)
+	session.Mu.RLock()
+	recorder := session.Recorder
+	session.Mu.RUnlock()
+
+	chunkSize := format.FrameBytes(frameDuration)
+	for i := 0; i < len(encoded); i += chunkSize {
+		end := i + chunkSize
+		if end > len(encoded) {
+			end = len(encoded)
+		}
+		chunk := encoded[i:end]
+
+		frameLength := format.Duration(len(chunk))
+		if err := sched.Next(ctx, frameLength); err != nil {
+			return false
+		}
+		if recorder != nil {
+			// The frame starts playing once the audio queued ahead of it has played
+			recorder.WriteBot(format.Decode(chunk), sched.Buffered()-frameLength)
+		}
 
 		// CRITICAL FIX: Use correct Exotel Voicebot media event format
 		mediaEvent := map[string]interface{}{
 			"event": "media",
 			"media": map[string]interface{}{
-				"payload": base64Payload,
+				"payload": audio.EncodePCMChunkToBase64(chunk),
 				"track":   "outbound", // Required by Exotel
 			},
 		}
 
-		eventJSON, err := json.Marshal(mediaEvent)
-		if err != nil {
-			h.logger.Error("Failed to marshal media event", zap.Error(err))
-			continue
-		}
-
-		if err := conn.WriteMessage(websocket.TextMessage, eventJSON); err != nil {
+		if err := session.writeJSON(mediaEvent); err != nil {
 			h.logger.Error("Failed to send media chunk", zap.Error(err))
-			return
+			return false
 		}
+	}
+	return true
+}
+
+// pcmDuration returns the playback length of 16kHz PCM16 mono audio
+func pcmDuration(pcm []byte) time.Duration {
+	return time.Duration(len(pcm)) * time.Millisecond / bytesPerMs16k
+}
+
+// playedFraction returns the share of the clip the caller heard
+func (r playbackResult) playedFraction() float64 {
+	if r.TotalMs == 0 {
+		return 0
+	}
+	return float64(r.PlayedMs) / float64(r.TotalMs)
+}
 
-		// No delay between chunks for low latency (<700ms requirement)
+// sendMark sends an Exotel mark event after the audio queued so far
+// The name is made unique per call; the returned channel receives the time Exotel echoes it back
+func (h *Handler) sendMark(session *VoiceSession, label string) <-chan time.Time {
+	ack := make(chan time.Time, 1)
+
+	session.Mu.Lock()
+	session.markSeq++
+	markName := fmt.Sprintf("%s_%d", label, session.markSeq)
+	if session.PendingMarks == nil {
+		session.PendingMarks = make(map[string]chan time.Time)
 	}
+	session.PendingMarks[markName] = ack
+	streamSid := session.StreamSid
+	session.Mu.Unlock()
 
-	// Send mark event after all chunks (optional, for synchronization)
 	markEvent := map[string]interface{}{
 		"event":      "mark",
 		"stream_sid": streamSid,
@@ -1438,97 +2115,150 @@ func (h *Handler) streamPCMAudio(session *VoiceSession, pcmData []byte, markName
 			"name": markName,
 		},
 	}
+	if err := session.writeJSON(markEvent); err != nil {
+		h.logger.Error("Failed to send mark event", zap.Error(err))
+		session.Mu.Lock()
+		delete(session.PendingMarks, markName)
+		session.Mu.Unlock()
+	}
+	return ack
+}
 
-	markJSON, err := json.Marshal(markEvent)
-	if err != nil {
-		h.logger.Error("Failed to marshal mark event", zap.Error(err))
+// waitForPlayback blocks until Exotel echoes the mark, i.e. the caller heard everything sent before it
+// Falls back to the scheduler's estimate if no echo arrives in time. Returns false on barge-in.
+func (h *Handler) waitForPlayback(ctx context.Context, session *VoiceSession, ack <-chan time.Time) (time.Time, bool) {
+	timer := time.NewTimer(session.Outbound.Buffered() + markAckGrace)
+	defer timer.Stop()
+
+	select {
+	case playedAt := <-ack:
+		return playedAt, true
+	case <-ctx.Done():
+		return time.Time{}, false
+	case <-timer.C:
+		h.logger.Warn("No mark acknowledgement from Exotel, assuming playback finished",
+			zap.String("call_sid", session.CallSid),
+		)
+		// Earlier marks will not arrive either; do not let them hold up turn-taking
+		session.Mu.Lock()
+		session.PendingMarks = nil
+		session.Mu.Unlock()
+		return time.Now(), true
+	}
+}
+
+// awaitingPlayback reports whether the bot is speaking or Exotel still has unacknowledged audio
+func (s *VoiceSession) awaitingPlayback() bool {
+	s.Mu.RLock()
+	defer s.Mu.RUnlock()
+	return s.Playback != nil || len(s.PendingMarks) > 0
+}
+
+// handleMarkEvent processes Exotel "mark" event: audio sent before the named mark has finished playing
+func (h *Handler) handleMarkEvent(callSid string, message []byte) {
+	var markEvent MarkEvent
+	if err := json.Unmarshal(message, &markEvent); err != nil {
+		h.logger.Warn("Failed to parse mark event", zap.Error(err))
 		return
 	}
 
-	if err := conn.WriteMessage(websocket.TextMessage, markJSON); err != nil {
-		h.logger.Error("Failed to send mark event", zap.Error(err))
+	session := getSession(callSid)
+	if session == nil {
 		return
 	}
 
-	h.logger.Info("Streamed PCM audio",
-		zap.String("call_sid", session.CallSid),
-		zap.Int("chunks", len(chunks)),
-		zap.Int("total_bytes", len(pcmData)),
-		zap.String("mark", markName),
-	)
+	session.Mu.Lock()
+	ack, ok := session.PendingMarks[markEvent.Mark.Name]
+	delete(session.PendingMarks, markEvent.Mark.Name)
+	session.Mu.Unlock()
+
+	if !ok {
+		h.logger.Debug("Unknown or stale mark acknowledgement",
+			zap.String("call_sid", callSid),
+			zap.String("mark", markEvent.Mark.Name),
+		)
+		return
+	}
+	ack <- time.Now()
 }
 
-// resample24kTo16k resamples 24kHz PCM16 to 16kHz using decimation
-func (h *Handler) resample24kTo16k(pcm24k []byte) []byte {
-	if len(pcm24k) == 0 {
-		return nil
+// interruptPlayback stops the in-flight outbound audio and tells Exotel to drop what it has buffered
+// Returns false if the bot was not speaking
+func (h *Handler) interruptPlayback(session *VoiceSession, reason string) bool {
+	return h.stopPlayback(session, reason, true)
+}
+
+// stopPlayback stops the in-flight outbound audio; sendClear also tells Exotel to drop its buffer,
+// which is not needed when Exotel cleared it itself
+func (h *Handler) stopPlayback(session *VoiceSession, reason string, sendClear bool) bool {
+	session.Mu.Lock()
+	current := session.Playback
+	session.Playback = nil
+	streamSid := session.StreamSid
+	recorder := session.Recorder
+	session.Mu.Unlock()
+
+	if current == nil {
+		return false
+	}
+	current.cancel()
+	// Exotel drops its buffered audio on clear, so the caller heard exactly up to here
+	sent := session.Outbound.Sent()
+	position := session.Outbound.Flush()
+	if recorder != nil {
+		recorder.TruncateBot(sent - position)
 	}
 
-	// Convert bytes to int16 samples
-	samples24k := make([]int16, len(pcm24k)/2)
-	for i := 0; i < len(samples24k); i++ {
-		samples24k[i] = int16(pcm24k[i*2]) | int16(pcm24k[i*2+1])<<8
-	}
-
-	// Resample: 24kHz → 16kHz = 2:3 ratio
-	// Take every 3rd sample from 24k, but average for better quality
-	// Simple approach: take every 3rd sample (24k/3 = 8k, but we need 16k)
-	// Better: linear interpolation
-	// For 24k → 16k: output sample i = input sample at position i * 24/16 = i * 1.5
-	samples16k := make([]int16, len(samples24k)*16/24) // 16/24 = 2/3
-
-	for i := 0; i < len(samples16k); i++ {
-		// Calculate source position (float)
-		srcPos := float64(i) * 24.0 / 16.0 // = i * 1.5
-
-		// Get integer and fractional parts
-		srcIdx := int(srcPos)
-		frac := srcPos - float64(srcIdx)
-
-		if srcIdx < len(samples24k)-1 {
-			// Linear interpolation
-			sample0 := float64(samples24k[srcIdx])
-			sample1 := float64(samples24k[srcIdx+1])
-			interpolated := sample0 + (sample1-sample0)*frac
-			samples16k[i] = int16(interpolated)
-		} else if srcIdx < len(samples24k) {
-			// Last sample
-			samples16k[i] = samples24k[srcIdx]
-		} else {
-			// Beyond end, repeat last sample
-			samples16k[i] = samples24k[len(samples24k)-1]
+	// Marks queued behind the cleared audio will not be echoed
+	session.Mu.Lock()
+	session.PendingMarks = nil
+	session.Mu.Unlock()
+
+	if sendClear {
+		clearEvent := map[string]interface{}{
+			"event":      "clear",
+			"stream_sid": streamSid,
+		}
+		if err := session.writeJSON(clearEvent); err != nil {
+			h.logger.Warn("Failed to send clear event", zap.String("call_sid", session.CallSid), zap.Error(err))
 		}
 	}
 
-	// Convert back to bytes (little-endian)
-	result := make([]byte, len(samples16k)*2)
-	for i, sample := range samples16k {
-		result[i*2] = byte(sample & 0xFF)
-		result[i*2+1] = byte((sample >> 8) & 0xFF)
-	}
+	h.logger.Info("Barge-in: stopped bot playback",
+		zap.String("call_sid", session.CallSid),
+		zap.String("reason", reason),
+		zap.Int64("position_ms", position.Milliseconds()),
+	)
+	return true
+}
 
-	return result
+// bargeInEnabled reports whether caller speech may interrupt the bot
+// Campaigns can set custom_parameters.allow_barge_in=false for disclosures that must be heard in full
+func bargeInEnabled(session *VoiceSession) bool {
+	session.Mu.RLock()
+	defer session.Mu.RUnlock()
+	switch v := session.CustomParameters["allow_barge_in"].(type) {
+	case bool:
+		return v
+	case string:
+		return v != "false" && v != "0"
+	}
+	return true
 }
 
 // sendTextResponse sends a text response to Exotel (fallback when TTS fails)
 func (h *Handler) sendTextResponse(session *VoiceSession, text string) {
 	session.Mu.RLock()
-	conn := session.Conn
 	streamSid := session.StreamSid
 	session.Mu.RUnlock()
 
-	if conn == nil {
-		return
-	}
-
 	response := map[string]interface{}{
 		"event":      "response",
 		"stream_sid": streamSid,
 		"text":       text,
 	}
 
-	responseJSON, _ := json.Marshal(response)
-	conn.WriteMessage(websocket.TextMessage, responseJSON)
+	session.writeJSON(response)
 }
 
 // handleClearEvent processes Exotel "clear" event for barge-in support
@@ -1539,6 +2269,10 @@ func (h *Handler) handleClearEvent(callSid string, message []byte) {
 
 	session := getSession(callSid)
 	if session != nil {
+		// Stop our own playback loop too, otherwise it keeps refilling Exotel's buffer;
+		// Exotel has already dropped what it buffered, so no clear is sent back
+		h.stopPlayback(session, "exotel_clear", false)
+
 		// Clear audio buffer to stop current processing
 		session.AudioBuffer.Clear()
 
@@ -1571,12 +2305,15 @@ func (h *Handler) handleStopEvent(callSid string, message []byte) {
 	if session != nil {
 		session.Mu.Lock()
 		session.IsActive = false
+		hasHistory := len(session.ConversationHistory) > 0
 		session.Mu.Unlock()
 
 		// Persist conversation summary if AI service is available
-		if h.cfg.FeatureAI && h.aiManager != nil && len(session.ConversationHistory) > 0 {
+		if h.cfg.FeatureAI && h.aiManager != nil && hasHistory {
 			go h.persistConversationSummary(session)
 		}
+
+		go h.saveRecording(session)
 	}
 
 	// Cleanup will happen when WebSocket connection closes
@@ -1587,8 +2324,9 @@ func (h *Handler) persistConversationSummary(session *VoiceSession) {
 	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
 	defer cancel()
 
-	// Build conversation text from history
+	// Build conversation text from history; turns are still being updated (played, truncated) until the call ends
 	conversationText := ""
+	session.Mu.RLock()
 	for _, msg := range session.ConversationHistory {
 		role, _ := msg["role"].(string)
 		content, _ := msg["content"].(string)
@@ -1598,6 +2336,7 @@ func (h *Handler) persistConversationSummary(session *VoiceSession) {
 			conversationText += "Assistant: " + content + "\n"
 		}
 	}
+	session.Mu.RUnlock()
 
 	// Use AI manager to summarize if available
 	if h.aiManager != nil {
@@ -1632,7 +2371,7 @@ func (h *Handler) getCallContext(callSid string) map[string]interface{} {
 
 	// Get call record with campaign and persona info
 	call, _ := h.mongoClient.NewQuery("calls").
-		Select("campaign_id", "contact_id").
+		Select("campaign_id", "contact_id", "direction").
 		Eq("call_sid", callSid).
 		FindOne(ctx)
 
@@ -1640,15 +2379,17 @@ func (h *Handler) getCallContext(callSid string) map[string]interface{} {
 	if call != nil {
 		callContext["campaign_id"] = call["campaign_id"]
 		callContext["contact_id"] = call["contact_id"]
+		callContext["direction"] = call["direction"]
 
 		// If campaign exists, get persona
 		if campaignID, ok := call["campaign_id"]; ok && campaignID != nil {
 			campaign, _ := h.mongoClient.NewQuery("campaigns").
-				Select("persona_id").
+				Select("persona_id", "persona_version").
 				Eq("id", fmt.Sprintf("%v", campaignID)).
 				FindOne(ctx)
 			if campaign != nil {
 				callContext["persona_id"] = campaign["persona_id"]
+				callContext["persona_version"] = campaign["persona_version"]
 			}
 		}
 	}
@@ -1656,168 +2397,193 @@ func (h *Handler) getCallContext(callSid string) map[string]interface{} {
 	return callContext
 }
 
-// generateAIResponse calls OpenAI directly with dynamic system prompt from custom_parameters
-// CRITICAL: Loads persona and documents from MongoDB if persona_id is available
-func (h *Handler) generateAIResponse(session *VoiceSession, userText string, callContext map[string]interface{}) string {
-	// If AI service is not enabled, return simple response
-	if !h.cfg.FeatureAI || h.cfg.OpenAIApiKey == "" {
+// fallbackReply is spoken when AI is disabled or no provider answered the turn
+func fallbackReply(aiEnabled bool, userText string) string {
+	if !aiEnabled {
 		return "Thank you for your input. I understand you said: " + userText + ". How can I help you further?"
 	}
+	return "I understand you said: " + userText + ". How can I help you further?"
+}
 
-	// Build conversation history from call context
-	conversationHistory := []map[string]interface{}{}
-	if hist, ok := callContext["conversation_history"].([]map[string]interface{}); ok {
-		conversationHistory = hist
-	}
-
-	// CRITICAL: Load persona and documents from MongoDB if personaLoader is available
-	var ragContext map[string]interface{}
-	if h.personaLoader != nil {
-		// Try to get persona_id from callContext (from campaign) or custom_parameters
-		var personaID *int64
-		
-		// First try: Get from callContext (from campaign)
-		if personaIDVal, ok := callContext["persona_id"]; ok && personaIDVal != nil {
-			// Try to convert to int64
-			if pid, ok := personaIDVal.(int64); ok {
-				personaID = &pid
-			} else if pidStr, ok := personaIDVal.(string); ok {
-				if pidInt, err := strconv.ParseInt(pidStr, 10, 64); err == nil {
-					personaID = &pidInt
-				}
-			} else if pidFloat, ok := personaIDVal.(float64); ok {
-				pidInt := int64(pidFloat)
-				personaID = &pidInt
-			}
-		}
-		
-		// Second try: Get from custom_parameters
-		if personaID == nil && session.CustomParameters != nil {
-			if pidVal, ok := session.CustomParameters["persona_id"]; ok && pidVal != nil {
-				if pid, ok := pidVal.(int64); ok {
-					personaID = &pid
-				} else if pidStr, ok := pidVal.(string); ok {
-					if pidInt, err := strconv.ParseInt(pidStr, 10, 64); err == nil {
-						personaID = &pidInt
-					}
-				} else if pidFloat, ok := pidVal.(float64); ok {
-					pidInt := int64(pidFloat)
-					personaID = &pidInt
-				}
-			}
+// loadSessionContext loads the call context and the call's persona and document index, once per call.
+// The start event begins the load; callers arriving before it finishes wait for it.
+func (h *Handler) loadSessionContext(session *VoiceSession) {
+	session.contextLoad.Do(func() {
+		callContext := map[string]interface{}{}
+		if h.mongoClient != nil {
+			callContext = h.getCallContext(session.CallSid)
 		}
 
-		// Load RAG context (persona data + documents) if persona_id is available
-		if personaID != nil {
-			ctxBg := context.Background()
-			ctx, cancel := context.WithTimeout(ctxBg, 5*time.Second)
-			ragCtx, err := h.personaLoader.BuildRAGContext(ctx, personaID)
+		var pc *ai.PersonaContext
+		if personaID := resolvePersonaID(session, callContext); personaID != nil && h.personaLoader != nil {
+			ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+			var err error
+			pc, err = h.personaLoader.LoadPersonaContext(ctx, *personaID, resolvePersonaVersion(session, callContext))
 			cancel()
-			
-			if err == nil && ragCtx != nil {
-				ragContext = ragCtx
-				h.logger.Info("Loaded RAG context from MongoDB",
+			if err != nil {
+				h.logger.Warn("Failed to load persona context",
 					zap.String("call_sid", session.CallSid),
 					zap.Int64("persona_id", *personaID),
-					zap.Bool("has_persona_data", ragContext["persona_data"] != nil),
-					zap.Bool("has_document_text", ragContext["document_text"] != nil && ragContext["document_text"] != ""),
+					zap.Error(err),
 				)
 			} else {
-				h.logger.Warn("Failed to load RAG context",
+				h.logger.Info("Loaded persona context",
 					zap.String("call_sid", session.CallSid),
-					zap.Int64("persona_id", *personaID),
-					zap.Error(err),
+					zap.Int64("persona_id", pc.PersonaID),
+					zap.String("version", pc.Version),
+					zap.Int("documents", len(pc.Documents)),
+					zap.Int("chunks", pc.Index.Len()),
 				)
 			}
 		}
-	}
 
-	// Build dynamic system prompt from custom_parameters and RAG context
-	systemPrompt := h.buildSystemPromptFromCustomParamsAndRAG(session.CustomParameters, ragContext)
+		session.Mu.Lock()
+		session.CallContext = callContext
+		session.Persona = pc
+		session.Mu.Unlock()
+	})
+}
 
-	// Build messages for OpenAI
-	messages := []map[string]interface{}{
-		{"role": "system", "content": systemPrompt},
-	}
+// sessionPersonaContext returns the call's persona and document index (nil when the call has none)
+func (h *Handler) sessionPersonaContext(session *VoiceSession) *ai.PersonaContext {
+	h.loadSessionContext(session)
+	session.Mu.RLock()
+	defer session.Mu.RUnlock()
+	return session.Persona
+}
 
-	// Add conversation history
-	for _, msg := range conversationHistory {
-		if role, ok := msg["role"].(string); ok {
-			if content, ok := msg["content"].(string); ok {
-				messages = append(messages, map[string]interface{}{
-					"role":    role,
-					"content": content,
-				})
-			}
-		}
+// sessionCallContext returns a copy of the call context for one turn to add its own entries to
+func (h *Handler) sessionCallContext(session *VoiceSession) map[string]interface{} {
+	h.loadSessionContext(session)
+	session.Mu.RLock()
+	defer session.Mu.RUnlock()
+	callContext := make(map[string]interface{}, len(session.CallContext)+2)
+	for k, v := range session.CallContext {
+		callContext[k] = v
 	}
+	return callContext
+}
 
-	// Add current user message
-	messages = append(messages, map[string]interface{}{
-		"role":    "user",
-		"content": userText,
-	})
+// loadRAGContext returns the call's persona and the document passages relevant to userText
+// Returns nil when the call has no persona
+func (h *Handler) loadRAGContext(session *VoiceSession, userText string) map[string]interface{} {
+	pc := h.sessionPersonaContext(session)
+	if pc == nil {
+		return nil
+	}
 
-	// Call OpenAI API directly
-	ctxBg := context.Background()
-	ctx, cancel := context.WithTimeout(ctxBg, time.Duration(h.cfg.AITimeoutMs)*time.Millisecond)
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
 	defer cancel()
 
-	requestBody := map[string]interface{}{
-		"model":       h.cfg.OpenAIModel,
-		"messages":    messages,
-		"max_tokens":  h.cfg.OpenAIMaxTokens,
-		"temperature": 0.7,
-	}
+	ragContext := h.personaLoader.RAGContext(ctx, pc, userText)
+	h.logger.Info("Built RAG context",
+		zap.String("call_sid", session.CallSid),
+		zap.Int64("persona_id", pc.PersonaID),
+		zap.Bool("has_persona_data", ragContext["persona_data"] != nil),
+		zap.Strings("chunks", ragSources(ragContext)),
+	)
+	return ragContext
+}
 
-	jsonData, err := json.Marshal(requestBody)
-	if err != nil {
-		h.logger.Warn("Failed to marshal OpenAI request", zap.Error(err))
-		return "I understand you said: " + userText + ". How can I help you further?"
+// ragSources returns the IDs of the document passages in a RAG context, best first
+func ragSources(ragContext map[string]interface{}) []string {
+	chunks, _ := ragContext["chunks"].([]ai.RetrievedChunk)
+	ids := make([]string, len(chunks))
+	for i, c := range chunks {
+		ids[i] = c.ID
 	}
+	return ids
+}
 
-	url := "https://api.openai.com/v1/chat/completions"
-	httpReq, err := http.NewRequestWithContext(ctx, "POST", url, bytes.NewBuffer(jsonData))
-	if err != nil {
-		h.logger.Warn("Failed to create OpenAI request", zap.Error(err))
-		return "I understand you said: " + userText + ". How can I help you further?"
+// buildConversationRequest assembles the system prompt (persona + RAG), conversation history and user message for ai.Manager
+// ragContext comes from loadRAGContext and may be nil
+func (h *Handler) buildConversationRequest(session *VoiceSession, userText string, callContext, ragContext map[string]interface{}) *ai.ConversationRequest {
+	// Build conversation history from call context
+	conversationHistory := []map[string]interface{}{}
+	if hist, ok := callContext["conversation_history"].([]map[string]interface{}); ok {
+		conversationHistory = hist
+	}
+	// The current utterance is already the last history entry; it is sent as UserText instead
+	if n := len(conversationHistory); n > 0 {
+		last := conversationHistory[n-1]
+		if role, _ := last["role"].(string); role == "user" {
+			if content, _ := last["content"].(string); content == userText {
+				conversationHistory = conversationHistory[:n-1]
+			}
+		}
 	}
 
-	httpReq.Header.Set("Content-Type", "application/json")
-	httpReq.Header.Set("Authorization", "Bearer "+h.cfg.OpenAIApiKey)
+	personaID := resolvePersonaID(session, callContext)
 
-	client := &http.Client{Timeout: time.Duration(h.cfg.AITimeoutMs) * time.Millisecond}
-	resp, err := client.Do(httpReq)
-	if err != nil {
-		h.logger.Warn("OpenAI API request failed", zap.Error(err))
-		return "I understand you said: " + userText + ". How can I help you further?"
+	// Build dynamic system prompt from custom_parameters and RAG context
+	systemPrompt := h.buildSystemPromptFromCustomParamsAndRAG(session.CustomParameters, ragContext)
+	if h.transferAvailable(session) {
+		systemPrompt += "\n\n" + transferInstruction
 	}
-	defer resp.Body.Close()
 
-	if resp.StatusCode != http.StatusOK {
-		body, _ := io.ReadAll(resp.Body)
-		h.logger.Warn("OpenAI API error", zap.Int("status", resp.StatusCode), zap.String("body", string(body)))
-		return "I understand you said: " + userText + ". How can I help you further?"
+	// Only role and content go to the providers; turn metadata stays in the session
+	history := make([]map[string]interface{}, 0, len(conversationHistory))
+	for _, msg := range conversationHistory {
+		role, _ := msg["role"].(string)
+		content, _ := msg["content"].(string)
+		if role != "" && content != "" {
+			history = append(history, map[string]interface{}{
+				"role":    role,
+				"content": content,
+			})
+		}
 	}
 
-	var openAIResp struct {
-		Choices []struct {
-			Message struct {
-				Content string `json:"content"`
-			} `json:"message"`
-		} `json:"choices"`
+	return &ai.ConversationRequest{
+		UserText:            userText,
+		PersonaID:           personaID,
+		ConversationHistory: history,
+		Context:             callContext,
+		SystemPrompt:        systemPrompt,
 	}
+}
 
-	if err := json.NewDecoder(resp.Body).Decode(&openAIResp); err != nil {
-		h.logger.Warn("Failed to decode OpenAI response", zap.Error(err))
-		return "I understand you said: " + userText + ". How can I help you further?"
+// resolvePersonaID returns the persona for the call: the campaign's persona from callContext,
+// otherwise persona_id from custom_parameters
+func resolvePersonaID(session *VoiceSession, callContext map[string]interface{}) *int64 {
+	toInt64 := func(val interface{}) *int64 {
+		switch v := val.(type) {
+		case int64:
+			return &v
+		case int32:
+			id := int64(v)
+			return &id
+		case float64:
+			id := int64(v)
+			return &id
+		case string:
+			if id, err := strconv.ParseInt(v, 10, 64); err == nil {
+				return &id
+			}
+		}
+		return nil
 	}
 
-	if len(openAIResp.Choices) == 0 {
-		return "I understand you said: " + userText + ". How can I help you further?"
+	// First try: Get from callContext (from campaign)
+	if personaID := toInt64(callContext["persona_id"]); personaID != nil {
+		return personaID
 	}
 
-	return strings.TrimSpace(openAIResp.Choices[0].Message.Content)
+	// Second try: Get from custom_parameters
+	session.Mu.RLock()
+	defer session.Mu.RUnlock()
+	return toInt64(session.CustomParameters["persona_id"])
+}
+
+// resolvePersonaVersion returns the persona version pinned for the call by the same source
+// resolvePersonaID took the persona from; 0 means the persona's current version
+func resolvePersonaVersion(session *VoiceSession, callContext map[string]interface{}) int64 {
+	if callContext["persona_id"] != nil {
+		return int64(getFloatFromMap(callContext, "persona_version", 0))
+	}
+	session.Mu.RLock()
+	defer session.Mu.RUnlock()
+	return int64(getFloatFromMap(session.CustomParameters, "persona_version", 0))
 }
 
 // buildSystemPromptFromCustomParamsAndRAG builds dynamic system prompt from custom_parameters and RAG context
@@ -1827,60 +2593,16 @@ func (h *Handler) buildSystemPromptFromCustomParamsAndRAG(customParams map[strin
 
 	// CRITICAL: Priority 1: Use persona data from MongoDB (RAG context) if available
 	if ragContext != nil {
-		personaData, hasPersonaData := ragContext["persona_data"].(map[string]interface{})
+		persona, _ := ragContext["persona_data"].(*ai.Persona)
 		documentText, hasDocText := ragContext["document_text"].(string)
 
-		// Extract persona information from MongoDB
-		if hasPersonaData && personaData != nil {
-			personaName := getStringFromMap(personaData, "name", "")
-			personaAge := getStringFromMap(personaData, "age", "")
-			tone := getStringFromMap(personaData, "tone", "")
-			gender := getStringFromMap(personaData, "gender", "")
-			city := getStringFromMap(personaData, "city", "")
-			language := getStringFromMap(personaData, "language", "")
-
-			// Build persona description from MongoDB data
-			if personaName != "" {
-				personaDesc := fmt.Sprintf("You are %s", personaName)
-				if personaAge != "" {
-					personaDesc += fmt.Sprintf(", %s saal ki", personaAge)
-				}
-				if tone != "" {
-					personaDesc += fmt.Sprintf(" %s", tone)
-				}
-				if gender != "" {
-					personaDesc += fmt.Sprintf(" %s", gender)
-				}
-				if city != "" {
-					personaDesc += fmt.Sprintf(" from %s", city)
-				}
-				personaDesc += "."
-				parts = append(parts, personaDesc)
-			}
-
-			// Add language instruction from MongoDB
-			if language != "" {
-				langInstruction := fmt.Sprintf("Baat karo %s mein", language)
-				if strings.ToLower(language) == "hindi" {
-					langInstruction += " (Hinglish if Hindi)."
-				}
-				parts = append(parts, langInstruction)
-			}
-
-			// Add additional persona fields from MongoDB if available
-			if script, ok := personaData["script"].(string); ok && script != "" {
-				parts = append(parts, fmt.Sprintf("Follow this script: %s", script))
-			}
+		if persona != nil {
+			parts = append(parts, personaPromptParts(persona, getStringFromMap(customParams, "customer_name", ""))...)
 		}
 
-		// Add document text from MongoDB (RAG context)
-		if hasDocText && documentText != "" && len(documentText) > 0 {
-			// Include document content in system prompt (truncate if too long)
-			maxDocLength := 2000 // Limit document text to avoid token limit
-			if len(documentText) > maxDocLength {
-				documentText = documentText[:maxDocLength] + "..."
-			}
-			parts = append(parts, fmt.Sprintf("Use the following information from documents to answer questions: %s", documentText))
+		// Add the document passages retrieved for this turn (RAG context)
+		if hasDocText && documentText != "" {
+			parts = append(parts, fmt.Sprintf("Use the following excerpts from documents to answer questions:\n%s", documentText))
 		}
 	}
 
@@ -1942,11 +2664,105 @@ func (h *Handler) buildSystemPromptFromCustomParamsAndRAG(customParams map[strin
 	return "You are a helpful AI assistant. Provide concise, professional responses."
 }
 
+// personaPromptParts renders a persona into system prompt instructions: its own template when it has
+// one, otherwise a description built from its profile, then its description, instructions, script,
+// objection handling, compliance disclaimers and closing lines
+func personaPromptParts(persona *ai.Persona, customerName string) []string {
+	var parts []string
+
+	if persona.SystemPromptTemplate != "" {
+		parts = append(parts, persona.RenderSystemPrompt(map[string]string{"customer_name": customerName}))
+	} else {
+		if persona.Name != "" {
+			personaDesc := fmt.Sprintf("You are %s", persona.Name)
+			if persona.Age > 0 {
+				personaDesc += fmt.Sprintf(", %d saal ki", persona.Age)
+			}
+			if persona.Tone != "" {
+				personaDesc += fmt.Sprintf(" %s", persona.Tone)
+			}
+			if persona.Gender != "" {
+				personaDesc += fmt.Sprintf(" %s", persona.Gender)
+			}
+			if persona.City != "" {
+				personaDesc += fmt.Sprintf(" from %s", persona.City)
+			}
+			personaDesc += "."
+			parts = append(parts, personaDesc)
+		}
+
+		if persona.Language != "" {
+			langInstruction := fmt.Sprintf("Baat karo %s mein", persona.Language)
+			if strings.ToLower(persona.Language) == "hindi" {
+				langInstruction += " (Hinglish if Hindi)."
+			}
+			parts = append(parts, langInstruction)
+		}
+	}
+
+	// Personas uploaded with cmd/upload-persona keep their whole brief in instructions
+	if persona.Description != "" {
+		parts = append(parts, fmt.Sprintf("About you: %s", persona.Description))
+	}
+	if persona.Instructions != "" {
+		parts = append(parts, fmt.Sprintf("Instructions: %s", persona.Instructions))
+	}
+	if persona.Script != "" {
+		parts = append(parts, fmt.Sprintf("Follow this script: %s", persona.Script))
+	}
+	if len(persona.ObjectionHandlers) > 0 {
+		lines := []string{"Handle these objections:"}
+		for _, handler := range persona.ObjectionHandlers {
+			lines = append(lines, fmt.Sprintf("- If the caller says %q, respond along these lines: %s", handler.Objection, handler.Response))
+		}
+		parts = append(parts, strings.Join(lines, "\n"))
+	}
+	if len(persona.ComplianceDisclaimers) > 0 {
+		parts = append(parts, "You must clearly tell the caller the following during the call:\n- "+strings.Join(persona.ComplianceDisclaimers, "\n- "))
+	}
+	if len(persona.ClosingLines) > 0 {
+		parts = append(parts, "End the conversation with one of these lines:\n- "+strings.Join(persona.ClosingLines, "\n- "))
+	}
+	return parts
+}
+
 // buildSystemPromptFromCustomParams builds dynamic system prompt from custom_parameters only (legacy function)
 func (h *Handler) buildSystemPromptFromCustomParams(customParams map[string]interface{}) string {
 	return h.buildSystemPromptFromCustomParamsAndRAG(customParams, nil)
 }
 
+// getFloatFromMap safely extracts a number from map, accepting JSON numbers and numeric strings
+func getFloatFromMap(m map[string]interface{}, key string, defaultValue float64) float64 {
+	switch val := m[key].(type) {
+	case float64:
+		return val
+	case int:
+		return float64(val)
+	case int64:
+		return float64(val)
+	case int32:
+		return float64(val)
+	case string:
+		if f, err := strconv.ParseFloat(strings.TrimSpace(val), 64); err == nil {
+			return f
+		}
+	}
+	return defaultValue
+}
+
+// getBoolFromMap safely extracts a flag from map, accepting JSON booleans and "true"/"false"/"1"/"0" strings
+func getBoolFromMap(m map[string]interface{}, key string, defaultValue bool) bool {
+	switch val := m[key].(type) {
+	case bool:
+		return val
+	case string:
+		if b, err := strconv.ParseBool(strings.TrimSpace(val)); err == nil {
+			return b
+		}
+	}
+	return defaultValue
+}
+
 // getStringFromMap safely extracts string from map
 func getStringFromMap(m map[string]interface{}, key, defaultValue string) string {
 	if val, ok := m[key]; ok {
@@ -0,0 +1,326 @@
+package handlers
+
+import (
+	"context"
+	"strings"
+	"sync"
+	"time"
+
+	"go.uber.org/zap"
+
+	"github.com/troikatech/calling-agent/pkg/audio"
+)
+
+// What to do when an outbound call reaches an answering machine
+const (
+	amdActionHangup    = "hangup"    // End the call straight away
+	amdActionVoicemail = "voicemail" // Play the pre-rendered voicemail message after the beep, then end the call
+)
+
+// amdIgnoreTranscripts drops what the caller said while detection ran ("Hello?") - the greeting answers it
+const amdIgnoreTranscripts = 1500 * time.Millisecond
+
+// defaultVoicemailPhrases are carrier and voicemail prompts that only a machine says
+var defaultVoicemailPhrases = []string{
+	"leave a message", "leave your message", "record your message", "after the tone", "after the beep",
+	"voicemail", "voice mail", "mailbox", "not available", "is not reachable", "switched off",
+	"संदेश छोड़", "उपलब्ध नहीं", "स्विच ऑफ",
+}
+
+// amdState tracks answering-machine detection for an outbound call
+type amdState struct {
+	detector    *audio.AMD     // Only used from the media reader goroutine
+	action      string         // amdActionHangup or amdActionVoicemail
+	phrases     []string       // Transcript phrases that mean a machine answered
+	startedAt   time.Time      // When detection started (start event)
+	decided     chan struct{}  // Closed once a decision has been made
+	result      audio.AMDEvent // AMDHuman, AMDMachine or AMDNotSure once decided; guarded by session.Mu
+	reason      string         // Heuristic that decided; guarded by session.Mu
+	ignoreUntil time.Time      // Transcripts before this are dropped; guarded by session.Mu
+	cue         chan struct{}  // Closed when the machine greeting is over (beep or trailing silence)
+	cueOnce     sync.Once      // Guards closing cue
+	voicemail   chan []byte    // Pre-rendered voicemail message (16kHz PCM16), nil if synthesis failed
+}
+
+// newAMDState builds detection state from campaign custom_parameters, or returns nil when AMD is off for the call
+// Only outbound calls (direction from the call record) are checked; an inbound caller is always a person
+// Supported keys: amd_enabled, amd_action, amd_keywords (comma separated), amd_greeting_ms,
+// amd_after_greeting_silence_ms, amd_initial_silence_ms, amd_total_analysis_ms, amd_energy_threshold
+func (h *Handler) newAMDState(params map[string]interface{}, direction string) *amdState {
+	if direction != "outbound" || !getBoolFromMap(params, "amd_enabled", h.cfg.VoicebotAMDEnabled) {
+		return nil
+	}
+
+	cfg := audio.DefaultAMDConfig()
+	cfg.GreetingMs = int(getFloatFromMap(params, "amd_greeting_ms", float64(cfg.GreetingMs)))
+	cfg.AfterGreetingSilenceMs = int(getFloatFromMap(params, "amd_after_greeting_silence_ms", float64(cfg.AfterGreetingSilenceMs)))
+	cfg.InitialSilenceMs = int(getFloatFromMap(params, "amd_initial_silence_ms", float64(cfg.InitialSilenceMs)))
+	cfg.TotalAnalysisMs = int(getFloatFromMap(params, "amd_total_analysis_ms", float64(cfg.TotalAnalysisMs)))
+	cfg.EnergyThreshold = getFloatFromMap(params, "amd_energy_threshold", cfg.EnergyThreshold)
+
+	action := getStringFromMap(params, "amd_action", h.cfg.VoicebotAMDAction)
+	if action != amdActionVoicemail {
+		action = amdActionHangup
+	}
+
+	phrases := append([]string(nil), defaultVoicemailPhrases...)
+	for _, phrase := range strings.Split(getStringFromMap(params, "amd_keywords", ""), ",") {
+		if phrase = strings.ToLower(strings.TrimSpace(phrase)); phrase != "" {
+			phrases = append(phrases, phrase)
+		}
+	}
+
+	return &amdState{
+		detector:  audio.NewAMD(cfg, 16000),
+		action:    action,
+		phrases:   phrases,
+		startedAt: time.Now(),
+		decided:   make(chan struct{}),
+		cue:       make(chan struct{}),
+		voicemail: make(chan []byte, 1),
+	}
+}
+
+// amd returns the call's detection state, nil until startAMD has found the call to be outbound
+func (s *VoiceSession) amd() *amdState {
+	s.Mu.RLock()
+	defer s.Mu.RUnlock()
+	return s.AMD
+}
+
+// startAMD waits for the call context to learn the call's direction. Outbound calls get detection, rendering the
+// voicemail message while it runs, and are greeted once they turn out to be a person; other calls are greeted at once
+func (h *Handler) startAMD(session *VoiceSession) {
+	callContext := h.sessionCallContext(session)
+	session.Mu.RLock()
+	params := session.CustomParameters
+	session.Mu.RUnlock()
+
+	state := h.newAMDState(params, getString(callContext, "direction"))
+	if state == nil {
+		h.sendGreeting(session)
+		return
+	}
+	session.Mu.Lock()
+	session.AMD = state
+	session.Mu.Unlock()
+
+	if state.action == amdActionVoicemail {
+		go func() {
+			session.Mu.RLock()
+			message := getStringFromMap(session.CustomParameters, "voicemail_message", "")
+			session.Mu.RUnlock()
+			if message == "" || !h.ttsAvailable() {
+				state.voicemail <- nil
+				return
+			}
+
+			ctx, cancel := context.WithTimeout(context.Background(), 15*time.Second)
+			defer cancel()
+			pcm, err := h.synthesizePrompt(ctx, session, message)
+			if err != nil {
+				h.logger.Warn("Failed to render voicemail message", zap.String("call_sid", session.CallSid), zap.Error(err))
+			}
+			state.voicemail <- pcm
+		}()
+	}
+
+	// Media may stop arriving before the detector decides, so bound the wait
+	timeout := time.Duration(state.detector.Config().TotalAnalysisMs)*time.Millisecond + time.Second
+	select {
+	case <-state.decided:
+	case <-time.After(timeout):
+		h.decideAMD(session, audio.AMDNotSure, "timeout")
+	}
+
+	session.Mu.RLock()
+	result := state.result
+	session.Mu.RUnlock()
+	if result == audio.AMDMachine {
+		return
+	}
+	h.sendGreeting(session)
+}
+
+// runAMD feeds inbound audio to the detector and reports whether the conversation pipeline should skip it
+// (detection still running, or a machine answered)
+func (h *Handler) runAMD(session *VoiceSession, pcm []byte) bool {
+	state := session.amd()
+
+	session.Mu.RLock()
+	result, reason := state.result, state.reason
+	sttStream := session.STTStream
+	session.Mu.RUnlock()
+
+	if result == audio.AMDHuman || result == audio.AMDNotSure {
+		return false
+	}
+	if result == audio.AMDMachine && state.detector.Result() == 0 {
+		// Decided from the transcript - keep listening for the end of the greeting
+		state.detector.Decide(audio.AMDMachine, reason)
+	}
+
+	for _, event := range state.detector.Process(pcm) {
+		switch event {
+		case audio.AMDHuman, audio.AMDMachine, audio.AMDNotSure:
+			h.decideAMD(session, event, state.detector.Reason())
+		case audio.AMDBeep, audio.AMDGreetingEnded:
+			state.cueOnce.Do(func() { close(state.cue) })
+		}
+	}
+
+	// While undecided the live STT stream still gets the audio so voicemail prompts can be spotted
+	if result == 0 && sttStream != nil {
+		if err := sttStream.Send(pcm); err != nil {
+			h.logger.Debug("Failed to forward AMD audio to STT stream", zap.String("call_sid", session.CallSid), zap.Error(err))
+		}
+	}
+
+	session.Mu.RLock()
+	defer session.Mu.RUnlock()
+	return state.result == 0 || state.result == audio.AMDMachine
+}
+
+// checkAMDTranscript decides "machine" when an interim or final transcript contains a voicemail prompt
+func (h *Handler) checkAMDTranscript(session *VoiceSession, text string) {
+	state := session.amd()
+	if state == nil || text == "" {
+		return
+	}
+	session.Mu.RLock()
+	undecided := state.result == 0
+	session.Mu.RUnlock()
+	if !undecided {
+		return
+	}
+
+	lower := strings.ToLower(text)
+	for _, phrase := range state.phrases {
+		if strings.Contains(lower, phrase) {
+			h.decideAMD(session, audio.AMDMachine, "keyword:"+phrase)
+			return
+		}
+	}
+}
+
+// amdHoldsTranscripts reports whether transcripts must not reach the LLM: detection is still running,
+// a machine answered, or the transcript is the caller's greeting from before the decision
+func amdHoldsTranscripts(session *VoiceSession) bool {
+	session.Mu.RLock()
+	defer session.Mu.RUnlock()
+	state := session.AMD
+	if state == nil {
+		return false
+	}
+	return state.result == 0 || state.result == audio.AMDMachine || time.Now().Before(state.ignoreUntil)
+}
+
+// decideAMD records the detection result once and acts on it
+func (h *Handler) decideAMD(session *VoiceSession, result audio.AMDEvent, reason string) {
+	state := session.amd()
+
+	session.Mu.Lock()
+	if state.result != 0 {
+		session.Mu.Unlock()
+		return
+	}
+	state.result = result
+	state.reason = reason
+	if result != audio.AMDMachine {
+		state.ignoreUntil = time.Now().Add(amdIgnoreTranscripts)
+		session.PendingTranscript = nil
+	}
+	close(state.decided)
+	session.Mu.Unlock()
+
+	label := map[audio.AMDEvent]string{
+		audio.AMDHuman:   "human",
+		audio.AMDMachine: "machine",
+		audio.AMDNotSure: "not_sure",
+	}[result]
+	detectionMs := time.Since(state.startedAt).Milliseconds()
+
+	h.logger.Info("Answering machine detection result",
+		zap.String("call_sid", session.CallSid),
+		zap.String("result", label),
+		zap.String("reason", reason),
+		zap.Int64("detection_ms", detectionMs),
+	)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	h.mongoClient.NewQuery("calls").
+		Eq("call_sid", session.CallSid).
+		UpdateOne(ctx, map[string]interface{}{
+			"amd_result":       label,
+			"amd_reason":       reason,
+			"amd_detection_ms": detectionMs,
+			"updated_at":       time.Now().Format(time.RFC3339),
+		})
+	cancel()
+
+	if result == audio.AMDMachine {
+		go h.handleAnsweringMachine(session)
+	}
+}
+
+// handleAnsweringMachine marks the contact as voicemail, then hangs up or leaves the voicemail message after the beep
+func (h *Handler) handleAnsweringMachine(session *VoiceSession) {
+	state := session.amd()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	h.mongoClient.NewQuery("campaign_contacts").
+		Eq("call_sid", session.CallSid).
+		UpdateOne(ctx, map[string]interface{}{
+			"disposition": "voicemail",
+		})
+	h.mongoClient.NewQuery("calls").
+		Eq("call_sid", session.CallSid).
+		UpdateOne(ctx, map[string]interface{}{
+			"disposition": "voicemail",
+			"updated_at":  time.Now().Format(time.RFC3339),
+		})
+	cancel()
+
+	// Nothing the machine says needs transcribing
+	h.stopSTTStream(session)
+
+	if state.action != amdActionVoicemail {
+		h.endStream(session, "voicemail")
+		return
+	}
+
+	// Speak after the beep; give up waiting for it eventually and speak anyway
+	session.Mu.RLock()
+	beepTimeout := time.Duration(getFloatFromMap(session.CustomParameters, "amd_beep_timeout_ms", 20000)) * time.Millisecond
+	session.Mu.RUnlock()
+	select {
+	case <-state.cue:
+	case <-time.After(beepTimeout):
+		h.logger.Info("No beep heard, leaving voicemail anyway", zap.String("call_sid", session.CallSid))
+	}
+
+	var pcm []byte
+	select {
+	case pcm = <-state.voicemail:
+	case <-time.After(10 * time.Second):
+	}
+	if len(pcm) == 0 {
+		h.logger.Warn("No voicemail message available, hanging up", zap.String("call_sid", session.CallSid))
+		h.endStream(session, "voicemail")
+		return
+	}
+
+	result := h.streamPCMAudio(session, pcm, "voicemail_done")
+
+	ctx, cancel = context.WithTimeout(context.Background(), 5*time.Second)
+	h.mongoClient.NewQuery("calls").
+		Eq("call_sid", session.CallSid).
+		UpdateOne(ctx, map[string]interface{}{
+			"voicemail_left":      !result.Interrupted,
+			"voicemail_played_ms": result.PlayedMs,
+			"updated_at":          time.Now().Format(time.RFC3339),
+		})
+	cancel()
+
+	h.endStream(session, "voicemail")
+}
@@ -0,0 +1,30 @@
+package handlers
+
+import (
+	"testing"
+
+	"github.com/troikatech/calling-agent/pkg/env"
+)
+
+func TestNewAMDState_OutboundOnly(t *testing.T) {
+	h := &Handler{cfg: &env.Config{VoicebotAMDEnabled: true}}
+
+	tests := []struct {
+		name      string
+		params    map[string]interface{}
+		direction string
+		want      bool
+	}{
+		{"outbound", nil, "outbound", true},
+		{"inbound", nil, "inbound", false},
+		{"no call record", nil, "", false},
+		{"disabled for the campaign", map[string]interface{}{"amd_enabled": false}, "outbound", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := h.newAMDState(tt.params, tt.direction) != nil; got != tt.want {
+				t.Errorf("AMD enabled = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,86 @@
+package handlers
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/troikatech/calling-agent/pkg/audio"
+)
+
+// supportedStreamRates are the media sample rates Exotel can be asked for
+var supportedStreamRates = map[int]bool{8000: true, 16000: true, 24000: true}
+
+// streamFormatFromQuery resolves the stream format from the WebSocket URL
+// A sample-rate without an encoding means linear PCM16 at that rate (Exotel's raw stream);
+// with neither, the stream is telephony-standard 8kHz μ-law
+func streamFormatFromQuery(sampleRate, encoding string) (audio.Format, error) {
+	format := audio.DefaultStreamFormat
+	if sampleRate == "" && encoding == "" {
+		return format, nil
+	}
+
+	if sampleRate != "" {
+		rate, err := strconv.Atoi(sampleRate)
+		if err != nil || !supportedStreamRates[rate] {
+			return audio.DefaultStreamFormat, fmt.Errorf("unsupported sample-rate %q", sampleRate)
+		}
+		format = audio.Format{Encoding: audio.EncodingPCM16, SampleRate: rate}
+	}
+
+	if encoding != "" {
+		enc, ok := audio.ParseEncoding(encoding)
+		if !ok {
+			return audio.DefaultStreamFormat, fmt.Errorf("unsupported encoding %q", encoding)
+		}
+		format.Encoding = enc
+	}
+	return format, nil
+}
+
+// streamFormat returns the start event's media_format, or fallback when it has none
+// Exotel nests media_format under "start"; sample_rate may be a string or a number.
+// Fields the event leaves out or that are not understood keep the fallback's value
+func (e *StartEvent) streamFormat(fallback audio.Format) audio.Format {
+	mf := e.MediaFormat
+	if mf == nil {
+		mf = e.Start.MediaFormat
+	}
+	if mf == nil {
+		return fallback
+	}
+
+	format := fallback
+	if rate := int(getFloatFromMap(mf, "sample_rate", 0)); supportedStreamRates[rate] {
+		format.SampleRate = rate
+	}
+
+	if enc, ok := audio.ParseEncoding(getStringFromMap(mf, "encoding", "")); ok {
+		format.Encoding = enc
+	} else if bits := bitsPerSample(getStringFromMap(mf, "bit_rate", ""), format.SampleRate); bits == 16 {
+		// "base64" only names the transport; the bit rate tells linear PCM from G.711
+		format.Encoding = audio.EncodingPCM16
+	} else if bits == 8 && format.Encoding == audio.EncodingPCM16 {
+		format.Encoding = audio.EncodingMuLaw
+	}
+	return format
+}
+
+// bitsPerSample derives the sample width from a bit rate such as "128kbps", or returns 0 when unknown
+func bitsPerSample(bitRate string, sampleRate int) int {
+	kbps, err := strconv.Atoi(strings.TrimSuffix(strings.ToLower(strings.TrimSpace(bitRate)), "kbps"))
+	if err != nil || sampleRate == 0 {
+		return 0
+	}
+	return kbps * 1000 / sampleRate
+}
+
+// streamFormat returns the session's negotiated media format
+func (s *VoiceSession) streamFormat() audio.Format {
+	s.Mu.RLock()
+	defer s.Mu.RUnlock()
+	if s.Format.SampleRate == 0 {
+		return audio.DefaultStreamFormat
+	}
+	return s.Format
+}
@@ -0,0 +1,282 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/gorilla/websocket"
+	"go.uber.org/zap"
+
+	"github.com/troikatech/calling-agent/pkg/ai"
+)
+
+// DTMFEvent represents Exotel "dtmf" event sent when the caller presses a key during the stream
+type DTMFEvent struct {
+	Event     string `json:"event"`
+	StreamSid string `json:"stream_sid"`
+	DTMF      struct {
+		Digit    string `json:"digit"`
+		Duration string `json:"duration,omitempty"`
+	} `json:"dtmf"`
+}
+
+// Keypress actions that can be mapped to digits in a campaign's or persona's keypress_map
+const (
+	keypressOptOut         = "opt_out"
+	keypressTransferAgent  = "transfer_agent"
+	keypressRepeatLast     = "repeat_last"
+	keypressSwitchLanguage = "switch_language" // Written as "switch_language:<code>", e.g. "switch_language:hi"
+	keypressConfirm        = "confirm"
+)
+
+// defaultKeypressMap keeps the long-standing "press 3 to opt out" behaviour when nothing is configured
+var defaultKeypressMap = map[string]string{
+	"3": keypressOptOut,
+}
+
+// handleDTMFEvent processes Exotel "dtmf" event and runs the action mapped to the digit
+func (h *Handler) handleDTMFEvent(callSid string, message []byte) {
+	var dtmfEvent DTMFEvent
+	if err := json.Unmarshal(message, &dtmfEvent); err != nil {
+		h.logger.Warn("Failed to parse dtmf event", zap.Error(err))
+		return
+	}
+
+	digit := strings.TrimSpace(dtmfEvent.DTMF.Digit)
+	session := getSession(callSid)
+	if session == nil || digit == "" {
+		return
+	}
+
+	session.Mu.Lock()
+	session.Digits += digit
+	digits := session.Digits
+	session.Mu.Unlock()
+	session.noteActivity(true)
+
+	// A keypress interrupts the bot just like speech does
+	h.interruptPlayback(session, "dtmf")
+
+	go func() {
+		// The keypress map may need the persona, which can still be loading
+		action := h.keypressMap(session)[digit]
+		h.logger.Info("Caller pressed key",
+			zap.String("call_sid", callSid),
+			zap.String("digit", digit),
+			zap.String("action", action),
+		)
+
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		h.mongoClient.NewQuery("campaign_contacts").
+			Eq("call_sid", callSid).
+			UpdateOne(ctx, map[string]interface{}{
+				"ivr_digits": digits,
+			})
+		cancel()
+
+		// Serialise with the STT → AI → TTS pipeline so actions never talk over a reply
+		session.ProcessingMu.Lock()
+		defer session.ProcessingMu.Unlock()
+		h.runKeypressAction(session, digit, action)
+	}()
+}
+
+// runKeypressAction performs a mapped keypress action; unmapped digits are handed to the LLM
+func (h *Handler) runKeypressAction(session *VoiceSession, digit, action string) {
+	name, value, _ := strings.Cut(action, ":")
+
+	switch name {
+	case keypressOptOut:
+		h.optOutCaller(session)
+	case keypressTransferAgent:
+		h.transferToAgent(session, "dtmf:"+digit, true)
+	case keypressRepeatLast:
+		h.repeatLastMessage(session)
+	case keypressSwitchLanguage:
+		h.switchLanguage(session, digit, value)
+	case keypressConfirm:
+		h.respondToTranscript(session, keypressUtterance(fmt.Sprintf("[Caller pressed key %s to confirm]", digit)))
+	default:
+		if action != "" {
+			h.logger.Warn("Unknown keypress action", zap.String("call_sid", session.CallSid), zap.String("action", action))
+		}
+		h.respondToTranscript(session, keypressUtterance(fmt.Sprintf("[Caller pressed key %s]", digit)))
+	}
+}
+
+// keypressMap resolves the digit → action map for the call, loading it once per session
+// custom_parameters.keypress_map takes priority over the persona's keypress_map
+func (h *Handler) keypressMap(session *VoiceSession) map[string]string {
+	session.Mu.RLock()
+	cached := session.KeypressMap
+	fromParams := parseKeypressMap(session.CustomParameters["keypress_map"])
+	session.Mu.RUnlock()
+	if cached != nil {
+		return cached
+	}
+
+	persona := h.sessionPersona(session)
+	keypressMap := fromParams
+	if keypressMap == nil && persona != nil && len(persona.KeypressMap) > 0 {
+		keypressMap = persona.KeypressMap
+	}
+	if keypressMap == nil {
+		keypressMap = defaultKeypressMap
+	}
+	if !persona.AllowsTool(ai.ToolKeypress) {
+		// Opting out must always work; other keys are still heard, but only as input to the conversation
+		keypressMap = optOutKeys(keypressMap)
+	}
+
+	session.Mu.Lock()
+	session.KeypressMap = keypressMap
+	session.Mu.Unlock()
+	return keypressMap
+}
+
+// optOutKeys keeps only the digits of a keypress map that opt the caller out
+func optOutKeys(keypressMap map[string]string) map[string]string {
+	optOut := make(map[string]string)
+	for digit, action := range keypressMap {
+		if action == keypressOptOut {
+			optOut[digit] = action
+		}
+	}
+	return optOut
+}
+
+// parseKeypressMap accepts a keypress map as an object or a JSON string (custom_parameters are often flattened)
+func parseKeypressMap(val interface{}) map[string]string {
+	var raw map[string]interface{}
+	switch v := val.(type) {
+	case map[string]interface{}:
+		raw = v
+	case map[string]string:
+		return v
+	case string:
+		if v == "" || json.Unmarshal([]byte(v), &raw) != nil {
+			return nil
+		}
+	default:
+		return nil
+	}
+
+	keypressMap := make(map[string]string, len(raw))
+	for digit, action := range raw {
+		if a, ok := action.(string); ok && a != "" {
+			keypressMap[digit] = a
+		}
+	}
+	if len(keypressMap) == 0 {
+		return nil
+	}
+	return keypressMap
+}
+
+// optOutCaller adds the caller to the suppression list, marks the contact as opted out and ends the call
+func (h *Handler) optOutCaller(session *VoiceSession) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	// Add to suppression list (idempotent by msisdn_e164)
+	h.mongoClient.NewQuery("suppression").Insert(ctx, map[string]interface{}{
+		"msisdn_e164": normalizePhoneNumber(session.To),
+		"source":      "ivr",
+		"reason":      "opt-out via DTMF",
+		"created_at":  time.Now().Format(time.RFC3339),
+	})
+
+	// Update campaign_contacts status to skipped
+	h.mongoClient.NewQuery("campaign_contacts").
+		Eq("call_sid", session.CallSid).
+		UpdateOne(ctx, map[string]interface{}{
+			"status":      "skipped",
+			"disposition": "opt_out",
+		})
+
+	h.mongoClient.NewQuery("calls").
+		Eq("call_sid", session.CallSid).
+		UpdateOne(ctx, map[string]interface{}{
+			"disposition": "opt_out",
+			"updated_at":  time.Now().Format(time.RFC3339),
+		})
+
+	session.Mu.RLock()
+	message := getStringFromMap(session.CustomParameters, "opt_out_message", "You have been unsubscribed and will not be called again. Goodbye.")
+	session.Mu.RUnlock()
+
+	h.sendPrompt(session, message)
+	h.endStream(session, "opt_out")
+}
+
+// repeatLastMessage replays the last thing the bot said in full
+func (h *Handler) repeatLastMessage(session *VoiceSession) {
+	session.Mu.RLock()
+	lastMessage := ""
+	for i := len(session.ConversationHistory) - 1; i >= 0; i-- {
+		turn := session.ConversationHistory[i]
+		if role, _ := turn["role"].(string); role != "assistant" {
+			continue
+		}
+		// Replay the full turn even if the caller cut it short the first time
+		if full, ok := turn["full_content"].(string); ok && full != "" {
+			lastMessage = full
+		} else {
+			lastMessage, _ = turn["content"].(string)
+		}
+		break
+	}
+	session.Mu.RUnlock()
+	if lastMessage == "" {
+		// Nothing said yet beyond the greeting
+		lastMessage = h.greetingText(session)
+	}
+
+	h.sendPrompt(session, lastMessage)
+}
+
+// switchLanguage changes the call language for STT and the LLM, then lets the LLM continue in it
+func (h *Handler) switchLanguage(session *VoiceSession, digit, language string) {
+	if language == "" {
+		h.logger.Warn("switch_language keypress without a language code", zap.String("call_sid", session.CallSid))
+		return
+	}
+
+	session.Mu.Lock()
+	if session.CustomParameters == nil {
+		session.CustomParameters = make(map[string]interface{})
+	}
+	session.CustomParameters["language"] = language
+	session.Mu.Unlock()
+
+	// The live STT stream is opened with a fixed language, so reopen it
+	h.stopSTTStream(session)
+	h.startSTTStream(session)
+
+	h.respondToTranscript(session, keypressUtterance(fmt.Sprintf("[Caller pressed key %s to switch the conversation language to %s. Continue in %s.]", digit, language, language)))
+}
+
+// endStream closes the voicebot WebSocket so Exotel moves on to the next applet in the flow (or hangs up)
+func (h *Handler) endStream(session *VoiceSession, reason string) {
+	session.Mu.RLock()
+	conn := session.Conn
+	session.Mu.RUnlock()
+	if conn == nil {
+		return
+	}
+
+	h.logger.Info("Ending voicebot stream",
+		zap.String("call_sid", session.CallSid),
+		zap.String("reason", reason),
+	)
+
+	session.WriteMu.Lock()
+	conn.WriteControl(websocket.CloseMessage,
+		websocket.FormatCloseMessage(websocket.CloseNormalClosure, reason),
+		time.Now().Add(time.Second))
+	session.WriteMu.Unlock()
+	conn.Close()
+}
@@ -0,0 +1,94 @@
+package handlers
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/troikatech/calling-agent/pkg/ai"
+)
+
+// personaSession is a voicebot session whose persona context is already loaded
+func personaSession(persona *ai.Persona, params map[string]interface{}) *VoiceSession {
+	session := &VoiceSession{CallSid: "CA123", CustomParameters: params, CallContext: map[string]interface{}{}}
+	if persona != nil {
+		session.Persona = &ai.PersonaContext{Persona: persona}
+	}
+	session.contextLoad.Do(func() {})
+	return session
+}
+
+func TestKeypressMap(t *testing.T) {
+	h := &Handler{}
+	personaMap := map[string]string{"1": keypressTransferAgent, "9": keypressOptOut}
+
+	tests := []struct {
+		name    string
+		persona *ai.Persona
+		params  map[string]interface{}
+		want    map[string]string
+	}{
+		{"no configuration", nil, nil, defaultKeypressMap},
+		{"persona map", &ai.Persona{KeypressMap: personaMap}, nil, personaMap},
+		{"custom parameters override the persona", &ai.Persona{KeypressMap: personaMap},
+			map[string]interface{}{"keypress_map": `{"2":"repeat_last"}`}, map[string]string{"2": keypressRepeatLast}},
+		{"keypress tool not allowed keeps opt-out", &ai.Persona{KeypressMap: personaMap, AllowedTools: []string{ai.ToolTransfer}},
+			nil, map[string]string{"9": keypressOptOut}},
+		{"keypress tool not allowed keeps the default opt-out", &ai.Persona{AllowedTools: []string{}},
+			nil, defaultKeypressMap},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := h.keypressMap(personaSession(tt.persona, tt.params))
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("keypressMap = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseKeypressMap(t *testing.T) {
+	tests := []struct {
+		name string
+		val  interface{}
+		want map[string]string
+	}{
+		{"object", map[string]interface{}{"1": "transfer_agent", "2": "", "3": 4}, map[string]string{"1": "transfer_agent"}},
+		{"string map", map[string]string{"9": "opt_out"}, map[string]string{"9": "opt_out"}},
+		{"flattened JSON", `{"5":"switch_language:hi"}`, map[string]string{"5": "switch_language:hi"}},
+		{"invalid JSON", `{"5":`, nil},
+		{"empty string", "", nil},
+		{"no usable actions", map[string]interface{}{"1": ""}, nil},
+		{"other type", 42, nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := parseKeypressMap(tt.val); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("parseKeypressMap = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestGreetingText(t *testing.T) {
+	h := &Handler{}
+	persona := &ai.Persona{GreetingText: "Namaste, this is Priya from Troika."}
+
+	tests := []struct {
+		name    string
+		persona *ai.Persona
+		params  map[string]interface{}
+		want    string
+	}{
+		{"default", nil, nil, defaultGreetingText},
+		{"persona", persona, nil, persona.GreetingText},
+		{"custom parameters over the persona", persona, map[string]interface{}{"greeting_text": "Hi there!"}, "Hi there!"},
+		{"empty custom parameter ignored", persona, map[string]interface{}{"greeting_text": ""}, persona.GreetingText},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := h.greetingText(personaSession(tt.persona, tt.params)); got != tt.want {
+				t.Errorf("greetingText = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,188 @@
+package handlers
+
+import (
+	"context"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+// inactivityPollInterval is how often the inactivity watcher checks the session
+const inactivityPollInterval = 250 * time.Millisecond
+
+// inactivitySettings controls silence reprompts and the inactivity hangup for a call
+type inactivitySettings struct {
+	Timeout      time.Duration // Caller silence (with the bot idle) before a reprompt
+	MaxReprompts int           // Reprompts before giving up; 0 closes the call on the first timeout
+	RepromptText string
+	ClosingText  string
+}
+
+// defaultInactivitySettings applies when neither custom_parameters nor the persona configure inactivity handling
+var defaultInactivitySettings = inactivitySettings{
+	Timeout:      8 * time.Second,
+	MaxReprompts: 2,
+	RepromptText: "Are you still there?",
+	ClosingText:  "I haven't heard from you, so I'll end the call now. Goodbye.",
+}
+
+// inactivitySettings resolves the call's inactivity settings once per session
+// Keys silence_timeout_sec, max_reprompts, reprompt_text and closing_text are read from
+// custom_parameters first, then from the persona
+func (h *Handler) inactivitySettings(session *VoiceSession) inactivitySettings {
+	session.Mu.RLock()
+	cached := session.Inactivity
+	params := session.CustomParameters
+	session.Mu.RUnlock()
+	if cached != nil {
+		return *cached
+	}
+
+	// custom_parameters win over the persona, which wins over the defaults
+	settings := defaultInactivitySettings
+	if persona := h.sessionPersona(session); persona != nil {
+		if persona.SilenceTimeoutSec > 0 {
+			settings.Timeout = time.Duration(persona.SilenceTimeoutSec * float64(time.Second))
+		}
+		if persona.MaxReprompts != nil {
+			settings.MaxReprompts = *persona.MaxReprompts
+		}
+		if persona.RepromptText != "" {
+			settings.RepromptText = persona.RepromptText
+		}
+		if persona.ClosingText != "" {
+			settings.ClosingText = persona.ClosingText
+		}
+	}
+	if params != nil {
+		if sec := getFloatFromMap(params, "silence_timeout_sec", 0); sec > 0 {
+			settings.Timeout = time.Duration(sec * float64(time.Second))
+		}
+		settings.MaxReprompts = int(getFloatFromMap(params, "max_reprompts", float64(settings.MaxReprompts)))
+		settings.RepromptText = getStringFromMap(params, "reprompt_text", settings.RepromptText)
+		settings.ClosingText = getStringFromMap(params, "closing_text", settings.ClosingText)
+	}
+	if settings.MaxReprompts < 0 {
+		settings.MaxReprompts = 0
+	}
+
+	session.Mu.Lock()
+	session.Inactivity = &settings
+	session.Mu.Unlock()
+	return settings
+}
+
+// noteActivity restarts the session's inactivity timer; caller activity also resets the reprompt count
+func (s *VoiceSession) noteActivity(fromCaller bool) {
+	s.Mu.Lock()
+	s.LastActivity = time.Now()
+	if fromCaller {
+		s.Reprompts = 0
+	}
+	s.Mu.Unlock()
+}
+
+// watchInactivity reprompts a silent caller and ends the call after too many reprompts
+// Silence is only counted while the bot is neither speaking nor working on a reply
+func (h *Handler) watchInactivity(session *VoiceSession) {
+	settings := h.inactivitySettings(session)
+	session.noteActivity(true)
+
+	ticker := time.NewTicker(inactivityPollInterval)
+	defer ticker.Stop()
+
+	for range ticker.C {
+		session.Mu.RLock()
+		ended := !session.IsActive || session.Transferring
+		idleFor := time.Since(session.LastActivity)
+		session.Mu.RUnlock()
+		if ended || getSession(session.CallSid) != session {
+			return
+		}
+
+		if session.awaitingPlayback() {
+			session.noteActivity(false)
+			continue
+		}
+		if idleFor < settings.Timeout {
+			continue
+		}
+
+		// A reply in progress is activity; holding the lock keeps reprompts out of its way
+		if !session.ProcessingMu.TryLock() {
+			session.noteActivity(false)
+			continue
+		}
+		done := h.handleInactivity(session, settings)
+		session.ProcessingMu.Unlock()
+		if done {
+			return
+		}
+	}
+}
+
+// handleInactivity plays a reprompt, or the closing line once reprompts are used up
+// Returns true when the call has been ended
+func (h *Handler) handleInactivity(session *VoiceSession, settings inactivitySettings) bool {
+	session.Mu.Lock()
+	reprompts := session.Reprompts
+	if reprompts < settings.MaxReprompts {
+		session.Reprompts++
+	}
+	session.Mu.Unlock()
+
+	if reprompts < settings.MaxReprompts {
+		h.logger.Info("Caller silent, reprompting",
+			zap.String("call_sid", session.CallSid),
+			zap.Int("reprompt", reprompts+1),
+			zap.Int("max_reprompts", settings.MaxReprompts),
+		)
+		h.speakAssistantLine(session, settings.RepromptText)
+		session.noteActivity(false)
+		return false
+	}
+
+	h.logger.Info("Caller unresponsive, ending call",
+		zap.String("call_sid", session.CallSid),
+		zap.Int("reprompts", reprompts),
+	)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	h.mongoClient.NewQuery("calls").
+		Eq("call_sid", session.CallSid).
+		UpdateOne(ctx, map[string]interface{}{
+			"disposition": "no_response",
+			"reprompts":   reprompts,
+			"updated_at":  time.Now().Format(time.RFC3339),
+		})
+	h.mongoClient.NewQuery("campaign_contacts").
+		Eq("call_sid", session.CallSid).
+		UpdateOne(ctx, map[string]interface{}{
+			"disposition": "no_response",
+		})
+	cancel()
+
+	h.speakAssistantLine(session, settings.ClosingText)
+	h.endStream(session, "no_response")
+	return true
+}
+
+// speakAssistantLine says a scripted line and records it in the conversation so the LLM knows it was said
+func (h *Handler) speakAssistantLine(session *VoiceSession, text string) {
+	session.Mu.Lock()
+	session.ConversationHistory = append(session.ConversationHistory, map[string]interface{}{
+		"role":    "assistant",
+		"content": text,
+	})
+	turnIndex := len(session.ConversationHistory) - 1
+	session.Mu.Unlock()
+
+	result := h.sendPrompt(session, text)
+	markTurnPlayed(session, turnIndex, result)
+
+	heard := text
+	if result.Interrupted {
+		heard = truncateAtFraction(text, result.playedFraction())
+	}
+	h.recordTurn(session, botTurn(turnPrompt, heard, text, result, turnLatency{}))
+}
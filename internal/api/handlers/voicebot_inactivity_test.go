@@ -0,0 +1,71 @@
+package handlers
+
+import (
+	"testing"
+	"time"
+
+	"github.com/troikatech/calling-agent/pkg/ai"
+)
+
+func TestInactivitySettings(t *testing.T) {
+	h := &Handler{}
+
+	tests := []struct {
+		name   string
+		params map[string]interface{}
+		want   inactivitySettings
+	}{
+		{"defaults", nil, defaultInactivitySettings},
+		{"custom parameters over defaults", map[string]interface{}{
+			"silence_timeout_sec": "5", "max_reprompts": float64(1), "reprompt_text": "Hello?", "closing_text": "Bye.",
+		}, inactivitySettings{Timeout: 5 * time.Second, MaxReprompts: 1, RepromptText: "Hello?", ClosingText: "Bye."}},
+		{"reprompts can be disabled", map[string]interface{}{"max_reprompts": float64(0)}, inactivitySettings{
+			Timeout: defaultInactivitySettings.Timeout, RepromptText: defaultInactivitySettings.RepromptText, ClosingText: defaultInactivitySettings.ClosingText,
+		}},
+		{"negative reprompts are clamped", map[string]interface{}{"max_reprompts": float64(-2)}, inactivitySettings{
+			Timeout: defaultInactivitySettings.Timeout, RepromptText: defaultInactivitySettings.RepromptText, ClosingText: defaultInactivitySettings.ClosingText,
+		}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			session := &VoiceSession{CustomParameters: tt.params}
+			if got := h.inactivitySettings(session); got != tt.want {
+				t.Errorf("inactivitySettings = %+v, want %+v", got, tt.want)
+			}
+			if session.Inactivity == nil {
+				t.Error("settings were not cached on the session")
+			}
+		})
+	}
+}
+
+func TestInactivitySettings_Persona(t *testing.T) {
+	h := &Handler{}
+	zero, three := 0, 3
+	persona := &ai.Persona{SilenceTimeoutSec: 12, MaxReprompts: &three, RepromptText: "Hello?"}
+
+	tests := []struct {
+		name    string
+		persona *ai.Persona
+		params  map[string]interface{}
+		want    inactivitySettings
+	}{
+		{"persona over defaults", persona, nil, inactivitySettings{
+			Timeout: 12 * time.Second, MaxReprompts: 3, RepromptText: "Hello?", ClosingText: defaultInactivitySettings.ClosingText,
+		}},
+		{"custom parameters over the persona", persona, map[string]interface{}{
+			"silence_timeout_sec": "5", "max_reprompts": float64(1), "closing_text": "Bye.",
+		}, inactivitySettings{Timeout: 5 * time.Second, MaxReprompts: 1, RepromptText: "Hello?", ClosingText: "Bye."}},
+		{"persona can disable reprompts", &ai.Persona{MaxReprompts: &zero}, nil, inactivitySettings{
+			Timeout: defaultInactivitySettings.Timeout, RepromptText: defaultInactivitySettings.RepromptText, ClosingText: defaultInactivitySettings.ClosingText,
+		}},
+		{"unset persona fields keep the defaults", &ai.Persona{}, nil, defaultInactivitySettings},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := h.inactivitySettings(personaSession(tt.persona, tt.params)); got != tt.want {
+				t.Errorf("inactivitySettings = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
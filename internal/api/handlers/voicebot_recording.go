@@ -0,0 +1,75 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"go.uber.org/zap"
+
+	"github.com/troikatech/calling-agent/pkg/audio"
+	"github.com/troikatech/calling-agent/pkg/storage"
+)
+
+// maxRecordingDuration bounds the memory a call recording can take (about 115MB for 8kHz stereo)
+const maxRecordingDuration = time.Hour
+
+// recordingKey is the storage key of a voicebot call recording
+func recordingKey(callSid string) string {
+	return fmt.Sprintf("voicebot/%s.wav", callSid)
+}
+
+// newCallRecorder returns a recorder at the stream's sample rate, or nil when recording is off
+func (h *Handler) newCallRecorder(format audio.Format) *audio.CallRecorder {
+	if !h.cfg.VoicebotRecording || h.storage == nil {
+		return nil
+	}
+	return audio.NewCallRecorder(format.SampleRate, maxRecordingDuration)
+}
+
+// saveRecording writes the session's recording through the storage driver and links it on the call
+// It runs at most once per session; later calls find no recorder
+func (h *Handler) saveRecording(session *VoiceSession) {
+	session.Mu.Lock()
+	recorder := session.Recorder
+	session.Recorder = nil
+	session.Mu.Unlock()
+
+	if recorder == nil || recorder.Duration() == 0 {
+		return
+	}
+
+	key := recordingKey(session.CallSid)
+	if err := h.storage.SaveRecording(key, recorder.WAV()); err != nil {
+		if errors.Is(err, storage.ErrNotSupported) {
+			h.logger.Info("Storage driver cannot keep voicebot recordings; set STORAGE_DRIVER=local",
+				zap.String("call_sid", session.CallSid),
+			)
+			return
+		}
+		h.logger.Error("Failed to save voicebot recording", zap.String("call_sid", session.CallSid), zap.Error(err))
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	_, err := h.mongoClient.NewQuery("calls").
+		Eq("call_sid", session.CallSid).
+		UpdateOne(ctx, map[string]interface{}{
+			"voicebot_recording_key":         key,
+			"voicebot_recording_duration_ms": recorder.Duration().Milliseconds(),
+			"voicebot_recording_rate":        recorder.SampleRate(),
+		})
+	if err != nil {
+		h.logger.Error("Failed to link voicebot recording", zap.String("call_sid", session.CallSid), zap.Error(err))
+		return
+	}
+
+	h.logger.Info("Voicebot recording saved",
+		zap.String("call_sid", session.CallSid),
+		zap.String("key", key),
+		zap.Duration("duration", recorder.Duration()),
+	)
+}
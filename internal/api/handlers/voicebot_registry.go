@@ -0,0 +1,364 @@
+package handlers
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"os"
+	"sort"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+	"go.uber.org/zap"
+)
+
+// Redis layout of the voicebot session registry
+const (
+	sessionKeyPrefix     = "voicebot:session:" // Hash per live call: owner, stream metadata and heartbeat
+	sessionIndexKey      = "voicebot:sessions" // Sorted set of call_sids scored by last heartbeat (unix ms)
+	controlChannelPrefix = "voicebot:control:" // Pub/sub channel per instance carrying session commands
+)
+
+// sessionTTL is how long an entry outlives its last heartbeat, so calls on a crashed instance drop out
+const sessionTTL = 30 * time.Second
+
+// heartbeatInterval is how often an instance refreshes the entries of the calls it holds
+const heartbeatInterval = 10 * time.Second
+
+// Commands a live session accepts over the control channel
+const (
+	commandHangup   = "hangup"   // End the stream
+	commandInject   = "inject"   // Speak text to the caller, cutting off the bot
+	commandTransfer = "transfer" // Hand the call to a human agent
+)
+
+// sessionInfo is a live call as seen in the registry
+type sessionInfo struct {
+	CallSid     string `json:"call_sid"`
+	StreamSid   string `json:"stream_sid"`
+	Owner       string `json:"owner"` // Instance holding the WebSocket
+	From        string `json:"from"`
+	To          string `json:"to"`
+	Format      string `json:"format"`
+	StartedAt   string `json:"started_at"`
+	HeartbeatAt string `json:"heartbeat_at"`
+}
+
+// sessionCommand is sent to the instance owning a session
+type sessionCommand struct {
+	ID       string `json:"id"`
+	CallSid  string `json:"call_sid"`
+	Action   string `json:"action"`
+	Text     string `json:"text,omitempty"`   // inject: line to speak
+	Reason   string `json:"reason,omitempty"` // hangup, transfer: recorded with the action
+	IssuedBy string `json:"issued_by,omitempty"`
+	IssuedAt string `json:"issued_at"`
+}
+
+// validSessionCommand reports whether action is a known command
+func validSessionCommand(action string) bool {
+	switch action {
+	case commandHangup, commandInject, commandTransfer:
+		return true
+	}
+	return false
+}
+
+// sessionRegistry records which instance holds each live call in Redis and carries commands between instances
+type sessionRegistry struct {
+	client     *redis.Client
+	instanceID string
+	logger     *zap.Logger
+}
+
+// unregisterScript deletes a session entry only if this instance still owns it;
+// a stream that reconnected to another instance has already taken the entry over
+var unregisterScript = redis.NewScript(`
+if redis.call("HGET", KEYS[1], "owner") == ARGV[1] then
+	redis.call("DEL", KEYS[1])
+	redis.call("ZREM", KEYS[2], ARGV[2])
+	return 1
+end
+return 0
+`)
+
+// newSessionRegistry creates a registry for this instance; an empty instanceID is derived from the hostname
+func newSessionRegistry(client *redis.Client, instanceID string, logger *zap.Logger) *sessionRegistry {
+	if instanceID == "" {
+		instanceID = defaultInstanceID()
+	}
+	return &sessionRegistry{
+		client:     client,
+		instanceID: instanceID,
+		logger:     logger,
+	}
+}
+
+// defaultInstanceID is the hostname (the pod name on Kubernetes) plus a random suffix, unique across restarts
+func defaultInstanceID() string {
+	host, err := os.Hostname()
+	if err != nil || host == "" {
+		host = "voicebot"
+	}
+	suffix := make([]byte, 4)
+	rand.Read(suffix)
+	return host + "-" + hex.EncodeToString(suffix)
+}
+
+// channel returns the control channel of an instance
+func (r *sessionRegistry) channel(instanceID string) string {
+	return controlChannelPrefix + instanceID
+}
+
+// describeSession builds the registry entry of a local session held by owner
+func describeSession(session *VoiceSession, owner string, now time.Time) sessionInfo {
+	session.Mu.RLock()
+	defer session.Mu.RUnlock()
+	return sessionInfo{
+		CallSid:     session.CallSid,
+		StreamSid:   session.StreamSid,
+		Owner:       owner,
+		From:        session.From,
+		To:          session.To,
+		Format:      session.Format.String(),
+		StartedAt:   session.StartedAt.UTC().Format(time.RFC3339),
+		HeartbeatAt: now.UTC().Format(time.RFC3339),
+	}
+}
+
+// register writes or refreshes the entries of local sessions, taking ownership of each
+func (r *sessionRegistry) register(ctx context.Context, sessions ...*VoiceSession) error {
+	if len(sessions) == 0 {
+		return nil
+	}
+	now := time.Now()
+	pipe := r.client.Pipeline()
+	for _, session := range sessions {
+		info := describeSession(session, r.instanceID, now)
+		key := sessionKeyPrefix + info.CallSid
+		pipe.HSet(ctx, key, map[string]interface{}{
+			"call_sid":     info.CallSid,
+			"stream_sid":   info.StreamSid,
+			"owner":        info.Owner,
+			"from":         info.From,
+			"to":           info.To,
+			"format":       info.Format,
+			"started_at":   info.StartedAt,
+			"heartbeat_at": info.HeartbeatAt,
+		})
+		pipe.Expire(ctx, key, sessionTTL)
+		pipe.ZAdd(ctx, sessionIndexKey, redis.Z{Score: float64(now.UnixMilli()), Member: info.CallSid})
+	}
+	if _, err := pipe.Exec(ctx); err != nil {
+		return fmt.Errorf("failed to register voice sessions: %w", err)
+	}
+	return nil
+}
+
+// unregister removes a session entry if this instance owns it
+func (r *sessionRegistry) unregister(ctx context.Context, callSid string) error {
+	keys := []string{sessionKeyPrefix + callSid, sessionIndexKey}
+	if err := unregisterScript.Run(ctx, r.client, keys, r.instanceID, callSid).Err(); err != nil {
+		return fmt.Errorf("failed to unregister voice session %s: %w", callSid, err)
+	}
+	return nil
+}
+
+// lookup returns the registry entry of a call, or nil if no instance holds it
+func (r *sessionRegistry) lookup(ctx context.Context, callSid string) (*sessionInfo, error) {
+	fields, err := r.client.HGetAll(ctx, sessionKeyPrefix+callSid).Result()
+	if err != nil {
+		return nil, fmt.Errorf("failed to look up voice session %s: %w", callSid, err)
+	}
+	if len(fields) == 0 {
+		return nil, nil
+	}
+	info := sessionInfoFromHash(fields)
+	return &info, nil
+}
+
+// list returns every live session across instances, oldest first.
+// Index entries whose hash has expired belong to instances that stopped heartbeating and are pruned.
+func (r *sessionRegistry) list(ctx context.Context) ([]sessionInfo, error) {
+	callSids, err := r.client.ZRange(ctx, sessionIndexKey, 0, -1).Result()
+	if err != nil {
+		return nil, fmt.Errorf("failed to list voice sessions: %w", err)
+	}
+	if len(callSids) == 0 {
+		return []sessionInfo{}, nil
+	}
+
+	pipe := r.client.Pipeline()
+	cmds := make([]*redis.MapStringStringCmd, len(callSids))
+	for i, callSid := range callSids {
+		cmds[i] = pipe.HGetAll(ctx, sessionKeyPrefix+callSid)
+	}
+	if _, err := pipe.Exec(ctx); err != nil {
+		return nil, fmt.Errorf("failed to read voice sessions: %w", err)
+	}
+
+	infos := make([]sessionInfo, 0, len(callSids))
+	var stale []interface{}
+	for i, cmd := range cmds {
+		if fields := cmd.Val(); len(fields) > 0 {
+			infos = append(infos, sessionInfoFromHash(fields))
+		} else {
+			stale = append(stale, callSids[i])
+		}
+	}
+	if len(stale) > 0 {
+		if err := r.client.ZRem(ctx, sessionIndexKey, stale...).Err(); err != nil {
+			r.logger.Debug("Failed to prune stale voice sessions", zap.Error(err))
+		}
+	}
+
+	sort.Slice(infos, func(i, j int) bool { return infos[i].StartedAt < infos[j].StartedAt })
+	return infos, nil
+}
+
+// publish sends a command to the instance owning the session and returns how many instances received it
+func (r *sessionRegistry) publish(ctx context.Context, owner string, cmd sessionCommand) (int64, error) {
+	payload, err := json.Marshal(cmd)
+	if err != nil {
+		return 0, fmt.Errorf("failed to encode session command: %w", err)
+	}
+	receivers, err := r.client.Publish(ctx, r.channel(owner), payload).Result()
+	if err != nil {
+		return 0, fmt.Errorf("failed to publish session command: %w", err)
+	}
+	return receivers, nil
+}
+
+// sessionInfoFromHash converts a registry hash to a sessionInfo
+func sessionInfoFromHash(fields map[string]string) sessionInfo {
+	return sessionInfo{
+		CallSid:     fields["call_sid"],
+		StreamSid:   fields["stream_sid"],
+		Owner:       fields["owner"],
+		From:        fields["from"],
+		To:          fields["to"],
+		Format:      fields["format"],
+		StartedAt:   fields["started_at"],
+		HeartbeatAt: fields["heartbeat_at"],
+	}
+}
+
+// localSessions returns a snapshot of the sessions held by this instance
+func localSessions() []*VoiceSession {
+	sessionsMu.RLock()
+	defer sessionsMu.RUnlock()
+	list := make([]*VoiceSession, 0, len(sessions))
+	for _, session := range sessions {
+		list = append(list, session)
+	}
+	return list
+}
+
+// registerSession records that this instance holds the session's stream
+func (h *Handler) registerSession(session *VoiceSession) {
+	if h.registry == nil {
+		return
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+	defer cancel()
+	if err := h.registry.register(ctx, session); err != nil {
+		h.logger.Warn("Voice session not registered", zap.String("call_sid", session.CallSid), zap.Error(err))
+	}
+}
+
+// unregisterSession removes the session's registry entry once its stream has closed
+func (h *Handler) unregisterSession(callSid string) {
+	if h.registry == nil || callSid == "" {
+		return
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+	defer cancel()
+	if err := h.registry.unregister(ctx, callSid); err != nil {
+		h.logger.Warn("Voice session not unregistered", zap.String("call_sid", callSid), zap.Error(err))
+	}
+}
+
+// RunSessionRegistry heartbeats this instance's sessions and executes commands sent to them from any
+// instance until ctx is cancelled, then drops their entries. It returns at once when Redis is not configured.
+func (h *Handler) RunSessionRegistry(ctx context.Context) {
+	if h.registry == nil {
+		return
+	}
+
+	pubsub := h.registry.client.Subscribe(ctx, h.registry.channel(h.registry.instanceID))
+	defer pubsub.Close()
+	commands := pubsub.Channel()
+
+	ticker := time.NewTicker(heartbeatInterval)
+	defer ticker.Stop()
+
+	h.logger.Info("Voice session registry started", zap.String("instance_id", h.registry.instanceID))
+
+	for {
+		select {
+		case <-ctx.Done():
+			for _, session := range localSessions() {
+				h.unregisterSession(session.CallSid)
+			}
+			return
+
+		case <-ticker.C:
+			beatCtx, cancel := context.WithTimeout(ctx, 5*time.Second)
+			if err := h.registry.register(beatCtx, localSessions()...); err != nil {
+				h.logger.Warn("Voice session heartbeat failed", zap.Error(err))
+			}
+			cancel()
+
+		case msg, ok := <-commands:
+			if !ok {
+				return
+			}
+			var cmd sessionCommand
+			if err := json.Unmarshal([]byte(msg.Payload), &cmd); err != nil {
+				h.logger.Warn("Invalid session command", zap.Error(err), zap.String("raw", msg.Payload))
+				continue
+			}
+			session := getSession(cmd.CallSid)
+			if session == nil {
+				h.logger.Warn("Session command for a call not held here",
+					zap.String("call_sid", cmd.CallSid),
+					zap.String("action", cmd.Action),
+				)
+				continue
+			}
+			go h.runSessionCommand(session, cmd)
+		}
+	}
+}
+
+// runSessionCommand carries out a command on a session held by this instance
+func (h *Handler) runSessionCommand(session *VoiceSession, cmd sessionCommand) {
+	h.logger.Info("Running session command",
+		zap.String("call_sid", session.CallSid),
+		zap.String("command_id", cmd.ID),
+		zap.String("action", cmd.Action),
+		zap.String("issued_by", cmd.IssuedBy),
+	)
+
+	reason := cmd.Reason
+	if reason == "" {
+		reason = "operator_" + cmd.Action
+	}
+
+	switch cmd.Action {
+	case commandHangup:
+		h.endStream(session, reason)
+	case commandTransfer:
+		h.transferToAgent(session, reason, true)
+	case commandInject:
+		// Cut the bot off, then wait for the turn it was answering to wind down
+		h.interruptPlayback(session, "operator message")
+		session.ProcessingMu.Lock()
+		defer session.ProcessingMu.Unlock()
+		h.speakAssistantLine(session, cmd.Text)
+	default:
+		h.logger.Warn("Unknown session command", zap.String("call_sid", session.CallSid), zap.String("action", cmd.Action))
+	}
+}
@@ -0,0 +1,155 @@
+package handlers
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"go.uber.org/zap"
+
+	"github.com/troikatech/calling-agent/pkg/errors"
+)
+
+// SessionCommandRequest is the body of POST /api/voicebot/sessions/:call_sid/commands
+type SessionCommandRequest struct {
+	Action string `json:"action" binding:"required"` // hangup, inject or transfer
+	Text   string `json:"text"`                      // inject: what the bot says
+	Reason string `json:"reason"`
+}
+
+// ListVoiceSessions lists live voicebot calls on every instance
+func (h *Handler) ListVoiceSessions(c *gin.Context) {
+	if h.registry == nil {
+		infos := make([]sessionInfo, 0)
+		now := time.Now()
+		for _, session := range localSessions() {
+			infos = append(infos, describeSession(session, h.instanceID(), now))
+		}
+		c.JSON(http.StatusOK, gin.H{"sessions": infos, "count": len(infos)})
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(c.Request.Context(), 5*time.Second)
+	defer cancel()
+
+	infos, err := h.registry.list(ctx)
+	if err != nil {
+		errors.InternalError(c, err, h.logger)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"sessions": infos, "count": len(infos)})
+}
+
+// GetVoiceSession returns the registry entry of a live call
+func (h *Handler) GetVoiceSession(c *gin.Context) {
+	info, err := h.findVoiceSession(c.Request.Context(), c.Param("call_sid"))
+	if err != nil {
+		errors.InternalError(c, err, h.logger)
+		return
+	}
+	if info == nil {
+		errors.NotFound(c, "no live session for this call")
+		return
+	}
+	c.JSON(http.StatusOK, info)
+}
+
+// SendVoiceSessionCommand delivers a command to a live call, on whichever instance holds its stream
+func (h *Handler) SendVoiceSessionCommand(c *gin.Context) {
+	callSID := c.Param("call_sid")
+
+	var req SessionCommandRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		errors.BadRequest(c, err.Error())
+		return
+	}
+	req.Action = strings.ToLower(strings.TrimSpace(req.Action))
+	if !validSessionCommand(req.Action) {
+		errors.BadRequest(c, fmt.Sprintf("unknown action %q (want hangup, inject or transfer)", req.Action))
+		return
+	}
+	req.Text = strings.TrimSpace(req.Text)
+	if req.Action == commandInject && req.Text == "" {
+		errors.BadRequest(c, "text is required for inject")
+		return
+	}
+
+	issuedBy := ""
+	if userID, ok := c.Get("user_id"); ok {
+		issuedBy = fmt.Sprint(userID)
+	}
+	cmd := sessionCommand{
+		ID:       uuid.New().String(),
+		CallSid:  callSID,
+		Action:   req.Action,
+		Text:     req.Text,
+		Reason:   req.Reason,
+		IssuedBy: issuedBy,
+		IssuedAt: time.Now().UTC().Format(time.RFC3339),
+	}
+
+	// Sessions held here skip the round trip through Redis
+	if session := getSession(callSID); session != nil {
+		go h.runSessionCommand(session, cmd)
+		c.JSON(http.StatusAccepted, gin.H{"id": cmd.ID, "call_sid": callSID, "action": cmd.Action, "owner": h.instanceID()})
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(c.Request.Context(), 5*time.Second)
+	defer cancel()
+
+	info, err := h.findVoiceSession(ctx, callSID)
+	if err != nil {
+		errors.InternalError(c, err, h.logger)
+		return
+	}
+	if info == nil {
+		errors.NotFound(c, "no live session for this call")
+		return
+	}
+
+	receivers, err := h.registry.publish(ctx, info.Owner, cmd)
+	if err != nil {
+		errors.InternalError(c, err, h.logger)
+		return
+	}
+	if receivers == 0 {
+		// The owner stopped listening without removing its entry; the entry expires with its heartbeat
+		errors.ErrorResponse(c, http.StatusServiceUnavailable, "Service Unavailable", "the instance holding this call is not responding")
+		return
+	}
+
+	h.logger.Info("Session command sent",
+		zap.String("call_sid", callSID),
+		zap.String("command_id", cmd.ID),
+		zap.String("action", cmd.Action),
+		zap.String("owner", info.Owner),
+	)
+	c.JSON(http.StatusAccepted, gin.H{"id": cmd.ID, "call_sid": callSID, "action": cmd.Action, "owner": info.Owner})
+}
+
+// findVoiceSession looks a call up locally, then in the registry; nil means no instance holds it
+func (h *Handler) findVoiceSession(ctx context.Context, callSID string) (*sessionInfo, error) {
+	if session := getSession(callSID); session != nil {
+		info := describeSession(session, h.instanceID(), time.Now())
+		return &info, nil
+	}
+	if h.registry == nil {
+		return nil, nil
+	}
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+	return h.registry.lookup(ctx, callSID)
+}
+
+// instanceID names this instance in session listings and command responses
+func (h *Handler) instanceID() string {
+	if h.registry != nil {
+		return h.registry.instanceID
+	}
+	return "local"
+}
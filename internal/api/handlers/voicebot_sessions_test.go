@@ -0,0 +1,68 @@
+package handlers
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+
+	"github.com/troikatech/calling-agent/pkg/audio"
+)
+
+// serve runs one request and decodes the JSON response
+func serve(t *testing.T, r http.Handler, req *http.Request) (int, map[string]interface{}) {
+	t.Helper()
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	var body map[string]interface{}
+	if err := json.Unmarshal(w.Body.Bytes(), &body); err != nil {
+		t.Fatalf("%s %s: invalid JSON %q", req.Method, req.URL.Path, w.Body.String())
+	}
+	return w.Code, body
+}
+
+func TestSendVoiceSessionCommand(t *testing.T) {
+	h := &Handler{logger: zap.NewNop()}
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	r.POST("/api/voicebot/sessions/:call_sid/commands", h.SendVoiceSessionCommand)
+	r.GET("/api/voicebot/sessions/:call_sid", h.GetVoiceSession)
+
+	getOrCreateSession("CA-live", "ST-live", "+911", "+912", nil, audio.Format{})
+	t.Cleanup(func() { removeSession("CA-live") })
+
+	tests := []struct {
+		name    string
+		callSid string
+		body    string
+		want    int
+	}{
+		{"hangup a local call", "CA-live", `{"action":" Hangup "}`, http.StatusAccepted},
+		{"unknown action", "CA-live", `{"action":"mute"}`, http.StatusBadRequest},
+		{"inject without text", "CA-live", `{"action":"inject","text":"  "}`, http.StatusBadRequest},
+		{"missing action", "CA-live", `{}`, http.StatusBadRequest},
+		{"no live session", "CA-gone", `{"action":"hangup"}`, http.StatusNotFound},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodPost, "/api/voicebot/sessions/"+tt.callSid+"/commands", bytes.NewBufferString(tt.body))
+			req.Header.Set("Content-Type", "application/json")
+			code, body := serve(t, r, req)
+			if code != tt.want {
+				t.Errorf("status = %d %v, want %d", code, body, tt.want)
+			}
+			if code == http.StatusAccepted && (body["action"] != commandHangup || body["owner"] != "local") {
+				t.Errorf("response = %v", body)
+			}
+		})
+	}
+
+	code, info := serve(t, r, httptest.NewRequest(http.MethodGet, "/api/voicebot/sessions/CA-live", nil))
+	if code != http.StatusOK || info["stream_sid"] != "ST-live" || info["owner"] != "local" {
+		t.Errorf("session = %d %v", code, info)
+	}
+}
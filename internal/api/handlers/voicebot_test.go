@@ -0,0 +1,376 @@
+package handlers
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gorilla/websocket"
+	"go.uber.org/zap"
+
+	"github.com/troikatech/calling-agent/pkg/ai"
+	"github.com/troikatech/calling-agent/pkg/audio"
+)
+
+func TestPersonaPromptParts(t *testing.T) {
+	persona := &ai.Persona{
+		Name:                  "Priya",
+		Language:              "hindi",
+		Age:                   28,
+		Description:           "Counsellor for SAT and GRE coaching",
+		Instructions:          "Qualify the lead before quoting fees.",
+		Script:                "Greet, ask about the exam date, offer a demo class.",
+		ObjectionHandlers:     []ai.ObjectionHandler{{Objection: "too expensive", Response: "Mention the EMI option."}},
+		ComplianceDisclaimers: []string{"This call is recorded."},
+		ClosingLines:          []string{"Thank you for your time!"},
+	}
+	prompt := strings.Join(personaPromptParts(persona, "Rahul"), "\n")
+
+	for _, want := range []string{
+		"You are Priya, 28 saal ki.",
+		"Baat karo hindi mein (Hinglish if Hindi).",
+		"About you: Counsellor for SAT and GRE coaching",
+		"Instructions: Qualify the lead before quoting fees.",
+		"Follow this script: Greet",
+		`If the caller says "too expensive"`,
+		"- This call is recorded.",
+		"- Thank you for your time!",
+	} {
+		if !strings.Contains(prompt, want) {
+			t.Errorf("prompt is missing %q:\n%s", want, prompt)
+		}
+	}
+}
+
+func TestPersonaPromptParts_Template(t *testing.T) {
+	persona := &ai.Persona{
+		Name:                 "Priya",
+		Language:             "hindi",
+		SystemPromptTemplate: "You are {{persona_name}} calling {{customer_name}}.",
+		Instructions:         "Never discuss competitors.",
+	}
+	parts := personaPromptParts(persona, "Rahul")
+
+	if len(parts) != 2 || parts[0] != "You are Priya calling Rahul." || parts[1] != "Instructions: Never discuss competitors." {
+		t.Errorf("parts = %q", parts)
+	}
+}
+
+func TestBuildSystemPromptFromCustomParamsAndRAG(t *testing.T) {
+	h := &Handler{}
+	params := map[string]interface{}{"customer_name": "Rahul", "persona_name": "Ignored"}
+
+	tests := []struct {
+		name string
+		rag  map[string]interface{}
+		want []string
+		not  []string
+	}{
+		{
+			name: "persona and passages",
+			rag: map[string]interface{}{
+				"persona_data":  &ai.Persona{Name: "Priya", Instructions: "Be brief."},
+				"document_text": "Fees are 499 a month.",
+			},
+			want: []string{"You are Priya.", "Instructions: Be brief.", "Fees are 499 a month.", "Customer ka naam: Rahul"},
+			not:  []string{"Ignored"},
+		},
+		{
+			name: "custom parameters only",
+			want: []string{"You are Ignored.", "Customer ka naam: Rahul"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			prompt := h.buildSystemPromptFromCustomParamsAndRAG(params, tt.rag)
+			for _, want := range tt.want {
+				if !strings.Contains(prompt, want) {
+					t.Errorf("prompt %q is missing %q", prompt, want)
+				}
+			}
+			for _, not := range tt.not {
+				if strings.Contains(prompt, not) {
+					t.Errorf("prompt %q should not contain %q", prompt, not)
+				}
+			}
+		})
+	}
+
+	if got := h.buildSystemPromptFromCustomParamsAndRAG(nil, nil); !strings.Contains(got, "helpful AI assistant") {
+		t.Errorf("default prompt = %q", got)
+	}
+}
+
+func TestSessionCallContext_CopiesPerTurn(t *testing.T) {
+	h := &Handler{}
+	session := &VoiceSession{CallSid: "CA123"}
+	session.contextLoad.Do(func() {
+		session.CallContext = map[string]interface{}{"campaign_id": "7", "persona_id": int64(3)}
+	})
+
+	first := h.sessionCallContext(session)
+	first["conversation_history"] = []map[string]interface{}{}
+	second := h.sessionCallContext(session)
+
+	if second["campaign_id"] != "7" {
+		t.Errorf("call context = %v", second)
+	}
+	if _, leaked := second["conversation_history"]; leaked {
+		t.Error("a turn's additions leaked into the session's call context")
+	}
+}
+
+// Run with -race: the summary is built while the last reply is still being marked as played
+func TestPersistConversationSummary_ConcurrentTurnUpdates(t *testing.T) {
+	h := &Handler{}
+	session := &VoiceSession{CallSid: "CA123"}
+	for i := 0; i < 50; i++ {
+		session.ConversationHistory = append(session.ConversationHistory, map[string]interface{}{"role": "assistant", "content": "Hello"})
+	}
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		for i := range session.ConversationHistory {
+			markTurnPlayed(session, i, playbackResult{PlayedAt: time.Now()})
+		}
+	}()
+	h.persistConversationSummary(session)
+	<-done
+}
+
+func TestTruncateAtFraction(t *testing.T) {
+	text := "Our plans start at 499 a month"
+	tests := []struct {
+		name     string
+		text     string
+		fraction float64
+		want     string
+	}{
+		{"nothing played", text, 0, ""},
+		{"negative", text, -0.5, ""},
+		{"fully played", text, 1, text},
+		{"past the end", text, 1.5, text},
+		{"cut on a space", text, 0.6, "Our plans start at"},
+		{"cut mid-word backs off to the word before", text, 0.55, "Our plans start"},
+		{"first word not finished", text, 0.05, ""},
+		{"empty text", "", 0.5, ""},
+		{"multibyte text", "नमस्ते आपका स्वागत है", 0.5, "नमस्ते"},
+		{"multibyte text cut mid-word", "नमस्ते आपका स्वागत है", 0.8, "नमस्ते आपका"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := truncateAtFraction(tt.text, tt.fraction); got != tt.want {
+				t.Errorf("truncateAtFraction(%q, %v) = %q, want %q", tt.text, tt.fraction, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestHeardText(t *testing.T) {
+	clips := []queuedClip{
+		{text: "Our plans start at 499 a month.", start: 0, end: time.Second},
+		{text: "Would you like to hear about the annual plan?", start: time.Second, end: 2 * time.Second},
+	}
+	tests := []struct {
+		position time.Duration
+		want     string
+	}{
+		{0, ""},
+		{500 * time.Millisecond, "Our plans start"},
+		{time.Second, "Our plans start at 499 a month."},
+		{1500 * time.Millisecond, "Our plans start at 499 a month. Would you like to hear"},
+		{3 * time.Second, "Our plans start at 499 a month. Would you like to hear about the annual plan?"},
+	}
+	for _, tt := range tests {
+		if got := heardText(clips, tt.position); got != tt.want {
+			t.Errorf("heardText at %v = %q, want %q", tt.position, got, tt.want)
+		}
+	}
+}
+
+func TestStopPlayback_TruncatesInterruptedTurn(t *testing.T) {
+	h := &Handler{logger: zap.NewNop()}
+	reply := "Our plans start at 499 a month. Would you like to hear about the annual plan?"
+	session := &VoiceSession{
+		CallSid:  "CA123",
+		Outbound: audio.NewOutboundScheduler(time.Second),
+		ConversationHistory: []map[string]interface{}{
+			{"role": "user", "content": "What are your fees?"},
+			{"role": "assistant", "content": reply},
+		},
+	}
+	if h.stopPlayback(session, "caller_speech", false) {
+		t.Fatal("stopped playback while the bot was silent")
+	}
+
+	// Two segments go out ahead of playback; the caller talks over the second one
+	ctx, cancel := context.WithCancel(context.Background())
+	session.Playback = &playback{cancel: cancel}
+	clips := []queuedClip{
+		{text: "Our plans start at 499 a month.", start: 0, end: 200 * time.Millisecond},
+		{text: "Would you like to hear about the annual plan?", start: 200 * time.Millisecond, end: 400 * time.Millisecond},
+	}
+	for _, c := range clips {
+		session.Outbound.Next(ctx, c.end-c.start)
+	}
+	time.Sleep(300 * time.Millisecond)
+
+	if !h.stopPlayback(session, "caller_speech", false) {
+		t.Fatal("stopPlayback found nothing playing")
+	}
+	if ctx.Err() == nil || session.Playback != nil {
+		t.Error("playback was not cancelled and detached")
+	}
+	position := session.Outbound.Position()
+	if sent := session.Outbound.Sent(); sent != position || position < 250*time.Millisecond || position > 350*time.Millisecond {
+		t.Fatalf("after stop: Sent %v, Position %v, want the unplayed audio dropped at ~300ms", sent, position)
+	}
+
+	heard := heardText(clips, position)
+	if !strings.HasPrefix(heard, clips[0].text+" Would") || heard == reply {
+		t.Fatalf("heard %q, want the first segment and part of the second", heard)
+	}
+	markTurnTruncated(session, 1, heard, int(position/time.Millisecond))
+
+	turn := session.ConversationHistory[1]
+	if turn["content"] != heard+"..." || turn["full_content"] != reply || turn["truncated"] != true {
+		t.Errorf("turn = %v", turn)
+	}
+	if session.ConversationHistory[0]["content"] != "What are your fees?" {
+		t.Error("caller turn changed")
+	}
+}
+
+// exotelSocket connects a voicebot-side WebSocket to a fake Exotel that forwards every event it receives
+func exotelSocket(t *testing.T) (*websocket.Conn, <-chan map[string]interface{}) {
+	t.Helper()
+	events := make(chan map[string]interface{}, 16)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		conn, err := (&websocket.Upgrader{}).Upgrade(w, r, nil)
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+		for {
+			var event map[string]interface{}
+			if err := conn.ReadJSON(&event); err != nil {
+				return
+			}
+			events <- event
+		}
+	}))
+	t.Cleanup(srv.Close)
+
+	conn, _, err := websocket.DefaultDialer.Dial("ws"+strings.TrimPrefix(srv.URL, "http"), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { conn.Close() })
+	return conn, events
+}
+
+// sentMark reads the next event Exotel received and returns its mark name
+func sentMark(t *testing.T, events <-chan map[string]interface{}) string {
+	t.Helper()
+	select {
+	case event := <-events:
+		mark, _ := event["mark"].(map[string]interface{})
+		if event["event"] != "mark" || event["stream_sid"] != "ST-marks" {
+			t.Fatalf("sent %v, want a mark", event)
+		}
+		name, _ := mark["name"].(string)
+		return name
+	case <-time.After(2 * time.Second):
+		t.Fatal("no mark sent")
+	}
+	return ""
+}
+
+// markEvent is an Exotel mark echo
+func markEvent(name string) []byte {
+	return []byte(`{"event":"mark","stream_sid":"ST-marks","mark":{"name":"` + name + `"}}`)
+}
+
+func TestMarkAcknowledgements(t *testing.T) {
+	h := &Handler{logger: zap.NewNop()}
+	conn, events := exotelSocket(t)
+	session := getOrCreateSession("CA-marks", "ST-marks", "+911", "+912", conn, audio.Format{})
+	t.Cleanup(func() { removeSession("CA-marks") })
+
+	segment := h.sendMark(session, "response_segment")
+	done := h.sendMark(session, "response_done")
+	segmentName, doneName := sentMark(t, events), sentMark(t, events)
+	if segmentName != "response_segment_1" || doneName != "response_done_2" {
+		t.Fatalf("mark names %q, %q", segmentName, doneName)
+	}
+	if !session.awaitingPlayback() {
+		t.Fatal("not awaiting playback with marks outstanding")
+	}
+
+	// Unknown and stale names, and marks for other calls, are ignored
+	h.handleMarkEvent("CA-marks", markEvent("greeting_done_9"))
+	h.handleMarkEvent("CA-other", markEvent(segmentName))
+	h.handleMarkEvent("CA-marks", []byte(`{"event":"mark"`))
+	select {
+	case <-segment:
+		t.Fatal("mark acknowledged by another name")
+	default:
+	}
+
+	// Earlier acks leave playback pending; the last one releases waitForPlayback
+	h.handleMarkEvent("CA-marks", markEvent(segmentName))
+	if _, ok := <-segment; !ok || !session.awaitingPlayback() {
+		t.Fatal("first ack not delivered, or playback no longer pending")
+	}
+	result := make(chan bool, 1)
+	go func() {
+		playedAt, ok := h.waitForPlayback(context.Background(), session, done)
+		result <- ok && !playedAt.IsZero()
+	}()
+	h.handleMarkEvent("CA-marks", markEvent(doneName))
+	select {
+	case ok := <-result:
+		if !ok {
+			t.Error("waitForPlayback reported an interruption on the last ack")
+		}
+	case <-time.After(time.Second):
+		t.Fatal("waitForPlayback did not return on the last ack")
+	}
+	if session.awaitingPlayback() {
+		t.Error("still awaiting playback after every mark was acknowledged")
+	}
+
+	// An Exotel clear stops playback: the wait ends as a barge-in and pending marks are dropped
+	ctx, cancel := context.WithCancel(context.Background())
+	session.Mu.Lock()
+	session.Playback = &playback{cancel: cancel}
+	session.Mu.Unlock()
+	cleared := h.sendMark(session, "response_done")
+	clearedName := sentMark(t, events)
+	go func() {
+		_, ok := h.waitForPlayback(ctx, session, cleared)
+		result <- ok
+	}()
+	h.handleClearEvent("CA-marks", []byte(`{"event":"clear","stream_sid":"ST-marks"}`))
+	select {
+	case ok := <-result:
+		if ok {
+			t.Error("waitForPlayback reported playback finished after a clear")
+		}
+	case <-time.After(time.Second):
+		t.Fatal("waitForPlayback did not return on a clear")
+	}
+	if session.awaitingPlayback() {
+		t.Error("still awaiting playback after a clear")
+	}
+	h.handleMarkEvent("CA-marks", markEvent(clearedName))
+	select {
+	case event := <-events:
+		t.Errorf("sent %v after an Exotel clear, want nothing", event)
+	case <-time.After(50 * time.Millisecond):
+	}
+}
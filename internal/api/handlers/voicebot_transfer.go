@@ -0,0 +1,324 @@
+package handlers
+
+import (
+	"context"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.uber.org/zap"
+
+	"github.com/troikatech/calling-agent/pkg/ai"
+	"github.com/troikatech/calling-agent/pkg/exotel"
+)
+
+// transferMarker starts an LLM reply when the caller should be handed to a human agent
+const transferMarker = "[[TRANSFER]]"
+
+// transferInstruction is appended to the system prompt when a transfer destination is configured
+const transferInstruction = "If the caller asks for a human, is upset, or needs something you cannot handle, begin your reply with " +
+	transferMarker + " followed by one short sentence telling them you are connecting them to a colleague."
+
+// Transfer modes
+const (
+	transferModeFlow     = "flow"     // End the stream; the Exotel flow's Connect applet asks ExotelTransferEndpoint where to route
+	transferModeCallback = "callback" // End the stream and bridge agent and customer with a new call via the Exotel API
+)
+
+// transferDestinations returns the agent numbers (tried in order) for the call:
+// custom_parameters.transfer_number, then the persona's transfer_number, then TRANSFER_AGENT_NUMBER
+func (h *Handler) transferDestinations(session *VoiceSession) []string {
+	session.Mu.RLock()
+	cached := session.TransferNumbers
+	fromParams := getStringFromMap(session.CustomParameters, "transfer_number", "")
+	session.Mu.RUnlock()
+	if cached != nil {
+		return cached
+	}
+
+	destination := fromParams
+	if persona := h.sessionPersona(session); destination == "" && persona != nil {
+		destination = persona.TransferNumber
+	}
+	if destination == "" {
+		destination = h.cfg.TransferAgentNumber
+	}
+
+	numbers := []string{}
+	for _, number := range strings.Split(destination, ",") {
+		if number = strings.TrimSpace(number); number != "" {
+			numbers = append(numbers, number)
+		}
+	}
+
+	session.Mu.Lock()
+	session.TransferNumbers = numbers
+	session.Mu.Unlock()
+	return numbers
+}
+
+// transferKeywordMatched applies the rule-based trigger: custom_parameters.transfer_keywords (comma separated)
+func transferKeywordMatched(session *VoiceSession, text string) bool {
+	session.Mu.RLock()
+	keywords := getStringFromMap(session.CustomParameters, "transfer_keywords", "")
+	session.Mu.RUnlock()
+
+	lower := strings.ToLower(text)
+	for _, keyword := range strings.Split(keywords, ",") {
+		if keyword = strings.ToLower(strings.TrimSpace(keyword)); keyword != "" && strings.Contains(lower, keyword) {
+			return true
+		}
+	}
+	return false
+}
+
+// extractTransferMarker strips the LLM transfer marker from a reply and reports whether it was present
+func extractTransferMarker(text string) (string, bool) {
+	if !strings.Contains(text, transferMarker) {
+		return text, false
+	}
+	return strings.TrimSpace(strings.ReplaceAll(text, transferMarker, "")), true
+}
+
+// transferToAgent hands the call to a human: it stores an AI summary on the call record for the agent,
+// ends the voicebot stream and, in callback mode, bridges agent and customer through the Exotel API.
+// announce speaks the transfer message first (the LLM has already said it when it triggered the transfer).
+func (h *Handler) transferToAgent(session *VoiceSession, reason string, announce bool) {
+	if !h.sessionPersona(session).AllowsTool(ai.ToolTransfer) {
+		h.logger.Warn("Transfer refused, persona does not allow it",
+			zap.String("call_sid", session.CallSid),
+			zap.String("reason", reason),
+		)
+		return
+	}
+
+	session.Mu.Lock()
+	if session.Transferring {
+		session.Mu.Unlock()
+		return
+	}
+	session.Transferring = true
+	message := getStringFromMap(session.CustomParameters, "transfer_message", "Please hold while I connect you to a colleague.")
+	session.Mu.Unlock()
+
+	destinations := h.transferDestinations(session)
+	mode := h.cfg.TransferMode
+	if mode == "" {
+		mode = transferModeFlow
+	}
+
+	h.logger.Info("Transferring call to agent",
+		zap.String("call_sid", session.CallSid),
+		zap.String("reason", reason),
+		zap.String("mode", mode),
+		zap.Strings("destinations", destinations),
+	)
+
+	// Summarise while the caller hears the hand-off message
+	summaryCh := make(chan string, 1)
+	go func() {
+		summaryCh <- h.summarizeForAgent(session)
+	}()
+
+	if announce {
+		h.sendPrompt(session, message)
+	}
+	summary := <-summaryCh
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	now := time.Now().Format(time.RFC3339)
+	h.mongoClient.NewQuery("calls").
+		Eq("call_sid", session.CallSid).
+		UpdateOne(ctx, map[string]interface{}{
+			"transfer": map[string]interface{}{
+				"status":       "requested",
+				"reason":       reason,
+				"mode":         mode,
+				"destinations": destinations,
+				"requested_at": now,
+			},
+			"ai_summary":  summary,
+			"disposition": "transferred",
+			"updated_at":  now,
+		})
+	h.mongoClient.NewQuery("campaign_contacts").
+		Eq("call_sid", session.CallSid).
+		UpdateOne(ctx, map[string]interface{}{
+			"disposition": "transferred",
+		})
+
+	h.endStream(session, "transfer")
+
+	if mode != transferModeCallback {
+		return
+	}
+	if len(destinations) == 0 {
+		h.logger.Warn("Callback transfer requested but no agent number configured", zap.String("call_sid", session.CallSid))
+		h.updateTransferStatus(session.CallSid, "failed", map[string]interface{}{"transfer.error": "no agent number configured"})
+		return
+	}
+
+	exotelClient := exotel.NewClient(h.cfg.ExotelSubdomain, h.cfg.ExotelAccountSID, h.cfg.ExotelAPIKey, h.cfg.ExotelAPIToken).
+		WithBaseURL(h.cfg.ExotelAPIBaseURL)
+
+	// Try agents in order until Exotel accepts one
+	for _, agent := range destinations {
+		resp, err := exotelClient.ConnectAgent(exotel.ConnectAgentRequest{
+			AgentNumber:    agent,
+			CustomerNumber: session.To,
+			CallerID:       h.cfg.ExotelExophone,
+		})
+		if err != nil {
+			h.logger.Warn("Exotel agent bridge failed", zap.String("call_sid", session.CallSid), zap.String("agent", agent), zap.Error(err))
+			continue
+		}
+		h.updateTransferStatus(session.CallSid, "dialing", map[string]interface{}{
+			"transfer.agent_number":    agent,
+			"transfer.bridge_call_sid": resp.Call.Sid,
+		})
+		return
+	}
+	h.updateTransferStatus(session.CallSid, "failed", map[string]interface{}{"transfer.error": "all agent numbers failed"})
+}
+
+// updateTransferStatus updates transfer.status (and extra fields) on the call record
+func (h *Handler) updateTransferStatus(callSid, status string, fields map[string]interface{}) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	update := map[string]interface{}{
+		"transfer.status": status,
+		"updated_at":      time.Now().Format(time.RFC3339),
+	}
+	for k, v := range fields {
+		update[k] = v
+	}
+	h.mongoClient.NewQuery("calls").
+		Eq("call_sid", callSid).
+		UpdateOne(ctx, update)
+}
+
+// summarizeForAgent produces a short summary of the conversation so far for the agent taking over
+// Falls back to the last few turns verbatim if the AI summary is unavailable
+func (h *Handler) summarizeForAgent(session *VoiceSession) string {
+	session.Mu.RLock()
+	var lines []string
+	for _, msg := range session.ConversationHistory {
+		role, _ := msg["role"].(string)
+		content, _ := msg["content"].(string)
+		if role == "user" {
+			lines = append(lines, "Caller: "+content)
+		} else if role == "assistant" {
+			lines = append(lines, "Bot: "+content)
+		}
+	}
+	session.Mu.RUnlock()
+
+	if len(lines) == 0 {
+		return ""
+	}
+	transcript := strings.Join(lines, "\n")
+
+	if h.cfg.FeatureAI && h.aiManager != nil {
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		summary, err := h.aiManager.SummarizeCall(ctx, &ai.SummarizeRequest{
+			CallSID:       session.CallSid,
+			Transcription: &transcript,
+		})
+		if err == nil && summary != nil && summary.Summary != "" {
+			return summary.Summary
+		}
+		h.logger.Warn("AI summary for transfer failed, using transcript tail", zap.String("call_sid", session.CallSid), zap.Error(err))
+	}
+
+	if len(lines) > 6 {
+		lines = lines[len(lines)-6:]
+	}
+	return strings.Join(lines, "\n")
+}
+
+// ExotelTransferEndpoint is the dynamic URL for an Exotel Connect applet placed after the voicebot applet
+// GET/POST /voicebot/transfer?CallSid=...
+// Returns the agent numbers for calls the bot handed over; 404 lets the flow take its "no transfer" branch
+func (h *Handler) ExotelTransferEndpoint(c *gin.Context) {
+	callSid := c.Query("CallSid")
+	if callSid == "" {
+		callSid = c.PostForm("CallSid")
+	}
+	if callSid == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "CallSid is required"})
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(c.Request.Context(), 5*time.Second)
+	defer cancel()
+
+	call, err := h.mongoClient.NewQuery("calls").
+		Select("transfer").
+		Eq("call_sid", callSid).
+		FindOne(ctx)
+	if err != nil || call == nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "call not found"})
+		return
+	}
+
+	transfer, _ := call["transfer"].(map[string]interface{})
+	numbers := toStringSlice(transfer["destinations"])
+	if transfer == nil || len(numbers) == 0 {
+		c.JSON(http.StatusNotFound, gin.H{"error": "no transfer requested for call"})
+		return
+	}
+
+	h.updateTransferStatus(callSid, "connecting", nil)
+
+	response := gin.H{
+		"fetch_after_attempt": false,
+		"destination": gin.H{
+			"numbers": numbers,
+		},
+		"record":               true,
+		"recording_channels":   "dual",
+		"max_ringing_duration": 45,
+		"music_on_hold": gin.H{
+			"type": "operator_tone",
+		},
+	}
+	if h.cfg.ExotelExophone != "" {
+		response["outgoing_phone_number"] = h.cfg.ExotelExophone
+	}
+
+	h.logger.Info("Routing transferred call to agent",
+		zap.String("call_sid", callSid),
+		zap.Strings("numbers", numbers),
+	)
+	c.JSON(http.StatusOK, response)
+}
+
+// toStringSlice converts a BSON array (primitive.A / []interface{}) or []string to []string
+func toStringSlice(val interface{}) []string {
+	switch v := val.(type) {
+	case []string:
+		return v
+	case primitive.A:
+		return toStringSlice([]interface{}(v))
+	case []interface{}:
+		out := make([]string, 0, len(v))
+		for _, item := range v {
+			if s, ok := item.(string); ok && s != "" {
+				out = append(out, s)
+			}
+		}
+		return out
+	}
+	return nil
+}
+
+// transferAvailable reports whether the LLM should be told it can transfer the caller
+func (h *Handler) transferAvailable(session *VoiceSession) bool {
+	return h.sessionPersona(session).AllowsTool(ai.ToolTransfer) && len(h.transferDestinations(session)) > 0
+}
@@ -0,0 +1,170 @@
+package handlers
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+
+	"github.com/troikatech/calling-agent/pkg/ai"
+	"github.com/troikatech/calling-agent/pkg/env"
+)
+
+func TestTransferKeywordMatched(t *testing.T) {
+	tests := []struct {
+		name     string
+		keywords interface{}
+		text     string
+		want     bool
+	}{
+		{"keyword in sentence", "agent, human", "Can I talk to a Human please", true},
+		{"second keyword", "agent,manager", "get me your manager", true},
+		{"no match", "agent", "what are your fees", false},
+		{"blank keywords ignored", " , ", "anything", false},
+		{"not configured", nil, "agent", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			session := &VoiceSession{CustomParameters: map[string]interface{}{}}
+			if tt.keywords != nil {
+				session.CustomParameters["transfer_keywords"] = tt.keywords
+			}
+			if got := transferKeywordMatched(session, tt.text); got != tt.want {
+				t.Errorf("transferKeywordMatched(%q) = %v, want %v", tt.text, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestExtractTransferMarker(t *testing.T) {
+	tests := []struct {
+		text     string
+		want     string
+		transfer bool
+	}{
+		{transferMarker + " Connecting you to a colleague now.", "Connecting you to a colleague now.", true},
+		{"Sure. " + transferMarker, "Sure.", true},
+		{"Our fees start at 499.", "Our fees start at 499.", false},
+	}
+	for _, tt := range tests {
+		got, transfer := extractTransferMarker(tt.text)
+		if got != tt.want || transfer != tt.transfer {
+			t.Errorf("extractTransferMarker(%q) = %q, %v; want %q, %v", tt.text, got, transfer, tt.want, tt.transfer)
+		}
+	}
+}
+
+func TestTransferDestinations(t *testing.T) {
+	h := &Handler{cfg: &env.Config{TransferAgentNumber: "+910000000000, +911111111111"}}
+
+	tests := []struct {
+		name   string
+		params map[string]interface{}
+		want   []string
+	}{
+		{"custom parameters first", map[string]interface{}{"transfer_number": "+913333333333"}, []string{"+913333333333"}},
+		{"then the configured agents", nil, []string{"+910000000000", "+911111111111"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			session := &VoiceSession{CustomParameters: tt.params}
+			if got := h.transferDestinations(session); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("transferDestinations = %v, want %v", got, tt.want)
+			}
+			if !h.transferAvailable(session) {
+				t.Error("transfer not offered with a destination configured")
+			}
+		})
+	}
+
+	// Without any destination the LLM is never offered a transfer
+	if (&Handler{cfg: &env.Config{}}).transferAvailable(&VoiceSession{}) {
+		t.Error("transfer offered without a destination")
+	}
+}
+
+func TestTransferDestinations_Persona(t *testing.T) {
+	h := &Handler{cfg: &env.Config{TransferAgentNumber: "+910000000000"}}
+	persona := &ai.Persona{TransferNumber: "+911111111111, +912222222222"}
+
+	tests := []struct {
+		name    string
+		persona *ai.Persona
+		params  map[string]interface{}
+		want    []string
+	}{
+		{"custom parameters over the persona", persona, map[string]interface{}{"transfer_number": "+913333333333"}, []string{"+913333333333"}},
+		{"persona over the configured agent", persona, nil, []string{"+911111111111", "+912222222222"}},
+		{"configured agent without a persona number", &ai.Persona{}, nil, []string{"+910000000000"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := h.transferDestinations(personaSession(tt.persona, tt.params)); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("transferDestinations = %v, want %v", got, tt.want)
+			}
+		})
+	}
+
+	// A persona that may not transfer never offers it to the LLM
+	noTransfer := personaSession(&ai.Persona{TransferNumber: "+911111111111", AllowedTools: []string{ai.ToolKeypress}}, nil)
+	if h.transferAvailable(noTransfer) {
+		t.Error("transfer offered to a persona without the transfer tool")
+	}
+}
+
+func TestToStringSlice(t *testing.T) {
+	tests := []struct {
+		name string
+		val  interface{}
+		want []string
+	}{
+		{"strings", []string{"+911"}, []string{"+911"}},
+		{"BSON array", primitive.A{"+911", "", 7, "+912"}, []string{"+911", "+912"}},
+		{"JSON array", []interface{}{"+911"}, []string{"+911"}},
+		{"not an array", "+911", nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := toStringSlice(tt.val); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("toStringSlice = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestExotelTransferEndpoint(t *testing.T) {
+	h := newMongoTestHandler(t)
+	ctx := context.Background()
+	if _, err := h.mongoClient.NewQuery("calls").Insert(ctx, map[string]interface{}{
+		"call_sid": "CA-transfer",
+		"transfer": map[string]interface{}{"status": "requested", "destinations": []string{"+919876543210"}},
+	}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := h.mongoClient.NewQuery("calls").Insert(ctx, map[string]interface{}{"call_sid": "CA-plain"}); err != nil {
+		t.Fatal(err)
+	}
+
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	r.GET("/voicebot/transfer", h.ExotelTransferEndpoint)
+
+	code, body := serve(t, r, httptest.NewRequest(http.MethodGet, "/voicebot/transfer?CallSid=CA-transfer", nil))
+	destination, _ := body["destination"].(map[string]interface{})
+	if code != http.StatusOK || !reflect.DeepEqual(destination["numbers"], []interface{}{"+919876543210"}) {
+		t.Errorf("transfer = %d %v", code, body)
+	}
+
+	for _, callSid := range []string{"CA-plain", "CA-unknown"} {
+		if code, _ := serve(t, r, httptest.NewRequest(http.MethodGet, "/voicebot/transfer?CallSid="+callSid, nil)); code != http.StatusNotFound {
+			t.Errorf("%s = %d, want 404", callSid, code)
+		}
+	}
+	if code, _ := serve(t, r, httptest.NewRequest(http.MethodGet, "/voicebot/transfer", nil)); code != http.StatusBadRequest {
+		t.Errorf("missing CallSid = %d, want 400", code)
+	}
+}
@@ -0,0 +1,216 @@
+package handlers
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"go.uber.org/zap"
+
+	"github.com/troikatech/calling-agent/pkg/ai"
+	"github.com/troikatech/calling-agent/pkg/audio"
+)
+
+// defaultGreetingText is spoken when neither custom_parameters nor the persona set greeting_text
+const defaultGreetingText = "Hello! How can I help you today?"
+
+// voiceSettings selects the TTS provider and voice for a call
+type voiceSettings struct {
+	Provider string  // Preferred provider ("openai", "elevenlabs", ...); empty uses the configured order
+	Voice    string  // Provider-specific voice name or ID
+	Model    string  // Provider-specific model
+	Speed    float64 // 0 uses the provider default
+}
+
+// voiceSettings resolves the call's TTS settings once per session
+// Keys tts_provider, voice_id, tts_model and tts_speed are read from custom_parameters first,
+// then from the persona
+func (h *Handler) voiceSettings(session *VoiceSession) voiceSettings {
+	session.Mu.RLock()
+	cached := session.Voice
+	params := session.CustomParameters
+	session.Mu.RUnlock()
+	if cached != nil {
+		return *cached
+	}
+
+	// custom_parameters win over the persona
+	settings := voiceSettingsFrom(personaVoiceSettings(h.sessionPersona(session)), params)
+
+	session.Mu.Lock()
+	session.Voice = &settings
+	session.Mu.Unlock()
+	return settings
+}
+
+// personaVoiceSettings returns the TTS settings of a persona (empty for nil)
+func personaVoiceSettings(persona *ai.Persona) voiceSettings {
+	if persona == nil {
+		return voiceSettings{}
+	}
+	return voiceSettings{
+		Provider: strings.ToLower(persona.TTSProvider),
+		Voice:    persona.VoiceID,
+		Model:    persona.TTSModel,
+		Speed:    persona.TTSSpeed,
+	}
+}
+
+// voiceSettingsFrom overrides settings with TTS settings read from the given maps; later maps win
+func voiceSettingsFrom(settings voiceSettings, sources ...map[string]interface{}) voiceSettings {
+	for _, source := range sources {
+		if source == nil {
+			continue
+		}
+		settings.Provider = strings.ToLower(getStringFromMap(source, "tts_provider", settings.Provider))
+		settings.Voice = getStringFromMap(source, "voice_id", settings.Voice)
+		settings.Model = getStringFromMap(source, "tts_model", settings.Model)
+		settings.Speed = getFloatFromMap(source, "tts_speed", settings.Speed)
+	}
+	return settings
+}
+
+// sessionPersona returns the persona for the call, or nil when the call has none
+func (h *Handler) sessionPersona(session *VoiceSession) *ai.Persona {
+	if pc := h.sessionPersonaContext(session); pc != nil {
+		return pc.Persona
+	}
+	return nil
+}
+
+// ttsAvailable reports whether the voicebot can speak rather than fall back to text
+func (h *Handler) ttsAvailable() bool {
+	return h.cfg.FeatureAI && h.ttsManager != nil && h.ttsManager.IsAvailable()
+}
+
+// synthesizeSpeech converts text to 16kHz PCM16 using the session's TTS provider and voice
+func (h *Handler) synthesizeSpeech(ctx context.Context, session *VoiceSession, text string) ([]byte, error) {
+	settings := h.voiceSettings(session)
+	pcm, provider, err := h.renderSpeech(ctx, settings, text)
+	if err != nil {
+		return nil, err
+	}
+
+	if settings.Provider != "" && provider != settings.Provider {
+		h.logger.Info("TTS fell back to another provider",
+			zap.String("call_sid", session.CallSid),
+			zap.String("preferred", settings.Provider),
+			zap.String("provider", provider),
+		)
+	}
+	return pcm, nil
+}
+
+// synthesizePrompt is synthesizeSpeech for fixed lines (greeting, reprompts, scripted messages):
+// the audio is served from the TTS cache and rendered only on a miss
+func (h *Handler) synthesizePrompt(ctx context.Context, session *VoiceSession, text string) ([]byte, error) {
+	pcm, _, err := h.cachedSpeech(ctx, h.voiceSettings(session), text)
+	return pcm, err
+}
+
+// renderSpeech synthesizes text and resamples it to 16kHz, returning the provider that produced it
+func (h *Handler) renderSpeech(ctx context.Context, settings voiceSettings, text string) ([]byte, string, error) {
+	if h.ttsManager == nil {
+		return nil, "", fmt.Errorf("no TTS providers configured")
+	}
+
+	speech, err := h.ttsManager.Synthesize(ctx, &ai.SpeechRequest{
+		Text:  text,
+		Voice: settings.Voice,
+		Model: settings.Model,
+		Speed: settings.Speed,
+	}, settings.Provider)
+	if err != nil {
+		return nil, "", err
+	}
+
+	// Exotel expects 16kHz
+	return audio.Resample(speech.PCM, speech.SampleRate, 16000), speech.Provider, nil
+}
+
+// cachedSpeech returns 16kHz audio for text from the TTS cache, rendering and storing it on a miss
+// Reports whether the audio was already cached
+func (h *Handler) cachedSpeech(ctx context.Context, settings voiceSettings, text string) ([]byte, bool, error) {
+	// The cache is keyed by the provider that should speak; audio from a fallback provider is not stored
+	provider := settings.Provider
+	if provider == "" && h.ttsManager != nil {
+		if available := h.ttsManager.Providers(); len(available) > 0 {
+			provider = available[0]
+		}
+	}
+	key := ai.TTSCacheKey(provider, settings.Voice, settings.Model, settings.Speed, text)
+
+	if h.ttsCache != nil {
+		if pcm, ok := h.ttsCache.Get(ctx, key); ok {
+			return pcm, true, nil
+		}
+	}
+
+	pcm, usedProvider, err := h.renderSpeech(ctx, settings, text)
+	if err != nil {
+		return nil, false, err
+	}
+
+	if h.ttsCache != nil && usedProvider == provider {
+		if err := h.ttsCache.Put(ctx, key, pcm); err != nil {
+			h.logger.Warn("Failed to store TTS audio", zap.Error(err))
+		}
+	}
+	return pcm, false, nil
+}
+
+// personaPrompts returns the fixed lines a call with this persona will say
+func personaPrompts(persona *ai.Persona) []string {
+	lines := []string{persona.GreetingText, persona.RepromptText, persona.ClosingText}
+	defaults := []string{defaultGreetingText, defaultInactivitySettings.RepromptText, defaultInactivitySettings.ClosingText}
+	for i := range lines {
+		if lines[i] == "" {
+			lines[i] = defaults[i]
+		}
+	}
+	return lines
+}
+
+// prewarmPersonaTTS renders the persona's greeting, reprompt and closing lines into the TTS cache
+func (h *Handler) prewarmPersonaTTS(ctx context.Context, persona *ai.Persona) []map[string]interface{} {
+	settings := personaVoiceSettings(persona)
+
+	results := make([]map[string]interface{}, 0, 3)
+	for _, text := range personaPrompts(persona) {
+		start := time.Now()
+		pcm, cached, err := h.cachedSpeech(ctx, settings, text)
+		result := map[string]interface{}{
+			"text":   text,
+			"cached": cached,
+		}
+		if err != nil {
+			result["error"] = err.Error()
+		} else {
+			result["duration_ms"] = pcmDuration(pcm).Milliseconds()
+			result["render_ms"] = time.Since(start).Milliseconds()
+		}
+		results = append(results, result)
+	}
+	return results
+}
+
+// prewarmPersonaTTSAsync pre-warms a saved persona in the background
+func (h *Handler) prewarmPersonaTTSAsync(persona *ai.Persona) {
+	if h.ttsCache == nil || !h.ttsAvailable() || persona == nil {
+		return
+	}
+	go func() {
+		ctx, cancel := context.WithTimeout(context.Background(), 60*time.Second)
+		defer cancel()
+		for _, result := range h.prewarmPersonaTTS(ctx, persona) {
+			if errMsg, ok := result["error"]; ok {
+				h.logger.Warn("Failed to pre-warm persona TTS",
+					zap.Int64("persona_id", persona.ID),
+					zap.Any("text", result["text"]),
+					zap.Any("error", errMsg),
+				)
+			}
+		}
+	}()
+}
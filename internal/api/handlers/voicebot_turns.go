@@ -0,0 +1,207 @@
+package handlers
+
+import (
+	"context"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+// Turn kinds stored in call_turns
+const (
+	turnSpeech   = "speech"   // Caller speech
+	turnKeypress = "keypress" // Caller DTMF, as the text given to the model
+	turnGreeting = "greeting" // Bot greeting
+	turnReply    = "reply"    // Bot answer to a caller turn
+	turnPrompt   = "prompt"   // Scripted bot line (reprompts, closings)
+)
+
+// callerUtterance is a finished caller turn handed to the response pipeline
+type callerUtterance struct {
+	Text          string
+	Kind          string
+	Confidence    float64   // STT confidence, 0 when unknown
+	Provider      string    // STT provider
+	SpeechStart   time.Time // When the VAD heard speech start (zero if unknown)
+	SpeechEnd     time.Time // When the VAD heard speech end (zero if unknown)
+	TranscribedAt time.Time // When the final transcript was available
+}
+
+// keypressUtterance wraps the text describing a keypress as a caller turn
+func keypressUtterance(text string) callerUtterance {
+	now := time.Now()
+	return callerUtterance{Text: text, Kind: turnKeypress, SpeechStart: now, SpeechEnd: now, TranscribedAt: now}
+}
+
+// sttLatency returns how long the final transcript took after the caller stopped speaking
+func (u callerUtterance) sttLatency() time.Duration {
+	if u.SpeechEnd.IsZero() || u.TranscribedAt.Before(u.SpeechEnd) {
+		return 0
+	}
+	return u.TranscribedAt.Sub(u.SpeechEnd)
+}
+
+// turnLatency breaks down how long the bot took to answer a caller turn
+type turnLatency struct {
+	STT        time.Duration // End of speech → final transcript
+	RAG        time.Duration // Persona and document passage retrieval
+	LLM        time.Duration // Request sent → first LLM token
+	TTS        time.Duration // Synthesis of the first reply segment
+	FirstFrame time.Duration // End of speech (or transcript) → first reply frame sent
+}
+
+// callTurn is one line of the call transcript
+type callTurn struct {
+	Speaker     string // "caller" or "bot"
+	Kind        string
+	Text        string
+	FullText    string  // Bot turns cut short by a barge-in: everything that was generated
+	Confidence  float64 // Caller turns: STT confidence
+	Provider    string  // Caller turns: STT provider
+	Start, End  time.Time
+	Latency     turnLatency
+	Interrupted bool
+	Sources     []string // Bot replies: IDs of the document passages given to the model
+}
+
+// markSpeechStart and markSpeechEnd track the VAD's view of the caller's current utterance
+func (s *VoiceSession) markSpeechStart(at time.Time) {
+	s.Mu.Lock()
+	s.SpeechStartedAt = at
+	s.SpeechEndedAt = time.Time{}
+	s.Mu.Unlock()
+}
+
+func (s *VoiceSession) markSpeechEnd(at time.Time) {
+	s.Mu.Lock()
+	s.SpeechEndedAt = at
+	s.Mu.Unlock()
+}
+
+// speechUtterance builds a caller turn from a transcript and the VAD timing of the utterance
+func (s *VoiceSession) speechUtterance(text string, confidence float64, provider string) callerUtterance {
+	s.Mu.Lock()
+	defer s.Mu.Unlock()
+
+	u := callerUtterance{
+		Text:          text,
+		Kind:          turnSpeech,
+		Confidence:    confidence,
+		Provider:      provider,
+		SpeechStart:   s.SpeechStartedAt,
+		SpeechEnd:     s.SpeechEndedAt,
+		TranscribedAt: time.Now(),
+	}
+	s.SpeechStartedAt, s.SpeechEndedAt = time.Time{}, time.Time{}
+	return u
+}
+
+// callerTurn converts a caller utterance to a transcript line
+func (u callerUtterance) callerTurn() callTurn {
+	start, end := u.SpeechStart, u.SpeechEnd
+	if start.IsZero() {
+		start = u.TranscribedAt
+	}
+	if end.IsZero() {
+		end = u.TranscribedAt
+	}
+	return callTurn{
+		Speaker:    "caller",
+		Kind:       u.Kind,
+		Text:       u.Text,
+		Confidence: u.Confidence,
+		Provider:   u.Provider,
+		Start:      start,
+		End:        end,
+		Latency:    turnLatency{STT: u.sttLatency()},
+	}
+}
+
+// botTurn converts a played bot line to a transcript line
+func botTurn(kind, heard, full string, result playbackResult, latency turnLatency) callTurn {
+	turn := callTurn{
+		Speaker:     "bot",
+		Kind:        kind,
+		Text:        heard,
+		Start:       result.StartedAt,
+		Latency:     latency,
+		Interrupted: result.Interrupted,
+	}
+	if heard != full {
+		turn.FullText = full
+	}
+	if turn.Start.IsZero() {
+		turn.Start = time.Now()
+	}
+	turn.End = result.PlayedAt
+	if turn.End.IsZero() {
+		turn.End = turn.Start.Add(time.Duration(result.PlayedMs) * time.Millisecond)
+	}
+	return turn
+}
+
+// recordTurn appends a turn to the call_turns collection as it happens, so the transcript
+// survives a crash mid-call. Offsets are relative to the start of the media stream.
+func (h *Handler) recordTurn(session *VoiceSession, turn callTurn) {
+	if h.mongoClient == nil || turn.Text == "" {
+		return
+	}
+
+	session.Mu.Lock()
+	seq := session.turnSeq
+	session.turnSeq++
+	streamStart := session.StartedAt
+	session.Mu.Unlock()
+
+	offsetMs := func(t time.Time) int64 {
+		if t.Before(streamStart) {
+			return 0
+		}
+		return t.Sub(streamStart).Milliseconds()
+	}
+
+	doc := map[string]interface{}{
+		"call_sid":    session.CallSid,
+		"seq":         seq,
+		"speaker":     turn.Speaker,
+		"kind":        turn.Kind,
+		"text":        turn.Text,
+		"start_ms":    offsetMs(turn.Start),
+		"end_ms":      offsetMs(turn.End),
+		"interrupted": turn.Interrupted,
+		"created_at":  time.Now().Format(time.RFC3339),
+	}
+	if turn.FullText != "" {
+		doc["full_text"] = turn.FullText
+	}
+	if len(turn.Sources) > 0 {
+		doc["rag_chunks"] = turn.Sources
+	}
+	if turn.Speaker == "caller" {
+		doc["confidence"] = turn.Confidence
+		doc["stt_provider"] = turn.Provider
+	}
+	for field, d := range map[string]time.Duration{
+		"stt_latency_ms":         turn.Latency.STT,
+		"rag_latency_ms":         turn.Latency.RAG,
+		"llm_latency_ms":         turn.Latency.LLM,
+		"tts_latency_ms":         turn.Latency.TTS,
+		"first_frame_latency_ms": turn.Latency.FirstFrame,
+	} {
+		if d > 0 {
+			doc[field] = d.Milliseconds()
+		}
+	}
+
+	go func() {
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		if _, err := h.mongoClient.NewQuery("call_turns").Insert(ctx, doc); err != nil {
+			h.logger.Warn("Failed to persist call turn",
+				zap.String("call_sid", session.CallSid),
+				zap.Int("seq", seq),
+				zap.Error(err),
+			)
+		}
+	}()
+}
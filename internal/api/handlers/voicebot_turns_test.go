@@ -0,0 +1,83 @@
+package handlers
+
+import (
+	"testing"
+	"time"
+)
+
+func TestBotTurn(t *testing.T) {
+	started := time.Date(2026, 10, 16, 10, 0, 0, 0, time.UTC)
+	full := "Our plans start at 499 a month. Would you like to hear about the annual plan?"
+	heard := "Our plans start at 499 a month. Would you"
+	latency := turnLatency{LLM: 300 * time.Millisecond}
+
+	// Played in full: the mark echo ends the turn and there is nothing else to keep
+	played := botTurn(turnReply, full, full, playbackResult{PlayedMs: 4000, StartedAt: started, PlayedAt: started.Add(4200 * time.Millisecond)}, latency)
+	if played.Speaker != "bot" || played.Kind != turnReply || played.Text != full || played.FullText != "" || played.Interrupted {
+		t.Errorf("played turn = %+v", played)
+	}
+	if !played.Start.Equal(started) || !played.End.Equal(started.Add(4200*time.Millisecond)) || played.Latency != latency {
+		t.Errorf("played turn timing = %v → %v, latency %+v", played.Start, played.End, played.Latency)
+	}
+
+	// Interrupted: the heard part is the text, everything generated is kept, and the end comes from PlayedMs
+	cut := botTurn(turnReply, heard, full, playbackResult{PlayedMs: 2500, Interrupted: true, StartedAt: started}, latency)
+	if cut.Text != heard || cut.FullText != full || !cut.Interrupted {
+		t.Errorf("interrupted turn = %+v", cut)
+	}
+	if !cut.End.Equal(started.Add(2500 * time.Millisecond)) {
+		t.Errorf("interrupted turn ends %v, want %v", cut.End, started.Add(2500*time.Millisecond))
+	}
+
+	// Nothing was sent: the turn is stamped now
+	before := time.Now()
+	silent := botTurn(turnPrompt, "", "Are you still there?", playbackResult{Interrupted: true}, turnLatency{})
+	if silent.Start.Before(before) || !silent.End.Equal(silent.Start) || silent.FullText != "Are you still there?" {
+		t.Errorf("unplayed turn = %+v", silent)
+	}
+}
+
+func TestSTTLatency(t *testing.T) {
+	end := time.Date(2026, 10, 16, 10, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name      string
+		utterance callerUtterance
+		want      time.Duration
+	}{
+		{"transcript after speech end", callerUtterance{SpeechEnd: end, TranscribedAt: end.Add(350 * time.Millisecond)}, 350 * time.Millisecond},
+		{"transcript at speech end", callerUtterance{SpeechEnd: end, TranscribedAt: end}, 0},
+		{"transcript before the VAD saw the end", callerUtterance{SpeechEnd: end, TranscribedAt: end.Add(-100 * time.Millisecond)}, 0},
+		{"speech end unknown", callerUtterance{TranscribedAt: end}, 0},
+		{"keypress", keypressUtterance("[Caller pressed key 1]"), 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.utterance.sttLatency(); got != tt.want {
+				t.Errorf("sttLatency = %v, want %v", got, tt.want)
+			}
+			if got := tt.utterance.callerTurn().Latency.STT; got != tt.want {
+				t.Errorf("callerTurn latency = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSpeechUtterance_ConsumesVADTiming(t *testing.T) {
+	session := &VoiceSession{}
+	start := time.Now().Add(-2 * time.Second)
+	session.markSpeechStart(start)
+	session.markSpeechEnd(start.Add(1500 * time.Millisecond))
+
+	u := session.speechUtterance("What are your fees?", 0.92, "deepgram")
+	if u.Kind != turnSpeech || !u.SpeechStart.Equal(start) || !u.SpeechEnd.Equal(start.Add(1500*time.Millisecond)) || u.sttLatency() <= 0 {
+		t.Errorf("utterance = %+v", u)
+	}
+
+	// A transcript without fresh VAD timing falls back to when it was transcribed
+	next := session.speechUtterance("Hello?", 0.8, "deepgram")
+	turn := next.callerTurn()
+	if !next.SpeechStart.IsZero() || !turn.Start.Equal(next.TranscribedAt) || !turn.End.Equal(next.TranscribedAt) {
+		t.Errorf("utterance without VAD timing = %+v, turn %v → %v", next, turn.Start, turn.End)
+	}
+}
@@ -38,7 +38,7 @@ func buildTestRouter() *gin.Engine {
 	docLoader := ai.NewDocumentLoader("", logger)
 	personaLoader := ai.NewPersonaLoader(mongoClient, docLoader, logger)
 
-	h := handlers.NewHandler(cfg, redisClient, mongoClient, aiManager, ttsService, sttService, personaLoader)
+	h := handlers.NewHandler(cfg, redisClient, mongoClient, aiManager, ttsService, nil, sttService, nil, personaLoader, nil)
 	rateLimiter := middleware.NewRateLimiter(redisClient, 60)
 	authRateLimiter := middleware.NewAuthRateLimiter(redisClient, 5, 900, 1800)
 
@@ -79,6 +79,7 @@ func buildTestRouter() *gin.Engine {
 		calls := api.Group("/calls")
 		{
 			calls.GET("/:call_sid", h.GetCall)
+			calls.GET("/:call_sid/transcript", h.GetCallTranscript)
 		}
 
 		campaigns := api.Group("/campaigns")
@@ -97,6 +98,13 @@ func buildTestRouter() *gin.Engine {
 			recordings.GET("/:call_sid", h.GetRecording)
 		}
 
+		voicebot := api.Group("/voicebot")
+		{
+			voicebot.GET("/sessions", h.ListVoiceSessions)
+			voicebot.GET("/sessions/:call_sid", h.GetVoiceSession)
+			voicebot.POST("/sessions/:call_sid/commands", middleware.RoleMiddleware("admin", "manager"), h.SendVoiceSessionCommand)
+		}
+
 		analytics := api.Group("/analytics")
 		{
 			analytics.GET("/overview", h.GetAnalyticsOverview)
@@ -109,6 +117,9 @@ func buildTestRouter() *gin.Engine {
 			personas.GET("/:id", middleware.ValidateIDParam("id"), h.GetPersona)
 			personas.POST("", middleware.RoleMiddleware("admin"), h.CreatePersona)
 			personas.PUT("/:id", middleware.ValidateIDParam("id"), middleware.RoleMiddleware("admin"), h.UpdatePersona)
+			personas.GET("/:id/versions", middleware.ValidateIDParam("id"), h.ListPersonaVersions)
+			personas.GET("/:id/versions/:version", middleware.ValidateIDParam("id"), h.GetPersonaVersion)
+			personas.POST("/:id/tts/prewarm", middleware.ValidateIDParam("id"), middleware.RoleMiddleware("admin"), h.PrewarmPersonaTTS)
 		}
 
 		users := api.Group("/users")
@@ -154,6 +165,7 @@ func buildTestRouter() *gin.Engine {
 	// Voicebot endpoints
 	router.POST("/voicebot/init", h.ExotelVoicebotEndpoint)
 	router.GET("/voicebot/ws", h.VoicebotWebSocket)
+	router.GET("/voicebot/transfer", h.ExotelTransferEndpoint)
 
 	return router
 }
@@ -187,10 +199,16 @@ var expectedRoutes = []struct {
 
 	// Calls
 	{"GET", "/api/calls/:call_sid"},
+	{"GET", "/api/calls/:call_sid/transcript"},
 
 	// Recordings
 	{"GET", "/api/recordings/:call_sid"},
 
+	// Voicebot sessions
+	{"GET", "/api/voicebot/sessions"},
+	{"GET", "/api/voicebot/sessions/:call_sid"},
+	{"POST", "/api/voicebot/sessions/:call_sid/commands"},
+
 	// Campaigns
 	{"POST", "/api/campaigns"},
 	{"GET", "/api/campaigns"},
@@ -209,6 +227,9 @@ var expectedRoutes = []struct {
 	{"GET", "/api/personas/:id"},
 	{"POST", "/api/personas"},
 	{"PUT", "/api/personas/:id"},
+	{"GET", "/api/personas/:id/versions"},
+	{"GET", "/api/personas/:id/versions/:version"},
+	{"POST", "/api/personas/:id/tts/prewarm"},
 
 	// Users
 	{"GET", "/api/users"},
@@ -233,6 +254,7 @@ var expectedRoutes = []struct {
 	{"POST", "/webhooks/exotel"},
 	{"POST", "/voicebot/init"},
 	{"GET", "/voicebot/ws"},
+	{"GET", "/voicebot/transfer"},
 }
 
 func Test_Routes_Registered(t *testing.T) {
@@ -273,6 +273,9 @@ func (p *AnthropicProvider) GenerateConversationResponse(ctx context.Context, re
 	}
 
 	systemPrompt := "You are a helpful AI assistant for a calling agent platform. Provide concise, professional responses."
+	if req.SystemPrompt != "" {
+		systemPrompt = req.SystemPrompt
+	}
 
 	// Build messages from conversation history
 	messages := []map[string]interface{}{}
@@ -281,6 +284,10 @@ func (p *AnthropicProvider) GenerateConversationResponse(ctx context.Context, re
 	for _, msg := range req.ConversationHistory {
 		if role, ok := msg["role"].(string); ok {
 			if content, ok := msg["content"].(string); ok {
+				// The Messages API takes the system prompt separately
+				if role != "user" && role != "assistant" {
+					continue
+				}
 				messages = append(messages, map[string]interface{}{
 					"role":    role,
 					"content": content,
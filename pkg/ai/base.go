@@ -59,5 +59,14 @@ type ConversationRequest struct {
 	PersonaID          *int64
 	ConversationHistory []map[string]interface{}
 	Context           map[string]interface{}
+	SystemPrompt      string // Used as-is when set, instead of the provider's default prompt
+}
+
+// StreamingProvider is implemented by providers that can stream a conversation response as it is generated
+type StreamingProvider interface {
+	Provider
+
+	// StreamConversationResponse calls onDelta for every text fragment and returns the full response
+	StreamConversationResponse(ctx context.Context, req *ConversationRequest, onDelta func(string) error) (string, error)
 }
 
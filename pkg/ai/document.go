@@ -2,12 +2,14 @@ package ai
 
 import (
 	"fmt"
-	"io"
 	"os"
 	"path/filepath"
 	"strings"
+	"time"
 
 	"go.uber.org/zap"
+
+	"github.com/troikatech/calling-agent/pkg/extract"
 )
 
 // DocumentLoader handles document text extraction
@@ -30,69 +32,89 @@ func NewDocumentLoader(basePath string, logger *zap.Logger) *DocumentLoader {
 
 // ExtractText extracts text from a document file
 func (d *DocumentLoader) ExtractText(filePath string) (string, error) {
-	// Handle relative paths
+	doc, err := d.ExtractDocument(filePath)
+	if err != nil {
+		return "", err
+	}
+	return doc.Text, nil
+}
+
+// ExtractDocument extracts the text of a document file with its page/section map and language
+func (d *DocumentLoader) ExtractDocument(filePath string) (*extract.Document, error) {
+	// Handle relative paths: uploads are stored relative to the working directory, older records relative to basePath
 	if !filepath.IsAbs(filePath) {
-		filePath = filepath.Join(d.basePath, filePath)
+		if _, err := os.Stat(filePath); err != nil {
+			filePath = filepath.Join(d.basePath, filePath)
+		}
 	}
 
 	if _, err := os.Stat(filePath); os.IsNotExist(err) {
 		d.logger.Warn("Document file not found", zap.String("path", filePath))
-		return "", fmt.Errorf("document file not found: %s", filePath)
+		return nil, fmt.Errorf("document file not found: %s", filePath)
 	}
 
-	// Get file extension
-	ext := strings.ToLower(filepath.Ext(filePath))
-
-	// Extract text based on file type
-	switch ext {
-	case ".txt", ".md":
-		return d.extractTextFile(filePath)
-	case ".pdf":
-		return d.extractPDF(filePath)
-	case ".docx", ".doc":
-		return d.extractDOCX(filePath)
-	default:
-		d.logger.Warn("Unsupported file format", zap.String("ext", ext))
-		return "", fmt.Errorf("unsupported file format: %s", ext)
+	if !extract.Supported(filepath.Ext(filePath)) {
+		d.logger.Warn("Unsupported file format", zap.String("ext", filepath.Ext(filePath)))
+		return nil, fmt.Errorf("unsupported file format: %s", filepath.Ext(filePath))
 	}
-}
 
-// extractTextFile extracts text from a plain text file
-func (d *DocumentLoader) extractTextFile(filePath string) (string, error) {
-	file, err := os.Open(filePath)
+	data, err := os.ReadFile(filePath)
 	if err != nil {
-		return "", fmt.Errorf("failed to open file: %w", err)
+		return nil, fmt.Errorf("failed to read file: %w", err)
 	}
-	defer file.Close()
 
-	data, err := io.ReadAll(file)
+	doc, err := extract.Extract(filePath, data)
 	if err != nil {
-		return "", fmt.Errorf("failed to read file: %w", err)
+		return nil, fmt.Errorf("failed to extract text: %w", err)
 	}
-
-	return string(data), nil
+	return doc, nil
 }
 
-// extractPDF extracts text from a PDF file
-// Note: This is a simplified version. For production, use a PDF library like:
-// - github.com/ledongthuc/pdf
-// - github.com/gen2brain/go-fitz
-// - github.com/unidoc/unipdf
-func (d *DocumentLoader) extractPDF(filePath string) (string, error) {
-	// TODO: Implement PDF extraction using a PDF library
-	// For now, return an error indicating PDF support is not yet implemented
-	d.logger.Warn("PDF extraction not yet implemented", zap.String("path", filePath))
-	return "", fmt.Errorf("PDF extraction not yet implemented. Please use a PDF library like github.com/ledongthuc/pdf")
+// ExtractionFields returns the fields an extracted document is stored under on its documents record.
+// Sections are kept as parallel arrays because nested documents come back from the driver as primitive.D.
+func ExtractionFields(doc *extract.Document) map[string]interface{} {
+	titles := make([]string, len(doc.Sections))
+	offsets := make([]int, len(doc.Sections))
+	pages := make([]int, len(doc.Sections))
+	for i, s := range doc.Sections {
+		titles[i], offsets[i], pages[i] = s.Title, s.Offset, s.Page
+	}
+	return map[string]interface{}{
+		"text":            doc.Text,
+		"text_length":     len(doc.Text),
+		"language":        doc.Language,
+		"page_count":      doc.Pages,
+		"section_titles":  titles,
+		"section_offsets": offsets,
+		"section_pages":   pages,
+		"extracted_at":    time.Now().Format(time.RFC3339),
+	}
 }
 
-// extractDOCX extracts text from a DOCX file
-// Note: This is a simplified version. For production, use a DOCX library like:
-// - github.com/unidoc/unioffice
-func (d *DocumentLoader) extractDOCX(filePath string) (string, error) {
-	// TODO: Implement DOCX extraction using a DOCX library
-	// For now, return an error indicating DOCX support is not yet implemented
-	d.logger.Warn("DOCX extraction not yet implemented", zap.String("path", filePath))
-	return "", fmt.Errorf("DOCX extraction not yet implemented. Please use a DOCX library like github.com/unidoc/unioffice")
+// StoredExtraction rebuilds the extracted document saved on a documents record, or returns nil if there is none
+func StoredExtraction(record map[string]interface{}) *extract.Document {
+	text, ok := record["text"].(string)
+	if !ok || record["extracted_at"] == nil {
+		return nil
+	}
+	doc := &extract.Document{Pages: toInt(record["page_count"])}
+	doc.Text = text
+	doc.Language, _ = record["language"].(string)
+
+	titles := toSlice(record["section_titles"])
+	offsets := toSlice(record["section_offsets"])
+	pages := toSlice(record["section_pages"])
+	for i := range offsets {
+		s := extract.Section{Offset: toInt(offsets[i])}
+		if i < len(titles) {
+			s.Title, _ = titles[i].(string)
+		}
+		if i < len(pages) {
+			s.Page = toInt(pages[i])
+		}
+		doc.Sections = append(doc.Sections, s)
+	}
+	return doc
 }
 
 // ExtractFromDocuments extracts text from multiple documents
@@ -0,0 +1,130 @@
+package ai
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+// embeddingBatchSize bounds the inputs sent in one embeddings request
+const embeddingBatchSize = 96
+
+// Embedder turns passages into vectors for semantic retrieval
+type Embedder interface {
+	// Model names the embedding model; vectors from different models are not comparable
+	Model() string
+	Embed(ctx context.Context, texts []string) ([][]float32, error)
+}
+
+// OpenAIEmbedder computes embeddings with the OpenAI embeddings API
+type OpenAIEmbedder struct {
+	apiKey  string
+	model   string
+	timeout time.Duration
+	logger  *zap.Logger
+	baseURL string
+}
+
+// NewOpenAIEmbedder creates an OpenAI embedder; model defaults to text-embedding-3-small
+func NewOpenAIEmbedder(apiKey, model string, timeout time.Duration, logger *zap.Logger) *OpenAIEmbedder {
+	if model == "" {
+		model = "text-embedding-3-small"
+	}
+	return &OpenAIEmbedder{
+		apiKey:  apiKey,
+		model:   model,
+		timeout: timeout,
+		logger:  logger,
+		baseURL: "https://api.openai.com/v1",
+	}
+}
+
+// WithBaseURL points the embedder at another OpenAI-compatible endpoint
+func (e *OpenAIEmbedder) WithBaseURL(baseURL string) *OpenAIEmbedder {
+	e.baseURL = baseURL
+	return e
+}
+
+// Model returns the embedding model
+func (e *OpenAIEmbedder) Model() string {
+	return e.model
+}
+
+// Embed returns one vector per text, in order
+func (e *OpenAIEmbedder) Embed(ctx context.Context, texts []string) ([][]float32, error) {
+	if e.apiKey == "" {
+		return nil, fmt.Errorf("OpenAI embeddings not available. Set OPENAI_API_KEY environment variable")
+	}
+
+	vectors := make([][]float32, 0, len(texts))
+	for start := 0; start < len(texts); start += embeddingBatchSize {
+		end := start + embeddingBatchSize
+		if end > len(texts) {
+			end = len(texts)
+		}
+		batch, err := e.embedBatch(ctx, texts[start:end])
+		if err != nil {
+			return nil, err
+		}
+		vectors = append(vectors, batch...)
+	}
+	return vectors, nil
+}
+
+// embedBatch sends one embeddings request
+func (e *OpenAIEmbedder) embedBatch(ctx context.Context, texts []string) ([][]float32, error) {
+	jsonData, err := json.Marshal(map[string]interface{}{
+		"model": e.model,
+		"input": texts,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal request: %w", err)
+	}
+
+	httpReq, err := http.NewRequestWithContext(ctx, "POST", e.baseURL+"/embeddings", bytes.NewBuffer(jsonData))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %w", err)
+	}
+	httpReq.Header.Set("Content-Type", "application/json")
+	httpReq.Header.Set("Authorization", "Bearer "+e.apiKey)
+
+	client := &http.Client{Timeout: e.timeout}
+	resp, err := client.Do(httpReq)
+	if err != nil {
+		return nil, fmt.Errorf("failed to execute request: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		body, _ := io.ReadAll(resp.Body)
+		return nil, fmt.Errorf("OpenAI embeddings API error: %d - %s", resp.StatusCode, string(body))
+	}
+
+	var result struct {
+		Data []struct {
+			Index     int       `json:"index"`
+			Embedding []float32 `json:"embedding"`
+		} `json:"data"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+		return nil, fmt.Errorf("failed to decode response: %w", err)
+	}
+	if len(result.Data) != len(texts) {
+		return nil, fmt.Errorf("OpenAI embeddings API returned %d vectors for %d inputs", len(result.Data), len(texts))
+	}
+
+	vectors := make([][]float32, len(texts))
+	for _, item := range result.Data {
+		if item.Index < 0 || item.Index >= len(texts) {
+			return nil, fmt.Errorf("OpenAI embeddings API returned index %d out of range", item.Index)
+		}
+		vectors[item.Index] = item.Embedding
+	}
+	return vectors, nil
+}
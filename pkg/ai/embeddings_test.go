@@ -0,0 +1,53 @@
+package ai
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+func TestOpenAIEmbedder_BatchesAndOrders(t *testing.T) {
+	requests := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		var body struct {
+			Input []string `json:"input"`
+		}
+		json.NewDecoder(r.Body).Decode(&body)
+
+		// Answer out of order; the vector's first component encodes the input
+		data := make([]map[string]interface{}, 0, len(body.Input))
+		for i := len(body.Input) - 1; i >= 0; i-- {
+			var n float32
+			fmt.Sscanf(body.Input[i], "text %g", &n)
+			data = append(data, map[string]interface{}{"index": i, "embedding": []float32{n, 1}})
+		}
+		json.NewEncoder(w).Encode(map[string]interface{}{"data": data})
+	}))
+	defer server.Close()
+
+	texts := make([]string, embeddingBatchSize+3)
+	for i := range texts {
+		texts[i] = fmt.Sprintf("text %d", i)
+	}
+
+	e := NewOpenAIEmbedder("test-key", "", 5*time.Second, zap.NewNop()).WithBaseURL(server.URL)
+	vectors, err := e.Embed(context.Background(), texts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if requests != 2 {
+		t.Errorf("sent %d requests, want 2", requests)
+	}
+	for i, v := range vectors {
+		if v[0] != float32(i) {
+			t.Fatalf("vector %d belongs to input %v", i, v[0])
+		}
+	}
+}
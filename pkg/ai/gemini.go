@@ -293,6 +293,12 @@ func (p *GeminiProvider) GenerateConversationResponse(ctx context.Context, req *
 	for _, msg := range req.ConversationHistory {
 		if role, ok := msg["role"].(string); ok {
 			if content, ok := msg["content"].(string); ok {
+				// Gemini only knows "user" and "model" turns
+				if role == "assistant" {
+					role = "model"
+				} else if role != "user" {
+					continue
+				}
 				contents = append(contents, map[string]interface{}{
 					"role": role,
 					"parts": []map[string]interface{}{
@@ -324,6 +330,15 @@ func (p *GeminiProvider) GenerateConversationResponse(ctx context.Context, req *
 			"maxOutputTokens": 500,
 		},
 	}
+	if req.SystemPrompt != "" {
+		requestBody["systemInstruction"] = map[string]interface{}{
+			"parts": []map[string]interface{}{
+				{
+					"text": req.SystemPrompt,
+				},
+			},
+		}
+	}
 
 	jsonData, err := json.Marshal(requestBody)
 	if err != nil {
@@ -0,0 +1,455 @@
+package ai
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.uber.org/zap"
+
+	"github.com/troikatech/calling-agent/pkg/extract"
+	"github.com/troikatech/calling-agent/pkg/mongo"
+	"github.com/troikatech/calling-agent/pkg/rag"
+)
+
+// Chunking of persona documents: passages of about a paragraph, overlapping by a sentence or two
+const (
+	chunkWords        = 120
+	chunkOverlapWords = 20
+)
+
+// defaultRetrievalTopK is how many passages go into the prompt when no other value is configured
+const defaultRetrievalTopK = 4
+
+// RetrievedChunk is a document passage selected for a prompt
+type RetrievedChunk struct {
+	ID         string  `json:"id"` // <document_id>#<index>, stable across re-indexing of unchanged text
+	DocumentID string  `json:"document_id"`
+	Document   string  `json:"document"` // Document name
+	Index      int     `json:"index"`
+	Section    string  `json:"section,omitempty"` // Heading or sheet the passage is under
+	Page       int     `json:"page,omitempty"`    // 1-based page for paged formats
+	Text       string  `json:"text"`
+	Score      float64 `json:"score"`
+}
+
+// KnowledgeBase chunks persona documents into the document_chunks collection and retrieves
+// the passages relevant to a query with BM25, fused with embedding similarity when an embedder is set
+type KnowledgeBase struct {
+	mongoClient *mongo.Client
+	docLoader   *DocumentLoader
+	embedder    Embedder
+	topK        int
+	logger      *zap.Logger
+	indexing    sync.Map // Document IDs being back-filled
+}
+
+// NewKnowledgeBase creates a knowledge base over documents read by docLoader
+func NewKnowledgeBase(mongoClient *mongo.Client, docLoader *DocumentLoader, logger *zap.Logger) *KnowledgeBase {
+	return &KnowledgeBase{
+		mongoClient: mongoClient,
+		docLoader:   docLoader,
+		topK:        defaultRetrievalTopK,
+		logger:      logger,
+	}
+}
+
+// WithEmbedder adds semantic retrieval; chunks indexed before it was set are matched by keywords only
+func (k *KnowledgeBase) WithEmbedder(embedder Embedder) *KnowledgeBase {
+	k.embedder = embedder
+	return k
+}
+
+// WithTopK sets how many passages Retrieve returns by default
+func (k *KnowledgeBase) WithTopK(topK int) *KnowledgeBase {
+	if topK > 0 {
+		k.topK = topK
+	}
+	return k
+}
+
+// DocumentID returns the ID chunks of a documents record are stored under
+func DocumentID(doc map[string]interface{}) string {
+	switch id := doc["_id"].(type) {
+	case primitive.ObjectID:
+		return id.Hex()
+	case string:
+		return id
+	}
+	if id, ok := doc["id"]; ok && id != nil {
+		return fmt.Sprint(id)
+	}
+	return ""
+}
+
+// documentFilePath returns the stored file of a documents record
+func documentFilePath(doc map[string]interface{}) string {
+	for _, field := range []string{"file_path", "filepath", "path"} {
+		if fp, ok := doc[field].(string); ok && fp != "" {
+			return fp
+		}
+	}
+	return ""
+}
+
+// IndexDocument splits a document's text into chunks and replaces its rows in document_chunks.
+// The text saved on the record by ExtractDocument is used when there is one; otherwise the file is
+// extracted now and the result saved too. It returns the number of chunks stored.
+func (k *KnowledgeBase) IndexDocument(ctx context.Context, doc map[string]interface{}) (int, error) {
+	if k.mongoClient == nil || k.docLoader == nil {
+		return 0, fmt.Errorf("knowledge base not available")
+	}
+	docID := DocumentID(doc)
+	if docID == "" {
+		return 0, fmt.Errorf("document has no ID")
+	}
+
+	var err error
+	update := map[string]interface{}{}
+	extracted := k.storedExtraction(ctx, doc)
+	if extracted == nil {
+		filePath := documentFilePath(doc)
+		if filePath == "" {
+			return 0, fmt.Errorf("document has no file path")
+		}
+		if extracted, err = k.docLoader.ExtractDocument(filePath); err != nil {
+			return 0, err
+		}
+		update = ExtractionFields(extracted)
+	}
+	chunks := rag.Split(extracted.Text, chunkWords, chunkOverlapWords)
+
+	var vectors [][]float32
+	embeddingModel := ""
+	if k.embedder != nil && len(chunks) > 0 {
+		texts := make([]string, len(chunks))
+		for i, c := range chunks {
+			texts[i] = c.Text
+		}
+		if vectors, err = k.embedder.Embed(ctx, texts); err != nil {
+			// Keyword retrieval still works; the document is embedded on its next re-index
+			k.logger.Warn("Failed to embed document chunks", zap.String("document_id", docID), zap.Error(err))
+			vectors = nil
+		} else {
+			embeddingModel = k.embedder.Model()
+		}
+	}
+
+	now := time.Now().Format(time.RFC3339)
+	rows := make([]interface{}, len(chunks))
+	for i, c := range chunks {
+		row := map[string]interface{}{
+			"chunk_id":    fmt.Sprintf("%s#%d", docID, c.Index),
+			"document_id": docID,
+			"chunk_index": c.Index,
+			"text":        c.Text,
+			"start":       c.Start,
+			"end":         c.End,
+			"created_at":  now,
+		}
+		if section, ok := extracted.SectionAt(c.Start); ok {
+			row["section"] = section.Title
+			row["page"] = section.Page
+		}
+		if vectors != nil {
+			row["embedding"] = vectors[i]
+			row["embedding_model"] = embeddingModel
+		}
+		rows[i] = row
+	}
+
+	if _, err := k.mongoClient.NewQuery("document_chunks").Eq("document_id", docID).Delete(ctx); err != nil {
+		return 0, fmt.Errorf("failed to clear old chunks: %w", err)
+	}
+	if len(rows) > 0 {
+		if _, err := k.mongoClient.NewQuery("document_chunks").InsertMany(ctx, rows); err != nil {
+			return 0, fmt.Errorf("failed to store chunks: %w", err)
+		}
+	}
+
+	update["chunk_count"] = len(chunks)
+	update["embedding_model"] = embeddingModel
+	update["indexed_at"] = now
+	if _, err := k.mongoClient.NewQuery("documents").Eq("_id", doc["_id"]).UpdateOne(ctx, update); err != nil {
+		k.logger.Warn("Failed to mark document indexed", zap.String("document_id", docID), zap.Error(err))
+	}
+
+	k.logger.Info("Indexed document",
+		zap.String("document_id", docID),
+		zap.Int("chunks", len(chunks)),
+		zap.String("embedding_model", embeddingModel),
+	)
+	return len(chunks), nil
+}
+
+// ExtractDocument extracts the text of a document's file and saves it with its section map and
+// language on the documents record, replacing any earlier extraction
+func (k *KnowledgeBase) ExtractDocument(ctx context.Context, doc map[string]interface{}) (*extract.Document, error) {
+	if k.mongoClient == nil || k.docLoader == nil {
+		return nil, fmt.Errorf("knowledge base not available")
+	}
+	filePath := documentFilePath(doc)
+	if filePath == "" {
+		return nil, fmt.Errorf("document has no file path")
+	}
+
+	extracted, err := k.docLoader.ExtractDocument(filePath)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := k.mongoClient.NewQuery("documents").Eq("_id", doc["_id"]).UpdateOne(ctx, ExtractionFields(extracted)); err != nil {
+		return nil, fmt.Errorf("failed to save extracted text: %w", err)
+	}
+	return extracted, nil
+}
+
+// storedExtraction returns the text saved on a documents record, reading it from the database
+// when doc was loaded without it
+func (k *KnowledgeBase) storedExtraction(ctx context.Context, doc map[string]interface{}) *extract.Document {
+	if extracted := StoredExtraction(doc); extracted != nil {
+		return extracted
+	}
+	if doc["_id"] == nil {
+		return nil
+	}
+	record, err := k.mongoClient.NewQuery("documents").
+		Select("text", "language", "page_count", "section_titles", "section_offsets", "section_pages", "extracted_at").
+		Eq("_id", doc["_id"]).
+		FindOne(ctx)
+	if err != nil || record == nil {
+		return nil
+	}
+	return StoredExtraction(record)
+}
+
+// DeleteDocument removes a document's chunks
+func (k *KnowledgeBase) DeleteDocument(ctx context.Context, docID string) error {
+	if k.mongoClient == nil || docID == "" {
+		return nil
+	}
+	if _, err := k.mongoClient.NewQuery("document_chunks").Eq("document_id", docID).Delete(ctx); err != nil {
+		return fmt.Errorf("failed to delete chunks: %w", err)
+	}
+	return nil
+}
+
+// ChunkIndex holds the chunks of a set of documents ready for searching, so a caller asking many
+// queries of the same documents loads and indexes them once
+type ChunkIndex struct {
+	chunks  map[string]RetrievedChunk
+	bm25    *rag.BM25
+	vectors map[string][]float32
+	model   string // Embedding model of vectors
+}
+
+// Len returns the number of chunks in the index
+func (ix *ChunkIndex) Len() int {
+	if ix == nil {
+		return 0
+	}
+	return len(ix.chunks)
+}
+
+// Retrieve returns the topK passages of documents most relevant to query (topK <= 0 uses the configured default).
+// Documents that have never been indexed are indexed in the background and join later turns.
+func (k *KnowledgeBase) Retrieve(ctx context.Context, documents []map[string]interface{}, query string, topK int) ([]RetrievedChunk, error) {
+	if k.mongoClient == nil || len(documents) == 0 || strings.TrimSpace(query) == "" {
+		return nil, nil
+	}
+	index, err := k.LoadIndex(ctx, documents)
+	if err != nil {
+		return nil, err
+	}
+	return k.Search(ctx, index, query, topK), nil
+}
+
+// LoadIndex loads the chunks of documents and builds their keyword index.
+// Documents that have never been indexed are indexed in the background and are missing from the result.
+func (k *KnowledgeBase) LoadIndex(ctx context.Context, documents []map[string]interface{}) (*ChunkIndex, error) {
+	index := &ChunkIndex{
+		chunks:  map[string]RetrievedChunk{},
+		vectors: map[string][]float32{},
+	}
+	if k.embedder != nil {
+		index.model = k.embedder.Model()
+	}
+	if k.mongoClient == nil || len(documents) == 0 {
+		index.bm25 = rag.NewBM25(nil)
+		return index, nil
+	}
+
+	names := make(map[string]string, len(documents))
+	ids := make([]string, 0, len(documents))
+	for _, doc := range documents {
+		if id := DocumentID(doc); id != "" {
+			names[id], _ = doc["name"].(string)
+			ids = append(ids, id)
+		}
+	}
+
+	rows, err := k.mongoClient.NewQuery("document_chunks").
+		Select("chunk_id", "document_id", "chunk_index", "section", "page", "text", "embedding", "embedding_model").
+		In("document_id", ids).
+		Find(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load chunks: %w", err)
+	}
+
+	indexed := make(map[string]bool, len(ids))
+	passages := make([]rag.Document, 0, len(rows))
+	for _, row := range rows {
+		c := RetrievedChunk{
+			DocumentID: fmt.Sprint(row["document_id"]),
+			Index:      toInt(row["chunk_index"]),
+			Page:       toInt(row["page"]),
+		}
+		c.ID, _ = row["chunk_id"].(string)
+		c.Section, _ = row["section"].(string)
+		c.Text, _ = row["text"].(string)
+		c.Document = names[c.DocumentID]
+		if c.ID == "" || c.Text == "" {
+			continue
+		}
+		indexed[c.DocumentID] = true
+		index.chunks[c.ID] = c
+		passages = append(passages, rag.Document{ID: c.ID, Text: c.Text})
+		if index.model != "" && row["embedding_model"] == index.model {
+			if v := toFloat32s(row["embedding"]); len(v) > 0 {
+				index.vectors[c.ID] = v
+			}
+		}
+	}
+	index.bm25 = rag.NewBM25(passages)
+
+	for _, doc := range documents {
+		// Documents with a status are indexed by the ingestion queue; older ones are back-filled here
+		if id := DocumentID(doc); id != "" && !indexed[id] && doc["indexed_at"] == nil && doc["status"] == nil {
+			k.backfill(doc)
+		}
+	}
+	return index, nil
+}
+
+// Search returns the topK passages of index most relevant to query (topK <= 0 uses the configured default)
+func (k *KnowledgeBase) Search(ctx context.Context, index *ChunkIndex, query string, topK int) []RetrievedChunk {
+	if topK <= 0 {
+		topK = k.topK
+	}
+	if index.Len() == 0 || strings.TrimSpace(query) == "" {
+		return nil
+	}
+
+	// Keyword and semantic candidates are fused by rank, so a few extra of each improve the final cut
+	hits := index.bm25.Search(query, topK*3)
+	if len(index.vectors) > 0 && k.embedder != nil && k.embedder.Model() == index.model {
+		if qv, err := k.embedder.Embed(ctx, []string{query}); err != nil {
+			k.logger.Warn("Failed to embed query, using keyword retrieval", zap.Error(err))
+		} else if len(qv) == 1 {
+			hits = rag.Fuse(hits, rag.VectorSearch(qv[0], index.vectors, topK*3))
+		}
+	}
+	if len(hits) > topK {
+		hits = hits[:topK]
+	}
+
+	result := make([]RetrievedChunk, 0, len(hits))
+	for _, hit := range hits {
+		c := index.chunks[hit.ID]
+		c.Score = hit.Score
+		result = append(result, c)
+	}
+	return result
+}
+
+// backfill indexes a document uploaded before chunking existed in the background, at most once at a time
+func (k *KnowledgeBase) backfill(doc map[string]interface{}) {
+	docID := DocumentID(doc)
+	if _, running := k.indexing.LoadOrStore(docID, true); running {
+		return
+	}
+	go func() {
+		ctx, cancel := context.WithTimeout(context.Background(), 2*time.Minute)
+		defer cancel()
+		if _, err := k.IndexDocument(ctx, doc); err != nil {
+			// Left marked so a document that cannot be read is not retried on every turn
+			k.logger.Warn("Failed to back-fill document chunks", zap.String("document_id", docID), zap.Error(err))
+			return
+		}
+		k.indexing.Delete(docID)
+	}()
+}
+
+// FormatChunks renders retrieved passages for a system prompt, numbered and labelled with their
+// document and the section or page they come from
+func FormatChunks(chunks []RetrievedChunk) string {
+	var b strings.Builder
+	for i, c := range chunks {
+		if i > 0 {
+			b.WriteString("\n")
+		}
+		var label []string
+		for _, part := range []string{c.Document, c.Section} {
+			if part != "" {
+				label = append(label, part)
+			}
+		}
+		if c.Page > 0 {
+			label = append(label, fmt.Sprintf("p. %d", c.Page))
+		}
+		if len(label) > 0 {
+			fmt.Fprintf(&b, "[%d] (%s) %s", i+1, strings.Join(label, ", "), c.Text)
+		} else {
+			fmt.Fprintf(&b, "[%d] %s", i+1, c.Text)
+		}
+	}
+	return b.String()
+}
+
+// toInt converts a numeric BSON value to int
+func toInt(val interface{}) int {
+	switch v := val.(type) {
+	case int32:
+		return int(v)
+	case int64:
+		return int(v)
+	case int:
+		return v
+	case float64:
+		return int(v)
+	}
+	return 0
+}
+
+// toSlice returns the items of a BSON array
+func toSlice(val interface{}) []interface{} {
+	switch v := val.(type) {
+	case primitive.A:
+		return v
+	case []interface{}:
+		return v
+	}
+	return nil
+}
+
+// toFloat32s converts a BSON array of numbers to a vector
+func toFloat32s(val interface{}) []float32 {
+	items := toSlice(val)
+	if items == nil {
+		return nil
+	}
+	vec := make([]float32, len(items))
+	for i, item := range items {
+		switch n := item.(type) {
+		case float64:
+			vec[i] = float32(n)
+		case float32:
+			vec[i] = n
+		default:
+			return nil
+		}
+	}
+	return vec
+}
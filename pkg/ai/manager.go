@@ -3,6 +3,8 @@ package ai
 import (
 	"context"
 	"fmt"
+	"time"
+
 	"go.uber.org/zap"
 )
 
@@ -102,3 +104,149 @@ func (m *Manager) GenerateConversationResponse(ctx context.Context, req *Convers
 	return result.(string), nil
 }
 
+// attemptTimeout splits what is left of a latency budget between provider attempts.
+// Every attempt but the last gets half of the remainder, so a hung provider still leaves time for the next one.
+func attemptTimeout(deadline time.Time, last bool) time.Duration {
+	remaining := time.Until(deadline)
+	if last {
+		return remaining
+	}
+	return remaining / 2
+}
+
+// availableProviders returns the providers that are configured, in fallback order
+func (m *Manager) availableProviders() []Provider {
+	var available []Provider
+	for _, provider := range m.providers {
+		if provider.IsAvailable() {
+			available = append(available, provider)
+		}
+	}
+	return available
+}
+
+// GenerateConversationResponseWithin generates a conversation response, failing over to the next provider
+// when one errors or takes too long, so that the whole turn stays within budget
+func (m *Manager) GenerateConversationResponseWithin(ctx context.Context, req *ConversationRequest, budget time.Duration) (string, error) {
+	providers := m.availableProviders()
+	if len(providers) == 0 {
+		return "", fmt.Errorf("no AI providers available")
+	}
+
+	deadline := time.Now().Add(budget)
+	var lastErr error
+	for i, provider := range providers {
+		timeout := attemptTimeout(deadline, i == len(providers)-1)
+		if timeout <= 0 {
+			break
+		}
+
+		attemptCtx, cancel := context.WithTimeout(ctx, timeout)
+		start := time.Now()
+		text, err := provider.GenerateConversationResponse(attemptCtx, req)
+		cancel()
+		if err == nil && text == "" {
+			err = fmt.Errorf("empty response")
+		}
+		if err == nil {
+			m.logger.Info("Successfully used AI provider",
+				zap.String("provider", provider.Name()),
+				zap.Duration("latency", time.Since(start)),
+			)
+			return text, nil
+		}
+		if ctx.Err() != nil {
+			return "", ctx.Err()
+		}
+
+		lastErr = err
+		m.logger.Warn("AI provider failed, trying next",
+			zap.String("provider", provider.Name()),
+			zap.Duration("timeout", timeout),
+			zap.Error(err),
+		)
+	}
+
+	if lastErr == nil {
+		lastErr = fmt.Errorf("latency budget of %s exhausted", budget)
+	}
+	return "", fmt.Errorf("all AI providers failed. Last error: %w", lastErr)
+}
+
+// StreamConversationResponse streams a conversation response through the first provider that starts answering.
+// budget bounds the time to the first fragment across all attempts; a provider that has not produced
+// anything within its share is abandoned for the next one. Providers without streaming deliver their
+// whole response as a single fragment. Once a fragment has been delivered there is no failover,
+// since the caller may already have acted on it.
+func (m *Manager) StreamConversationResponse(ctx context.Context, req *ConversationRequest, budget time.Duration, onDelta func(string) error) (string, error) {
+	providers := m.availableProviders()
+	if len(providers) == 0 {
+		return "", fmt.Errorf("no AI providers available")
+	}
+
+	deadline := time.Now().Add(budget)
+	var lastErr error
+	for i, provider := range providers {
+		timeout := attemptTimeout(deadline, i == len(providers)-1)
+		if timeout <= 0 {
+			break
+		}
+
+		start := time.Now()
+		text, started, err := m.streamAttempt(ctx, provider, req, timeout, onDelta)
+		if err == nil && text == "" {
+			err = fmt.Errorf("empty response")
+		}
+		if err == nil {
+			m.logger.Info("Successfully used AI provider",
+				zap.String("provider", provider.Name()),
+				zap.Duration("latency", time.Since(start)),
+			)
+			return text, nil
+		}
+		if started || ctx.Err() != nil {
+			return text, err
+		}
+
+		lastErr = err
+		m.logger.Warn("AI provider failed before responding, trying next",
+			zap.String("provider", provider.Name()),
+			zap.Duration("timeout", timeout),
+			zap.Error(err),
+		)
+	}
+
+	if lastErr == nil {
+		lastErr = fmt.Errorf("latency budget of %s exhausted", budget)
+	}
+	return "", fmt.Errorf("all AI providers failed. Last error: %w", lastErr)
+}
+
+// streamAttempt runs one provider with a first-fragment timeout and reports whether any fragment was delivered
+func (m *Manager) streamAttempt(ctx context.Context, provider Provider, req *ConversationRequest, firstDelta time.Duration, onDelta func(string) error) (string, bool, error) {
+	streaming, ok := provider.(StreamingProvider)
+	if !ok {
+		attemptCtx, cancel := context.WithTimeout(ctx, firstDelta)
+		defer cancel()
+		text, err := provider.GenerateConversationResponse(attemptCtx, req)
+		if err != nil || text == "" {
+			return "", false, err
+		}
+		return text, true, onDelta(text)
+	}
+
+	attemptCtx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	timer := time.AfterFunc(firstDelta, cancel)
+	defer timer.Stop()
+
+	started := false
+	text, err := streaming.StreamConversationResponse(attemptCtx, req, func(delta string) error {
+		if !started {
+			started = true
+			timer.Stop()
+		}
+		return onDelta(delta)
+	})
+	return text, started, err
+}
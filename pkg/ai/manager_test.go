@@ -3,7 +3,9 @@ package ai
 import (
 	"context"
 	"errors"
+	"strings"
 	"testing"
+	"time"
 
 	"go.uber.org/zap"
 )
@@ -223,3 +225,137 @@ func TestManager_GenerateConversationResponse_WithFallback(t *testing.T) {
 	}
 }
 
+// slowProvider answers after a delay (or never, if the context ends first), optionally streaming word by word
+type slowProvider struct {
+	MockProvider
+	delay  time.Duration
+	answer string
+}
+
+func (p *slowProvider) GenerateConversationResponse(ctx context.Context, req *ConversationRequest) (string, error) {
+	select {
+	case <-time.After(p.delay):
+		return p.answer, nil
+	case <-ctx.Done():
+		return "", ctx.Err()
+	}
+}
+
+// streamingProvider streams its answer word by word after a delay
+type streamingProvider struct {
+	slowProvider
+	failAfterFirst bool
+}
+
+func (p *streamingProvider) StreamConversationResponse(ctx context.Context, req *ConversationRequest, onDelta func(string) error) (string, error) {
+	select {
+	case <-time.After(p.delay):
+	case <-ctx.Done():
+		return "", ctx.Err()
+	}
+	var full strings.Builder
+	for _, word := range strings.SplitAfter(p.answer, " ") {
+		full.WriteString(word)
+		if err := onDelta(word); err != nil {
+			return full.String(), err
+		}
+		if p.failAfterFirst {
+			return full.String(), errors.New("stream broke")
+		}
+	}
+	return full.String(), nil
+}
+
+func TestManager_GenerateConversationResponseWithin_FailsOverSlowPrimary(t *testing.T) {
+	m := NewManager([]Provider{
+		&slowProvider{MockProvider: MockProvider{name: "slow", available: true}, delay: time.Second, answer: "late"},
+		&slowProvider{MockProvider: MockProvider{name: "fast", available: true}, delay: 10 * time.Millisecond, answer: "fallback answer"},
+	}, zap.NewNop())
+
+	start := time.Now()
+	resp, err := m.GenerateConversationResponseWithin(context.Background(), &ConversationRequest{UserText: "Hello"}, 300*time.Millisecond)
+	if err != nil {
+		t.Fatalf("GenerateConversationResponseWithin() error = %v", err)
+	}
+	if resp != "fallback answer" {
+		t.Errorf("response = %q, want the fallback provider's answer", resp)
+	}
+	if elapsed := time.Since(start); elapsed > 300*time.Millisecond {
+		t.Errorf("took %s, want within the 300ms budget", elapsed)
+	}
+}
+
+func TestManager_GenerateConversationResponseWithin_AllFail(t *testing.T) {
+	m := NewManager([]Provider{
+		&MockProvider{name: "provider1", available: true, shouldErr: true},
+		&MockProvider{name: "provider2", available: true, shouldErr: true},
+	}, zap.NewNop())
+
+	if _, err := m.GenerateConversationResponseWithin(context.Background(), &ConversationRequest{UserText: "Hello"}, time.Second); err == nil {
+		t.Error("expected error when all providers fail")
+	}
+}
+
+func TestManager_StreamConversationResponse(t *testing.T) {
+	tests := []struct {
+		name      string
+		providers []Provider
+		want      string
+		wantErr   bool
+	}{
+		{
+			name: "streams from primary",
+			providers: []Provider{
+				&streamingProvider{slowProvider: slowProvider{MockProvider: MockProvider{name: "primary", available: true}, answer: "hello there friend"}},
+			},
+			want: "hello there friend",
+		},
+		{
+			name: "fails over when primary does not start in time",
+			providers: []Provider{
+				&streamingProvider{slowProvider: slowProvider{MockProvider: MockProvider{name: "primary", available: true}, delay: time.Second, answer: "too late"}},
+				&streamingProvider{slowProvider: slowProvider{MockProvider: MockProvider{name: "secondary", available: true}, answer: "from secondary"}},
+			},
+			want: "from secondary",
+		},
+		{
+			name: "non-streaming fallback delivers one fragment",
+			providers: []Provider{
+				&MockProvider{name: "broken", available: true, shouldErr: true},
+				&slowProvider{MockProvider: MockProvider{name: "plain", available: true}, answer: "whole answer"},
+			},
+			want: "whole answer",
+		},
+		{
+			name: "no failover once a fragment was delivered",
+			providers: []Provider{
+				&streamingProvider{slowProvider: slowProvider{MockProvider: MockProvider{name: "primary", available: true}, answer: "partial answer"}, failAfterFirst: true},
+				&streamingProvider{slowProvider: slowProvider{MockProvider: MockProvider{name: "secondary", available: true}, answer: "should not be used"}},
+			},
+			want:    "partial ",
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			m := NewManager(tt.providers, zap.NewNop())
+
+			var delivered strings.Builder
+			got, err := m.StreamConversationResponse(context.Background(), &ConversationRequest{UserText: "Hello"}, 300*time.Millisecond, func(delta string) error {
+				delivered.WriteString(delta)
+				return nil
+			})
+
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("StreamConversationResponse() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("StreamConversationResponse() = %q, want %q", got, tt.want)
+			}
+			if delivered.String() != tt.want {
+				t.Errorf("delivered fragments = %q, want %q", delivered.String(), tt.want)
+			}
+		})
+	}
+}
@@ -274,68 +274,7 @@ func (p *OpenAIProvider) GenerateConversationResponse(ctx context.Context, req *
 		return "", fmt.Errorf("OpenAI provider not available")
 	}
 
-	// Build system prompt with persona and document context
-	systemPrompt := "You are a helpful AI assistant for a calling agent platform. Provide concise, professional responses."
-
-	// Add persona and document context if available
-	if req.Context != nil {
-		if ragContext, ok := req.Context["rag_context"].(map[string]interface{}); ok {
-			// Build enhanced system prompt with persona data
-			if personaData, ok := ragContext["persona_data"].(map[string]interface{}); ok && personaData != nil {
-				personaInfo := ""
-				
-				// Extract persona name, description, tone, etc.
-				if name, ok := personaData["name"].(string); ok && name != "" {
-					personaInfo += fmt.Sprintf("You are acting as: %s. ", name)
-				}
-				if desc, ok := personaData["description"].(string); ok && desc != "" {
-					personaInfo += fmt.Sprintf("Description: %s. ", desc)
-				}
-				if tone, ok := personaData["tone"].(string); ok && tone != "" {
-					personaInfo += fmt.Sprintf("Communication tone: %s. ", tone)
-				}
-				if instructions, ok := personaData["instructions"].(string); ok && instructions != "" {
-					personaInfo += fmt.Sprintf("Special instructions: %s. ", instructions)
-				}
-				
-				if personaInfo != "" {
-					systemPrompt = personaInfo + systemPrompt
-				}
-			}
-
-			// Add document context (RAG) to system prompt
-			if documentText, ok := ragContext["document_text"].(string); ok && documentText != "" {
-				// Truncate if too long (OpenAI has token limits)
-				maxDocLength := 3000 // ~750 tokens
-				if len(documentText) > maxDocLength {
-					documentText = documentText[:maxDocLength] + "..."
-				}
-				systemPrompt += fmt.Sprintf("\n\nRelevant information from knowledge base:\n%s\n\nUse this information to provide accurate, context-aware responses.", documentText)
-			}
-		}
-	}
-
-	messages := []map[string]interface{}{
-		{"role": "system", "content": systemPrompt},
-	}
-
-	// Add conversation history
-	for _, msg := range req.ConversationHistory {
-		if role, ok := msg["role"].(string); ok {
-			if content, ok := msg["content"].(string); ok {
-				messages = append(messages, map[string]interface{}{
-					"role":    role,
-					"content": content,
-				})
-			}
-		}
-	}
-
-	// Add current user message
-	messages = append(messages, map[string]interface{}{
-		"role":    "user",
-		"content": req.UserText,
-	})
+	messages := p.conversationMessages(req)
 
 	requestBody := map[string]interface{}{
 		"model":       p.model,
@@ -388,3 +327,73 @@ func (p *OpenAIProvider) GenerateConversationResponse(ctx context.Context, req *
 	return strings.TrimSpace(openAIResp.Choices[0].Message.Content), nil
 }
 
+// conversationMessages builds the chat messages for a conversation request: system prompt, history, user message
+func (p *OpenAIProvider) conversationMessages(req *ConversationRequest) []map[string]interface{} {
+	// Build system prompt with persona and document context
+	systemPrompt := "You are a helpful AI assistant for a calling agent platform. Provide concise, professional responses."
+
+	// Add persona and document context if available
+	if req.SystemPrompt != "" {
+		// The caller has already built the full prompt (persona, RAG, call instructions)
+		systemPrompt = req.SystemPrompt
+	} else if req.Context != nil {
+		if ragContext, ok := req.Context["rag_context"].(map[string]interface{}); ok {
+			// Build enhanced system prompt with persona data
+			if persona, ok := ragContext["persona_data"].(*Persona); ok && persona != nil {
+				personaInfo := ""
+
+				// Extract persona name, description, tone, etc.
+				if persona.Name != "" {
+					personaInfo += fmt.Sprintf("You are acting as: %s. ", persona.Name)
+				}
+				if persona.Description != "" {
+					personaInfo += fmt.Sprintf("Description: %s. ", persona.Description)
+				}
+				if persona.Tone != "" {
+					personaInfo += fmt.Sprintf("Communication tone: %s. ", persona.Tone)
+				}
+				if persona.Instructions != "" {
+					personaInfo += fmt.Sprintf("Special instructions: %s. ", persona.Instructions)
+				}
+
+				if personaInfo != "" {
+					systemPrompt = personaInfo + systemPrompt
+				}
+			}
+
+			// Add document context (RAG) to system prompt
+			if documentText, ok := ragContext["document_text"].(string); ok && documentText != "" {
+				// Truncate if too long (OpenAI has token limits)
+				maxDocLength := 3000 // ~750 tokens
+				if len(documentText) > maxDocLength {
+					documentText = documentText[:maxDocLength] + "..."
+				}
+				systemPrompt += fmt.Sprintf("\n\nRelevant information from knowledge base:\n%s\n\nUse this information to provide accurate, context-aware responses.", documentText)
+			}
+		}
+	}
+
+	messages := []map[string]interface{}{
+		{"role": "system", "content": systemPrompt},
+	}
+
+	// Add conversation history
+	for _, msg := range req.ConversationHistory {
+		if role, ok := msg["role"].(string); ok {
+			if content, ok := msg["content"].(string); ok {
+				messages = append(messages, map[string]interface{}{
+					"role":    role,
+					"content": content,
+				})
+			}
+		}
+	}
+
+	// Add current user message
+	messages = append(messages, map[string]interface{}{
+		"role":    "user",
+		"content": req.UserText,
+	})
+
+	return messages
+}
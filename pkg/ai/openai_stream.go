@@ -0,0 +1,104 @@
+package ai
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+)
+
+// StreamChatCompletion runs a chat completion with stream=true and calls onDelta for every content
+// fragment as it arrives. It returns the full response text.
+// The request is bounded by ctx only, so callers should set their own deadline.
+func (p *OpenAIProvider) StreamChatCompletion(ctx context.Context, messages []map[string]interface{}, onDelta func(string) error) (string, error) {
+	if !p.IsAvailable() {
+		return "", fmt.Errorf("OpenAI provider not available")
+	}
+
+	requestBody := map[string]interface{}{
+		"model":       p.model,
+		"messages":    messages,
+		"max_tokens":  p.maxTokens,
+		"temperature": 0.7,
+		"stream":      true,
+	}
+
+	jsonData, err := json.Marshal(requestBody)
+	if err != nil {
+		return "", fmt.Errorf("failed to marshal request: %w", err)
+	}
+
+	httpReq, err := http.NewRequestWithContext(ctx, "POST", p.baseURL+"/chat/completions", bytes.NewBuffer(jsonData))
+	if err != nil {
+		return "", fmt.Errorf("failed to create request: %w", err)
+	}
+
+	httpReq.Header.Set("Content-Type", "application/json")
+	httpReq.Header.Set("Authorization", "Bearer "+p.apiKey)
+	httpReq.Header.Set("Accept", "text/event-stream")
+
+	// No client timeout: it would cut off long replies mid-stream
+	resp, err := http.DefaultClient.Do(httpReq)
+	if err != nil {
+		return "", fmt.Errorf("failed to execute request: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		body, _ := io.ReadAll(resp.Body)
+		return "", fmt.Errorf("OpenAI API error: %d - %s", resp.StatusCode, string(body))
+	}
+
+	var full strings.Builder
+	scanner := bufio.NewScanner(resp.Body)
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+
+	// Server-sent events: one "data: {...}" line per chunk, terminated by "data: [DONE]"
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if !strings.HasPrefix(line, "data:") {
+			continue
+		}
+		data := strings.TrimSpace(strings.TrimPrefix(line, "data:"))
+		if data == "[DONE]" {
+			return full.String(), nil
+		}
+
+		var chunk struct {
+			Choices []struct {
+				Delta struct {
+					Content string `json:"content"`
+				} `json:"delta"`
+			} `json:"choices"`
+		}
+		if err := json.Unmarshal([]byte(data), &chunk); err != nil {
+			return full.String(), fmt.Errorf("failed to decode stream chunk: %w", err)
+		}
+		if len(chunk.Choices) == 0 || chunk.Choices[0].Delta.Content == "" {
+			continue
+		}
+
+		delta := chunk.Choices[0].Delta.Content
+		full.WriteString(delta)
+		if onDelta != nil {
+			if err := onDelta(delta); err != nil {
+				return full.String(), err
+			}
+		}
+	}
+
+	if err := scanner.Err(); err != nil {
+		return full.String(), fmt.Errorf("failed to read stream: %w", err)
+	}
+	return full.String(), fmt.Errorf("stream ended without [DONE]")
+}
+
+// StreamConversationResponse streams a conversational response (StreamingProvider)
+func (p *OpenAIProvider) StreamConversationResponse(ctx context.Context, req *ConversationRequest, onDelta func(string) error) (string, error) {
+	text, err := p.StreamChatCompletion(ctx, p.conversationMessages(req), onDelta)
+	return strings.TrimSpace(text), err
+}
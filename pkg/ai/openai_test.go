@@ -1,6 +1,10 @@
 package ai
 
 import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
 	"testing"
 	"time"
 
@@ -56,3 +60,34 @@ func TestOpenAIProvider_IsAvailable_WithoutAPIKey(t *testing.T) {
 // Note: Integration tests for actual API calls would require API keys
 // and should be in a separate test file or use test fixtures
 
+
+func TestOpenAIProvider_StreamChatCompletion(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/event-stream")
+		for _, delta := range []string{"Hello", " there", "."} {
+			fmt.Fprintf(w, "data: {\"choices\":[{\"delta\":{\"content\":%q}}]}\n\n", delta)
+		}
+		fmt.Fprint(w, "data: [DONE]\n\n")
+	}))
+	defer server.Close()
+
+	p := NewOpenAIProvider("test-key", "gpt-4o-mini", 200, 30*time.Second, zap.NewNop())
+	p.baseURL = server.URL
+
+	var deltas []string
+	full, err := p.StreamChatCompletion(context.Background(), []map[string]interface{}{
+		{"role": "user", "content": "hi"},
+	}, func(d string) error {
+		deltas = append(deltas, d)
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("StreamChatCompletion() error = %v", err)
+	}
+	if full != "Hello there." {
+		t.Errorf("StreamChatCompletion() = %q, want %q", full, "Hello there.")
+	}
+	if len(deltas) != 3 {
+		t.Errorf("got %d deltas, want 3", len(deltas))
+	}
+}
@@ -7,10 +7,11 @@ import (
 	"fmt"
 	"io"
 	"net/http"
-	"os/exec"
 	"time"
 
 	"go.uber.org/zap"
+
+	"github.com/troikatech/calling-agent/pkg/audio"
 )
 
 // OpenAITTSService handles Text-to-Speech using OpenAI TTS API
@@ -134,34 +135,10 @@ func (s *OpenAITTSService) TextToSpeechPCM(ctx context.Context, req *OpenAITTSRe
 		return audioData, nil
 	}
 
-	// Otherwise, convert MP3/other formats to PCM16 using ffmpeg
-	pcmData, err := s.convertMP3ToPCM16k(audioData)
+	// Otherwise decode MP3/WAV to 16kHz PCM16 in-process
+	pcmData, err := audio.DecodeToPCM16(audioData, 16000)
 	if err != nil {
 		return nil, fmt.Errorf("failed to convert audio to PCM: %w", err)
 	}
 	return pcmData, nil
 }
-
-// convertMP3ToPCM16k converts MP3 to raw 16-bit 16kHz mono PCM
-func (s *OpenAITTSService) convertMP3ToPCM16k(mp3Data []byte) ([]byte, error) {
-	// Use ffmpeg: -i pipe:0 (MP3 input) -> -f s16le -ar 16000 -ac 1 (16-bit 16kHz mono PCM output)
-	cmd := exec.Command("ffmpeg",
-		"-i", "pipe:0",     // Read MP3 from stdin
-		"-f", "s16le",      // Output format: 16-bit signed little-endian PCM
-		"-ar", "16000",     // Output sample rate: 16kHz
-		"-ac", "1",         // Output channels: mono
-		"-",                // Output to stdout
-	)
-
-	cmd.Stdin = bytes.NewReader(mp3Data)
-	var out bytes.Buffer
-	cmd.Stdout = &out
-	cmd.Stderr = &bytes.Buffer{} // Suppress stderr
-
-	if err := cmd.Run(); err != nil {
-		return nil, fmt.Errorf("ffmpeg conversion failed: %w", err)
-	}
-
-	return out.Bytes(), nil
-}
-
@@ -3,6 +3,7 @@ package ai
 import (
 	"context"
 	"fmt"
+	"time"
 
 	"go.uber.org/zap"
 
@@ -13,6 +14,8 @@ import (
 type PersonaLoader struct {
 	mongoClient *mongo.Client
 	docLoader   *DocumentLoader
+	knowledge   *KnowledgeBase
+	cache       *personaCache
 	logger      *zap.Logger
 }
 
@@ -21,43 +24,59 @@ func NewPersonaLoader(mongoClient *mongo.Client, docLoader *DocumentLoader, logg
 	return &PersonaLoader{
 		mongoClient: mongoClient,
 		docLoader:   docLoader,
+		knowledge:   NewKnowledgeBase(mongoClient, docLoader, logger),
+		cache:       newPersonaCache(defaultPersonaCacheSize, defaultPersonaCacheTTL),
 		logger:      logger,
 	}
 }
 
+// WithCache sets how many persona contexts are kept in memory and for how long; size <= 0 disables caching
+func (l *PersonaLoader) WithCache(size int, ttl time.Duration) *PersonaLoader {
+	l.cache = newPersonaCache(size, ttl)
+	return l
+}
+
+// Knowledge returns the knowledge base holding the chunks of persona documents
+func (l *PersonaLoader) Knowledge() *KnowledgeBase {
+	return l.knowledge
+}
+
 // LoadPersonaData loads persona data from MongoDB
 func (l *PersonaLoader) LoadPersonaData(ctx context.Context, personaID int64) (map[string]interface{}, error) {
+	persona, err := l.findPersona(ctx, personaID)
+	if err != nil {
+		return nil, err
+	}
+
+	l.logger.Info("Loaded persona",
+		zap.Int64("persona_id", personaID),
+		zap.Any("persona", persona),
+	)
+	return persona, nil
+}
+
+// findPersona reads the fields of a persona record (all of them when none are given).
+// The ID may be stored as an int64 or a string.
+func (l *PersonaLoader) findPersona(ctx context.Context, personaID int64, fields ...string) (map[string]interface{}, error) {
 	if l.mongoClient == nil {
 		l.logger.Warn("MongoDB not connected, cannot load persona")
 		return nil, fmt.Errorf("MongoDB client not available")
 	}
-
-	// Try to find persona by ID (could be int64 or string)
-	persona, err := l.mongoClient.NewQuery("personas").
-		Eq("id", personaID).
-		FindOne(ctx)
-	
-	if err != nil {
-		return nil, fmt.Errorf("failed to query persona: %w", err)
+	if len(fields) == 0 {
+		fields = []string{"*"}
 	}
 
-	if persona == nil {
-		// Try as string
-		persona, err = l.mongoClient.NewQuery("personas").
-			Eq("id", fmt.Sprintf("%d", personaID)).
+	for _, id := range []interface{}{personaID, fmt.Sprintf("%d", personaID)} {
+		persona, err := l.mongoClient.NewQuery("personas").
+			Select(fields...).
+			Eq("id", id).
 			FindOne(ctx)
-		
 		if err != nil {
 			return nil, fmt.Errorf("failed to query persona: %w", err)
 		}
-	}
-
-	if persona != nil {
-		l.logger.Info("Loaded persona",
-			zap.Int64("persona_id", personaID),
-			zap.Any("persona", persona),
-		)
-		return persona, nil
+		if persona != nil {
+			return persona, nil
+		}
 	}
 
 	l.logger.Warn("Persona not found",
@@ -66,6 +85,20 @@ func (l *PersonaLoader) LoadPersonaData(ctx context.Context, personaID int64) (m
 	return nil, fmt.Errorf("persona %d not found", personaID)
 }
 
+// personaVersion identifies a revision of a persona record: its version number when it has one,
+// otherwise when it was last written
+func personaVersion(persona map[string]interface{}) string {
+	for _, field := range []string{"version", "updated_at", "created_at"} {
+		if v, ok := persona[field]; ok && v != nil {
+			return fmt.Sprintf("%s:%v", field, v)
+		}
+	}
+	return ""
+}
+
+// documentFields are the documents fields retrieval needs; extracted text is read only when indexing
+var documentFields = []string{"id", "name", "type", "persona_id", "file_path", "language", "status", "indexed_at", "chunk_count"}
+
 // LoadDocumentsForPersona loads documents linked to persona from MongoDB
 func (l *PersonaLoader) LoadDocumentsForPersona(ctx context.Context, personaID int64) ([]map[string]interface{}, error) {
 	if l.mongoClient == nil {
@@ -75,6 +108,7 @@ func (l *PersonaLoader) LoadDocumentsForPersona(ctx context.Context, personaID i
 
 	// Find documents linked to this persona
 	documents, err := l.mongoClient.NewQuery("documents").
+		Select(documentFields...).
 		Eq("persona_id", personaID).
 		Find(ctx)
 	
@@ -85,6 +119,7 @@ func (l *PersonaLoader) LoadDocumentsForPersona(ctx context.Context, personaID i
 	if len(documents) == 0 {
 		// Try as string
 		documents, err = l.mongoClient.NewQuery("documents").
+			Select(documentFields...).
 			Eq("persona_id", fmt.Sprintf("%d", personaID)).
 			Find(ctx)
 		
@@ -100,94 +135,149 @@ func (l *PersonaLoader) LoadDocumentsForPersona(ctx context.Context, personaID i
 	return documents, nil
 }
 
-// ExtractDocumentTexts extracts text content from document files
-func (l *PersonaLoader) ExtractDocumentTexts(ctx context.Context, documents []map[string]interface{}) (string, error) {
-	if l.docLoader == nil {
-		l.logger.Warn("Document loader not available, cannot extract text")
-		return "", fmt.Errorf("document loader not available")
+// LoadPersonaVersion loads one immutable version of a persona from persona_versions.
+// The current persona record stands in for a version that has no copy yet.
+func (l *PersonaLoader) LoadPersonaVersion(ctx context.Context, personaID, version int64) (*Persona, error) {
+	if l.mongoClient == nil {
+		return nil, fmt.Errorf("MongoDB client not available")
 	}
 
-	documentPaths := []string{}
+	record, err := l.mongoClient.NewQuery("persona_versions").
+		Eq("persona_id", personaID).
+		Eq("version", version).
+		FindOne(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query persona version: %w", err)
+	}
+	if record != nil {
+		return PersonaFromRecord(record), nil
+	}
 
-	for _, doc := range documents {
-		// Try different field names for file path
-		filePath := ""
-		if fp, ok := doc["file_path"].(string); ok && fp != "" {
-			filePath = fp
-		} else if fp, ok := doc["filepath"].(string); ok && fp != "" {
-			filePath = fp
-		} else if fp, ok := doc["path"].(string); ok && fp != "" {
-			filePath = fp
-		}
+	current, err := l.findPersona(ctx, personaID)
+	if err != nil {
+		return nil, err
+	}
+	if persona := PersonaFromRecord(current); persona.Version == version {
+		persona.ID = personaID
+		return persona, nil
+	}
+	return nil, fmt.Errorf("persona %d has no version %d", personaID, version)
+}
 
-		if filePath != "" {
-			documentPaths = append(documentPaths, filePath)
+// LoadPersonaContext returns a persona, its documents and their chunk index, from the cache when the
+// persona has not changed since they were loaded. version pins an immutable persona version (a
+// campaign's); 0 uses the current one.
+func (l *PersonaLoader) LoadPersonaContext(ctx context.Context, personaID, version int64) (*PersonaContext, error) {
+	key := fmt.Sprintf("version:%d", version)
+	if version == 0 {
+		// Only the version is read to validate the cache; the rest is loaded on a miss
+		current, err := l.findPersona(ctx, personaID, "version", "updated_at", "created_at")
+		if err != nil {
+			return nil, err
 		}
+		key = personaVersion(current)
 	}
-
-	if len(documentPaths) == 0 {
-		l.logger.Warn("No document file paths found")
-		return "", nil
+	if pc := l.cache.get(personaID, key); pc != nil {
+		return pc, nil
 	}
 
-	combinedText, err := l.docLoader.ExtractFromDocuments(documentPaths)
+	var persona *Persona
+	if version > 0 {
+		var err error
+		if persona, err = l.LoadPersonaVersion(ctx, personaID, version); err != nil {
+			return nil, err
+		}
+	} else {
+		record, err := l.LoadPersonaData(ctx, personaID)
+		if err != nil {
+			return nil, err
+		}
+		persona = PersonaFromRecord(record)
+		persona.ID = personaID
+		key = personaVersion(record)
+	}
+	documents, err := l.LoadDocumentsForPersona(ctx, personaID)
 	if err != nil {
-		l.logger.Warn("Failed to extract text from documents",
-			zap.Error(err),
-		)
-		return "", err
+		return nil, err
+	}
+	index, err := l.knowledge.LoadIndex(ctx, documents)
+	if err != nil {
+		return nil, err
 	}
 
-	l.logger.Info("Extracted text from documents",
-		zap.Int("document_count", len(documentPaths)),
-		zap.Int("text_length", len(combinedText)),
-	)
-	return combinedText, nil
+	pc := &PersonaContext{
+		PersonaID: personaID,
+		Version:   key,
+		Persona:   persona,
+		Documents: documents,
+		Index:     index,
+		LoadedAt:  time.Now(),
+	}
+	// A document still being ingested would be missing from the cached index until the entry expires
+	if !documentsSettled(documents) {
+		return pc, nil
+	}
+	l.cache.add(pc)
+	return pc, nil
 }
 
-// BuildRAGContext builds RAG context from persona and documents
-func (l *PersonaLoader) BuildRAGContext(ctx context.Context, personaID *int64) (map[string]interface{}, error) {
-	context := map[string]interface{}{
-		"persona_data": nil,
-		"document_text": "",
-		"has_context": false,
+// documentsSettled reports whether none of documents is waiting to be indexed
+func documentsSettled(documents []map[string]interface{}) bool {
+	for _, doc := range documents {
+		status, _ := doc["status"].(string)
+		if status == "pending" || status == "processing" || (doc["status"] == nil && doc["indexed_at"] == nil) {
+			return false
+		}
 	}
+	return true
+}
 
+// InvalidatePersona drops the cached context of a persona after it or its documents change
+func (l *PersonaLoader) InvalidatePersona(personaID int64) {
+	l.cache.invalidate(personaID)
+}
+
+// BuildRAGContext builds RAG context from persona data and the document passages most relevant to query.
+// "chunks" holds the retrieved passages and "document_text" the same passages formatted for a prompt;
+// an empty query returns persona data only.
+func (l *PersonaLoader) BuildRAGContext(ctx context.Context, personaID *int64, query string) (map[string]interface{}, error) {
 	if personaID == nil {
-		return context, nil
+		return l.RAGContext(ctx, nil, query), nil
 	}
 
-	// Load persona data
-	personaData, err := l.LoadPersonaData(ctx, *personaID)
+	pc, err := l.LoadPersonaContext(ctx, *personaID, 0)
 	if err != nil {
-		l.logger.Warn("Failed to load persona data",
+		l.logger.Warn("Failed to load persona context",
 			zap.Int64("persona_id", *personaID),
 			zap.Error(err),
 		)
-	} else if personaData != nil {
-		context["persona_data"] = personaData
-		context["has_context"] = true
 	}
+	return l.RAGContext(ctx, pc, query), nil
+}
 
-	// Load and extract documents
-	documents, err := l.LoadDocumentsForPersona(ctx, *personaID)
-	if err != nil {
-		l.logger.Warn("Failed to load documents",
-			zap.Int64("persona_id", *personaID),
-			zap.Error(err),
-		)
-	} else if len(documents) > 0 {
-		documentText, err := l.ExtractDocumentTexts(ctx, documents)
-		if err != nil {
-			l.logger.Warn("Failed to extract document text",
-				zap.Error(err),
-			)
-		} else if documentText != "" {
-			context["document_text"] = documentText
-			context["has_context"] = true
-		}
+// RAGContext builds the RAG context of one turn from a loaded persona context (which may be nil),
+// searching its document index for the passages most relevant to query
+func (l *PersonaLoader) RAGContext(ctx context.Context, pc *PersonaContext, query string) map[string]interface{} {
+	context := map[string]interface{}{
+		"persona_data":  nil,
+		"chunks":        []RetrievedChunk{},
+		"document_text": "",
+		"has_context":   false,
+	}
+	if pc == nil {
+		return context
 	}
 
-	return context, nil
-}
+	if pc.Persona != nil {
+		context["persona_data"] = pc.Persona
+		context["has_context"] = true
+	}
 
+	// Retrieve the passages relevant to the query instead of sending whole documents
+	if chunks := l.knowledge.Search(ctx, pc.Index, query, 0); len(chunks) > 0 {
+		context["chunks"] = chunks
+		context["document_text"] = FormatChunks(chunks)
+		context["has_context"] = true
+	}
+	return context
+}
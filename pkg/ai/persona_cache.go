@@ -0,0 +1,112 @@
+package ai
+
+import (
+	"container/list"
+	"sync"
+	"time"
+)
+
+// Defaults for the persona context cache
+const (
+	defaultPersonaCacheSize = 256
+	defaultPersonaCacheTTL  = 10 * time.Minute
+)
+
+// PersonaContext is everything a call needs from its persona: the persona record, its documents and
+// their chunk index. It is built once per persona version and shared read-only by every call using it.
+type PersonaContext struct {
+	PersonaID int64
+	Version   string // Persona version the context was built from
+	Persona   *Persona
+	Documents []map[string]interface{}
+	Index     *ChunkIndex
+	LoadedAt  time.Time
+}
+
+// personaCache is an LRU of persona contexts keyed by persona ID and version.
+// Entries also expire after ttl, which bounds how stale another instance's copy can get,
+// since invalidation only reaches the local process.
+type personaCache struct {
+	mu      sync.Mutex
+	size    int
+	ttl     time.Duration
+	order   *list.List // Front is most recently used
+	entries map[personaCacheKey]*list.Element
+}
+
+type personaCacheKey struct {
+	personaID int64
+	version   string
+}
+
+// newPersonaCache creates a cache holding up to size contexts; size <= 0 disables caching
+func newPersonaCache(size int, ttl time.Duration) *personaCache {
+	return &personaCache{
+		size:    size,
+		ttl:     ttl,
+		order:   list.New(),
+		entries: make(map[personaCacheKey]*list.Element),
+	}
+}
+
+// get returns the cached context for a persona version, or nil
+func (c *personaCache) get(personaID int64, version string) *PersonaContext {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	el, ok := c.entries[personaCacheKey{personaID, version}]
+	if !ok {
+		return nil
+	}
+	pc := el.Value.(*PersonaContext)
+	if c.ttl > 0 && time.Since(pc.LoadedAt) > c.ttl {
+		c.remove(el)
+		return nil
+	}
+	c.order.MoveToFront(el)
+	return pc
+}
+
+// add caches pc, evicting the least recently used contexts over size. Versions of the same persona
+// are kept side by side, since campaigns pinned to different versions can be calling at once.
+func (c *personaCache) add(pc *PersonaContext) {
+	if c.size <= 0 {
+		return
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	key := personaCacheKey{pc.PersonaID, pc.Version}
+	if el, ok := c.entries[key]; ok {
+		c.remove(el)
+	}
+	c.entries[key] = c.order.PushFront(pc)
+	for c.order.Len() > c.size {
+		c.remove(c.order.Back())
+	}
+}
+
+// invalidate drops every cached version of a persona
+func (c *personaCache) invalidate(personaID int64) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for key, el := range c.entries {
+		if key.personaID == personaID {
+			c.remove(el)
+		}
+	}
+}
+
+// remove deletes an element; the caller holds mu
+func (c *personaCache) remove(el *list.Element) {
+	pc := el.Value.(*PersonaContext)
+	delete(c.entries, personaCacheKey{pc.PersonaID, pc.Version})
+	c.order.Remove(el)
+}
+
+// len returns the number of cached contexts
+func (c *personaCache) len() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.order.Len()
+}
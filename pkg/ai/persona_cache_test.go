@@ -0,0 +1,52 @@
+package ai
+
+import (
+	"testing"
+	"time"
+)
+
+func TestPersonaCache_EvictsLeastRecentlyUsed(t *testing.T) {
+	c := newPersonaCache(2, time.Minute)
+	for id := int64(1); id <= 2; id++ {
+		c.add(&PersonaContext{PersonaID: id, Version: "v1", LoadedAt: time.Now()})
+	}
+	c.get(1, "v1") // 2 is now the least recently used
+	c.add(&PersonaContext{PersonaID: 3, Version: "v1", LoadedAt: time.Now()})
+
+	if c.get(2, "v1") != nil {
+		t.Error("persona 2 should have been evicted")
+	}
+	if c.get(1, "v1") == nil || c.get(3, "v1") == nil {
+		t.Error("personas 1 and 3 should be cached")
+	}
+}
+
+func TestPersonaCache_VersionsAndInvalidation(t *testing.T) {
+	c := newPersonaCache(4, time.Minute)
+	c.add(&PersonaContext{PersonaID: 1, Version: "v1", LoadedAt: time.Now()})
+	c.add(&PersonaContext{PersonaID: 1, Version: "v2", LoadedAt: time.Now()})
+
+	// Campaigns pinned to different versions share the cache
+	if c.get(1, "v1") == nil || c.get(1, "v2") == nil {
+		t.Fatal("both versions should be cached")
+	}
+
+	c.invalidate(1)
+	if c.len() != 0 {
+		t.Errorf("len = %d after invalidate, want 0", c.len())
+	}
+}
+
+func TestPersonaCache_Expiry(t *testing.T) {
+	c := newPersonaCache(4, time.Minute)
+	c.add(&PersonaContext{PersonaID: 1, Version: "v1", LoadedAt: time.Now().Add(-2 * time.Minute)})
+	if c.get(1, "v1") != nil {
+		t.Error("expired context returned")
+	}
+
+	disabled := newPersonaCache(0, time.Minute)
+	disabled.add(&PersonaContext{PersonaID: 1, Version: "v1", LoadedAt: time.Now()})
+	if disabled.len() != 0 {
+		t.Error("size 0 should disable caching")
+	}
+}
@@ -0,0 +1,490 @@
+package ai
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"regexp"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+
+	"github.com/troikatech/calling-agent/pkg/validation"
+)
+
+// Tools a persona can be allowed to use on a call
+const (
+	ToolTransfer = "transfer" // Warm transfer to a human agent
+	ToolKeypress = "keypress" // Acting on DTMF keypresses through the keypress map; opt-out keys always work
+)
+
+// PersonaTools lists every tool name accepted in allowed_tools
+var PersonaTools = []string{ToolTransfer, ToolKeypress}
+
+// PersonaTemplateVars are the placeholders a system prompt template may use, written {{name}}
+var PersonaTemplateVars = []string{"persona_name", "language", "tone", "city", "customer_name"}
+
+// PersonaStatuses are the accepted values of a persona's status
+var PersonaStatuses = []string{"draft", "active", "live", "inactive", "archived"}
+
+// personaTTSProviders are the TTS providers a persona can pick
+var personaTTSProviders = []string{"openai", "elevenlabs"}
+
+var (
+	templateVarPattern = regexp.MustCompile(`\{\{\s*([A-Za-z_]+)\s*\}\}`)
+	languagePattern    = regexp.MustCompile(`^\p{L}[\p{L} -]*$`)
+	keypressDigit      = regexp.MustCompile(`^[0-9*#]$`)
+)
+
+// Persona is the typed persona record. The personas collection holds the current version of each
+// persona and persona_versions an immutable copy of every version; both store these fields flat.
+type Persona struct {
+	ID      int64  `json:"id,omitempty"`
+	Version int64  `json:"version,omitempty"`
+	Status  string `json:"status,omitempty"`
+
+	Name        string `json:"name"`
+	Description string `json:"description,omitempty"`
+	Language    string `json:"language"`
+	Age         int    `json:"age,omitempty"`
+	Gender      string `json:"gender,omitempty"`
+	Tone        string `json:"tone,omitempty"`
+	City        string `json:"city,omitempty"`
+
+	// Voice
+	TTSProvider string  `json:"tts_provider,omitempty"`
+	VoiceID     string  `json:"voice_id,omitempty"`
+	TTSModel    string  `json:"tts_model,omitempty"`
+	TTSSpeed    float64 `json:"tts_speed,omitempty"`
+
+	// What the agent says
+	GreetingText          string             `json:"greeting_text,omitempty"`
+	SystemPromptTemplate  string             `json:"system_prompt_template,omitempty"` // Replaces the generated persona description; see PersonaTemplateVars
+	Script                string             `json:"script,omitempty"`
+	Instructions          string             `json:"instructions,omitempty"`
+	ObjectionHandlers     []ObjectionHandler `json:"objection_handlers,omitempty"`
+	ClosingLines          []string           `json:"closing_lines,omitempty"`          // Lines the agent wraps a conversation up with
+	ComplianceDisclaimers []string           `json:"compliance_disclaimers,omitempty"` // Statements the agent must make on every call
+
+	// Call control
+	AllowedTools      []string          `json:"allowed_tools"` // nil allows every tool, empty allows none
+	KeypressMap       map[string]string `json:"keypress_map,omitempty"`
+	TransferNumber    string            `json:"transfer_number,omitempty"` // Comma-separated E.164 numbers
+	SilenceTimeoutSec float64           `json:"silence_timeout_sec,omitempty"`
+	MaxReprompts      *int              `json:"max_reprompts,omitempty"`
+	RepromptText      string            `json:"reprompt_text,omitempty"`
+	ClosingText       string            `json:"closing_text,omitempty"` // Said before hanging up on a silent caller
+}
+
+// ObjectionHandler is how the agent answers one kind of caller objection
+type ObjectionHandler struct {
+	Objection string `json:"objection"`
+	Response  string `json:"response"`
+}
+
+// PersonaValidationError lists every problem found in a persona
+type PersonaValidationError struct {
+	Problems []string
+}
+
+func (e *PersonaValidationError) Error() string {
+	return "invalid persona: " + strings.Join(e.Problems, "; ")
+}
+
+// DecodePersona decodes a JSON persona onto p, rejecting fields the model does not have.
+// Fields missing from data keep their current value, so an update can be decoded onto the stored persona;
+// a field that is sent replaces the current value whole, so a keypress_map sent without a digit drops it.
+func DecodePersona(data []byte, p *Persona) error {
+	var sent map[string]json.RawMessage
+	if err := json.Unmarshal(data, &sent); err != nil {
+		return fmt.Errorf("invalid persona: %w", err)
+	}
+	var patch Persona
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(&patch); err != nil {
+		return fmt.Errorf("invalid persona: %w", err)
+	}
+
+	dst, src := reflect.ValueOf(p).Elem(), reflect.ValueOf(&patch).Elem()
+	for i := 0; i < dst.NumField(); i++ {
+		name, _, _ := strings.Cut(dst.Type().Field(i).Tag.Get("json"), ",")
+		if _, ok := sent[name]; ok {
+			dst.Field(i).Set(src.Field(i))
+		}
+	}
+	return nil
+}
+
+// Validate checks the persona before it is written, returning a *PersonaValidationError
+func (p *Persona) Validate() error {
+	var problems []string
+	fail := func(format string, args ...interface{}) {
+		problems = append(problems, fmt.Sprintf(format, args...))
+	}
+	maxLen := func(field, value string, n int) {
+		if utf8.RuneCountInString(value) > n {
+			fail("%s must be at most %d characters", field, n)
+		}
+	}
+
+	if strings.TrimSpace(p.Name) == "" {
+		fail("name is required")
+	}
+	maxLen("name", p.Name, 100)
+	if p.Language == "" {
+		fail("language is required")
+	} else if !languagePattern.MatchString(p.Language) || utf8.RuneCountInString(p.Language) > 35 {
+		fail("language %q is not a language name or code", p.Language)
+	}
+	if p.Status != "" && !contains(PersonaStatuses, p.Status) {
+		fail("status must be one of %v", PersonaStatuses)
+	}
+	if p.Age != 0 && (p.Age < 18 || p.Age > 100) {
+		fail("age must be between 18 and 100")
+	}
+	maxLen("description", p.Description, 2000)
+
+	if p.TTSProvider != "" && !contains(personaTTSProviders, strings.ToLower(p.TTSProvider)) {
+		fail("tts_provider must be one of %v", personaTTSProviders)
+	}
+	if p.TTSSpeed != 0 && (p.TTSSpeed < 0.25 || p.TTSSpeed > 4) {
+		fail("tts_speed must be between 0.25 and 4")
+	}
+
+	maxLen("greeting_text", p.GreetingText, 500)
+	maxLen("system_prompt_template", p.SystemPromptTemplate, 8000)
+	if err := validateTemplate(p.SystemPromptTemplate); err != nil {
+		fail("system_prompt_template: %v", err)
+	}
+	maxLen("script", p.Script, 8000)
+	maxLen("instructions", p.Instructions, 4000)
+
+	if len(p.ObjectionHandlers) > 50 {
+		fail("at most 50 objection_handlers are allowed")
+	}
+	seen := map[string]bool{}
+	for i, h := range p.ObjectionHandlers {
+		key := strings.ToLower(strings.TrimSpace(h.Objection))
+		switch {
+		case key == "" || strings.TrimSpace(h.Response) == "":
+			fail("objection_handlers[%d] needs an objection and a response", i)
+		case seen[key]:
+			fail("objection_handlers[%d] repeats objection %q", i, h.Objection)
+		}
+		seen[key] = true
+		maxLen(fmt.Sprintf("objection_handlers[%d].response", i), h.Response, 1000)
+	}
+	validateLines := func(field string, lines []string, max, maxChars int) {
+		if len(lines) > max {
+			fail("at most %d %s are allowed", max, field)
+		}
+		for i, line := range lines {
+			if strings.TrimSpace(line) == "" {
+				fail("%s[%d] is empty", field, i)
+			}
+			maxLen(fmt.Sprintf("%s[%d]", field, i), line, maxChars)
+		}
+	}
+	validateLines("closing_lines", p.ClosingLines, 10, 300)
+	validateLines("compliance_disclaimers", p.ComplianceDisclaimers, 10, 1000)
+
+	for i, tool := range p.AllowedTools {
+		if !contains(PersonaTools, tool) {
+			fail("allowed_tools[%d]: unknown tool %q, expected one of %v", i, tool, PersonaTools)
+		} else if contains(p.AllowedTools[:i], tool) {
+			fail("allowed_tools lists %q twice", tool)
+		}
+	}
+	for digit, action := range p.KeypressMap {
+		if !keypressDigit.MatchString(digit) {
+			fail("keypress_map key %q is not a single keypad digit", digit)
+		}
+		if strings.TrimSpace(action) == "" {
+			fail("keypress_map[%s] has no action", digit)
+		}
+	}
+	for _, number := range strings.Split(p.TransferNumber, ",") {
+		if number = strings.TrimSpace(number); number != "" {
+			if _, err := validation.NormalizeE164(number); err != nil {
+				fail("transfer_number %q: %v", number, err)
+			}
+		}
+	}
+	if p.SilenceTimeoutSec != 0 && (p.SilenceTimeoutSec < 1 || p.SilenceTimeoutSec > 60) {
+		fail("silence_timeout_sec must be between 1 and 60")
+	}
+	if p.MaxReprompts != nil && (*p.MaxReprompts < 0 || *p.MaxReprompts > 5) {
+		fail("max_reprompts must be between 0 and 5")
+	}
+	maxLen("reprompt_text", p.RepromptText, 500)
+	maxLen("closing_text", p.ClosingText, 500)
+
+	if len(problems) > 0 {
+		return &PersonaValidationError{Problems: problems}
+	}
+	return nil
+}
+
+// validateTemplate checks that a system prompt template only uses known placeholders
+func validateTemplate(template string) error {
+	for _, m := range templateVarPattern.FindAllStringSubmatch(template, -1) {
+		if !contains(PersonaTemplateVars, m[1]) {
+			return fmt.Errorf("unknown placeholder {{%s}}, expected one of %v", m[1], PersonaTemplateVars)
+		}
+	}
+	rest := templateVarPattern.ReplaceAllString(template, "")
+	if strings.Contains(rest, "{{") || strings.Contains(rest, "}}") {
+		return fmt.Errorf("unbalanced {{ }} placeholder")
+	}
+	return nil
+}
+
+// RenderSystemPrompt fills the persona's system prompt template. vars supplies call values such as
+// customer_name and overrides the persona's own; placeholders without a value are left empty.
+func (p *Persona) RenderSystemPrompt(vars map[string]string) string {
+	values := map[string]string{
+		"persona_name": p.Name,
+		"language":     p.Language,
+		"tone":         p.Tone,
+		"city":         p.City,
+	}
+	for k, v := range vars {
+		if v != "" {
+			values[k] = v
+		}
+	}
+	return templateVarPattern.ReplaceAllStringFunc(p.SystemPromptTemplate, func(m string) string {
+		return values[templateVarPattern.FindStringSubmatch(m)[1]]
+	})
+}
+
+// AllowsTool reports whether the persona may use a tool; a nil persona or allowed_tools allows all
+func (p *Persona) AllowsTool(tool string) bool {
+	if p == nil || p.AllowedTools == nil {
+		return true
+	}
+	return contains(p.AllowedTools, tool)
+}
+
+// Fields returns the persona's content as a flat document, every field present so that writing it
+// replaces what was stored. ID, version and timestamps are left to the caller.
+func (p *Persona) Fields() map[string]interface{} {
+	handlers := make([]map[string]interface{}, len(p.ObjectionHandlers))
+	for i, h := range p.ObjectionHandlers {
+		handlers[i] = map[string]interface{}{"objection": h.Objection, "response": h.Response}
+	}
+	keypressMap := make(map[string]interface{}, len(p.KeypressMap))
+	for digit, action := range p.KeypressMap {
+		keypressMap[digit] = action
+	}
+	var maxReprompts interface{}
+	if p.MaxReprompts != nil {
+		maxReprompts = *p.MaxReprompts
+	}
+	var allowedTools interface{}
+	if p.AllowedTools != nil {
+		allowedTools = p.AllowedTools
+	}
+
+	return map[string]interface{}{
+		"status":                 p.Status,
+		"name":                   p.Name,
+		"description":            p.Description,
+		"language":               p.Language,
+		"age":                    p.Age,
+		"gender":                 p.Gender,
+		"tone":                   p.Tone,
+		"city":                   p.City,
+		"tts_provider":           strings.ToLower(p.TTSProvider),
+		"voice_id":               p.VoiceID,
+		"tts_model":              p.TTSModel,
+		"tts_speed":              p.TTSSpeed,
+		"greeting_text":          p.GreetingText,
+		"system_prompt_template": p.SystemPromptTemplate,
+		"script":                 p.Script,
+		"instructions":           p.Instructions,
+		"objection_handlers":     handlers,
+		"closing_lines":          nonNil(p.ClosingLines),
+		"compliance_disclaimers": nonNil(p.ComplianceDisclaimers),
+		"allowed_tools":          allowedTools,
+		"keypress_map":           keypressMap,
+		"transfer_number":        p.TransferNumber,
+		"silence_timeout_sec":    p.SilenceTimeoutSec,
+		"max_reprompts":          maxReprompts,
+		"reprompt_text":          p.RepromptText,
+		"closing_text":           p.ClosingText,
+	}
+}
+
+// PersonaFromRecord reads a personas or persona_versions document. It is lenient with records written
+// before the schema existed: numbers stored as strings and strings stored as numbers are converted,
+// and fields of the wrong type are skipped.
+func PersonaFromRecord(record map[string]interface{}) *Persona {
+	if record == nil {
+		return nil
+	}
+	p := &Persona{
+		ID:                    recordInt64(record["id"]),
+		Version:               recordInt64(record["version"]),
+		Status:                recordString(record["status"]),
+		Name:                  recordString(record["name"]),
+		Description:           recordString(record["description"]),
+		Language:              recordString(record["language"]),
+		Age:                   int(recordInt64(record["age"])),
+		Gender:                recordString(record["gender"]),
+		Tone:                  recordString(record["tone"]),
+		City:                  recordString(record["city"]),
+		TTSProvider:           recordString(record["tts_provider"]),
+		VoiceID:               recordString(record["voice_id"]),
+		TTSModel:              recordString(record["tts_model"]),
+		TTSSpeed:              recordFloat(record["tts_speed"]),
+		GreetingText:          recordString(record["greeting_text"]),
+		SystemPromptTemplate:  recordString(record["system_prompt_template"]),
+		Script:                recordString(record["script"]),
+		Instructions:          recordString(record["instructions"]),
+		ClosingLines:          recordStrings(record["closing_lines"]),
+		ComplianceDisclaimers: recordStrings(record["compliance_disclaimers"]),
+		TransferNumber:        recordString(record["transfer_number"]),
+		SilenceTimeoutSec:     recordFloat(record["silence_timeout_sec"]),
+		RepromptText:          recordString(record["reprompt_text"]),
+		ClosingText:           recordString(record["closing_text"]),
+	}
+	if record["persona_id"] != nil {
+		// persona_versions documents carry the persona's ID separately
+		p.ID = recordInt64(record["persona_id"])
+	}
+	if tools := toSlice(record["allowed_tools"]); tools != nil {
+		p.AllowedTools = recordStrings(tools)
+	}
+	for _, h := range recordMaps(record["objection_handlers"]) {
+		p.ObjectionHandlers = append(p.ObjectionHandlers, ObjectionHandler{
+			Objection: recordString(h["objection"]),
+			Response:  recordString(h["response"]),
+		})
+	}
+	if keypress := recordMap(record["keypress_map"]); len(keypress) > 0 {
+		p.KeypressMap = make(map[string]string, len(keypress))
+		for digit, action := range keypress {
+			if a := recordString(action); a != "" {
+				p.KeypressMap[digit] = a
+			}
+		}
+	} else if s, ok := record["keypress_map"].(string); ok && s != "" {
+		// Older personas stored the map as a JSON string
+		json.Unmarshal([]byte(s), &p.KeypressMap)
+	}
+	if v, ok := record["max_reprompts"]; ok && v != nil {
+		n := int(recordInt64(v))
+		p.MaxReprompts = &n
+	}
+	return p
+}
+
+// recordString reads a string field, formatting numbers
+func recordString(val interface{}) string {
+	switch v := val.(type) {
+	case string:
+		return v
+	case int32, int64, int:
+		return fmt.Sprint(v)
+	case float64:
+		return strconv.FormatFloat(v, 'f', -1, 64)
+	}
+	return ""
+}
+
+// recordInt64 reads an integer field, parsing strings
+func recordInt64(val interface{}) int64 {
+	if s, ok := val.(string); ok {
+		n, _ := strconv.ParseInt(strings.TrimSpace(s), 10, 64)
+		return n
+	}
+	return int64(toInt(val))
+}
+
+// recordFloat reads a numeric field, parsing strings
+func recordFloat(val interface{}) float64 {
+	switch v := val.(type) {
+	case float64:
+		return v
+	case string:
+		f, _ := strconv.ParseFloat(strings.TrimSpace(v), 64)
+		return f
+	}
+	return float64(toInt(val))
+}
+
+// recordStrings reads an array of strings; a single string becomes one item
+func recordStrings(val interface{}) []string {
+	switch v := val.(type) {
+	case []string:
+		return v
+	case string:
+		if v != "" {
+			return []string{v}
+		}
+	}
+	items := toSlice(val)
+	if items == nil {
+		return nil
+	}
+	out := make([]string, 0, len(items))
+	for _, item := range items {
+		if s := recordString(item); s != "" {
+			out = append(out, s)
+		}
+	}
+	return out
+}
+
+// recordMap reads an embedded document, which the driver may decode as primitive.D or primitive.M
+func recordMap(val interface{}) map[string]interface{} {
+	switch v := val.(type) {
+	case map[string]interface{}:
+		return v
+	case primitive.M:
+		return v
+	case primitive.D:
+		m := make(map[string]interface{}, len(v))
+		for _, e := range v {
+			m[e.Key] = e.Value
+		}
+		return m
+	}
+	return nil
+}
+
+// recordMaps reads an array of embedded documents
+func recordMaps(val interface{}) []map[string]interface{} {
+	if maps, ok := val.([]map[string]interface{}); ok {
+		return maps
+	}
+	var out []map[string]interface{}
+	for _, item := range toSlice(val) {
+		if m := recordMap(item); m != nil {
+			out = append(out, m)
+		}
+	}
+	return out
+}
+
+// nonNil returns an empty slice for nil, so the stored field is an empty array rather than null
+func nonNil(lines []string) []string {
+	if lines == nil {
+		return []string{}
+	}
+	return lines
+}
+
+func contains(list []string, value string) bool {
+	for _, item := range list {
+		if item == value {
+			return true
+		}
+	}
+	return false
+}
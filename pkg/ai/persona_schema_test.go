@@ -0,0 +1,124 @@
+package ai
+
+import (
+	"strings"
+	"testing"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+func validPersona() *Persona {
+	return &Persona{
+		Name:                 "Priya",
+		Language:             "hindi",
+		GreetingText:         "Namaste!",
+		SystemPromptTemplate: "You are {{persona_name}} speaking to {{ customer_name }}.",
+		ObjectionHandlers:    []ObjectionHandler{{Objection: "too expensive", Response: "Mention the EMI option."}},
+		AllowedTools:         []string{ToolTransfer},
+		TransferNumber:       "+919876543210",
+	}
+}
+
+func TestPersonaValidate(t *testing.T) {
+	if err := validPersona().Validate(); err != nil {
+		t.Fatalf("valid persona rejected: %v", err)
+	}
+
+	p := validPersona()
+	p.Name = ""
+	p.TTSSpeed = 9
+	p.SystemPromptTemplate = "Hi {{company}}"
+	p.AllowedTools = []string{"send_sms"}
+	p.ObjectionHandlers = append(p.ObjectionHandlers, ObjectionHandler{Objection: "Too expensive ", Response: "x"})
+	p.KeypressMap = map[string]string{"12": "opt_out"}
+
+	err := p.Validate()
+	verr, ok := err.(*PersonaValidationError)
+	if !ok {
+		t.Fatalf("err = %v, want *PersonaValidationError", err)
+	}
+	for _, want := range []string{"name is required", "tts_speed", "{{company}}", "send_sms", "repeats objection", "keypress_map key"} {
+		if !strings.Contains(verr.Error(), want) {
+			t.Errorf("error %q does not mention %q", verr.Error(), want)
+		}
+	}
+}
+
+func TestDecodePersona_OverlaysAndRejectsUnknownFields(t *testing.T) {
+	p := validPersona()
+	if err := DecodePersona([]byte(`{"greeting_text":"Hello","closing_lines":["Thanks!"]}`), p); err != nil {
+		t.Fatalf("DecodePersona: %v", err)
+	}
+	if p.GreetingText != "Hello" || p.Name != "Priya" || len(p.ClosingLines) != 1 {
+		t.Errorf("overlay = %+v", p)
+	}
+
+	if err := DecodePersona([]byte(`{"nmae":"typo"}`), p); err == nil || !strings.Contains(err.Error(), "nmae") {
+		t.Errorf("err = %v, want unknown field error", err)
+	}
+
+	// A map or slice that is sent replaces the stored one rather than merging into it
+	p.KeypressMap = map[string]string{"1": "transfer_agent", "3": "opt_out"}
+	if err := DecodePersona([]byte(`{"keypress_map":{"3":"opt_out"},"closing_lines":[]}`), p); err != nil {
+		t.Fatalf("DecodePersona: %v", err)
+	}
+	if _, ok := p.KeypressMap["1"]; ok || len(p.KeypressMap) != 1 || len(p.ClosingLines) != 0 {
+		t.Errorf("keypress_map = %v, closing_lines = %v", p.KeypressMap, p.ClosingLines)
+	}
+}
+
+func TestPersonaFromRecord(t *testing.T) {
+	p := PersonaFromRecord(map[string]interface{}{
+		"id":            "7",
+		"version":       int64(3),
+		"name":          "Priya",
+		"age":           "28",
+		"tts_speed":     "1.1",
+		"closing_lines": primitive.A{"Bye", "Take care"},
+		"objection_handlers": primitive.A{
+			primitive.D{{Key: "objection", Value: "busy"}, {Key: "response", Value: "Offer a callback."}},
+		},
+		"keypress_map":  primitive.M{"1": "opt_out"},
+		"max_reprompts": int32(0),
+	})
+
+	if p.ID != 7 || p.Version != 3 || p.Age != 28 || p.TTSSpeed != 1.1 {
+		t.Errorf("scalars = %+v", p)
+	}
+	if len(p.ClosingLines) != 2 || len(p.ObjectionHandlers) != 1 || p.ObjectionHandlers[0].Response != "Offer a callback." {
+		t.Errorf("lists = %+v, %+v", p.ClosingLines, p.ObjectionHandlers)
+	}
+	if p.KeypressMap["1"] != "opt_out" || p.MaxReprompts == nil || *p.MaxReprompts != 0 {
+		t.Errorf("call control = %+v, %v", p.KeypressMap, p.MaxReprompts)
+	}
+	if p.AllowedTools != nil || !p.AllowsTool(ToolTransfer) {
+		t.Error("a record without allowed_tools should allow every tool")
+	}
+
+	roundTrip := PersonaFromRecord(p.Fields())
+	if roundTrip.Name != p.Name || len(roundTrip.ObjectionHandlers) != 1 || roundTrip.KeypressMap["1"] != "opt_out" {
+		t.Errorf("Fields round trip = %+v", roundTrip)
+	}
+}
+
+func TestPersonaRenderSystemPrompt(t *testing.T) {
+	got := validPersona().RenderSystemPrompt(map[string]string{"customer_name": "Rahul"})
+	if want := "You are Priya speaking to Rahul."; got != want {
+		t.Errorf("RenderSystemPrompt = %q, want %q", got, want)
+	}
+}
+
+func TestPersonaAllowsTool(t *testing.T) {
+	p := validPersona()
+	if !p.AllowsTool(ToolTransfer) || p.AllowsTool(ToolKeypress) {
+		t.Error("allowed_tools not honoured")
+	}
+	p.AllowedTools = []string{}
+	if p.AllowsTool(ToolTransfer) {
+		t.Error("empty allowed_tools should allow nothing")
+	}
+	var none *Persona
+	if !none.AllowsTool(ToolTransfer) {
+		t.Error("a call without a persona keeps every tool")
+	}
+}
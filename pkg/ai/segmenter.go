@@ -0,0 +1,117 @@
+package ai
+
+import (
+	"strings"
+	"unicode"
+)
+
+// Segmenter splits streamed LLM output into sentence or clause sized pieces for TTS.
+// Short first segments get audio out quickly; later segments are longer so speech sounds natural.
+type Segmenter struct {
+	buf           []rune
+	emitted       int
+	FirstMinChars int // Minimum length of the first clause-level segment
+	MinChars      int // Minimum length of later clause-level segments
+	MaxChars      int // Force a split at a space once a segment would grow past this
+}
+
+// NewSegmenter creates a segmenter with defaults tuned for phone conversations
+func NewSegmenter() *Segmenter {
+	return &Segmenter{
+		FirstMinChars: 20,
+		MinChars:      60,
+		MaxChars:      200,
+	}
+}
+
+// abbreviations that end with a period but do not end a sentence
+var abbreviations = map[string]bool{
+	"mr": true, "mrs": true, "ms": true, "dr": true, "rs": true, "vs": true,
+	"st": true, "no": true, "etc": true, "e.g": true, "i.e": true, "approx": true,
+}
+
+// Push adds streamed text and returns any segments that are now complete
+func (s *Segmenter) Push(delta string) []string {
+	s.buf = append(s.buf, []rune(delta)...)
+
+	var segments []string
+	for {
+		cut := s.findCut()
+		if cut <= 0 {
+			break
+		}
+		if seg := strings.TrimSpace(string(s.buf[:cut])); seg != "" {
+			segments = append(segments, seg)
+			s.emitted++
+		}
+		s.buf = s.buf[cut:]
+	}
+	return segments
+}
+
+// Flush returns whatever text remains once the stream has ended
+func (s *Segmenter) Flush() string {
+	seg := strings.TrimSpace(string(s.buf))
+	s.buf = s.buf[:0]
+	if seg != "" {
+		s.emitted++
+	}
+	return seg
+}
+
+// findCut returns the length of the next complete segment in buf, or 0 if none is complete yet
+func (s *Segmenter) findCut() int {
+	minChars := s.MinChars
+	if s.emitted == 0 {
+		minChars = s.FirstMinChars
+	}
+
+	lastSpace := 0
+	// A boundary needs the following character, so the last rune is never a cut point
+	for i := 0; i < len(s.buf)-1; i++ {
+		r, next := s.buf[i], s.buf[i+1]
+		if unicode.IsSpace(r) && (s.MaxChars <= 0 || i < s.MaxChars) {
+			lastSpace = i + 1
+		}
+		if r == '\n' {
+			if i > 0 {
+				return i + 1
+			}
+			continue
+		}
+		if !unicode.IsSpace(next) {
+			// "3.5", "e.g.", "Hmm..." mid-token
+			continue
+		}
+
+		switch r {
+		case '.', '!', '?', '।', '|':
+			if r == '.' && s.isAbbreviation(i) {
+				continue
+			}
+			return i + 1
+		case ',', ';', ':', '—':
+			if i+1 >= minChars {
+				return i + 1
+			}
+		}
+	}
+
+	if s.MaxChars > 0 && len(s.buf) > s.MaxChars && lastSpace > 0 {
+		return lastSpace
+	}
+	return 0
+}
+
+// isAbbreviation reports whether the period at index i ends a known abbreviation or a single initial
+func (s *Segmenter) isAbbreviation(i int) bool {
+	start := i
+	for start > 0 && !unicode.IsSpace(s.buf[start-1]) {
+		start--
+	}
+	word := strings.ToLower(string(s.buf[start:i]))
+	if len([]rune(word)) == 1 && unicode.IsLetter([]rune(word)[0]) {
+		return true
+	}
+	return abbreviations[word]
+}
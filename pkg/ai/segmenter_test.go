@@ -0,0 +1,63 @@
+package ai
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestSegmenter_Push(t *testing.T) {
+	tests := []struct {
+		name   string
+		deltas []string
+		want   []string
+	}{
+		{
+			name:   "splits on sentence end once the next token arrives",
+			deltas: []string{"Hello there", ". How are", " you today?", " Fine"},
+			want:   []string{"Hello there.", "How are you today?", "Fine"},
+		},
+		{
+			name:   "keeps decimals and abbreviations together",
+			deltas: []string{"The plan costs Rs. 3.5 lakh per year. Thanks"},
+			want:   []string{"The plan costs Rs. 3.5 lakh per year.", "Thanks"},
+		},
+		{
+			name:   "first clause split is short, later ones wait for more text",
+			deltas: []string{"Sure, I can help with that, ", "and the details are simple, really."},
+			want:   []string{"Sure, I can help with that,", "and the details are simple, really."},
+		},
+		{
+			name:   "hindi danda ends a sentence",
+			deltas: []string{"नमस्ते। आप कैसे हैं?"},
+			want:   []string{"नमस्ते।", "आप कैसे हैं?"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := NewSegmenter()
+			var got []string
+			for _, d := range tt.deltas {
+				got = append(got, s.Push(d)...)
+			}
+			if rest := s.Flush(); rest != "" {
+				got = append(got, rest)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("segments = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSegmenter_MaxChars(t *testing.T) {
+	s := NewSegmenter()
+	s.MaxChars = 20
+	got := s.Push("one two three four five six seven")
+	if len(got) == 0 {
+		t.Fatal("expected a forced split for text without punctuation")
+	}
+	if len(got[0]) > 20 {
+		t.Errorf("segment %q longer than MaxChars", got[0])
+	}
+}
@@ -6,6 +6,7 @@ import (
 	"encoding/json"
 	"fmt"
 	"io"
+	"math"
 	"mime/multipart"
 	"net/http"
 	"time"
@@ -46,17 +47,27 @@ func (s *STTService) IsAvailable() bool {
 
 // STTRequest represents a STT request
 type STTRequest struct {
-	AudioData  []byte
-	AudioFormat string
-	Language   string
-	Prompt     string
+	AudioData      []byte
+	AudioFormat    string
+	Language       string
+	Prompt         string
+	WordTimestamps bool // Request word-level timing (verbose_json)
 }
 
 // STTResponse represents a STT response
 type STTResponse struct {
-	Text     string
-	Language string
-	Duration *float64
+	Text       string
+	Language   string
+	Duration   *float64
+	Words      []STTWord // Only with WordTimestamps
+	Confidence float64   // Mean segment probability, only with WordTimestamps
+}
+
+// STTWord is a transcribed word with its timing in seconds
+type STTWord struct {
+	Word  string  `json:"word"`
+	Start float64 `json:"start"`
+	End   float64 `json:"end"`
 }
 
 // SpeechToText converts speech audio to text
@@ -118,7 +129,18 @@ func (s *STTService) SpeechToText(ctx context.Context, req *STTRequest) (*STTRes
 	}
 
 	// Add response format
-	if err := writer.WriteField("response_format", "json"); err != nil {
+	responseFormat := "json"
+	if req.WordTimestamps {
+		responseFormat = "verbose_json"
+		if err := writer.WriteField("timestamp_granularities[]", "word"); err != nil {
+			return nil, fmt.Errorf("failed to write timestamp_granularities field: %w", err)
+		}
+		// Segments are still needed for confidence
+		if err := writer.WriteField("timestamp_granularities[]", "segment"); err != nil {
+			return nil, fmt.Errorf("failed to write timestamp_granularities field: %w", err)
+		}
+	}
+	if err := writer.WriteField("response_format", responseFormat); err != nil {
 		return nil, fmt.Errorf("failed to write response_format field: %w", err)
 	}
 
@@ -151,9 +173,13 @@ func (s *STTService) SpeechToText(ctx context.Context, req *STTRequest) (*STTRes
 
 	// Parse response
 	var whisperResp struct {
-		Text     string   `json:"text"`
-		Language string   `json:"language"`
-		Duration *float64 `json:"duration"`
+		Text     string    `json:"text"`
+		Language string    `json:"language"`
+		Duration *float64  `json:"duration"`
+		Words    []STTWord `json:"words"`
+		Segments []struct {
+			AvgLogprob float64 `json:"avg_logprob"`
+		} `json:"segments"`
 	}
 
 	if err := json.NewDecoder(resp.Body).Decode(&whisperResp); err != nil {
@@ -169,10 +195,18 @@ func (s *STTService) SpeechToText(ctx context.Context, req *STTRequest) (*STTRes
 		}
 	}
 
+	// Whisper has no per-word confidence; average the segment probabilities instead
+	confidence := 0.0
+	for _, segment := range whisperResp.Segments {
+		confidence += math.Exp(segment.AvgLogprob) / float64(len(whisperResp.Segments))
+	}
+
 	return &STTResponse{
-		Text:     whisperResp.Text,
-		Language: language,
-		Duration: whisperResp.Duration,
+		Text:       whisperResp.Text,
+		Language:   language,
+		Duration:   whisperResp.Duration,
+		Words:      whisperResp.Words,
+		Confidence: confidence,
 	}, nil
 }
 
@@ -0,0 +1,65 @@
+package ai
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+	"go.uber.org/zap"
+)
+
+// ttsCachePrefix namespaces rendered audio in Redis; bump the version if the stored format changes
+const ttsCachePrefix = "tts:pcm16k:v1:"
+
+// TTSCache stores rendered 16kHz PCM16 in Redis, addressed by a hash of everything that shapes the audio
+type TTSCache struct {
+	client *redis.Client
+	ttl    time.Duration
+	logger *zap.Logger
+}
+
+// NewTTSCache creates a Redis-backed TTS cache; a zero ttl keeps entries until Redis evicts them
+func NewTTSCache(client *redis.Client, ttl time.Duration, logger *zap.Logger) *TTSCache {
+	return &TTSCache{
+		client: client,
+		ttl:    ttl,
+		logger: logger,
+	}
+}
+
+// TTSCacheKey returns the content address for text rendered by provider with the given voice, model and speed
+func TTSCacheKey(provider, voice, model string, speed float64, text string) string {
+	h := sha256.New()
+	for _, part := range []string{provider, voice, model, strconv.FormatFloat(speed, 'f', -1, 64), text} {
+		// Length-prefix each part so field boundaries cannot be forged by the text
+		fmt.Fprintf(h, "%d:%s|", len(part), part)
+	}
+	return ttsCachePrefix + hex.EncodeToString(h.Sum(nil))
+}
+
+// Get returns the cached audio for key; Redis errors count as a miss
+func (c *TTSCache) Get(ctx context.Context, key string) ([]byte, bool) {
+	pcm, err := c.client.Get(ctx, key).Bytes()
+	if err != nil {
+		if err != redis.Nil {
+			c.logger.Debug("TTS cache read failed", zap.Error(err))
+		}
+		return nil, false
+	}
+	return pcm, len(pcm) > 0
+}
+
+// Put stores rendered audio under key
+func (c *TTSCache) Put(ctx context.Context, key string, pcm []byte) error {
+	if len(pcm) == 0 {
+		return nil
+	}
+	if err := c.client.Set(ctx, key, pcm, c.ttl).Err(); err != nil {
+		return fmt.Errorf("failed to cache TTS audio: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,31 @@
+package ai
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestTTSCacheKey(t *testing.T) {
+	base := TTSCacheKey("openai", "shimmer", "tts-1-hd", 1.0, "Hello! How can I help you today?")
+	if !strings.HasPrefix(base, ttsCachePrefix) {
+		t.Errorf("key %q is missing prefix %q", base, ttsCachePrefix)
+	}
+	if again := TTSCacheKey("openai", "shimmer", "tts-1-hd", 1.0, "Hello! How can I help you today?"); again != base {
+		t.Errorf("key is not deterministic: %q != %q", again, base)
+	}
+
+	variants := []string{
+		TTSCacheKey("elevenlabs", "shimmer", "tts-1-hd", 1.0, "Hello! How can I help you today?"),
+		TTSCacheKey("openai", "nova", "tts-1-hd", 1.0, "Hello! How can I help you today?"),
+		TTSCacheKey("openai", "shimmer", "tts-1", 1.0, "Hello! How can I help you today?"),
+		TTSCacheKey("openai", "shimmer", "tts-1-hd", 1.25, "Hello! How can I help you today?"),
+		TTSCacheKey("openai", "shimmer", "tts-1-hd", 1.0, "Hello!"),
+		// Shifting text between fields must not collide
+		TTSCacheKey("openai", "shimmer|", "tts-1-hd", 1.0, "Hello! How can I help you today?"),
+	}
+	for i, key := range variants {
+		if key == base {
+			t.Errorf("variant %d collides with the base key", i)
+		}
+	}
+}
@@ -0,0 +1,219 @@
+package ai
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"strings"
+
+	"go.uber.org/zap"
+
+	"github.com/troikatech/calling-agent/pkg/audio"
+)
+
+// SpeechRequest is a provider-neutral speech synthesis request
+type SpeechRequest struct {
+	Text  string
+	Voice string  // Provider-specific voice name or ID; empty uses the provider default
+	Model string  // Provider-specific model; empty uses the provider default
+	Speed float64 // 0 uses the provider default
+}
+
+// Speech is synthesized mono PCM16 (little-endian) audio
+type Speech struct {
+	PCM        []byte
+	SampleRate int
+	Provider   string
+}
+
+// TTSProvider synthesizes speech as raw PCM16 at a declared sample rate
+type TTSProvider interface {
+	Synthesize(ctx context.Context, req *SpeechRequest) (*Speech, error)
+	IsAvailable() bool
+	Name() string
+}
+
+// Name returns the provider name
+func (s *OpenAITTSService) Name() string {
+	return "openai"
+}
+
+// Synthesize implements TTSProvider; OpenAI returns 24kHz PCM16
+func (s *OpenAITTSService) Synthesize(ctx context.Context, req *SpeechRequest) (*Speech, error) {
+	pcm, err := s.TextToSpeechPCM(ctx, &OpenAITTSRequest{
+		Text:   req.Text,
+		Model:  req.Model,
+		Voice:  req.Voice,
+		Format: "pcm",
+		Speed:  req.Speed,
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &Speech{PCM: pcm, SampleRate: 24000, Provider: s.Name()}, nil
+}
+
+// Name returns the provider name
+func (s *TTSService) Name() string {
+	return "elevenlabs"
+}
+
+// Synthesize implements TTSProvider; ElevenLabs is asked for 16kHz PCM16
+func (s *TTSService) Synthesize(ctx context.Context, req *SpeechRequest) (*Speech, error) {
+	data, err := s.TextToSpeech(ctx, &TTSRequest{
+		Text:         req.Text,
+		VoiceID:      req.Voice,
+		ModelID:      req.Model,
+		OutputFormat: "pcm_16000",
+	})
+	if err != nil {
+		return nil, err
+	}
+	if len(data) == 0 {
+		return nil, fmt.Errorf("no audio data received")
+	}
+	pcm, sampleRate, err := speechPCM(data, 16000)
+	if err != nil {
+		return nil, err
+	}
+	return &Speech{PCM: pcm, SampleRate: sampleRate, Provider: s.Name()}, nil
+}
+
+// speechPCM decodes MP3 or WAV a provider returned in place of raw PCM (e.g. when a plan does not
+// allow PCM output); raw PCM is passed through at rawRate
+func speechPCM(data []byte, rawRate int) ([]byte, int, error) {
+	if audio.DetectContainer(data) == "" {
+		return data, rawRate, nil
+	}
+	pcm, sampleRate, err := audio.DecodeAudio(data)
+	if err != nil {
+		return nil, 0, fmt.Errorf("failed to decode provider audio: %w", err)
+	}
+	return pcm, sampleRate, nil
+}
+
+// FakeTTSProvider returns canned PCM, or a sine tone sized to the text, without calling any API
+type FakeTTSProvider struct {
+	sampleRate int
+	pcm        []byte
+}
+
+// NewFakeTTSProvider creates a fake provider. With nil pcm it synthesizes a 440Hz tone
+// of about 60ms per character of text at sampleRate.
+func NewFakeTTSProvider(sampleRate int, pcm []byte) *FakeTTSProvider {
+	if sampleRate <= 0 {
+		sampleRate = 16000
+	}
+	return &FakeTTSProvider{sampleRate: sampleRate, pcm: pcm}
+}
+
+// IsAvailable always returns true
+func (f *FakeTTSProvider) IsAvailable() bool {
+	return true
+}
+
+// Name returns the provider name
+func (f *FakeTTSProvider) Name() string {
+	return "fake"
+}
+
+// Synthesize returns the canned PCM or a tone
+func (f *FakeTTSProvider) Synthesize(ctx context.Context, req *SpeechRequest) (*Speech, error) {
+	if req.Text == "" {
+		return nil, fmt.Errorf("text cannot be empty")
+	}
+	if f.pcm != nil {
+		return &Speech{PCM: append([]byte(nil), f.pcm...), SampleRate: f.sampleRate, Provider: f.Name()}, nil
+	}
+
+	samples := f.sampleRate * 60 * len([]rune(req.Text)) / 1000
+	pcm := make([]byte, samples*2)
+	for i := 0; i < samples; i++ {
+		s := int16(4000 * math.Sin(2*math.Pi*440*float64(i)/float64(f.sampleRate)))
+		pcm[i*2] = byte(s)
+		pcm[i*2+1] = byte(s >> 8)
+	}
+	return &Speech{PCM: pcm, SampleRate: f.sampleRate, Provider: f.Name()}, nil
+}
+
+// TTSManager synthesizes speech with fallback across TTS providers
+type TTSManager struct {
+	providers []TTSProvider
+	logger    *zap.Logger
+}
+
+// NewTTSManager creates a TTS manager; providers are tried in order
+func NewTTSManager(providers []TTSProvider, logger *zap.Logger) *TTSManager {
+	return &TTSManager{
+		providers: providers,
+		logger:    logger,
+	}
+}
+
+// Providers returns the names of the available providers in fallback order
+func (m *TTSManager) Providers() []string {
+	var names []string
+	for _, provider := range m.providers {
+		if provider.IsAvailable() {
+			names = append(names, provider.Name())
+		}
+	}
+	return names
+}
+
+// IsAvailable reports whether any provider can synthesize speech
+func (m *TTSManager) IsAvailable() bool {
+	return len(m.Providers()) > 0
+}
+
+// Synthesize tries the preferred provider first (with the requested voice and model), then the
+// others in order. Voices and models are provider-specific, so fallbacks use their own defaults.
+func (m *TTSManager) Synthesize(ctx context.Context, req *SpeechRequest, preferred string) (*Speech, error) {
+	ordered := make([]TTSProvider, 0, len(m.providers))
+	for _, provider := range m.providers {
+		if strings.EqualFold(provider.Name(), preferred) {
+			ordered = append([]TTSProvider{provider}, ordered...)
+		} else {
+			ordered = append(ordered, provider)
+		}
+	}
+	// Without a preference the request's voice belongs to the first provider
+	if preferred == "" && len(ordered) > 0 {
+		preferred = ordered[0].Name()
+	}
+
+	var lastErr error
+	for _, provider := range ordered {
+		if !provider.IsAvailable() {
+			continue
+		}
+		if ctx.Err() != nil {
+			break
+		}
+
+		attempt := &SpeechRequest{Text: req.Text, Speed: req.Speed}
+		if strings.EqualFold(provider.Name(), preferred) {
+			attempt.Voice = req.Voice
+			attempt.Model = req.Model
+		}
+
+		speech, err := provider.Synthesize(ctx, attempt)
+		if err == nil {
+			return speech, nil
+		}
+
+		lastErr = err
+		m.logger.Warn("TTS provider failed, trying next",
+			zap.String("provider", provider.Name()),
+			zap.Error(err),
+		)
+	}
+
+	if lastErr == nil {
+		if ctx.Err() != nil {
+			return nil, ctx.Err()
+		}
+		return nil, fmt.Errorf("no TTS providers available")
+	}
+	return nil, fmt.Errorf("all TTS providers failed. Last error: %w", lastErr)
+}
@@ -0,0 +1,104 @@
+package ai
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"go.uber.org/zap"
+)
+
+// recordingTTSProvider wraps the fake provider and records what it was asked for
+type recordingTTSProvider struct {
+	*FakeTTSProvider
+	name      string
+	available bool
+	shouldErr bool
+	got       []*SpeechRequest
+}
+
+func (r *recordingTTSProvider) Name() string      { return r.name }
+func (r *recordingTTSProvider) IsAvailable() bool { return r.available }
+
+func (r *recordingTTSProvider) Synthesize(ctx context.Context, req *SpeechRequest) (*Speech, error) {
+	r.got = append(r.got, req)
+	if r.shouldErr {
+		return nil, errors.New("mock error")
+	}
+	speech, err := r.FakeTTSProvider.Synthesize(ctx, req)
+	if err != nil {
+		return nil, err
+	}
+	speech.Provider = r.name
+	return speech, nil
+}
+
+func TestFakeTTSProvider_Tone(t *testing.T) {
+	speech, err := NewFakeTTSProvider(8000, nil).Synthesize(context.Background(), &SpeechRequest{Text: "hello"})
+	if err != nil {
+		t.Fatalf("Synthesize() error = %v", err)
+	}
+	if speech.SampleRate != 8000 || speech.Provider != "fake" {
+		t.Errorf("speech = %d Hz from %q", speech.SampleRate, speech.Provider)
+	}
+	// 5 characters at 60ms each, 2 bytes per sample
+	if want := 8000 * 300 / 1000 * 2; len(speech.PCM) != want {
+		t.Errorf("len(PCM) = %d, want %d", len(speech.PCM), want)
+	}
+}
+
+func TestTTSManager_PreferredProviderGetsVoice(t *testing.T) {
+	openai := &recordingTTSProvider{FakeTTSProvider: NewFakeTTSProvider(24000, []byte{1, 2}), name: "openai", available: true}
+	eleven := &recordingTTSProvider{FakeTTSProvider: NewFakeTTSProvider(16000, []byte{3, 4}), name: "elevenlabs", available: true}
+	manager := NewTTSManager([]TTSProvider{openai, eleven}, zap.NewNop())
+
+	speech, err := manager.Synthesize(context.Background(), &SpeechRequest{Text: "hi", Voice: "rachel"}, "elevenlabs")
+	if err != nil {
+		t.Fatalf("Synthesize() error = %v", err)
+	}
+	if speech.Provider != "elevenlabs" || speech.SampleRate != 16000 {
+		t.Errorf("speech from %q at %d Hz", speech.Provider, speech.SampleRate)
+	}
+	if len(openai.got) != 0 {
+		t.Errorf("openai called %d times", len(openai.got))
+	}
+	if eleven.got[0].Voice != "rachel" {
+		t.Errorf("voice = %q, want rachel", eleven.got[0].Voice)
+	}
+}
+
+func TestTTSManager_FallbackUsesDefaultVoice(t *testing.T) {
+	openai := &recordingTTSProvider{FakeTTSProvider: NewFakeTTSProvider(24000, nil), name: "openai", available: true, shouldErr: true}
+	offline := &recordingTTSProvider{FakeTTSProvider: NewFakeTTSProvider(16000, nil), name: "offline", available: false}
+	eleven := &recordingTTSProvider{FakeTTSProvider: NewFakeTTSProvider(16000, nil), name: "elevenlabs", available: true}
+	manager := NewTTSManager([]TTSProvider{openai, offline, eleven}, zap.NewNop())
+
+	speech, err := manager.Synthesize(context.Background(), &SpeechRequest{Text: "hi", Voice: "shimmer"}, "")
+	if err != nil {
+		t.Fatalf("Synthesize() error = %v", err)
+	}
+	if speech.Provider != "elevenlabs" {
+		t.Errorf("provider = %q, want elevenlabs", speech.Provider)
+	}
+	if openai.got[0].Voice != "shimmer" {
+		t.Errorf("first provider voice = %q, want shimmer", openai.got[0].Voice)
+	}
+	if len(offline.got) != 0 {
+		t.Error("unavailable provider should be skipped")
+	}
+	if eleven.got[0].Voice != "" {
+		t.Errorf("fallback voice = %q, want provider default", eleven.got[0].Voice)
+	}
+}
+
+func TestTTSManager_AllFail(t *testing.T) {
+	failing := &recordingTTSProvider{FakeTTSProvider: NewFakeTTSProvider(16000, nil), name: "openai", available: true, shouldErr: true}
+	manager := NewTTSManager([]TTSProvider{failing}, zap.NewNop())
+
+	if _, err := manager.Synthesize(context.Background(), &SpeechRequest{Text: "hi"}, ""); err == nil {
+		t.Error("expected error when every provider fails")
+	}
+	if _, err := NewTTSManager(nil, zap.NewNop()).Synthesize(context.Background(), &SpeechRequest{Text: "hi"}, ""); err == nil {
+		t.Error("expected error with no providers")
+	}
+}
@@ -0,0 +1,74 @@
+package audio
+
+// DecodeALawToPCM16 converts G.711 A-law (8-bit) to 16-bit signed little-endian PCM
+func DecodeALawToPCM16(aLaw []byte) []byte {
+	if len(aLaw) == 0 {
+		return nil
+	}
+
+	result := make([]byte, len(aLaw)*2)
+	for i, a := range aLaw {
+		sample := aLawToLinear(a)
+		result[i*2] = byte(sample)
+		result[i*2+1] = byte(sample >> 8)
+	}
+	return result
+}
+
+// EncodePCM16ToALaw converts 16-bit signed little-endian PCM to G.711 A-law (8-bit)
+func EncodePCM16ToALaw(pcm []byte) []byte {
+	if len(pcm) < 2 {
+		return nil
+	}
+
+	result := make([]byte, len(pcm)/2)
+	for i := range result {
+		result[i] = linearToALaw(int16(pcm[i*2]) | int16(pcm[i*2+1])<<8)
+	}
+	return result
+}
+
+// aLawToLinear expands one A-law byte
+func aLawToLinear(a byte) int16 {
+	// A-law bytes are transmitted with the even bits inverted
+	a ^= 0x55
+	exponent := (a >> 4) & 0x07
+	mantissa := int(a & 0x0F)
+
+	magnitude := mantissa<<4 + 8
+	if exponent > 0 {
+		magnitude = (magnitude + 0x100) << (exponent - 1)
+	}
+	if a&0x80 != 0 {
+		return int16(magnitude)
+	}
+	return int16(-magnitude)
+}
+
+// linearToALaw compresses one PCM16 sample
+func linearToALaw(sample int16) byte {
+	magnitude := int(sample)
+	sign := byte(0x80)
+	if magnitude < 0 {
+		// -1 keeps the negative range symmetric with the positive one
+		magnitude = -magnitude - 1
+		sign = 0
+	}
+	if magnitude > 0x7FFF {
+		magnitude = 0x7FFF
+	}
+
+	var encoded byte
+	if magnitude < 0x100 {
+		encoded = byte(magnitude >> 4)
+	} else {
+		// The exponent is the position of the highest set bit above bit 7
+		exponent := byte(1)
+		for m := magnitude >> 9; m > 0 && exponent < 7; m >>= 1 {
+			exponent++
+		}
+		encoded = exponent<<4 | byte(magnitude>>(exponent+3))&0x0F
+	}
+
+	return (sign | encoded) ^ 0x55
+}
@@ -0,0 +1,334 @@
+package audio
+
+import "math"
+
+// AMDEvent is emitted by AMD.Process as answering-machine detection progresses
+type AMDEvent int
+
+const (
+	// AMDHuman fires when the answer looks like a person: a short greeting followed by silence
+	AMDHuman AMDEvent = iota + 1
+	// AMDMachine fires when the answer looks like a recording: a long greeting, many words or a beep
+	AMDMachine
+	// AMDNotSure fires when analysis ran out of time (or the line stayed silent) without a clear answer
+	AMDNotSure
+	// AMDBeep fires when a record tone is heard; a voicemail message should start right after it
+	AMDBeep
+	// AMDGreetingEnded fires after a machine greeting is followed by EndOfGreetingSilenceMs of silence,
+	// for voicemail systems that record without a beep
+	AMDGreetingEnded
+)
+
+// AMDConfig holds answering-machine detection thresholds.
+// Defaults follow the classic Asterisk AMD() heuristics.
+type AMDConfig struct {
+	FrameMs                int     // Analysis frame length in ms (default 20)
+	EnergyThreshold        float64 // Minimum frame RMS (PCM16 scale) to count as voice
+	InitialSilenceMs       int     // Silence before any voice after which the result is AMDNotSure
+	GreetingMs             int     // Voice longer than this before a pause means a machine greeting
+	AfterGreetingSilenceMs int     // Silence after a short greeting that means a person is waiting for a reply
+	MinWordMs              int     // Voice runs shorter than this are not counted as words
+	BetweenWordsSilenceMs  int     // Silence that separates two words
+	MaxWords               int     // More words than this before a pause means a machine
+	TotalAnalysisMs        int     // Give up (AMDNotSure) after this much audio
+	EndOfGreetingSilenceMs int     // Silence that ends a machine greeting when no beep is heard
+	BeepMinMs              int     // A steady tone at least this long is a beep
+	BeepMinHz              float64 // Lowest beep frequency considered
+	BeepMaxHz              float64 // Highest beep frequency considered
+	BeepPurity             float64 // Fraction of frame energy that must sit at the tone frequency (0-1)
+}
+
+// DefaultAMDConfig returns thresholds tuned for 8kHz telephony audio upsampled to 16kHz
+func DefaultAMDConfig() AMDConfig {
+	return AMDConfig{
+		FrameMs:                20,
+		EnergyThreshold:        400,
+		InitialSilenceMs:       2500,
+		GreetingMs:             1500,
+		AfterGreetingSilenceMs: 800,
+		MinWordMs:              100,
+		BetweenWordsSilenceMs:  50,
+		MaxWords:               4,
+		TotalAnalysisMs:        5000,
+		EndOfGreetingSilenceMs: 1500,
+		BeepMinMs:              160,
+		BeepMinHz:              300,
+		BeepMaxHz:              2500,
+		BeepPurity:             0.6,
+	}
+}
+
+// AMD classifies the start of an answered outbound call as a person or an answering machine
+// using voice timing and record-tone detection on PCM16 mono audio.
+// After a machine decision it keeps listening for the beep or the end of the greeting.
+// It is not safe for concurrent use.
+type AMD struct {
+	cfg        AMDConfig
+	sampleRate int
+	frameBytes int
+	pending    []byte
+
+	result    AMDEvent // Decision so far (0 while undecided)
+	reason    string
+	elapsedMs int
+
+	heardVoice bool
+	inWord     bool
+	voiceMs    int // Voice in the current greeting
+	wordRunMs  int // Length of the current voice run
+	silenceMs  int // Length of the current silence run
+	words      int
+
+	toneMs        int     // Length of the current steady-tone run
+	toneHz        float64 // Frequency the current tone run started at
+	beepHeard     bool
+	greetingEnded bool
+}
+
+// NewAMD creates a detector for PCM16 audio at the given sample rate.
+// Zero fields in cfg fall back to DefaultAMDConfig values.
+func NewAMD(cfg AMDConfig, sampleRate int) *AMD {
+	def := DefaultAMDConfig()
+	if cfg.FrameMs <= 0 {
+		cfg.FrameMs = def.FrameMs
+	}
+	if cfg.EnergyThreshold <= 0 {
+		cfg.EnergyThreshold = def.EnergyThreshold
+	}
+	if cfg.InitialSilenceMs <= 0 {
+		cfg.InitialSilenceMs = def.InitialSilenceMs
+	}
+	if cfg.GreetingMs <= 0 {
+		cfg.GreetingMs = def.GreetingMs
+	}
+	if cfg.AfterGreetingSilenceMs <= 0 {
+		cfg.AfterGreetingSilenceMs = def.AfterGreetingSilenceMs
+	}
+	if cfg.MinWordMs <= 0 {
+		cfg.MinWordMs = def.MinWordMs
+	}
+	if cfg.BetweenWordsSilenceMs <= 0 {
+		cfg.BetweenWordsSilenceMs = def.BetweenWordsSilenceMs
+	}
+	if cfg.MaxWords <= 0 {
+		cfg.MaxWords = def.MaxWords
+	}
+	if cfg.TotalAnalysisMs <= 0 {
+		cfg.TotalAnalysisMs = def.TotalAnalysisMs
+	}
+	if cfg.EndOfGreetingSilenceMs <= 0 {
+		cfg.EndOfGreetingSilenceMs = def.EndOfGreetingSilenceMs
+	}
+	if cfg.BeepMinMs <= 0 {
+		cfg.BeepMinMs = def.BeepMinMs
+	}
+	if cfg.BeepMinHz <= 0 {
+		cfg.BeepMinHz = def.BeepMinHz
+	}
+	if cfg.BeepMaxHz <= 0 {
+		cfg.BeepMaxHz = def.BeepMaxHz
+	}
+	if cfg.BeepPurity <= 0 {
+		cfg.BeepPurity = def.BeepPurity
+	}
+	if sampleRate <= 0 {
+		sampleRate = 16000
+	}
+
+	return &AMD{
+		cfg:        cfg,
+		sampleRate: sampleRate,
+		frameBytes: sampleRate * cfg.FrameMs / 1000 * 2,
+	}
+}
+
+// Config returns the effective thresholds
+func (a *AMD) Config() AMDConfig {
+	return a.cfg
+}
+
+// Result returns the decision (AMDHuman, AMDMachine or AMDNotSure), or 0 while undecided
+func (a *AMD) Result() AMDEvent {
+	return a.result
+}
+
+// Reason describes which heuristic made the decision, e.g. "long_greeting" or "beep"
+func (a *AMD) Reason() string {
+	return a.reason
+}
+
+// Process feeds PCM16 audio of any length and returns the events it caused, in order
+func (a *AMD) Process(pcm []byte) []AMDEvent {
+	var events []AMDEvent
+
+	a.pending = append(a.pending, pcm...)
+	for len(a.pending) >= a.frameBytes {
+		events = append(events, a.processFrame(a.pending[:a.frameBytes])...)
+		a.pending = a.pending[a.frameBytes:]
+	}
+
+	// Keep the remainder without holding on to the caller's backing arrays
+	if len(a.pending) > 0 {
+		a.pending = append([]byte(nil), a.pending...)
+	} else {
+		a.pending = a.pending[:0]
+	}
+
+	return events
+}
+
+// processFrame classifies a single frame and advances detection
+func (a *AMD) processFrame(frame []byte) []AMDEvent {
+	var events []AMDEvent
+	frameMs := a.cfg.FrameMs
+	a.elapsedMs += frameMs
+
+	voiced, tone, toneHz := a.analyse(frame)
+
+	// A record tone decides the call on its own and cues the voicemail message
+	if a.trackTone(tone, toneHz) && !a.beepHeard {
+		a.beepHeard = true
+		if a.result == 0 {
+			events = append(events, a.decide(AMDMachine, "beep"))
+		}
+		events = append(events, AMDBeep)
+		return events
+	}
+	if tone {
+		// Tone frames are not voice; do not let a beep count as a long greeting
+		voiced = false
+	}
+
+	if voiced {
+		a.heardVoice = true
+		a.silenceMs = 0
+		a.wordRunMs += frameMs
+		a.voiceMs += frameMs
+		if !a.inWord && a.wordRunMs >= a.cfg.MinWordMs {
+			a.inWord = true
+			a.words++
+		}
+	} else {
+		a.silenceMs += frameMs
+		if a.silenceMs >= a.cfg.BetweenWordsSilenceMs {
+			a.inWord = false
+			a.wordRunMs = 0
+		}
+	}
+
+	if a.result == AMDMachine {
+		if !a.greetingEnded && !a.beepHeard && a.heardVoice && a.silenceMs >= a.cfg.EndOfGreetingSilenceMs {
+			a.greetingEnded = true
+			events = append(events, AMDGreetingEnded)
+		}
+		return events
+	}
+	if a.result != 0 {
+		return events
+	}
+
+	switch {
+	case voiced && a.voiceMs >= a.cfg.GreetingMs:
+		events = append(events, a.decide(AMDMachine, "long_greeting"))
+	case voiced && a.words > a.cfg.MaxWords:
+		events = append(events, a.decide(AMDMachine, "max_words"))
+	case !a.heardVoice && a.silenceMs >= a.cfg.InitialSilenceMs:
+		events = append(events, a.decide(AMDNotSure, "initial_silence"))
+	case a.heardVoice && a.silenceMs >= a.cfg.AfterGreetingSilenceMs:
+		events = append(events, a.decide(AMDHuman, "short_greeting"))
+	case a.elapsedMs >= a.cfg.TotalAnalysisMs:
+		events = append(events, a.decide(AMDNotSure, "max_analysis_time"))
+	}
+	return events
+}
+
+// Decide records a decision made outside the audio heuristics (e.g. voicemail phrases in the transcript).
+// It returns false if a decision had already been made.
+func (a *AMD) Decide(result AMDEvent, reason string) bool {
+	if a.result != 0 {
+		return false
+	}
+	a.decide(result, reason)
+	return true
+}
+
+// decide records the decision and returns it as an event
+func (a *AMD) decide(result AMDEvent, reason string) AMDEvent {
+	a.result = result
+	a.reason = reason
+	if result == AMDMachine {
+		// Wait for the greeting to finish from here on
+		a.silenceMs = 0
+	}
+	return result
+}
+
+// trackTone extends or restarts the steady-tone run and reports whether it is now long enough to be a beep
+func (a *AMD) trackTone(tone bool, hz float64) bool {
+	if !tone {
+		a.toneMs = 0
+		return false
+	}
+	// A beep holds its pitch; speech formants wander
+	if a.toneMs == 0 || math.Abs(hz-a.toneHz) > a.toneHz*0.06 {
+		a.toneMs = a.cfg.FrameMs
+		a.toneHz = hz
+		return false
+	}
+	a.toneMs += a.cfg.FrameMs
+	return a.toneMs >= a.cfg.BeepMinMs
+}
+
+// analyse returns whether a frame is voiced and whether it is a pure tone (and its frequency).
+// The tone frequency is estimated from zero crossings and confirmed with a Goertzel filter.
+func (a *AMD) analyse(frame []byte) (bool, bool, float64) {
+	samples := len(frame) / 2
+	if samples == 0 {
+		return false, false, 0
+	}
+
+	x := make([]float64, samples)
+	var sumSquares float64
+	crossings := 0
+	for i := 0; i < samples; i++ {
+		x[i] = float64(int16(frame[i*2]) | int16(frame[i*2+1])<<8)
+		sumSquares += x[i] * x[i]
+		if i > 0 && (x[i] >= 0) != (x[i-1] >= 0) {
+			crossings++
+		}
+	}
+
+	rms := math.Sqrt(sumSquares / float64(samples))
+	if rms < a.cfg.EnergyThreshold {
+		return false, false, 0
+	}
+
+	hz := float64(crossings) * float64(a.sampleRate) / float64(2*samples)
+	if hz < a.cfg.BeepMinHz || hz > a.cfg.BeepMaxHz {
+		return true, false, 0
+	}
+
+	// Zero crossings only resolve the pitch to half a crossing per frame, so try both neighbours
+	step := float64(a.sampleRate) / float64(2*samples)
+	best, bestHz := 0.0, hz
+	for _, f := range []float64{hz - step/2, hz, hz + step/2} {
+		if p := goertzelPower(x, f, a.sampleRate); p > best {
+			best, bestHz = p, f
+		}
+	}
+	// A pure sine of amplitude A over N samples gives power (A*N/2)^2 and energy A^2*N/2
+	purity := best * 2 / (float64(samples) * sumSquares)
+	return true, purity >= a.cfg.BeepPurity, bestHz
+}
+
+// goertzelPower returns the signal power at frequency hz
+func goertzelPower(x []float64, hz float64, sampleRate int) float64 {
+	coeff := 2 * math.Cos(2*math.Pi*hz/float64(sampleRate))
+	var s1, s2 float64
+	for _, v := range x {
+		s0 := v + coeff*s1 - s2
+		s2 = s1
+		s1 = s0
+	}
+	return s1*s1 + s2*s2 - coeff*s1*s2
+}
@@ -0,0 +1,127 @@
+package audio
+
+import (
+	"math"
+	"math/rand"
+	"testing"
+)
+
+// tone returns ms of a PCM16 sine at hz
+func tone(hz float64, ms int, amplitude float64) []byte {
+	n := 16000 * ms / 1000
+	out := make([]byte, n*2)
+	for i := 0; i < n; i++ {
+		s := int16(amplitude * math.Sin(2*math.Pi*hz*float64(i)/16000))
+		out[i*2] = byte(s)
+		out[i*2+1] = byte(s >> 8)
+	}
+	return out
+}
+
+// voice returns ms of loud noise with a wandering pitch, standing in for speech
+func voice(ms int, rng *rand.Rand) []byte {
+	n := 16000 * ms / 1000
+	out := make([]byte, n*2)
+	phase := 0.0
+	for i := 0; i < n; i++ {
+		hz := 150 + 100*math.Sin(float64(i)/800)
+		phase += 2 * math.Pi * hz / 16000
+		s := int16(3000*math.Sin(phase) + 2000*math.Sin(3.1*phase) + rng.NormFloat64()*1500)
+		out[i*2] = byte(s)
+		out[i*2+1] = byte(s >> 8)
+	}
+	return out
+}
+
+func silence(ms int) []byte {
+	return make([]byte, 16000*ms/1000*2)
+}
+
+func feed(amd *AMD, chunks ...[]byte) []AMDEvent {
+	var events []AMDEvent
+	for _, c := range chunks {
+		// Exotel-sized pieces
+		for len(c) > 0 {
+			n := 640
+			if n > len(c) {
+				n = len(c)
+			}
+			events = append(events, amd.Process(c[:n])...)
+			c = c[n:]
+		}
+	}
+	return events
+}
+
+func TestAMD_ShortGreetingIsHuman(t *testing.T) {
+	rng := rand.New(rand.NewSource(1))
+	amd := NewAMD(AMDConfig{}, 16000)
+
+	events := feed(amd, silence(300), voice(600, rng), silence(1000))
+	if len(events) != 1 || events[0] != AMDHuman {
+		t.Fatalf("events = %v, want [AMDHuman]", events)
+	}
+	if amd.Reason() != "short_greeting" {
+		t.Errorf("reason = %q", amd.Reason())
+	}
+}
+
+func TestAMD_LongGreetingIsMachine(t *testing.T) {
+	rng := rand.New(rand.NewSource(2))
+	amd := NewAMD(AMDConfig{}, 16000)
+
+	events := feed(amd, silence(200), voice(2500, rng), silence(2000))
+	want := []AMDEvent{AMDMachine, AMDGreetingEnded}
+	if len(events) != len(want) || events[0] != want[0] || events[1] != want[1] {
+		t.Fatalf("events = %v, want %v", events, want)
+	}
+	if amd.Reason() != "long_greeting" {
+		t.Errorf("reason = %q", amd.Reason())
+	}
+}
+
+func TestAMD_BeepAfterGreeting(t *testing.T) {
+	rng := rand.New(rand.NewSource(3))
+	amd := NewAMD(AMDConfig{}, 16000)
+
+	events := feed(amd, voice(2000, rng), silence(300), tone(1000, 400, 8000), silence(500))
+	want := []AMDEvent{AMDMachine, AMDBeep}
+	if len(events) != len(want) || events[0] != want[0] || events[1] != want[1] {
+		t.Fatalf("events = %v, want %v", events, want)
+	}
+}
+
+func TestAMD_BeepAloneIsMachine(t *testing.T) {
+	amd := NewAMD(AMDConfig{}, 16000)
+
+	events := feed(amd, silence(200), tone(1400, 300, 6000))
+	want := []AMDEvent{AMDMachine, AMDBeep}
+	if len(events) != len(want) || events[0] != want[0] || events[1] != want[1] {
+		t.Fatalf("events = %v, want %v", events, want)
+	}
+	if amd.Reason() != "beep" {
+		t.Errorf("reason = %q", amd.Reason())
+	}
+}
+
+func TestAMD_SilenceIsNotSure(t *testing.T) {
+	amd := NewAMD(AMDConfig{}, 16000)
+
+	events := feed(amd, silence(3000))
+	if len(events) != 1 || events[0] != AMDNotSure {
+		t.Fatalf("events = %v, want [AMDNotSure]", events)
+	}
+}
+
+func TestAMD_DecideExternally(t *testing.T) {
+	amd := NewAMD(AMDConfig{}, 16000)
+	if !amd.Decide(AMDMachine, "keyword") {
+		t.Fatal("first Decide should succeed")
+	}
+	if amd.Decide(AMDHuman, "late") {
+		t.Error("second Decide should be ignored")
+	}
+	if amd.Result() != AMDMachine || amd.Reason() != "keyword" {
+		t.Errorf("result = %v/%q", amd.Result(), amd.Reason())
+	}
+}
@@ -0,0 +1,98 @@
+package audio
+
+import (
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Encoding identifies how samples are carried on a media stream
+type Encoding string
+
+// Stream encodings
+const (
+	EncodingPCM16 Encoding = "pcm16" // 16-bit signed little-endian linear PCM
+	EncodingMuLaw Encoding = "mulaw" // G.711 μ-law, 8 bits per sample
+	EncodingALaw  Encoding = "alaw"  // G.711 A-law, 8 bits per sample
+)
+
+// ParseEncoding maps a media format label to an Encoding
+// Accepts MIME types (audio/x-mulaw), codec names (PCMU, PCMA, slin, linear16) and
+// Exotel's "raw" label for linear PCM16
+func ParseEncoding(label string) (Encoding, bool) {
+	label = strings.ToLower(strings.TrimSpace(label))
+	label = strings.TrimPrefix(label, "audio/")
+	label = strings.TrimPrefix(label, "x-")
+
+	switch label {
+	case "mulaw", "ulaw", "pcmu", "g711u", "g711_ulaw":
+		return EncodingMuLaw, true
+	case "alaw", "pcma", "g711a", "g711_alaw":
+		return EncodingALaw, true
+	case "pcm", "pcm16", "l16", "raw", "slin", "slin16", "linear16":
+		return EncodingPCM16, true
+	}
+	return "", false
+}
+
+// Format is the negotiated encoding and sample rate of a media stream
+type Format struct {
+	Encoding   Encoding
+	SampleRate int
+}
+
+// DefaultStreamFormat is telephony-standard 8kHz μ-law, used when the stream does not say otherwise
+var DefaultStreamFormat = Format{Encoding: EncodingMuLaw, SampleRate: 8000}
+
+// String returns e.g. "mulaw/8000"
+func (f Format) String() string {
+	return string(f.Encoding) + "/" + strconv.Itoa(f.SampleRate)
+}
+
+// BytesPerSample returns the encoded size of one sample
+func (f Format) BytesPerSample() int {
+	if f.Encoding == EncodingPCM16 {
+		return 2
+	}
+	return 1
+}
+
+// FrameBytes returns the encoded size of d of audio, rounded down to whole samples
+func (f Format) FrameBytes(d time.Duration) int {
+	samples := int(time.Duration(f.SampleRate) * d / time.Second)
+	return samples * f.BytesPerSample()
+}
+
+// Duration returns the playback length of n encoded bytes
+func (f Format) Duration(n int) time.Duration {
+	if f.SampleRate == 0 {
+		return 0
+	}
+	samples := n / f.BytesPerSample()
+	return time.Duration(samples) * time.Second / time.Duration(f.SampleRate)
+}
+
+// Decode converts an encoded payload to PCM16 at the format's sample rate
+func (f Format) Decode(payload []byte) []byte {
+	switch f.Encoding {
+	case EncodingMuLaw:
+		return DecodeMuLawToPCM16(payload)
+	case EncodingALaw:
+		return DecodeALawToPCM16(payload)
+	default:
+		// Drop a trailing odd byte rather than misalign every following sample
+		return payload[:len(payload)&^1]
+	}
+}
+
+// Encode converts PCM16 at the format's sample rate to the encoded payload
+func (f Format) Encode(pcm []byte) []byte {
+	switch f.Encoding {
+	case EncodingMuLaw:
+		return EncodePCM16ToMuLaw(pcm)
+	case EncodingALaw:
+		return EncodePCM16ToALaw(pcm)
+	default:
+		return pcm[:len(pcm)&^1]
+	}
+}
@@ -0,0 +1,83 @@
+package audio
+
+import (
+	"testing"
+	"time"
+)
+
+func TestG711_RoundTrip(t *testing.T) {
+	for _, enc := range []Encoding{EncodingMuLaw, EncodingALaw} {
+		format := Format{Encoding: enc, SampleRate: 8000}
+
+		// Every code word decodes to a value that encodes back to itself
+		codes := make([]byte, 256)
+		for i := range codes {
+			codes[i] = byte(i)
+		}
+		pcm := format.Decode(codes)
+		again := format.Encode(pcm)
+		for i := range codes {
+			// μ-law has two encodings of zero (0x7F and 0xFF); both decode to 0
+			if again[i] != codes[i] && !(enc == EncodingMuLaw && codes[i] == 0x7F && again[i] == 0xFF) {
+				t.Errorf("%s: code %#02x re-encoded as %#02x", enc, codes[i], again[i])
+			}
+		}
+
+		// Companding error stays within a few percent of the signal
+		for _, sample := range []int16{0, 100, -100, 1000, -1000, 12345, -12345, 32767, -32768} {
+			in := []byte{byte(sample), byte(sample >> 8)}
+			out := format.Decode(format.Encode(in))
+			got := int16(out[0]) | int16(out[1])<<8
+			diff := int(got) - int(sample)
+			if diff < 0 {
+				diff = -diff
+			}
+			limit := int(sample) / 16
+			if limit < 0 {
+				limit = -limit
+			}
+			if diff > limit+16 {
+				t.Errorf("%s: %d decoded as %d", enc, sample, got)
+			}
+		}
+	}
+}
+
+func TestFormat_Framing(t *testing.T) {
+	tests := []struct {
+		format Format
+		frame  int
+	}{
+		{Format{EncodingMuLaw, 8000}, 160},
+		{Format{EncodingALaw, 8000}, 160},
+		{Format{EncodingPCM16, 8000}, 320},
+		{Format{EncodingPCM16, 16000}, 640},
+		{Format{EncodingPCM16, 24000}, 960},
+	}
+	for _, tt := range tests {
+		if got := tt.format.FrameBytes(20 * time.Millisecond); got != tt.frame {
+			t.Errorf("%s: FrameBytes(20ms) = %d, want %d", tt.format, got, tt.frame)
+		}
+		if got := tt.format.Duration(tt.frame); got != 20*time.Millisecond {
+			t.Errorf("%s: Duration(%d) = %v, want 20ms", tt.format, tt.frame, got)
+		}
+	}
+}
+
+func TestParseEncoding(t *testing.T) {
+	tests := map[string]Encoding{
+		"audio/x-mulaw": EncodingMuLaw,
+		"PCMU":          EncodingMuLaw,
+		"pcma":          EncodingALaw,
+		"raw":           EncodingPCM16,
+		"linear16":      EncodingPCM16,
+	}
+	for label, want := range tests {
+		if got, ok := ParseEncoding(label); !ok || got != want {
+			t.Errorf("ParseEncoding(%q) = %q, %v; want %q", label, got, ok, want)
+		}
+	}
+	if _, ok := ParseEncoding("opus"); ok {
+		t.Error("ParseEncoding(opus) should fail")
+	}
+}
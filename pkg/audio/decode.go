@@ -0,0 +1,62 @@
+package audio
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+
+	"github.com/troikatech/calling-agent/pkg/audio/mp3"
+)
+
+// ErrUnsupportedFormat is returned for audio that cannot be decoded in-process
+var ErrUnsupportedFormat = errors.New("unsupported audio format")
+
+// Container formats reported by DetectContainer
+const (
+	ContainerWAV = "wav"
+	ContainerMP3 = "mp3"
+	ContainerOgg = "ogg"
+)
+
+// DetectContainer sniffs compressed or containerised audio; raw PCM returns ""
+func DetectContainer(data []byte) string {
+	switch {
+	case len(data) >= 12 && string(data[0:4]) == "RIFF" && string(data[8:12]) == "WAVE":
+		return ContainerWAV
+	case len(data) >= 4 && string(data[0:4]) == "OggS":
+		return ContainerOgg
+	case mp3.IsMP3(data):
+		return ContainerMP3
+	}
+	return ""
+}
+
+// DecodeAudio decodes a WAV or MP3 file to mono PCM16 at its native sample rate
+func DecodeAudio(data []byte) ([]byte, int, error) {
+	switch DetectContainer(data) {
+	case ContainerWAV:
+		return DecodeWAV(data)
+	case ContainerMP3:
+		pcm, sampleRate, err := mp3.Decode(data)
+		if err != nil {
+			return nil, 0, fmt.Errorf("failed to decode MP3: %w", err)
+		}
+		return pcm, sampleRate, nil
+	case ContainerOgg:
+		// Opus needs a SILK/CELT decoder; ask providers for MP3, WAV or raw PCM instead
+		if bytes.Contains(data[:min(len(data), 64)], []byte("OpusHead")) {
+			return nil, 0, fmt.Errorf("%w: Ogg Opus", ErrUnsupportedFormat)
+		}
+		return nil, 0, fmt.Errorf("%w: Ogg", ErrUnsupportedFormat)
+	}
+	return nil, 0, fmt.Errorf("%w: unrecognised container", ErrUnsupportedFormat)
+}
+
+// DecodeToPCM16 decodes a WAV or MP3 file to mono PCM16 resampled to sampleRate
+func DecodeToPCM16(data []byte, sampleRate int) ([]byte, error) {
+	pcm, rate, err := DecodeAudio(data)
+	if err != nil {
+		return nil, err
+	}
+	return Resample(pcm, rate, sampleRate), nil
+}
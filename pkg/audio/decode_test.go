@@ -0,0 +1,107 @@
+package audio
+
+import (
+	"encoding/binary"
+	"errors"
+	"math"
+	"os"
+	"testing"
+)
+
+func TestDecodeWAV_Formats(t *testing.T) {
+	pcm := tone(1000, 100, 8000)
+
+	// PCMToWAV output decodes back unchanged
+	out, rate, err := DecodeWAV(PCMToWAV(pcm, 8000))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if rate != 8000 || string(out) != string(pcm) {
+		t.Fatalf("round trip changed audio: rate %d, %d bytes", rate, len(out))
+	}
+
+	// Stereo 8-bit μ-law with identical channels averages to the mono signal
+	mu := EncodePCM16ToMuLaw(pcm)
+	stereo := make([]byte, 0, 2*len(mu))
+	for _, b := range mu {
+		stereo = append(stereo, b, b)
+	}
+	wav := PCMToWAV(stereo, 8000) // Header fields are rewritten below
+	binary.LittleEndian.PutUint16(wav[20:22], wavFormatMuLaw)
+	binary.LittleEndian.PutUint16(wav[22:24], 2)
+	binary.LittleEndian.PutUint16(wav[32:34], 2)
+	binary.LittleEndian.PutUint16(wav[34:36], 8)
+	out, _, err = DecodeWAV(wav)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(out) != string(DecodeMuLawToPCM16(mu)) {
+		t.Error("stereo μ-law WAV did not decode to the mono signal")
+	}
+
+	// Unsupported codecs are reported as such
+	binary.LittleEndian.PutUint16(wav[20:22], 2) // MS ADPCM
+	if _, _, err := DecodeWAV(wav); !errors.Is(err, ErrUnsupportedFormat) {
+		t.Errorf("ADPCM: got %v, want ErrUnsupportedFormat", err)
+	}
+}
+
+func TestDecodeToPCM16_MP3(t *testing.T) {
+	// The two fixtures are the same recording encoded as MPEG-2 (22.05kHz) and MPEG-2.5 (8kHz)
+	decode := func(name string) []float64 {
+		data, err := os.ReadFile("mp3/testdata/" + name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if DetectContainer(data) != ContainerMP3 {
+			t.Fatalf("%s not detected as MP3", name)
+		}
+		pcm, err := DecodeToPCM16(data, 8000)
+		if err != nil {
+			t.Fatal(err)
+		}
+		samples := make([]float64, len(pcm)/2)
+		for i := range samples {
+			samples[i] = float64(int16(binary.LittleEndian.Uint16(pcm[2*i:])))
+		}
+		return samples
+	}
+	a := decode("mpeg2_22k_mono.mp3")
+	b := decode("mpeg25_8k_mono.mp3")
+
+	// Independently coded streams only agree if every decoding stage is right;
+	// the encoders' different delays are found by searching the lag
+	best := 0.0
+	for lag := 0; lag < 2000; lag++ {
+		var dot, ea, eb float64
+		for i := 4000; i < 30000 && i+lag < len(b) && i < len(a); i++ {
+			dot += a[i] * b[i+lag]
+			ea += a[i] * a[i]
+			eb += b[i+lag] * b[i+lag]
+		}
+		if c := dot / math.Sqrt(ea*eb); c > best {
+			best = c
+		}
+	}
+	if best < 0.95 {
+		t.Errorf("decodes correlate at %.3f, want >= 0.95", best)
+	}
+}
+
+func TestDetectContainer(t *testing.T) {
+	cases := map[string][]byte{
+		ContainerWAV: PCMToWAV(make([]byte, 320), 8000),
+		ContainerOgg: append([]byte("OggS\x00\x02"), make([]byte, 22)...),
+		"":           make([]byte, 320), // Raw PCM silence
+	}
+	for want, data := range cases {
+		if got := DetectContainer(data); got != want {
+			t.Errorf("DetectContainer = %q, want %q", got, want)
+		}
+	}
+
+	ogg := append([]byte("OggS\x00\x02"), []byte("....OpusHead")...)
+	if _, _, err := DecodeAudio(ogg); !errors.Is(err, ErrUnsupportedFormat) {
+		t.Errorf("Ogg Opus: got %v, want ErrUnsupportedFormat", err)
+	}
+}
@@ -0,0 +1,37 @@
+package mp3
+
+import "errors"
+
+// errBitstreamOverrun means a granule claimed more bits than the frame holds
+var errBitstreamOverrun = errors.New("bitstream overrun")
+
+// bitReader reads big-endian bit fields from a byte slice
+type bitReader struct {
+	data []byte
+	pos  int // Position in bits
+}
+
+// remaining returns the number of unread bits
+func (br *bitReader) remaining() int {
+	return len(br.data)*8 - br.pos
+}
+
+// readBit reads one bit; reading past the end returns zeros
+func (br *bitReader) readBit() int {
+	if br.pos >= len(br.data)*8 {
+		br.pos++
+		return 0
+	}
+	bit := int(br.data[br.pos>>3]>>(7-uint(br.pos&7))) & 1
+	br.pos++
+	return bit
+}
+
+// readBits reads an n-bit unsigned field (n <= 32)
+func (br *bitReader) readBits(n int) int {
+	v := 0
+	for i := 0; i < n; i++ {
+		v = v<<1 | br.readBit()
+	}
+	return v
+}
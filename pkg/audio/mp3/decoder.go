@@ -0,0 +1,212 @@
+// Package mp3 decodes MPEG-1, MPEG-2 and MPEG-2.5 Layer III audio without external tools
+package mp3
+
+import (
+	"encoding/binary"
+	"errors"
+	"math"
+)
+
+// ErrNoFrames is returned when the input holds no decodable MPEG audio frames
+var ErrNoFrames = errors.New("mp3: no audio frames found")
+
+// maxReservoir is the furthest back main_data_begin can point (9 bits)
+const maxReservoir = 511
+
+// decoder carries the state that spans frames
+type decoder struct {
+	reservoir []byte
+	overlap   [2][32][18]float64
+	synth     [2]synthesizer
+	prevSF    [2][]int
+}
+
+// Decode decodes an MP3 stream to mono 16-bit little-endian PCM, returning the stream's sample rate.
+// Stereo streams are downmixed; frames whose sample rate differs from the first frame are skipped.
+func Decode(data []byte) ([]byte, int, error) {
+	d := &decoder{}
+	var out []byte
+	sampleRate := 0
+
+	pos := skipID3v2(data)
+	for pos+4 <= len(data) {
+		h, ok := parseHeader(data[pos:])
+		if !ok || !frameFollows(data, pos, h) {
+			pos++
+			continue
+		}
+		size := h.frameSize()
+		if pos+size > len(data) {
+			break // Truncated final frame
+		}
+		if sampleRate == 0 {
+			sampleRate = h.sampleRate
+		}
+		if h.sampleRate == sampleRate {
+			out = d.decodeFrame(h, data[pos:pos+size], out)
+		}
+		pos += size
+	}
+
+	if sampleRate == 0 {
+		return nil, 0, ErrNoFrames
+	}
+	return out, sampleRate, nil
+}
+
+// skipID3v2 returns the offset just past a leading ID3v2 tag, or 0 if there is none
+func skipID3v2(data []byte) int {
+	if len(data) < 10 || string(data[:3]) != "ID3" {
+		return 0
+	}
+	// The tag size is a 28-bit synchsafe integer excluding the 10-byte header
+	size := int(data[6]&0x7F)<<21 | int(data[7]&0x7F)<<14 | int(data[8]&0x7F)<<7 | int(data[9]&0x7F)
+	size += 10
+	if data[5]&0x10 != 0 {
+		size += 10 // Footer
+	}
+	if size > len(data) {
+		return len(data)
+	}
+	return size
+}
+
+// frameFollows reports whether a frame at pos is plausible: it fits the data and, if there is room, another
+// header follows it. This keeps sync from locking onto stray 0xFF bytes.
+func frameFollows(data []byte, pos int, h frameHeader) bool {
+	size := h.frameSize()
+	if size < 4+h.sideInfoSize() {
+		return false
+	}
+	next := pos + size
+	if next+4 > len(data) {
+		return true
+	}
+	_, ok := parseHeader(data[next:])
+	return ok
+}
+
+// IsMP3 reports whether data starts with an ID3v2 tag or with two consecutive Layer III frame headers
+func IsMP3(data []byte) bool {
+	if skipID3v2(data) > 0 {
+		return true
+	}
+	h, ok := parseHeader(data)
+	if !ok {
+		return false
+	}
+	next := h.frameSize()
+	if next+4 > len(data) {
+		return next <= len(data)
+	}
+	_, ok = parseHeader(data[next:])
+	return ok
+}
+
+// decodeFrame decodes one frame and appends its samples to out
+func (d *decoder) decodeFrame(h frameHeader, frame []byte, out []byte) []byte {
+	offset := 4
+	if h.crc {
+		offset += 2
+	}
+	if offset+h.sideInfoSize() > len(frame) {
+		return out
+	}
+	si, err := readSideInfo(h, frame[offset:offset+h.sideInfoSize()])
+	mainData := frame[offset+h.sideInfoSize():]
+	if err != nil {
+		d.appendReservoir(mainData)
+		return out
+	}
+
+	if si.mainDataBegin > len(d.reservoir) {
+		// The bit reservoir points into frames we never saw (stream start or after a cut)
+		d.appendReservoir(mainData)
+		return out
+	}
+	start := len(d.reservoir) - si.mainDataBegin
+	d.reservoir = append(d.reservoir, mainData...)
+	br := &bitReader{data: d.reservoir[start:]}
+
+	channels := h.channels()
+	var pcm [2][576]float64
+	for gr := 0; gr < h.granules(); gr++ {
+		var gcs [2]granuleChannel
+		for ch := 0; ch < channels; ch++ {
+			gc := &gcs[ch]
+			gc.info = &si.granules[gr][ch]
+			gc.bands = bandLayout(h.rateIndex, gc.info)
+
+			part2Start := br.pos
+			if h.lsf {
+				readScalefactorsLSF(br, gc, ch == 1 && h.intensityStereo())
+			} else {
+				readScalefactorsMPEG1(br, gc, gr, si.scfsi[ch], d.prevSF[ch])
+				if gr == 0 {
+					d.prevSF[ch] = gc.scalefactors
+				}
+			}
+
+			end := part2Start + gc.info.part23Length
+			if err := readHuffman(br, gc, end); err != nil {
+				// A corrupt granule decodes as silence rather than noise
+				gc.samples = [576]int{}
+				gc.nonZero = 0
+			}
+			br.pos = end
+			requantize(gc)
+		}
+
+		if channels == 2 {
+			stereo(h, &gcs[0], &gcs[1])
+		}
+
+		for ch := 0; ch < channels; ch++ {
+			gc := &gcs[ch]
+			reorder(gc)
+			antialias(gc)
+
+			var slots [18][32]float64
+			hybrid(gc, &d.overlap[ch], &slots)
+			for t := 0; t < 18; t++ {
+				d.synth[ch].run(&slots[t], pcm[ch][t*32:t*32+32])
+			}
+		}
+
+		for i := 0; i < 576; i++ {
+			v := pcm[0][i]
+			if channels == 2 {
+				v = (v + pcm[1][i]) / 2
+			}
+			out = binary.LittleEndian.AppendUint16(out, uint16(toInt16(v)))
+		}
+	}
+
+	d.trimReservoir()
+	return out
+}
+
+// appendReservoir keeps a frame's main data for later frames to reference
+func (d *decoder) appendReservoir(mainData []byte) {
+	d.reservoir = append(d.reservoir, mainData...)
+	d.trimReservoir()
+}
+
+// trimReservoir drops main data that no later frame can reach
+func (d *decoder) trimReservoir() {
+	if len(d.reservoir) > maxReservoir {
+		d.reservoir = append(d.reservoir[:0], d.reservoir[len(d.reservoir)-maxReservoir:]...)
+	}
+}
+
+// toInt16 scales a sample in [-1, 1] to 16 bits with clipping
+func toInt16(v float64) int16 {
+	s := math.Round(v * 32768)
+	if s > 32767 {
+		return 32767
+	}
+	if s < -32768 {
+		return -32768
+	}
+	return int16(s)
+}
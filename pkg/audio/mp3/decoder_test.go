@@ -0,0 +1,95 @@
+package mp3
+
+import (
+	"encoding/binary"
+	"math"
+	"os"
+	"testing"
+)
+
+func TestHuffmanTables_ArePrefixComplete(t *testing.T) {
+	// Every table is a complete prefix code: the Kraft sum of its code lengths is exactly 1
+	tables := map[string][]uint8{
+		"1": hlen1, "2": hlen2, "3": hlen3, "5": hlen5, "6": hlen6, "7": hlen7, "8": hlen8,
+		"9": hlen9, "10": hlen10, "11": hlen11, "12": hlen12, "13": hlen13, "15": hlen15,
+		"16": hlen16, "24": hlen24, "A": hlenQuadA, "B": hlenQuadB,
+	}
+	for name, lengths := range tables {
+		var sum float64
+		for _, l := range lengths {
+			sum += math.Pow(2, -float64(l))
+		}
+		if math.Abs(sum-1) > 1e-9 {
+			t.Errorf("table %s: Kraft sum %v", name, sum)
+		}
+	}
+}
+
+func TestSynthWindow_MatchesStandard(t *testing.T) {
+	// Spot values of D[i] from ISO/IEC 11172-3 Table B.3
+	want := map[int]float64{
+		64: 0.003250122, 128: 0.031082153, 160: -0.078628540, 192: 0.100311279,
+		224: -0.572036743, 256: 1.144989014, 288: 0.572036743, 320: 0.100311279,
+	}
+	for i, v := range want {
+		if diff := math.Abs(synthWindow[i] - v); diff > 2e-3 {
+			t.Errorf("D[%d] = %.6f, want %.6f", i, synthWindow[i], v)
+		}
+	}
+}
+
+func TestParseHeader(t *testing.T) {
+	// MPEG-1 Layer III, 128 kbps, 44.1 kHz, padded, joint stereo
+	h, ok := parseHeader([]byte{0xFF, 0xFB, 0x92, 0x40})
+	if !ok {
+		t.Fatal("header not recognised")
+	}
+	if h.lsf || h.bitRate != 128 || h.sampleRate != 44100 || h.padding != 1 || h.mode != modeJointStereo {
+		t.Fatalf("unexpected header %+v", h)
+	}
+	if h.frameSize() != 418 {
+		t.Errorf("frame size %d, want 418", h.frameSize())
+	}
+
+	// Layer II is rejected
+	if _, ok := parseHeader([]byte{0xFF, 0xFD, 0x92, 0x40}); ok {
+		t.Error("Layer II header accepted")
+	}
+}
+
+func TestDecode_MPEG25(t *testing.T) {
+	data, err := os.ReadFile("testdata/mpeg25_8k_mono.mp3")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !IsMP3(data) {
+		t.Fatal("fixture not detected as MP3")
+	}
+
+	pcm, sampleRate, err := Decode(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if sampleRate != 8000 {
+		t.Fatalf("sample rate %d, want 8000", sampleRate)
+	}
+	// MPEG-2.5 frames carry one 576-sample granule
+	if len(pcm) == 0 || len(pcm)%(576*2) != 0 {
+		t.Fatalf("decoded %d bytes, want whole granules", len(pcm))
+	}
+
+	var energy float64
+	for i := 0; i+1 < len(pcm); i += 2 {
+		v := float64(int16(binary.LittleEndian.Uint16(pcm[i:])))
+		energy += v * v
+	}
+	if rms := math.Sqrt(energy / float64(len(pcm)/2)); rms < 100 {
+		t.Errorf("decoded audio is near silent (rms %.1f)", rms)
+	}
+}
+
+func TestDecode_NoFrames(t *testing.T) {
+	if _, _, err := Decode([]byte("definitely not audio")); err != ErrNoFrames {
+		t.Errorf("got %v, want ErrNoFrames", err)
+	}
+}
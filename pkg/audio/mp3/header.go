@@ -0,0 +1,116 @@
+package mp3
+
+// Channel modes
+const (
+	modeStereo      = 0
+	modeJointStereo = 1
+	modeDualChannel = 2
+	modeMono        = 3
+)
+
+// Layer III bit rates in kbps by bitrate_index; index 0 (free format) and 15 are not supported
+var bitRates = [2][15]int{
+	{0, 32, 40, 48, 56, 64, 80, 96, 112, 128, 160, 192, 224, 256, 320}, // MPEG-1
+	{0, 8, 16, 24, 32, 40, 48, 56, 64, 80, 96, 112, 128, 144, 160},     // MPEG-2 and 2.5
+}
+
+// sampleRates by overall index: MPEG-1 0-2, MPEG-2 3-5, MPEG-2.5 6-8
+var sampleRates = [9]int{44100, 48000, 32000, 22050, 24000, 16000, 11025, 12000, 8000}
+
+// frameHeader is a parsed 4-byte MPEG audio frame header
+type frameHeader struct {
+	lsf        bool // MPEG-2 or 2.5 low sampling frequency extension
+	rateIndex  int  // Index into sampleRates
+	crc        bool // A 16-bit CRC follows the header
+	bitRate    int  // kbps
+	padding    int
+	mode       int
+	modeExt    int
+	sampleRate int
+}
+
+// parseHeader decodes a Layer III frame header, reporting false if b does not start with one
+func parseHeader(b []byte) (frameHeader, bool) {
+	if len(b) < 4 || b[0] != 0xFF || b[1]&0xE0 != 0xE0 {
+		return frameHeader{}, false
+	}
+
+	version := (b[1] >> 3) & 3 // 0 = MPEG-2.5, 1 = reserved, 2 = MPEG-2, 3 = MPEG-1
+	layer := (b[1] >> 1) & 3   // 1 = Layer III
+	bitRateIndex := int(b[2] >> 4)
+	rateIndex := int(b[2]>>2) & 3
+	if version == 1 || layer != 1 || bitRateIndex == 0 || bitRateIndex == 15 || rateIndex == 3 {
+		return frameHeader{}, false
+	}
+
+	h := frameHeader{
+		lsf:     version != 3,
+		crc:     b[1]&1 == 0,
+		padding: int(b[2]>>1) & 1,
+		mode:    int(b[3] >> 6),
+		modeExt: int(b[3]>>4) & 3,
+	}
+	switch version {
+	case 3:
+		h.rateIndex = rateIndex
+	case 2:
+		h.rateIndex = 3 + rateIndex
+	default:
+		h.rateIndex = 6 + rateIndex
+	}
+	if h.lsf {
+		h.bitRate = bitRates[1][bitRateIndex]
+	} else {
+		h.bitRate = bitRates[0][bitRateIndex]
+	}
+	h.sampleRate = sampleRates[h.rateIndex]
+	return h, true
+}
+
+// channels returns 1 for mono streams and 2 otherwise
+func (h frameHeader) channels() int {
+	if h.mode == modeMono {
+		return 1
+	}
+	return 2
+}
+
+// granules returns the number of 576-sample granules per frame
+func (h frameHeader) granules() int {
+	if h.lsf {
+		return 1
+	}
+	return 2
+}
+
+// frameSize returns the frame length in bytes, header included
+func (h frameHeader) frameSize() int {
+	if h.lsf {
+		return 72000*h.bitRate/h.sampleRate + h.padding
+	}
+	return 144000*h.bitRate/h.sampleRate + h.padding
+}
+
+// sideInfoSize returns the side information length in bytes
+func (h frameHeader) sideInfoSize() int {
+	switch {
+	case h.lsf && h.channels() == 1:
+		return 9
+	case h.lsf:
+		return 17
+	case h.channels() == 1:
+		return 17
+	default:
+		return 32
+	}
+}
+
+// msStereo reports whether mid/side stereo coding is on
+func (h frameHeader) msStereo() bool {
+	return h.mode == modeJointStereo && h.modeExt&2 != 0
+}
+
+// intensityStereo reports whether intensity stereo coding is on
+func (h frameHeader) intensityStereo() bool {
+	return h.mode == modeJointStereo && h.modeExt&1 != 0
+}
@@ -0,0 +1,96 @@
+package mp3
+
+import "fmt"
+
+// huffmanTree decodes one Huffman code table bit by bit
+// nodes[i] holds the child indexes for bits 0 and 1; a negative child is a leaf with value -(child+1)
+type huffmanTree struct {
+	nodes [][2]int32
+}
+
+// newHuffmanTree builds a decoding tree from parallel code and length tables
+func newHuffmanTree(codes []uint16, lengths []uint8) *huffmanTree {
+	t := &huffmanTree{nodes: make([][2]int32, 1, 2*len(codes))}
+	for value, code := range codes {
+		node := 0
+		for bit := int(lengths[value]) - 1; bit >= 0; bit-- {
+			b := (code >> uint(bit)) & 1
+			if bit == 0 {
+				t.nodes[node][b] = -int32(value) - 1
+				break
+			}
+			if t.nodes[node][b] == 0 {
+				t.nodes = append(t.nodes, [2]int32{})
+				t.nodes[node][b] = int32(len(t.nodes) - 1)
+			}
+			node = int(t.nodes[node][b])
+		}
+	}
+	return t
+}
+
+// decode reads one code word and returns its table index
+func (t *huffmanTree) decode(br *bitReader) (int, error) {
+	node := 0
+	for {
+		if br.remaining() <= 0 {
+			return 0, errBitstreamOverrun
+		}
+		child := t.nodes[node][br.readBit()]
+		if child < 0 {
+			return int(-child - 1), nil
+		}
+		if child == 0 {
+			return 0, fmt.Errorf("invalid huffman code")
+		}
+		node = int(child)
+	}
+}
+
+// bigValueTable is one of the 32 tables selectable for the big_values region
+type bigValueTable struct {
+	tree    *huffmanTree // nil for table 0, which codes only zeros
+	size    int          // x and y range over 0..size-1
+	linbits int          // Extra bits appended to values of 15
+}
+
+var (
+	bigValueTables [32]bigValueTable
+	quadTables     [2]*huffmanTree
+)
+
+func init() {
+	trees := map[int]struct {
+		tree *huffmanTree
+		size int
+	}{
+		1:  {newHuffmanTree(hcod1, hlen1), 2},
+		2:  {newHuffmanTree(hcod2, hlen2), 3},
+		3:  {newHuffmanTree(hcod3, hlen3), 3},
+		5:  {newHuffmanTree(hcod5, hlen5), 4},
+		6:  {newHuffmanTree(hcod6, hlen6), 4},
+		7:  {newHuffmanTree(hcod7, hlen7), 6},
+		8:  {newHuffmanTree(hcod8, hlen8), 6},
+		9:  {newHuffmanTree(hcod9, hlen9), 6},
+		10: {newHuffmanTree(hcod10, hlen10), 8},
+		11: {newHuffmanTree(hcod11, hlen11), 8},
+		12: {newHuffmanTree(hcod12, hlen12), 8},
+		13: {newHuffmanTree(hcod13, hlen13), 16},
+		15: {newHuffmanTree(hcod15, hlen15), 16},
+		16: {newHuffmanTree(hcod16, hlen16), 16},
+		24: {newHuffmanTree(hcod24, hlen24), 16},
+	}
+	for i, entry := range trees {
+		bigValueTables[i] = bigValueTable{tree: entry.tree, size: entry.size}
+	}
+	// Tables 16-23 and 24-31 share codes and differ only in linbits
+	for i, linbits := range []int{1, 2, 3, 4, 6, 8, 10, 13} {
+		bigValueTables[16+i] = bigValueTable{tree: trees[16].tree, size: 16, linbits: linbits}
+	}
+	for i, linbits := range []int{4, 5, 6, 7, 8, 9, 11, 13} {
+		bigValueTables[24+i] = bigValueTable{tree: trees[24].tree, size: 16, linbits: linbits}
+	}
+
+	quadTables[0] = newHuffmanTree(hcodQuadA, hlenQuadA)
+	quadTables[1] = newHuffmanTree(hcodQuadB, hlenQuadB)
+}
@@ -0,0 +1,104 @@
+package mp3
+
+import "math"
+
+var (
+	imdctLongCos  [36][18]float64
+	imdctShortCos [12][6]float64
+	blockWindows  [4][36]float64 // By block type; index 2 holds the 12-point short window
+)
+
+func init() {
+	for i := 0; i < 36; i++ {
+		for k := 0; k < 18; k++ {
+			imdctLongCos[i][k] = math.Cos(math.Pi / 72 * float64((2*i+1+18)*(2*k+1)))
+		}
+	}
+	for i := 0; i < 12; i++ {
+		for k := 0; k < 6; k++ {
+			imdctShortCos[i][k] = math.Cos(math.Pi / 24 * float64((2*i+1+6)*(2*k+1)))
+		}
+	}
+
+	longSine := func(i int) float64 { return math.Sin(math.Pi / 36 * (float64(i) + 0.5)) }
+	shortSine := func(i int) float64 { return math.Sin(math.Pi / 12 * (float64(i) + 0.5)) }
+	for i := 0; i < 36; i++ {
+		blockWindows[0][i] = longSine(i)
+	}
+	// Start block: long rise, short fall
+	for i := 0; i < 18; i++ {
+		blockWindows[1][i] = longSine(i)
+	}
+	for i := 18; i < 24; i++ {
+		blockWindows[1][i] = 1
+	}
+	for i := 24; i < 30; i++ {
+		blockWindows[1][i] = shortSine(i - 18)
+	}
+	for i := 0; i < 12; i++ {
+		blockWindows[2][i] = shortSine(i)
+	}
+	// Stop block: short rise, long fall
+	for i := 6; i < 12; i++ {
+		blockWindows[3][i] = shortSine(i - 6)
+	}
+	for i := 12; i < 18; i++ {
+		blockWindows[3][i] = 1
+	}
+	for i := 18; i < 36; i++ {
+		blockWindows[3][i] = longSine(i)
+	}
+}
+
+// imdctLong transforms 18 frequency lines into 36 windowed time samples
+func imdctLong(in []float64, blockType int, out *[36]float64) {
+	for i := 0; i < 36; i++ {
+		var sum float64
+		for k := 0; k < 18; k++ {
+			sum += in[k] * imdctLongCos[i][k]
+		}
+		out[i] = sum * blockWindows[blockType][i]
+	}
+}
+
+// imdctShort transforms three interleaved 6-line windows into 36 overlapped time samples
+func imdctShort(in []float64, out *[36]float64) {
+	*out = [36]float64{}
+	for w := 0; w < 3; w++ {
+		for i := 0; i < 12; i++ {
+			var sum float64
+			for k := 0; k < 6; k++ {
+				sum += in[3*k+w] * imdctShortCos[i][k]
+			}
+			out[6+6*w+i] += sum * blockWindows[2][i]
+		}
+	}
+}
+
+// hybrid runs the IMDCT and overlap-add for every subband, producing 18 time slots of 32 subband samples
+func hybrid(gc *granuleChannel, overlap *[32][18]float64, out *[18][32]float64) {
+	var raw [36]float64
+	for sb := 0; sb < 32; sb++ {
+		blockType := gc.info.blockType
+		if gc.info.mixed && sb < 2 {
+			blockType = 0
+		}
+
+		in := gc.xr[sb*18 : sb*18+18]
+		if blockType == 2 {
+			imdctShort(in, &raw)
+		} else {
+			imdctLong(in, blockType, &raw)
+		}
+
+		for i := 0; i < 18; i++ {
+			v := raw[i] + overlap[sb][i]
+			overlap[sb][i] = raw[i+18]
+			// Compensate the frequency inversion of odd subbands
+			if sb&1 == 1 && i&1 == 1 {
+				v = -v
+			}
+			out[i][sb] = v
+		}
+	}
+}
@@ -0,0 +1,535 @@
+package mp3
+
+import (
+	"fmt"
+	"math"
+)
+
+// granuleInfo is the side information for one granule of one channel
+type granuleInfo struct {
+	part23Length     int
+	bigValues        int
+	globalGain       int
+	scalefacCompress int
+	windowSwitching  bool
+	blockType        int
+	mixed            bool
+	tableSelect      [3]int
+	subblockGain     [3]int
+	region0Count     int
+	region1Count     int
+	preflag          bool
+	scalefacScale    int
+	count1Table      int
+}
+
+// sideInfo is the side information of one frame
+type sideInfo struct {
+	mainDataBegin int
+	scfsi         [2][4]bool
+	granules      [2][2]granuleInfo // [granule][channel]
+}
+
+// readSideInfo parses the side information following the header (and CRC)
+func readSideInfo(h frameHeader, data []byte) (*sideInfo, error) {
+	br := &bitReader{data: data}
+	channels := h.channels()
+	si := &sideInfo{}
+
+	if h.lsf {
+		si.mainDataBegin = br.readBits(8)
+		br.readBits(channels) // private bits
+	} else {
+		si.mainDataBegin = br.readBits(9)
+		if channels == 1 {
+			br.readBits(5)
+		} else {
+			br.readBits(3)
+		}
+		for ch := 0; ch < channels; ch++ {
+			for band := 0; band < 4; band++ {
+				si.scfsi[ch][band] = br.readBit() == 1
+			}
+		}
+	}
+
+	for gr := 0; gr < h.granules(); gr++ {
+		for ch := 0; ch < channels; ch++ {
+			gi := &si.granules[gr][ch]
+			gi.part23Length = br.readBits(12)
+			gi.bigValues = br.readBits(9)
+			if gi.bigValues > 288 {
+				return nil, fmt.Errorf("big_values %d out of range", gi.bigValues)
+			}
+			gi.globalGain = br.readBits(8)
+			if h.lsf {
+				gi.scalefacCompress = br.readBits(9)
+			} else {
+				gi.scalefacCompress = br.readBits(4)
+			}
+
+			gi.windowSwitching = br.readBit() == 1
+			if gi.windowSwitching {
+				gi.blockType = br.readBits(2)
+				if gi.blockType == 0 {
+					return nil, fmt.Errorf("window switching with normal block type")
+				}
+				gi.mixed = br.readBit() == 1
+				gi.tableSelect[0] = br.readBits(5)
+				gi.tableSelect[1] = br.readBits(5)
+				for w := 0; w < 3; w++ {
+					gi.subblockGain[w] = br.readBits(3)
+				}
+				// Region 0 covers the first 36 samples; region 1 the rest of big_values
+				gi.region0Count = 7
+				if gi.blockType == 2 && !gi.mixed {
+					gi.region0Count = 8
+				}
+				gi.region1Count = 255
+			} else {
+				for i := 0; i < 3; i++ {
+					gi.tableSelect[i] = br.readBits(5)
+				}
+				gi.region0Count = br.readBits(4)
+				gi.region1Count = br.readBits(3)
+			}
+
+			if !h.lsf {
+				gi.preflag = br.readBit() == 1
+			}
+			gi.scalefacScale = br.readBit()
+			gi.count1Table = br.readBit()
+		}
+	}
+	return si, nil
+}
+
+// band is one scalefactor band of a granule; short-block bands appear once per window
+type band struct {
+	start  int
+	width  int
+	short  bool
+	window int // Short blocks: window 0-2
+	index  int // Scalefactor band number within its long or short table
+}
+
+// bandLayout lists the scalefactor bands of a granule in bitstream order
+func bandLayout(rateIndex int, gi *granuleInfo) []band {
+	longWidths := longBandWidths[rateIndex]
+	shortWidths := shortBandWidths[rateIndex]
+
+	var bands []band
+	start := 0
+	firstShort := 0
+	if gi.blockType != 2 {
+		for i, w := range longWidths {
+			bands = append(bands, band{start: start, width: w, index: i})
+			start += w
+		}
+		return bands
+	}
+
+	if gi.mixed {
+		// Mixed blocks code the first 36 samples (two subbands) as long blocks
+		for i := 0; start < 36; i++ {
+			bands = append(bands, band{start: start, width: longWidths[i], index: i})
+			start += longWidths[i]
+		}
+		firstShort = 3
+	}
+	for i := firstShort; i < len(shortWidths); i++ {
+		for w := 0; w < 3; w++ {
+			bands = append(bands, band{start: start, width: shortWidths[i], short: true, window: w, index: i})
+			start += shortWidths[i]
+		}
+	}
+	return bands
+}
+
+// granuleChannel holds the decoding state of one granule of one channel
+type granuleChannel struct {
+	info         *granuleInfo
+	bands        []band
+	scalefactors []int
+	illegalPos   []bool // Intensity stereo: the band's position is the escape value
+	samples      [576]int
+	nonZero      int // Samples from here on are zero
+	xr           [576]float64
+}
+
+// readScalefactorsMPEG1 reads MPEG-1 scalefactors; prev holds granule 0's long scalefactors for scfsi reuse
+func readScalefactorsMPEG1(br *bitReader, gc *granuleChannel, gr int, scfsi [4]bool, prev []int) {
+	gi := gc.info
+	slen1, slen2 := slenMPEG1[gi.scalefacCompress][0], slenMPEG1[gi.scalefacCompress][1]
+	gc.scalefactors = make([]int, len(gc.bands))
+	gc.illegalPos = make([]bool, len(gc.bands))
+
+	if gi.blockType == 2 {
+		for i, b := range gc.bands {
+			bits := slen2
+			switch {
+			case !b.short || b.index < 6:
+				bits = slen1
+			case b.index == 12:
+				bits = 0
+			}
+			gc.scalefactors[i] = br.readBits(bits)
+			gc.illegalPos[i] = gc.scalefactors[i] == 7
+		}
+		return
+	}
+
+	groups := [5]int{0, 6, 11, 16, 21}
+	for g := 0; g < 4; g++ {
+		bits := slen1
+		if g >= 2 {
+			bits = slen2
+		}
+		for sfb := groups[g]; sfb < groups[g+1]; sfb++ {
+			if gr == 1 && scfsi[g] {
+				gc.scalefactors[sfb] = prev[sfb]
+			} else {
+				gc.scalefactors[sfb] = br.readBits(bits)
+			}
+			gc.illegalPos[sfb] = gc.scalefactors[sfb] == 7
+		}
+	}
+	// The last band has no scalefactor of its own; for intensity stereo it repeats the one before
+	gc.illegalPos[21] = gc.illegalPos[20]
+}
+
+// readScalefactorsLSF reads MPEG-2/2.5 scalefactors; intensityRight selects the intensity stereo coding
+func readScalefactorsLSF(br *bitReader, gc *granuleChannel, intensityRight bool) {
+	gi := gc.info
+	var slen [4]int
+	table := 0
+	sfc := gi.scalefacCompress
+
+	if intensityRight {
+		sfc >>= 1
+		switch {
+		case sfc < 180:
+			slen = [4]int{sfc / 36, (sfc % 36) / 6, sfc % 6, 0}
+			table = 3
+		case sfc < 244:
+			sfc -= 180
+			slen = [4]int{(sfc & 63) >> 4, (sfc & 15) >> 2, sfc & 3, 0}
+			table = 4
+		default:
+			sfc -= 244
+			slen = [4]int{sfc / 3, sfc % 3, 0, 0}
+			table = 5
+		}
+	} else {
+		switch {
+		case sfc < 400:
+			slen = [4]int{(sfc >> 4) / 5, (sfc >> 4) % 5, (sfc & 15) >> 2, sfc & 3}
+		case sfc < 500:
+			sfc -= 400
+			slen = [4]int{(sfc >> 2) / 5, (sfc >> 2) % 5, sfc & 3, 0}
+			table = 1
+		default:
+			sfc -= 500
+			slen = [4]int{sfc / 3, sfc % 3, 0, 0}
+			table = 2
+			gi.preflag = true
+		}
+	}
+
+	kind := 0
+	if gi.blockType == 2 {
+		kind = 1
+		if gi.mixed {
+			kind = 2
+		}
+	}
+
+	gc.scalefactors = make([]int, len(gc.bands))
+	gc.illegalPos = make([]bool, len(gc.bands))
+	i := 0
+	for group, count := range lsfScalefactorCounts[table][kind] {
+		maxValue := (1 << uint(slen[group])) - 1
+		for k := 0; k < count && i < len(gc.bands); k++ {
+			gc.scalefactors[i] = br.readBits(slen[group])
+			gc.illegalPos[i] = slen[group] > 0 && gc.scalefactors[i] == maxValue
+			i++
+		}
+	}
+}
+
+// readHuffman decodes the quantized spectrum; end is the bit position where part2_3 ends
+func readHuffman(br *bitReader, gc *granuleChannel, end int) error {
+	gi := gc.info
+	gc.samples = [576]int{}
+
+	// Region boundaries are scalefactor band edges
+	region1, region2 := 576, 576
+	if n := gi.region0Count + 1; n < len(gc.bands) {
+		region1 = gc.bands[n].start
+		if m := n + gi.region1Count + 1; m < len(gc.bands) {
+			region2 = gc.bands[m].start
+		}
+	}
+	bigEnd := gi.bigValues * 2
+
+	i := 0
+	for ; i < bigEnd; i += 2 {
+		region := 0
+		if i >= region2 {
+			region = 2
+		} else if i >= region1 {
+			region = 1
+		}
+		table := bigValueTables[gi.tableSelect[region]]
+		if table.tree == nil {
+			if gi.tableSelect[region] != 0 {
+				return fmt.Errorf("invalid huffman table %d", gi.tableSelect[region])
+			}
+			continue
+		}
+
+		value, err := table.tree.decode(br)
+		if err != nil {
+			return err
+		}
+		x, y := value/table.size, value%table.size
+		gc.samples[i] = readValue(br, x, table.linbits)
+		gc.samples[i+1] = readValue(br, y, table.linbits)
+	}
+
+	// count1 region: quadruples of -1, 0 or 1 until the granule's bits run out
+	quad := quadTables[gi.count1Table]
+	for i+4 <= 576 && br.pos < end {
+		value, err := quad.decode(br)
+		if err != nil {
+			return err
+		}
+		var q [4]int
+		for k := 0; k < 4; k++ {
+			q[k] = readValue(br, (value>>uint(3-k))&1, 0)
+		}
+		if br.pos > end {
+			// The last quadruple ran past the granule: it is stuffing, not data
+			break
+		}
+		copy(gc.samples[i:i+4], q[:])
+		i += 4
+	}
+
+	gc.nonZero = 0
+	for k := 575; k >= 0; k-- {
+		if gc.samples[k] != 0 {
+			gc.nonZero = k + 1
+			break
+		}
+	}
+	return nil
+}
+
+// readValue reads the linbits extension and sign of one Huffman-coded magnitude
+func readValue(br *bitReader, v, linbits int) int {
+	if v == 15 && linbits > 0 {
+		v += br.readBits(linbits)
+	}
+	if v != 0 && br.readBit() == 1 {
+		return -v
+	}
+	return v
+}
+
+// pow43 caches |x|^(4/3) for every magnitude Huffman decoding can produce
+var pow43 = func() []float64 {
+	t := make([]float64, 8207) // 15 + 2^13 - 1
+	for i := range t {
+		t[i] = math.Pow(float64(i), 4.0/3.0)
+	}
+	return t
+}()
+
+// requantize scales the quantized spectrum by global gain, subblock gain and scalefactors
+func requantize(gc *granuleChannel) {
+	gi := gc.info
+	scale := 0.5
+	if gi.scalefacScale == 1 {
+		scale = 1
+	}
+
+	for i, b := range gc.bands {
+		var exponent float64
+		if b.short {
+			exponent = 0.25*float64(gi.globalGain-210-8*gi.subblockGain[b.window]) - scale*float64(gc.scalefactors[i])
+		} else {
+			sf := gc.scalefactors[i]
+			if gi.preflag {
+				sf += pretab[b.index]
+			}
+			exponent = 0.25*float64(gi.globalGain-210) - scale*float64(sf)
+		}
+		gain := math.Pow(2, exponent)
+
+		for k := b.start; k < b.start+b.width && k < 576; k++ {
+			v := gc.samples[k]
+			switch {
+			case v > 0:
+				gc.xr[k] = pow43[v] * gain
+			case v < 0:
+				gc.xr[k] = -pow43[-v] * gain
+			default:
+				gc.xr[k] = 0
+			}
+		}
+	}
+}
+
+// stereo undoes mid/side and intensity stereo coding, leaving left and right in the two channels
+func stereo(h frameHeader, left, right *granuleChannel) {
+	ms := h.msStereo()
+	intensity := make([]bool, len(right.bands))
+	if h.intensityStereo() {
+		markIntensityBands(right, intensity)
+	}
+
+	var intensityScale float64
+	if h.lsf {
+		// Position steps are 2^-1/4 or 2^-1/2 depending on the right channel's scalefac_compress
+		intensityScale = math.Pow(2, -0.25)
+		if right.info.scalefacCompress&1 == 1 {
+			intensityScale = math.Sqrt(0.5)
+		}
+	}
+
+	for i, b := range right.bands {
+		end := b.start + b.width
+		if end > 576 {
+			end = 576
+		}
+
+		if intensity[i] && !right.illegalPos[i] {
+			pos := right.scalefactors[i]
+			if !b.short && b.index == 21 && i > 0 {
+				pos = right.scalefactors[i-1]
+			}
+			kl, kr := intensityRatio(h.lsf, pos, intensityScale)
+			for k := b.start; k < end; k++ {
+				v := left.xr[k]
+				left.xr[k] = v * kl
+				right.xr[k] = v * kr
+			}
+			continue
+		}
+
+		if ms {
+			for k := b.start; k < end; k++ {
+				m, s := left.xr[k], right.xr[k]
+				left.xr[k] = (m + s) * math.Sqrt2 / 2
+				right.xr[k] = (m - s) * math.Sqrt2 / 2
+			}
+		}
+	}
+}
+
+// markIntensityBands flags the bands above the right channel's last non-zero band, per window for short blocks
+func markIntensityBands(right *granuleChannel, intensity []bool) {
+	hasData := func(b band) bool {
+		for k := b.start; k < b.start+b.width && k < 576; k++ {
+			if right.samples[k] != 0 {
+				return true
+			}
+		}
+		return false
+	}
+
+	// Scanning from the top, a band is intensity-coded until its window (or the long part) has data
+	var seenData [4]bool // windows 0-2, then long bands
+	for i := len(right.bands) - 1; i >= 0; i-- {
+		b := right.bands[i]
+		slot := 3
+		if b.short {
+			slot = b.window
+		}
+		if !b.short && right.info.blockType == 2 {
+			// The long part of a mixed block is intensity-coded only if no short window has data
+			seenData[3] = seenData[3] || seenData[0] || seenData[1] || seenData[2]
+		}
+		if !seenData[slot] && hasData(b) {
+			seenData[slot] = true
+		}
+		intensity[i] = !seenData[slot]
+	}
+}
+
+// intensityRatio returns the left and right gains for an intensity stereo position
+func intensityRatio(lsf bool, pos int, scale float64) (float64, float64) {
+	if lsf {
+		switch {
+		case pos == 0:
+			return 1, 1
+		case pos%2 == 1:
+			return math.Pow(scale, float64(pos+1)/2), 1
+		default:
+			return 1, math.Pow(scale, float64(pos)/2)
+		}
+	}
+	if pos == 6 {
+		return 1, 0
+	}
+	ratio := math.Tan(float64(pos) * math.Pi / 12)
+	return ratio / (1 + ratio), 1 / (1 + ratio)
+}
+
+// reorder interleaves short-block windows so each subband holds its three windows' lines in turn
+func reorder(gc *granuleChannel) {
+	if gc.info.blockType != 2 {
+		return
+	}
+	var out [576]float64
+	copy(out[:], gc.xr[:])
+	for i := 0; i+2 < len(gc.bands); i++ {
+		b := gc.bands[i]
+		if !b.short || b.window != 0 {
+			continue
+		}
+		for w := 0; w < 3; w++ {
+			for j := 0; j < b.width; j++ {
+				src := b.start + w*b.width + j
+				dst := b.start + 3*j + w
+				if src < 576 && dst < 576 {
+					out[dst] = gc.xr[src]
+				}
+			}
+		}
+	}
+	gc.xr = out
+}
+
+// Alias reduction butterfly coefficients (ISO/IEC 11172-3 Table B.9)
+var aliasCS, aliasCA = func() ([8]float64, [8]float64) {
+	c := [8]float64{-0.6, -0.535, -0.33, -0.185, -0.095, -0.041, -0.0142, -0.0037}
+	var cs, ca [8]float64
+	for i, ci := range c {
+		sq := math.Sqrt(1 + ci*ci)
+		cs[i] = 1 / sq
+		ca[i] = ci / sq
+	}
+	return cs, ca
+}()
+
+// antialias applies the butterflies between adjacent long-block subbands
+func antialias(gc *granuleChannel) {
+	gi := gc.info
+	limit := 32
+	if gi.blockType == 2 {
+		if !gi.mixed {
+			return
+		}
+		limit = 2 // Only the boundary between the two long subbands of a mixed block
+	}
+	for sb := 1; sb < limit; sb++ {
+		for i := 0; i < 8; i++ {
+			lo, hi := sb*18-1-i, sb*18+i
+			a, b := gc.xr[lo], gc.xr[hi]
+			gc.xr[lo] = a*aliasCS[i] - b*aliasCA[i]
+			gc.xr[hi] = b*aliasCS[i] + a*aliasCA[i]
+		}
+	}
+}
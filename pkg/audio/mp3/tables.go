@@ -0,0 +1,46 @@
+package mp3
+
+// Scalefactor band widths in samples, by sample rate index (ISO/IEC 11172-3 Table B.8, 13818-3 Table B.2)
+var longBandWidths = [9][22]int{
+	{4, 4, 4, 4, 4, 4, 6, 6, 8, 8, 10, 12, 16, 20, 24, 28, 34, 42, 50, 54, 76, 158},     // 44100
+	{4, 4, 4, 4, 4, 4, 6, 6, 6, 8, 10, 12, 16, 18, 22, 28, 34, 40, 46, 54, 54, 192},     // 48000
+	{4, 4, 4, 4, 4, 4, 6, 6, 8, 10, 12, 16, 20, 24, 30, 38, 46, 56, 68, 84, 102, 26},    // 32000
+	{6, 6, 6, 6, 6, 6, 8, 10, 12, 14, 16, 20, 24, 28, 32, 38, 46, 52, 60, 68, 58, 54},   // 22050
+	{6, 6, 6, 6, 6, 6, 8, 10, 12, 14, 16, 18, 22, 26, 32, 38, 46, 54, 62, 70, 76, 36},   // 24000
+	{6, 6, 6, 6, 6, 6, 8, 10, 12, 14, 16, 20, 24, 28, 32, 38, 46, 52, 60, 68, 58, 54},   // 16000
+	{6, 6, 6, 6, 6, 6, 8, 10, 12, 14, 16, 20, 24, 28, 32, 38, 46, 52, 60, 68, 58, 54},   // 11025
+	{6, 6, 6, 6, 6, 6, 8, 10, 12, 14, 16, 20, 24, 28, 32, 38, 46, 52, 60, 68, 58, 54},   // 12000
+	{12, 12, 12, 12, 12, 12, 16, 20, 24, 28, 32, 40, 48, 56, 64, 76, 90, 2, 2, 2, 2, 2}, // 8000
+}
+
+var shortBandWidths = [9][13]int{
+	{4, 4, 4, 4, 6, 8, 10, 12, 14, 18, 22, 30, 56},  // 44100
+	{4, 4, 4, 4, 6, 6, 10, 12, 14, 16, 20, 26, 66},  // 48000
+	{4, 4, 4, 4, 6, 8, 12, 16, 20, 26, 34, 42, 12},  // 32000
+	{4, 4, 4, 6, 6, 8, 10, 14, 18, 26, 32, 42, 18},  // 22050
+	{4, 4, 4, 6, 8, 10, 12, 14, 18, 24, 32, 44, 12}, // 24000
+	{4, 4, 4, 6, 8, 10, 12, 14, 18, 24, 30, 40, 18}, // 16000
+	{4, 4, 4, 6, 8, 10, 12, 14, 18, 24, 30, 40, 18}, // 11025
+	{4, 4, 4, 6, 8, 10, 12, 14, 18, 24, 30, 40, 18}, // 12000
+	{8, 8, 8, 12, 16, 20, 24, 28, 36, 2, 2, 2, 26},  // 8000
+}
+
+// pretab is added to long-block scalefactors when preflag is set
+var pretab = [22]int{0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 1, 1, 1, 1, 2, 2, 3, 3, 3, 2, 0}
+
+// slenMPEG1 maps MPEG-1 scalefac_compress to the bit widths of the two scalefactor groups
+var slenMPEG1 = [16][2]int{
+	{0, 0}, {0, 1}, {0, 2}, {0, 3}, {3, 0}, {1, 1}, {1, 2}, {1, 3},
+	{2, 1}, {2, 2}, {2, 3}, {3, 1}, {3, 2}, {3, 3}, {4, 2}, {4, 3},
+}
+
+// lsfScalefactorCounts is nr_of_sfb_block from ISO/IEC 13818-3 Table B.3:
+// [scalefac_compress range][block kind: long, short, mixed][slen group]
+var lsfScalefactorCounts = [6][3][4]int{
+	{{6, 5, 5, 5}, {9, 9, 9, 9}, {6, 9, 9, 9}},
+	{{6, 5, 7, 3}, {9, 9, 12, 6}, {6, 9, 12, 6}},
+	{{11, 10, 0, 0}, {18, 18, 0, 0}, {15, 18, 0, 0}},
+	{{7, 7, 7, 0}, {12, 12, 12, 0}, {6, 15, 12, 0}},
+	{{6, 6, 6, 3}, {12, 9, 9, 6}, {6, 12, 9, 6}},
+	{{8, 8, 5, 0}, {15, 12, 9, 0}, {6, 18, 9, 0}},
+}
@@ -1,58 +1,76 @@
 package audio
 
+// G.711 μ-law constants (ITU-T G.711, as in the Sun reference implementation)
+const (
+	muLawBias = 0x84  // Added to the magnitude before encoding so every segment has a leading one
+	muLawClip = 32635 // Largest magnitude that survives the bias without overflowing
+)
+
 // DecodeMuLawToPCM16 converts G.711 μ-law (8-bit) to 16-bit signed PCM
 // μ-law is a companding algorithm used in telephony
-// Input: μ-law encoded bytes (8-bit samples at 8kHz)
-// Output: 16-bit signed little-endian PCM samples
+// Input: μ-law encoded bytes (8-bit samples, usually at 8kHz)
+// Output: 16-bit signed little-endian PCM samples at the same rate
 func DecodeMuLawToPCM16(muLaw []byte) []byte {
 	if len(muLaw) == 0 {
 		return nil
 	}
 
-	// μ-law to linear conversion table
-	// This implements the ITU-T G.711 standard
-	pcm := make([]int16, len(muLaw))
-
+	result := make([]byte, len(muLaw)*2)
 	for i, mu := range muLaw {
-		// Invert all bits (μ-law uses inverted representation)
-		mu = ^mu
-
-		// Extract sign bit (bit 7)
-		sign := (mu & 0x80) >> 7
-
-		// Extract exponent (bits 4-6)
-		exponent := (mu & 0x70) >> 4
+		sample := muLawToLinear(mu)
+		result[i*2] = byte(sample)
+		result[i*2+1] = byte(sample >> 8)
+	}
+	return result
+}
 
-		// Extract mantissa (bits 0-3)
-		mantissa := mu & 0x0F
+// EncodePCM16ToMuLaw converts 16-bit signed little-endian PCM to G.711 μ-law (8-bit)
+func EncodePCM16ToMuLaw(pcm []byte) []byte {
+	if len(pcm) < 2 {
+		return nil
+	}
 
-		// Calculate linear value
-		var linear int16
+	result := make([]byte, len(pcm)/2)
+	for i := range result {
+		result[i] = linearToMuLaw(int16(pcm[i*2]) | int16(pcm[i*2+1])<<8)
+	}
+	return result
+}
 
-		if exponent == 0 {
-			// Special case for exponent 0
-			linear = int16(33 + 2*mantissa)
-		} else {
-			// Normal case: (33 + 2*mantissa) * 2^(exponent-1) - 33
-			linear = int16((33 + 2*int(mantissa)) << (exponent - 1))
-			linear -= 33
-		}
+// muLawToLinear expands one μ-law byte
+func muLawToLinear(mu byte) int16 {
+	// μ-law bytes are transmitted with all bits inverted
+	mu = ^mu
+	exponent := (mu >> 4) & 0x07
+	mantissa := int(mu & 0x0F)
 
-		// Apply sign
-		if sign == 0 {
-			linear = -linear
-		}
+	magnitude := ((mantissa << 3) + muLawBias) << exponent
+	magnitude -= muLawBias
+	if mu&0x80 != 0 {
+		return int16(-magnitude)
+	}
+	return int16(magnitude)
+}
 
-		pcm[i] = linear
+// linearToMuLaw compresses one PCM16 sample
+func linearToMuLaw(sample int16) byte {
+	magnitude := int(sample)
+	sign := byte(0)
+	if magnitude < 0 {
+		magnitude = -magnitude
+		sign = 0x80
+	}
+	if magnitude > muLawClip {
+		magnitude = muLawClip
 	}
+	magnitude += muLawBias
 
-	// Convert to byte array (little-endian 16-bit)
-	result := make([]byte, len(pcm)*2)
-	for i, sample := range pcm {
-		result[i*2] = byte(sample & 0xFF)
-		result[i*2+1] = byte((sample >> 8) & 0xFF)
+	// The exponent is the position of the highest set bit above bit 7
+	exponent := byte(7)
+	for mask := 0x4000; magnitude&mask == 0 && exponent > 0; mask >>= 1 {
+		exponent--
 	}
+	mantissa := byte(magnitude>>(exponent+3)) & 0x0F
 
-	return result
+	return ^(sign | exponent<<4 | mantissa)
 }
-
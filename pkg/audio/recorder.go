@@ -0,0 +1,120 @@
+package audio
+
+import (
+	"encoding/binary"
+	"sync"
+	"time"
+)
+
+// CallRecorder captures both sides of a call as two time-aligned PCM16 tracks.
+// The caller track is the timeline: it grows with every inbound frame, which arrive in real time.
+// Bot audio is placed where it will start playing relative to that timeline, so the silence
+// between replies is kept. It is safe for concurrent use.
+type CallRecorder struct {
+	mu         sync.Mutex
+	sampleRate int
+	maxSamples int     // Recording stops growing past this length
+	caller     []int16 // Left channel
+	bot        []int16 // Right channel
+}
+
+// NewCallRecorder creates a recorder for audio at sampleRate, holding at most maxDuration per track
+func NewCallRecorder(sampleRate int, maxDuration time.Duration) *CallRecorder {
+	if sampleRate <= 0 {
+		sampleRate = 8000
+	}
+	return &CallRecorder{
+		sampleRate: sampleRate,
+		maxSamples: int(maxDuration.Seconds() * float64(sampleRate)),
+	}
+}
+
+// SampleRate returns the rate both tracks are recorded at
+func (r *CallRecorder) SampleRate() int {
+	return r.sampleRate
+}
+
+// WriteCaller appends one inbound frame of PCM16 to the caller track
+func (r *CallRecorder) WriteCaller(pcm []byte) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.caller = appendPCM(r.caller, pcm, r.maxSamples)
+}
+
+// WriteBot adds outbound PCM16 that starts playing delay from now.
+// Audio that follows on from earlier bot audio is appended back to back.
+func (r *CallRecorder) WriteBot(pcm []byte, delay time.Duration) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if delay < 0 {
+		delay = 0
+	}
+	start := len(r.caller) + int(delay.Seconds()*float64(r.sampleRate))
+	if start > r.maxSamples {
+		return
+	}
+	for len(r.bot) < start {
+		r.bot = append(r.bot, 0)
+	}
+	r.bot = appendPCM(r.bot, pcm, r.maxSamples)
+}
+
+// TruncateBot removes the last d of bot audio, e.g. audio dropped at the far end on barge-in
+func (r *CallRecorder) TruncateBot(d time.Duration) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	n := int(d.Seconds() * float64(r.sampleRate))
+	if n <= 0 {
+		return
+	}
+	if n > len(r.bot) {
+		n = len(r.bot)
+	}
+	r.bot = r.bot[:len(r.bot)-n]
+}
+
+// Duration returns the length of the recording so far
+func (r *CallRecorder) Duration() time.Duration {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return time.Duration(r.lengthLocked()) * time.Second / time.Duration(r.sampleRate)
+}
+
+// WAV returns the recording as a 16-bit stereo WAV file: caller on the left, bot on the right
+func (r *CallRecorder) WAV() []byte {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	frames := r.lengthLocked()
+	pcm := make([]byte, frames*4)
+	for i := 0; i < frames; i++ {
+		var left, right int16
+		if i < len(r.caller) {
+			left = r.caller[i]
+		}
+		if i < len(r.bot) {
+			right = r.bot[i]
+		}
+		binary.LittleEndian.PutUint16(pcm[i*4:], uint16(left))
+		binary.LittleEndian.PutUint16(pcm[i*4+2:], uint16(right))
+	}
+	return pcmToWAV(pcm, r.sampleRate, 2)
+}
+
+// lengthLocked returns the longer track's length in samples; callers must hold r.mu
+func (r *CallRecorder) lengthLocked() int {
+	if len(r.bot) > len(r.caller) {
+		return len(r.bot)
+	}
+	return len(r.caller)
+}
+
+// appendPCM appends little-endian PCM16 to samples without growing past max
+func appendPCM(samples []int16, pcm []byte, max int) []int16 {
+	for i := 0; i+1 < len(pcm) && len(samples) < max; i += 2 {
+		samples = append(samples, int16(binary.LittleEndian.Uint16(pcm[i:])))
+	}
+	return samples
+}
@@ -0,0 +1,59 @@
+package audio
+
+import (
+	"encoding/binary"
+	"testing"
+	"time"
+)
+
+func TestCallRecorder_AlignsTracks(t *testing.T) {
+	r := NewCallRecorder(8000, time.Minute)
+	frame := func(value int16, ms int) []byte {
+		pcm := make([]byte, 8*ms*2)
+		for i := 0; i < len(pcm); i += 2 {
+			binary.LittleEndian.PutUint16(pcm[i:], uint16(value))
+		}
+		return pcm
+	}
+
+	// 100ms of caller audio, then bot audio queued 40ms ahead of playback
+	for i := 0; i < 5; i++ {
+		r.WriteCaller(frame(100, 20))
+	}
+	r.WriteBot(frame(200, 20), 40*time.Millisecond)
+	r.WriteBot(frame(200, 20), 60*time.Millisecond) // Follows on directly
+	r.WriteBot(frame(300, 20), 80*time.Millisecond) // Dropped by a barge-in below
+	r.TruncateBot(20 * time.Millisecond)
+
+	wav := r.WAV()
+	out, rate, err := DecodeWAV(wav)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if rate != 8000 || binary.LittleEndian.Uint16(wav[22:24]) != 2 {
+		t.Fatalf("want 8kHz stereo, got %d Hz, %d channels", rate, binary.LittleEndian.Uint16(wav[22:24]))
+	}
+	if r.Duration() != 180*time.Millisecond {
+		t.Fatalf("duration %v, want 180ms", r.Duration())
+	}
+
+	// DecodeWAV averages the channels: caller only, then silence, then bot only
+	sample := func(ms int) int16 { return int16(binary.LittleEndian.Uint16(out[ms*8*2:])) }
+	for _, c := range []struct {
+		ms   int
+		want int16
+	}{{10, 50}, {110, 0}, {150, 100}, {175, 100}} {
+		if got := sample(c.ms); got != c.want {
+			t.Errorf("at %dms: got %d, want %d", c.ms, got, c.want)
+		}
+	}
+}
+
+func TestCallRecorder_StopsAtMaxDuration(t *testing.T) {
+	r := NewCallRecorder(8000, 100*time.Millisecond)
+	r.WriteCaller(make([]byte, 8000*2))
+	r.WriteBot(make([]byte, 320), time.Second)
+	if r.Duration() != 100*time.Millisecond {
+		t.Errorf("duration %v, want 100ms", r.Duration())
+	}
+}
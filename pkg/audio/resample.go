@@ -1,76 +1,212 @@
 package audio
 
-// Resample8kTo16k resamples 8kHz PCM16 audio to 16kHz using linear interpolation
-// Input: 16-bit signed little-endian PCM at 8kHz
-// Output: 16-bit signed little-endian PCM at 16kHz
-func Resample8kTo16k(pcm8k []byte) []byte {
-	if len(pcm8k) == 0 {
-		return nil
-	}
+import "math"
+
+// Resampler design parameters
+const (
+	resampleTaps   = 32   // Filter taps per output sample when upsampling; scaled up when decimating
+	resampleCutoff = 0.92 // Low-pass corner as a fraction of the lower Nyquist frequency
+	resampleBeta   = 8.0  // Kaiser window shape: ~80dB stopband
+)
 
-	// Convert bytes to int16 samples
-	samples8k := make([]int16, len(pcm8k)/2)
-	for i := 0; i < len(samples8k); i++ {
-		samples8k[i] = int16(pcm8k[i*2]) | int16(pcm8k[i*2+1])<<8
+// Resampler converts 16-bit mono PCM between sample rates with a polyphase windowed-sinc filter.
+// The ratio is reduced to L/M; the signal is conceptually upsampled by L, low-pass filtered below
+// the lower of the two Nyquist frequencies and decimated by M, computing only the outputs that are kept.
+// A Resampler keeps filter history between calls, so a stream can be fed in arbitrary frames
+// (e.g. 20ms media frames) without clicks at the boundaries. It is not safe for concurrent use.
+type Resampler struct {
+	up, down int         // L and M
+	taps     int         // Taps per polyphase branch
+	phases   [][]float64 // phases[p][k] = h[p + k*L], scaled so each branch has unity gain
+	history  []float64   // Last taps-1 input samples
+	pos      int         // Upsampled-domain position of the next output, relative to the start of history
+}
+
+// NewResampler creates a streaming resampler from fromRate to toRate
+// Equal or non-positive rates give a resampler that passes audio through unchanged
+func NewResampler(fromRate, toRate int) *Resampler {
+	if fromRate <= 0 || toRate <= 0 || fromRate == toRate {
+		return &Resampler{up: 1, down: 1}
 	}
 
-	// Resample: 8kHz -> 16kHz means 2x samples
-	// Simple linear interpolation: for each input sample, output 2 samples
-	// Sample 0: output sample 0
-	// Sample 1: interpolate between sample 0 and sample 1, then output sample 1
-	samples16k := make([]int16, len(samples8k)*2)
+	g := gcd(fromRate, toRate)
+	up, down := toRate/g, fromRate/g
 
-	for i := 0; i < len(samples8k); i++ {
-		// First sample: direct copy
-		samples16k[i*2] = samples8k[i]
+	// Decimation narrows the passband relative to the input, so the filter needs proportionally more taps
+	taps := resampleTaps
+	if down > up {
+		taps = resampleTaps * ((down + up - 1) / up)
+	}
 
-		// Second sample: interpolate with next sample (or repeat if last)
-		if i < len(samples8k)-1 {
-			// Linear interpolation: average of current and next
-			samples16k[i*2+1] = int16((int32(samples8k[i]) + int32(samples8k[i+1])) / 2)
-		} else {
-			// Last sample: repeat
-			samples16k[i*2+1] = samples8k[i]
+	// Prototype low-pass at the upsampled rate, cutoff in cycles per upsampled sample
+	n := taps * up
+	cutoff := resampleCutoff * 0.5 / float64(max(up, down))
+	center := float64(n-1) / 2
+	proto := make([]float64, n)
+	for i := range proto {
+		x := float64(i) - center
+		proto[i] = 2 * cutoff * sinc(2*cutoff*x) * kaiser(x, center, resampleBeta)
+	}
+
+	phases := make([][]float64, up)
+	for p := range phases {
+		branch := make([]float64, taps)
+		sum := 0.0
+		for k := range branch {
+			branch[k] = proto[p+k*up]
+			sum += branch[k]
+		}
+		// Normalise each branch so DC passes at unity gain regardless of phase
+		for k := range branch {
+			branch[k] /= sum
 		}
+		phases[p] = branch
 	}
 
-	// Convert back to bytes (little-endian)
-	result := make([]byte, len(samples16k)*2)
-	for i, sample := range samples16k {
-		result[i*2] = byte(sample & 0xFF)
-		result[i*2+1] = byte((sample >> 8) & 0xFF)
+	return &Resampler{
+		up:      up,
+		down:    down,
+		taps:    taps,
+		phases:  phases,
+		history: make([]float64, taps-1),
+		pos:     (taps - 1) * up,
 	}
+}
 
-	return result
+// Process resamples the next chunk of a stream
+// Input and output: 16-bit signed little-endian mono PCM
+func (r *Resampler) Process(pcm []byte) []byte {
+	if r.phases == nil {
+		return pcm[:len(pcm)&^1]
+	}
+
+	in := make([]float64, len(r.history), len(r.history)+len(pcm)/2)
+	copy(in, r.history)
+	for i := 0; i+1 < len(pcm); i += 2 {
+		in = append(in, float64(int16(pcm[i])|int16(pcm[i+1])<<8))
+	}
+
+	out := r.filter(in)
+
+	// Keep the tail as history for the next chunk and rebase the output position onto it
+	consumed := len(in) - len(r.history)
+	copy(r.history, in[consumed:])
+	r.pos -= consumed * r.up
+
+	return floatsToPCM(out)
 }
 
-// Resample16kTo8k resamples 16kHz PCM16 audio to 8kHz by decimation
-// Input: 16-bit signed little-endian PCM at 16kHz
-// Output: 16-bit signed little-endian PCM at 8kHz
-func Resample16kTo8k(pcm16k []byte) []byte {
-	if len(pcm16k) == 0 {
-		return nil
+// Reset clears the filter history, e.g. after a gap in the stream
+func (r *Resampler) Reset() {
+	for i := range r.history {
+		r.history[i] = 0
+	}
+	if r.phases != nil {
+		r.pos = (r.taps - 1) * r.up
 	}
+}
 
-	// Convert bytes to int16 samples
-	samples16k := make([]int16, len(pcm16k)/2)
-	for i := 0; i < len(samples16k); i++ {
-		samples16k[i] = int16(pcm16k[i*2]) | int16(pcm16k[i*2+1])<<8
+// filter computes every output whose filter window ends inside in
+func (r *Resampler) filter(in []float64) []float64 {
+	var out []float64
+	for ; r.pos/r.up < len(in); r.pos += r.down {
+		base := r.pos / r.up
+		branch := r.phases[r.pos%r.up]
+		acc := 0.0
+		for k, coef := range branch {
+			acc += coef * in[base-k]
+		}
+		out = append(out, acc)
 	}
+	return out
+}
 
-	// Decimate: take every other sample
-	samples8k := make([]int16, len(samples16k)/2)
-	for i := 0; i < len(samples8k); i++ {
-		samples8k[i] = samples16k[i*2]
+// Resample converts a complete PCM16 clip between sample rates
+// The filter delay is compensated, so the output is time-aligned with the input and
+// len(output) = len(input) * toRate / fromRate (rounded down to whole samples)
+// Input and output: 16-bit signed little-endian mono PCM
+func Resample(pcm []byte, fromRate, toRate int) []byte {
+	if len(pcm) < 2 || fromRate <= 0 || toRate <= 0 {
+		return nil
+	}
+	if fromRate == toRate {
+		return pcm
+	}
+
+	r := NewResampler(fromRate, toRate)
+	samples := len(pcm) / 2
+	want := samples * r.up / r.down
+
+	// Start at the filter's centre instead of its edge to remove the group delay,
+	// and pad the end with silence so the last samples are flushed out
+	r.pos += (r.taps*r.up - 1) / 2
+	in := make([]float64, len(r.history), len(r.history)+samples+r.taps)
+	for i := 0; i < samples; i++ {
+		in = append(in, float64(int16(pcm[i*2])|int16(pcm[i*2+1])<<8))
 	}
+	in = append(in, make([]float64, r.taps)...)
 
-	// Convert back to bytes (little-endian)
-	result := make([]byte, len(samples8k)*2)
-	for i, sample := range samples8k {
-		result[i*2] = byte(sample & 0xFF)
-		result[i*2+1] = byte((sample >> 8) & 0xFF)
+	out := r.filter(in)
+	if len(out) > want {
+		out = out[:want]
 	}
+	return floatsToPCM(out)
+}
 
+// floatsToPCM rounds and clips samples to 16-bit little-endian PCM
+func floatsToPCM(samples []float64) []byte {
+	result := make([]byte, len(samples)*2)
+	for i, v := range samples {
+		v = math.Round(v)
+		if v > math.MaxInt16 {
+			v = math.MaxInt16
+		} else if v < math.MinInt16 {
+			v = math.MinInt16
+		}
+		sample := int16(v)
+		result[i*2] = byte(sample)
+		result[i*2+1] = byte(sample >> 8)
+	}
 	return result
 }
 
+// sinc is the normalised sinc function sin(πx)/(πx)
+func sinc(x float64) float64 {
+	if x == 0 {
+		return 1
+	}
+	return math.Sin(math.Pi*x) / (math.Pi * x)
+}
+
+// kaiser evaluates a Kaiser window of half-width halfWidth at offset x from its centre
+func kaiser(x, halfWidth, beta float64) float64 {
+	if halfWidth == 0 {
+		return 1
+	}
+	ratio := x / halfWidth
+	if ratio*ratio > 1 {
+		return 0
+	}
+	return besselI0(beta*math.Sqrt(1-ratio*ratio)) / besselI0(beta)
+}
+
+// besselI0 is the zeroth-order modified Bessel function of the first kind, by power series
+func besselI0(x float64) float64 {
+	sum, term := 1.0, 1.0
+	for k := 1; k < 50; k++ {
+		term *= (x / (2 * float64(k))) * (x / (2 * float64(k)))
+		sum += term
+		if term < sum*1e-12 {
+			break
+		}
+	}
+	return sum
+}
+
+// gcd returns the greatest common divisor of a and b
+func gcd(a, b int) int {
+	for b != 0 {
+		a, b = b, a%b
+	}
+	return a
+}
@@ -0,0 +1,99 @@
+package audio
+
+import (
+	"bytes"
+	"math"
+	"testing"
+)
+
+// sine returns ms of a PCM16 sine at hz sampled at rate
+func sine(hz float64, rate, ms int, amplitude float64) []byte {
+	n := rate * ms / 1000
+	out := make([]byte, n*2)
+	for i := 0; i < n; i++ {
+		s := int16(amplitude * math.Sin(2*math.Pi*hz*float64(i)/float64(rate)))
+		out[i*2] = byte(s)
+		out[i*2+1] = byte(s >> 8)
+	}
+	return out
+}
+
+// levelDB returns the amplitude at hz relative to fullScale, in dB, ignoring the first and last 10ms
+func levelDB(pcm []byte, rate int, hz, fullScale float64) float64 {
+	edge := rate / 100
+	x := make([]float64, 0, len(pcm)/2)
+	for i := edge; i < len(pcm)/2-edge; i++ {
+		x = append(x, float64(int16(pcm[i*2])|int16(pcm[i*2+1])<<8))
+	}
+	// A sine of amplitude A over N samples gives Goertzel power (A*N/2)^2
+	amplitude := 2 * math.Sqrt(goertzelPower(x, hz, rate)) / float64(len(x))
+	return 20 * math.Log10(amplitude/fullScale+1e-12)
+}
+
+func TestResample_FrequencyResponse(t *testing.T) {
+	const amplitude = 10000
+	tests := []struct {
+		name     string
+		from, to int
+		hz       float64 // Input tone
+		measure  float64 // Where the tone (or its alias/image) lands in the output
+		minDB    float64
+		maxDB    float64
+	}{
+		{"8k→16k passband", 8000, 16000, 1000, 1000, -0.5, 0.5},
+		{"8k→16k image", 8000, 16000, 1000, 7000, -300, -50},
+		{"16k→8k passband", 16000, 8000, 3000, 3000, -0.5, 0.5},
+		{"16k→8k alias", 16000, 8000, 6000, 2000, -300, -50},
+		{"24k→16k passband", 24000, 16000, 5000, 5000, -0.5, 0.5},
+		{"24k→16k alias", 24000, 16000, 10000, 6000, -300, -50},
+		{"16k→24k image", 16000, 24000, 2000, 10000, -300, -50},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			out := Resample(sine(tt.hz, tt.from, 500, amplitude), tt.from, tt.to)
+			if want := tt.to / 2; len(out)/2 != want {
+				t.Fatalf("got %d samples, want %d", len(out)/2, want)
+			}
+			if db := levelDB(out, tt.to, tt.measure, amplitude); db < tt.minDB || db > tt.maxDB {
+				t.Errorf("level at %.0fHz = %.1fdB, want [%.1f, %.1f]", tt.measure, db, tt.minDB, tt.maxDB)
+			}
+		})
+	}
+}
+
+func TestResample_TimeAligned(t *testing.T) {
+	// A click in the middle of the clip stays in the middle after conversion
+	in := make([]byte, 1600*2)
+	in[800*2], in[800*2+1] = 0x10, 0x27 // 10000
+
+	out := Resample(in, 8000, 16000)
+	peak, peakAt := int16(0), 0
+	for i := 0; i < len(out)/2; i++ {
+		if s := int16(out[i*2]) | int16(out[i*2+1])<<8; s > peak {
+			peak, peakAt = s, i
+		}
+	}
+	if peakAt < 1599 || peakAt > 1601 {
+		t.Errorf("peak at sample %d, want 1600", peakAt)
+	}
+}
+
+func TestResampler_StreamingMatchesWholeClip(t *testing.T) {
+	in := sine(440, 8000, 200, 8000)
+
+	whole := NewResampler(8000, 16000).Process(in)
+
+	stream := NewResampler(8000, 16000)
+	var framed []byte
+	for i := 0; i < len(in); i += 160 * 2 { // 20ms at 8kHz
+		framed = append(framed, stream.Process(in[i:i+320])...)
+	}
+
+	if !bytes.Equal(whole, framed) {
+		t.Error("streaming output differs from processing the clip in one call")
+	}
+	if len(framed) != len(in)*2 {
+		t.Errorf("got %d bytes, want %d", len(framed), len(in)*2)
+	}
+}
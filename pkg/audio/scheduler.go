@@ -0,0 +1,121 @@
+package audio
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// OutboundScheduler paces outbound audio frames at real-time rate.
+// Frames may run at most Lead ahead of what the far end has played, so audio that has not
+// been sent yet can still be cancelled, and Position reports what the listener has heard.
+// It is safe for concurrent use.
+type OutboundScheduler struct {
+	mu            sync.Mutex
+	lead          time.Duration
+	sent          time.Duration // Total audio released since creation
+	bufferedUntil time.Time     // Wall-clock time at which all released audio will have played
+}
+
+// NewOutboundScheduler creates a scheduler allowing lead of audio to be buffered at the far end
+func NewOutboundScheduler(lead time.Duration) *OutboundScheduler {
+	if lead < 0 {
+		lead = 0
+	}
+	return &OutboundScheduler{
+		lead: lead,
+	}
+}
+
+// Lead returns how far ahead of playback frames may be released
+func (s *OutboundScheduler) Lead() time.Duration {
+	return s.lead
+}
+
+// Next blocks until a frame of length d may be sent, then accounts for it.
+// It returns ctx.Err() if ctx is cancelled first, in which case the frame must not be sent.
+func (s *OutboundScheduler) Next(ctx context.Context, d time.Duration) error {
+	for {
+		s.mu.Lock()
+		now := time.Now()
+		if s.bufferedUntil.Before(now) {
+			// Nothing queued at the far end - playback (re)starts now, gaps are silence
+			s.bufferedUntil = now
+		}
+		wait := s.bufferedUntil.Sub(now) - s.lead
+		if wait <= 0 {
+			s.bufferedUntil = s.bufferedUntil.Add(d)
+			s.sent += d
+			s.mu.Unlock()
+			return nil
+		}
+		s.mu.Unlock()
+
+		timer := time.NewTimer(wait)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return ctx.Err()
+		case <-timer.C:
+		}
+	}
+}
+
+// Sent returns the total audio released so far. Use it to mark where a clip starts.
+func (s *OutboundScheduler) Sent() time.Duration {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.sent
+}
+
+// Buffered returns the audio released but not yet played
+func (s *OutboundScheduler) Buffered() time.Duration {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.bufferedLocked()
+}
+
+// Position returns how much released audio has been played, on the same timeline as Sent
+func (s *OutboundScheduler) Position() time.Duration {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.sent - s.bufferedLocked()
+}
+
+// Flush discards audio buffered at the far end (after a clear event) and returns the playback position.
+// Audio released afterwards starts playing immediately.
+func (s *OutboundScheduler) Flush() time.Duration {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	// Dropped audio was never heard, so take it off the timeline; clips that started
+	// after the new Sent value were not heard at all
+	s.sent -= s.bufferedLocked()
+	s.bufferedUntil = time.Now()
+	return s.sent
+}
+
+// WaitDrained blocks until all released audio has played or ctx is cancelled
+func (s *OutboundScheduler) WaitDrained(ctx context.Context) error {
+	for {
+		remaining := s.Buffered()
+		if remaining <= 0 {
+			return nil
+		}
+		timer := time.NewTimer(remaining)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return ctx.Err()
+		case <-timer.C:
+		}
+	}
+}
+
+// bufferedLocked returns unplayed audio; callers must hold s.mu
+func (s *OutboundScheduler) bufferedLocked() time.Duration {
+	buffered := s.bufferedUntil.Sub(time.Now())
+	if buffered < 0 {
+		return 0
+	}
+	return buffered
+}
@@ -0,0 +1,161 @@
+package audio
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+// schedulerSlack absorbs timer and scheduling jitter in the real-time assertions below
+const schedulerSlack = 40 * time.Millisecond
+
+// within reports whether got is in [want-slack, want+slack]
+func within(got, want time.Duration) bool {
+	return got >= want-schedulerSlack && got <= want+schedulerSlack
+}
+
+// release sends n frames of d through the scheduler
+func release(t *testing.T, s *OutboundScheduler, n int, d time.Duration) {
+	t.Helper()
+	for i := 0; i < n; i++ {
+		if err := s.Next(context.Background(), d); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+func TestOutboundScheduler_Pacing(t *testing.T) {
+	s := NewOutboundScheduler(0)
+	frame := 10 * time.Millisecond
+
+	// Without lead each frame waits for the previous one to play out
+	start := time.Now()
+	release(t, s, 10, frame)
+	if elapsed := time.Since(start); !within(elapsed, 90*time.Millisecond) {
+		t.Errorf("10 frames of 10ms released over %v, want ~90ms", elapsed)
+	}
+	if sent := s.Sent(); sent != 100*time.Millisecond {
+		t.Errorf("Sent = %v, want 100ms", sent)
+	}
+}
+
+func TestOutboundScheduler_LeadBound(t *testing.T) {
+	lead := 60 * time.Millisecond
+	frame := 10 * time.Millisecond
+	s := NewOutboundScheduler(lead)
+	if s.Lead() != lead {
+		t.Fatalf("Lead = %v", s.Lead())
+	}
+
+	// Up to lead is released straight away
+	start := time.Now()
+	release(t, s, 7, frame)
+	if elapsed := time.Since(start); elapsed > schedulerSlack {
+		t.Errorf("frames within the lead took %v to release", elapsed)
+	}
+
+	// From then on the far end never holds more than lead plus the frame in flight
+	for i := 0; i < 10; i++ {
+		release(t, s, 1, frame)
+		if buffered := s.Buffered(); buffered > lead+frame {
+			t.Fatalf("Buffered = %v, want at most %v", buffered, lead+frame)
+		}
+	}
+	if elapsed := time.Since(start); !within(elapsed, 170*time.Millisecond-lead-frame) {
+		t.Errorf("17 frames released over %v, want ~%v", elapsed, 170*time.Millisecond-lead-frame)
+	}
+
+	if NewOutboundScheduler(-time.Second).Lead() != 0 {
+		t.Error("negative lead not clamped to zero")
+	}
+}
+
+func TestOutboundScheduler_Position(t *testing.T) {
+	s := NewOutboundScheduler(time.Second)
+	release(t, s, 5, 20*time.Millisecond)
+
+	if sent, buffered, position := s.Sent(), s.Buffered(), s.Position(); sent != 100*time.Millisecond ||
+		!within(buffered, 100*time.Millisecond) || !within(position, 0) {
+		t.Errorf("after release: Sent %v, Buffered %v, Position %v", sent, buffered, position)
+	}
+
+	time.Sleep(50 * time.Millisecond)
+	if position := s.Position(); !within(position, 50*time.Millisecond) {
+		t.Errorf("Position = %v, want ~50ms", position)
+	}
+
+	time.Sleep(100 * time.Millisecond)
+	if buffered, position := s.Buffered(), s.Position(); buffered != 0 || position != 100*time.Millisecond {
+		t.Errorf("after playback: Buffered %v, Position %v", buffered, position)
+	}
+
+	// A gap is silence: the next frame starts playing when it is released
+	release(t, s, 1, 20*time.Millisecond)
+	if sent, position := s.Sent(), s.Position(); sent != 120*time.Millisecond || !within(position, 100*time.Millisecond) {
+		t.Errorf("after a gap: Sent %v, Position %v", sent, position)
+	}
+}
+
+func TestOutboundScheduler_Flush(t *testing.T) {
+	s := NewOutboundScheduler(time.Second)
+	release(t, s, 10, 20*time.Millisecond)
+	time.Sleep(50 * time.Millisecond)
+
+	// Audio dropped at the far end comes off the timeline
+	position := s.Flush()
+	if !within(position, 50*time.Millisecond) || s.Sent() != position || s.Buffered() != 0 {
+		t.Errorf("Flush = %v, Sent %v, Buffered %v", position, s.Sent(), s.Buffered())
+	}
+
+	// The next clip starts at the flushed position and plays immediately
+	start := time.Now()
+	release(t, s, 1, 20*time.Millisecond)
+	if elapsed := time.Since(start); elapsed > schedulerSlack {
+		t.Errorf("frame after Flush took %v to release", elapsed)
+	}
+	if sent := s.Sent(); sent != position+20*time.Millisecond {
+		t.Errorf("Sent = %v, want %v", sent, position+20*time.Millisecond)
+	}
+}
+
+func TestOutboundScheduler_Cancel(t *testing.T) {
+	s := NewOutboundScheduler(0)
+	release(t, s, 1, 200*time.Millisecond)
+
+	// A cancelled wait does not account for the frame
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	if err := s.Next(ctx, 20*time.Millisecond); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("Next = %v, want deadline exceeded", err)
+	}
+	if sent := s.Sent(); sent != 200*time.Millisecond {
+		t.Errorf("Sent = %v after a cancelled frame", sent)
+	}
+
+	ctx, cancel = context.WithCancel(context.Background())
+	cancel()
+	if err := s.WaitDrained(ctx); !errors.Is(err, context.Canceled) {
+		t.Errorf("WaitDrained = %v, want canceled", err)
+	}
+}
+
+func TestOutboundScheduler_WaitDrained(t *testing.T) {
+	s := NewOutboundScheduler(time.Second)
+
+	start := time.Now()
+	if err := s.WaitDrained(context.Background()); err != nil || time.Since(start) > schedulerSlack {
+		t.Fatalf("WaitDrained on an idle scheduler = %v after %v", err, time.Since(start))
+	}
+
+	release(t, s, 4, 20*time.Millisecond)
+	if err := s.WaitDrained(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if elapsed := time.Since(start); !within(elapsed, 80*time.Millisecond) {
+		t.Errorf("WaitDrained returned after %v, want ~80ms", elapsed)
+	}
+	if s.Buffered() != 0 || s.Position() != s.Sent() {
+		t.Errorf("after drain: Buffered %v, Position %v, Sent %v", s.Buffered(), s.Position(), s.Sent())
+	}
+}
@@ -0,0 +1,240 @@
+package audio
+
+import "math"
+
+// VADEvent is emitted by VAD.Process when the speech state changes
+type VADEvent int
+
+const (
+	// VADSpeechStart fires once speech has lasted SpeechStartMs
+	VADSpeechStart VADEvent = iota + 1
+	// VADSpeechEnd fires after HangoverMs of silence following an utterance of at least MinUtteranceMs
+	VADSpeechEnd
+	// VADSpeechDiscarded fires when speech stops before reaching MinUtteranceMs (coughs, clicks, line noise)
+	VADSpeechDiscarded
+)
+
+// VADConfig holds voice-activity-detection thresholds
+type VADConfig struct {
+	FrameMs          int     // Analysis frame length in ms (default 20)
+	EnergyThreshold  float64 // Minimum frame RMS (PCM16 scale, 0-32767) to count as speech
+	MaxZeroCrossRate float64 // Frames with more zero crossings per sample than this are treated as noise
+	SpeechStartMs    int     // Consecutive speech required before an utterance starts
+	HangoverMs       int     // Silence after speech before the utterance is considered finished
+	MinUtteranceMs   int     // Utterances shorter than this are discarded
+}
+
+// DefaultVADConfig returns thresholds tuned for 8kHz telephony audio upsampled to 16kHz
+func DefaultVADConfig() VADConfig {
+	return VADConfig{
+		FrameMs:          20,
+		EnergyThreshold:  400,
+		MaxZeroCrossRate: 0.35,
+		SpeechStartMs:    100,
+		HangoverMs:       700,
+		MinUtteranceMs:   250,
+	}
+}
+
+// vadState tracks where the detector is within an utterance
+type vadState int
+
+const (
+	vadIdle     vadState = iota // No speech
+	vadStarting                 // Speech seen, waiting for SpeechStartMs
+	vadSpeaking                 // Utterance in progress
+	vadTrailing                 // Silence after speech, waiting for HangoverMs
+)
+
+// VAD is an energy plus zero-crossing voice activity detector for PCM16 mono audio.
+// It is not safe for concurrent use.
+type VAD struct {
+	cfg        VADConfig
+	frameBytes int
+	pending    []byte
+
+	state      vadState
+	speechMs   int // Speech accumulated in the current utterance
+	runMs      int // Length of the current speech or silence run
+	noiseFloor float64
+}
+
+// NewVAD creates a detector for PCM16 audio at the given sample rate.
+// Zero fields in cfg fall back to DefaultVADConfig values.
+func NewVAD(cfg VADConfig, sampleRate int) *VAD {
+	def := DefaultVADConfig()
+	if cfg.FrameMs <= 0 {
+		cfg.FrameMs = def.FrameMs
+	}
+	if cfg.EnergyThreshold <= 0 {
+		cfg.EnergyThreshold = def.EnergyThreshold
+	}
+	if cfg.MaxZeroCrossRate <= 0 {
+		cfg.MaxZeroCrossRate = def.MaxZeroCrossRate
+	}
+	if cfg.SpeechStartMs <= 0 {
+		cfg.SpeechStartMs = def.SpeechStartMs
+	}
+	if cfg.HangoverMs <= 0 {
+		cfg.HangoverMs = def.HangoverMs
+	}
+	if cfg.MinUtteranceMs <= 0 {
+		cfg.MinUtteranceMs = def.MinUtteranceMs
+	}
+	if sampleRate <= 0 {
+		sampleRate = 16000
+	}
+
+	return &VAD{
+		cfg:        cfg,
+		frameBytes: sampleRate * cfg.FrameMs / 1000 * 2,
+	}
+}
+
+// Config returns the effective thresholds
+func (v *VAD) Config() VADConfig {
+	return v.cfg
+}
+
+// Process feeds PCM16 audio of any length and returns the state changes it caused, in order
+func (v *VAD) Process(pcm []byte) []VADEvent {
+	var events []VADEvent
+
+	v.pending = append(v.pending, pcm...)
+	for len(v.pending) >= v.frameBytes {
+		frame := v.pending[:v.frameBytes]
+		if ev := v.processFrame(frame); ev != 0 {
+			events = append(events, ev)
+		}
+		v.pending = v.pending[v.frameBytes:]
+	}
+
+	// Keep the remainder without holding on to the caller's backing arrays
+	if len(v.pending) > 0 {
+		v.pending = append([]byte(nil), v.pending...)
+	} else {
+		v.pending = v.pending[:0]
+	}
+
+	return events
+}
+
+// InUtterance reports whether speech has been detected and the utterance has not yet ended.
+// It is true from the first candidate speech frame so callers can keep pre-roll audio.
+func (v *VAD) InUtterance() bool {
+	return v.state != vadIdle
+}
+
+// Speaking reports whether a confirmed utterance is in progress
+func (v *VAD) Speaking() bool {
+	return v.state == vadSpeaking || v.state == vadTrailing
+}
+
+// Reset returns the detector to idle, keeping the learned noise floor
+func (v *VAD) Reset() {
+	v.state = vadIdle
+	v.speechMs = 0
+	v.runMs = 0
+	v.pending = v.pending[:0]
+}
+
+// processFrame classifies a single frame and advances the state machine
+func (v *VAD) processFrame(frame []byte) VADEvent {
+	frameMs := v.cfg.FrameMs
+	isSpeech := v.isSpeech(frame)
+
+	switch v.state {
+	case vadIdle:
+		if isSpeech {
+			v.state = vadStarting
+			v.speechMs = frameMs
+			v.runMs = frameMs
+		}
+
+	case vadStarting:
+		if !isSpeech {
+			// Blip shorter than SpeechStartMs - not worth reporting
+			v.state = vadIdle
+			v.speechMs = 0
+			v.runMs = 0
+			return 0
+		}
+		v.speechMs += frameMs
+		v.runMs += frameMs
+		if v.runMs >= v.cfg.SpeechStartMs {
+			v.state = vadSpeaking
+			return VADSpeechStart
+		}
+
+	case vadSpeaking:
+		if isSpeech {
+			v.speechMs += frameMs
+		} else {
+			v.state = vadTrailing
+			v.runMs = frameMs
+		}
+
+	case vadTrailing:
+		if isSpeech {
+			v.state = vadSpeaking
+			v.speechMs += frameMs
+			v.runMs = 0
+			return 0
+		}
+		v.runMs += frameMs
+		if v.runMs >= v.cfg.HangoverMs {
+			speechMs := v.speechMs
+			v.state = vadIdle
+			v.speechMs = 0
+			v.runMs = 0
+			if speechMs < v.cfg.MinUtteranceMs {
+				return VADSpeechDiscarded
+			}
+			return VADSpeechEnd
+		}
+	}
+
+	return 0
+}
+
+// isSpeech decides whether a frame contains voice using RMS energy and zero-crossing rate.
+// The energy threshold adapts upwards to the line's noise floor.
+func (v *VAD) isSpeech(frame []byte) bool {
+	samples := len(frame) / 2
+	if samples == 0 {
+		return false
+	}
+
+	var sumSquares float64
+	crossings := 0
+	prev := int16(0)
+	for i := 0; i < samples; i++ {
+		sample := int16(frame[i*2]) | int16(frame[i*2+1])<<8
+		sumSquares += float64(sample) * float64(sample)
+		if i > 0 && (sample >= 0) != (prev >= 0) {
+			crossings++
+		}
+		prev = sample
+	}
+
+	rms := math.Sqrt(sumSquares / float64(samples))
+	zcr := float64(crossings) / float64(samples)
+
+	threshold := math.Max(v.cfg.EnergyThreshold, v.noiseFloor*3)
+	speech := rms >= threshold && zcr <= v.cfg.MaxZeroCrossRate
+	// Loud fricatives ("s", "sh") have a high crossing rate but are still speech
+	if !speech && rms >= threshold*3 {
+		speech = true
+	}
+
+	// Track the background level on non-speech frames only
+	if !speech {
+		if v.noiseFloor == 0 {
+			v.noiseFloor = rms
+		} else {
+			v.noiseFloor = v.noiseFloor*0.95 + rms*0.05
+		}
+	}
+
+	return speech
+}
@@ -0,0 +1,175 @@
+package audio
+
+import (
+	"math/rand"
+	"reflect"
+	"testing"
+)
+
+// vadEventAt is a VAD event and the audio time (ms from the start) of the frame that produced it
+type vadEventAt struct {
+	Event VADEvent
+	AtMs  int
+}
+
+// runVAD feeds the chunks in 20ms frames and returns the events with their timing
+func runVAD(vad *VAD, chunks ...[]byte) []vadEventAt {
+	var events []vadEventAt
+	elapsedMs := 0
+	for _, c := range chunks {
+		for len(c) > 0 {
+			n := 640
+			if n > len(c) {
+				n = len(c)
+			}
+			elapsedMs += n / 32
+			for _, ev := range vad.Process(c[:n]) {
+				events = append(events, vadEventAt{ev, elapsedMs})
+			}
+			c = c[n:]
+		}
+	}
+	return events
+}
+
+// noise returns ms of uniform white noise within ±amplitude (high zero-crossing rate)
+func noise(ms int, amplitude float64, rng *rand.Rand) []byte {
+	n := 16000 * ms / 1000
+	out := make([]byte, n*2)
+	for i := 0; i < n; i++ {
+		s := int16((rng.Float64()*2 - 1) * amplitude)
+		out[i*2] = byte(s)
+		out[i*2+1] = byte(s >> 8)
+	}
+	return out
+}
+
+func TestVAD_UtteranceStartAndEnd(t *testing.T) {
+	vad := NewVAD(VADConfig{}, 16000)
+
+	if events := runVAD(vad, silence(500)); len(events) != 0 || vad.InUtterance() {
+		t.Fatalf("silence: events %v, in utterance %v", events, vad.InUtterance())
+	}
+
+	// Speech is a candidate from the first frame and confirmed after SpeechStartMs (100ms)
+	events := runVAD(vad, tone(200, 40, 3000))
+	if len(events) != 0 || !vad.InUtterance() || vad.Speaking() {
+		t.Fatalf("starting: events %v, in utterance %v, speaking %v", events, vad.InUtterance(), vad.Speaking())
+	}
+	events = runVAD(vad, tone(200, 960, 3000))
+	if want := []vadEventAt{{VADSpeechStart, 60}}; !reflect.DeepEqual(events, want) || !vad.Speaking() {
+		t.Fatalf("speaking: events %v, want %v", events, want)
+	}
+
+	// Trailing silence keeps the utterance open until HangoverMs (700ms) has passed
+	if events := runVAD(vad, silence(680)); len(events) != 0 || !vad.Speaking() {
+		t.Fatalf("trailing: events %v, speaking %v", events, vad.Speaking())
+	}
+	events = runVAD(vad, silence(100))
+	if want := []vadEventAt{{VADSpeechEnd, 20}}; !reflect.DeepEqual(events, want) {
+		t.Fatalf("end: events %v, want %v", events, want)
+	}
+	if vad.InUtterance() || vad.Speaking() {
+		t.Error("detector not idle after the utterance ended")
+	}
+}
+
+func TestVAD_Timeline(t *testing.T) {
+	rng := rand.New(rand.NewSource(1))
+
+	tests := []struct {
+		name   string
+		chunks [][]byte
+		want   []vadEventAt
+	}{
+		{
+			"pause shorter than the hangover",
+			[][]byte{silence(200), tone(200, 400, 3000), silence(500), tone(200, 400, 3000), silence(800)},
+			[]vadEventAt{{VADSpeechStart, 300}, {VADSpeechEnd, 2200}},
+		},
+		{
+			"two utterances",
+			[][]byte{tone(200, 400, 3000), silence(800), tone(200, 400, 3000), silence(800)},
+			[]vadEventAt{{VADSpeechStart, 100}, {VADSpeechEnd, 1100}, {VADSpeechStart, 1300}, {VADSpeechEnd, 2300}},
+		},
+		{
+			"short burst is discarded",
+			[][]byte{silence(200), tone(200, 160, 3000), silence(1000)},
+			[]vadEventAt{{VADSpeechStart, 300}, {VADSpeechDiscarded, 1060}},
+		},
+		{
+			"blip shorter than the speech start is ignored",
+			[][]byte{silence(200), tone(200, 60, 3000), silence(1000)},
+			nil,
+		},
+		{
+			"quiet hum stays below the threshold",
+			[][]byte{tone(200, 1000, 400)},
+			nil,
+		},
+		{
+			"noise bursts are rejected by the zero-crossing rate",
+			[][]byte{noise(300, 1000, rng), silence(300), noise(300, 1000, rng), silence(300)},
+			nil,
+		},
+		{
+			"loud fricative overrides the zero-crossing rate",
+			[][]byte{noise(400, 6000, rng), silence(800)},
+			[]vadEventAt{{VADSpeechStart, 100}, {VADSpeechEnd, 1100}},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := runVAD(NewVAD(VADConfig{}, 16000), tt.chunks...); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("events = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestVAD_AdaptiveNoiseFloor(t *testing.T) {
+	// rms ≈ 600: above the 400 threshold on a quiet line
+	speech := tone(200, 400, 850)
+
+	quiet := NewVAD(VADConfig{}, 16000)
+	if got := runVAD(quiet, silence(1000), speech); len(got) != 1 || got[0].Event != VADSpeechStart {
+		t.Fatalf("quiet line: events %v, want a speech start", got)
+	}
+
+	// A steady rms ≈ 300 hum raises the threshold to 3x the floor, so the same level is background
+	noisy := NewVAD(VADConfig{}, 16000)
+	if got := runVAD(noisy, tone(200, 1000, 424), speech); len(got) != 0 {
+		t.Errorf("noisy line: events %v, want none", got)
+	}
+
+	// Louder than 3x the floor is still speech; Reset keeps the learned floor
+	noisy.Reset()
+	if got := runVAD(noisy, speech); len(got) != 0 {
+		t.Errorf("after reset: events %v, want none", got)
+	}
+	if got := runVAD(noisy, tone(200, 400, 3000)); len(got) != 1 || got[0].Event != VADSpeechStart {
+		t.Errorf("loud speech on a noisy line: events %v, want a speech start", got)
+	}
+}
+
+func TestVAD_ConfigAndChunking(t *testing.T) {
+	vad := NewVAD(VADConfig{SpeechStartMs: 40, HangoverMs: 200, MinUtteranceMs: 100}, 16000)
+	if cfg := vad.Config(); cfg.FrameMs != 20 || cfg.EnergyThreshold != 400 || cfg.HangoverMs != 200 {
+		t.Errorf("config = %+v, want defaults for zero fields", cfg)
+	}
+
+	// Odd-sized chunks are framed the same as whole frames
+	pcm := append(append(silence(100), tone(200, 300, 3000)...), silence(300)...)
+	var events []VADEvent
+	for len(pcm) > 0 {
+		n := 333
+		if n > len(pcm) {
+			n = len(pcm)
+		}
+		events = append(events, vad.Process(pcm[:n])...)
+		pcm = pcm[n:]
+	}
+	if want := []VADEvent{VADSpeechStart, VADSpeechEnd}; !reflect.DeepEqual(events, want) {
+		t.Errorf("events = %v, want %v", events, want)
+	}
+}
@@ -0,0 +1,139 @@
+package audio
+
+import (
+	"encoding/binary"
+	"fmt"
+	"math"
+)
+
+// PCMToWAV wraps raw 16-bit mono PCM in a 44-byte WAV (RIFF) header
+func PCMToWAV(pcm []byte, sampleRate int) []byte {
+	if sampleRate == 0 {
+		sampleRate = 16000 // Default 16kHz
+	}
+	return pcmToWAV(pcm, sampleRate, 1)
+}
+
+// pcmToWAV wraps interleaved 16-bit PCM with the given channel count in a WAV header
+func pcmToWAV(pcm []byte, sampleRate, channels int) []byte {
+	const bitsPerSample = 16
+
+	wav := make([]byte, 44+len(pcm))
+	copy(wav[0:4], "RIFF")
+	binary.LittleEndian.PutUint32(wav[4:8], uint32(36+len(pcm)))
+	copy(wav[8:12], "WAVE")
+	copy(wav[12:16], "fmt ")
+	binary.LittleEndian.PutUint32(wav[16:20], 16) // fmt chunk size
+	binary.LittleEndian.PutUint16(wav[20:22], 1)  // Audio format (1 = PCM)
+	binary.LittleEndian.PutUint16(wav[22:24], uint16(channels))
+	binary.LittleEndian.PutUint32(wav[24:28], uint32(sampleRate))
+	binary.LittleEndian.PutUint32(wav[28:32], uint32(sampleRate*channels*bitsPerSample/8)) // Byte rate
+	binary.LittleEndian.PutUint16(wav[32:34], uint16(channels*bitsPerSample/8))            // Block align
+	binary.LittleEndian.PutUint16(wav[34:36], bitsPerSample)
+	copy(wav[36:40], "data")
+	binary.LittleEndian.PutUint32(wav[40:44], uint32(len(pcm)))
+	copy(wav[44:], pcm)
+
+	return wav
+}
+
+// WAV format tags understood by DecodeWAV
+const (
+	wavFormatPCM        = 1
+	wavFormatFloat      = 3
+	wavFormatALaw       = 6
+	wavFormatMuLaw      = 7
+	wavFormatExtensible = 0xFFFE
+)
+
+// DecodeWAV decodes a RIFF/WAVE file to mono 16-bit PCM, returning its sample rate.
+// Integer PCM (8-32 bit), IEEE float, A-law and μ-law are supported; multichannel audio is averaged.
+func DecodeWAV(data []byte) ([]byte, int, error) {
+	if len(data) < 12 || string(data[0:4]) != "RIFF" || string(data[8:12]) != "WAVE" {
+		return nil, 0, fmt.Errorf("not a WAV file")
+	}
+
+	var (
+		formatTag, channels, blockAlign, bits int
+		sampleRate                            int
+		haveFormat                            bool
+		samples                               []byte
+	)
+	for pos := 12; pos+8 <= len(data); {
+		id := string(data[pos : pos+4])
+		size := int(binary.LittleEndian.Uint32(data[pos+4 : pos+8]))
+		body := data[pos+8:]
+		if size > len(body) || (id == "data" && size == 0) {
+			// Streaming writers leave the size as 0 or 0xFFFFFFFF; take what is there
+			size = len(body)
+		}
+		body = body[:size]
+
+		switch id {
+		case "fmt ":
+			if size < 16 {
+				return nil, 0, fmt.Errorf("WAV fmt chunk too short")
+			}
+			formatTag = int(binary.LittleEndian.Uint16(body[0:2]))
+			channels = int(binary.LittleEndian.Uint16(body[2:4]))
+			sampleRate = int(binary.LittleEndian.Uint32(body[4:8]))
+			blockAlign = int(binary.LittleEndian.Uint16(body[12:14]))
+			bits = int(binary.LittleEndian.Uint16(body[14:16]))
+			if formatTag == wavFormatExtensible && size >= 26 {
+				formatTag = int(binary.LittleEndian.Uint16(body[24:26])) // First two bytes of the subformat GUID
+			}
+			haveFormat = true
+		case "data":
+			samples = body
+		}
+		pos += 8 + size + size&1 // Chunks are word aligned
+	}
+
+	if !haveFormat {
+		return nil, 0, fmt.Errorf("WAV file has no fmt chunk")
+	}
+	if channels <= 0 || sampleRate <= 0 {
+		return nil, 0, fmt.Errorf("invalid WAV format: %d channels at %d Hz", channels, sampleRate)
+	}
+
+	bytesPerSample := (bits + 7) / 8
+	var readSample func(b []byte) float64
+	switch {
+	case formatTag == wavFormatPCM && bits == 8:
+		readSample = func(b []byte) float64 { return (float64(b[0]) - 128) / 128 }
+	case formatTag == wavFormatPCM && bits == 16:
+		readSample = func(b []byte) float64 { return float64(int16(binary.LittleEndian.Uint16(b))) / 32768 }
+	case formatTag == wavFormatPCM && bits == 24:
+		readSample = func(b []byte) float64 {
+			v := int32(b[0]) | int32(b[1])<<8 | int32(int8(b[2]))<<16
+			return float64(v) / (1 << 23)
+		}
+	case formatTag == wavFormatPCM && bits == 32:
+		readSample = func(b []byte) float64 { return float64(int32(binary.LittleEndian.Uint32(b))) / (1 << 31) }
+	case formatTag == wavFormatFloat && bits == 32:
+		readSample = func(b []byte) float64 { return float64(math.Float32frombits(binary.LittleEndian.Uint32(b))) }
+	case formatTag == wavFormatFloat && bits == 64:
+		readSample = func(b []byte) float64 { return math.Float64frombits(binary.LittleEndian.Uint64(b)) }
+	case formatTag == wavFormatALaw && bits == 8:
+		readSample = func(b []byte) float64 { return float64(aLawToLinear(b[0])) / 32768 }
+	case formatTag == wavFormatMuLaw && bits == 8:
+		readSample = func(b []byte) float64 { return float64(muLawToLinear(b[0])) / 32768 }
+	default:
+		return nil, 0, fmt.Errorf("%w: WAV format %d with %d-bit samples", ErrUnsupportedFormat, formatTag, bits)
+	}
+
+	if blockAlign < channels*bytesPerSample {
+		blockAlign = channels * bytesPerSample
+	}
+	frames := len(samples) / blockAlign
+	mono := make([]float64, frames)
+	for i := 0; i < frames; i++ {
+		frame := samples[i*blockAlign:]
+		var sum float64
+		for ch := 0; ch < channels; ch++ {
+			sum += readSample(frame[ch*bytesPerSample:])
+		}
+		mono[i] = sum / float64(channels) * 32768
+	}
+	return floatsToPCM(mono), sampleRate, nil
+}
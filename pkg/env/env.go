@@ -28,6 +28,13 @@ type Config struct {
 	AITimeoutMs int
 	FeatureAI   bool
 
+	// Retrieval over persona documents
+	RAGTopK            int    // Document passages added to the prompt per turn
+	RAGEmbeddings      bool   // Fuse keyword search with OpenAI embeddings (needs OPENAI_API_KEY)
+	RAGEmbeddingModel  string // OpenAI embedding model
+	PersonaCacheSize   int    // Persona contexts (persona, documents and chunk index) kept in memory
+	PersonaCacheTTLSec int    // Seconds a cached persona context is trusted without a local invalidation
+
 	// AI Provider API Keys
 	OpenAIApiKey    string
 	OpenAIModel     string
@@ -60,7 +67,18 @@ type Config struct {
 	ExotelVoicebotAppletID string
 	ExotelWebhookSecret    string
 	ExotelVoicebotToken    string // Bearer token for WebSocket authentication (optional)
+	ExotelAPIBaseURL       string // Overrides https://<subdomain>.exotel.com (e.g. a local fake API)
 	VoicebotBaseURL        string // Public WSS URL for Exotel (e.g., https://api.example.com)
+	VoicebotPlaybackLeadMs int    // How far outbound audio may run ahead of playback (barge-in latency)
+	TransferAgentNumber    string // Default agent number(s) for bot → human transfer, comma separated
+	TransferMode           string // "flow" (Exotel Connect applet after the voicebot) or "callback" (API call bridging agent and customer)
+	VoicebotAMDEnabled     bool   // Answering-machine detection on outbound voicebot calls (custom_parameters.amd_enabled overrides)
+	VoicebotAMDAction      string // On a machine: "hangup" or "voicemail" (custom_parameters.amd_action overrides)
+	VoicebotTTSProviders   string // TTS fallback order, comma separated: openai, elevenlabs, fake (custom_parameters.tts_provider picks the first)
+	VoicebotSTTProviders   string // STT fallback order, comma separated: deepgram, whisper, fake
+	VoicebotTTSCacheHours  int    // Lifetime of cached greeting/prompt audio in Redis (0 = no expiry, negative = cache off)
+	VoicebotRecording      bool   // Record caller and bot audio as a stereo WAV through the storage driver
+	VoicebotInstanceID     string // Names this instance in the Redis session registry (default: hostname plus a random suffix)
 
 	DialBusinessStartHour int
 	DialBusinessEndHour   int
@@ -114,6 +132,12 @@ func Load(envFile string) (*Config, error) {
 		AITimeoutMs: getEnvInt("AI_TIMEOUT_MS", 3500),
 		FeatureAI:   getEnvBool("FEATURE_AI", true),
 
+		RAGTopK:            getEnvInt("RAG_TOP_K", 4),
+		RAGEmbeddings:      getEnvBool("RAG_EMBEDDINGS_ENABLED", false),
+		RAGEmbeddingModel:  getEnv("RAG_EMBEDDING_MODEL", "text-embedding-3-small"),
+		PersonaCacheSize:   getEnvInt("PERSONA_CACHE_SIZE", 256),
+		PersonaCacheTTLSec: getEnvInt("PERSONA_CACHE_TTL_SEC", 600),
+
 		// AI Provider API Keys
 		OpenAIApiKey:    getEnv("OPENAI_API_KEY", ""),
 		OpenAIModel:     getEnv("OPENAI_MODEL", "gpt-4o-mini"),
@@ -146,7 +170,18 @@ func Load(envFile string) (*Config, error) {
 		ExotelVoicebotAppletID: getEnv("EXOTEL_VOICEBOT_APPLET_ID", ""),
 		ExotelWebhookSecret:    getEnv("EXOTEL_WEBHOOK_SIGNATURE_SECRET", ""),
 		ExotelVoicebotToken:    getEnv("EXOTEL_VOICEBOT_TOKEN", ""), // Bearer token for WebSocket auth (set in Exotel dashboard)
-		VoicebotBaseURL:        getEnv("VOICEBOT_BASE_URL", ""),     // Public HTTPS URL for WSS (e.g., https://api.example.com)
+		ExotelAPIBaseURL:       getEnv("EXOTEL_API_BASE_URL", ""),
+		VoicebotBaseURL:        getEnv("VOICEBOT_BASE_URL", ""), // Public HTTPS URL for WSS (e.g., https://api.example.com)
+		VoicebotPlaybackLeadMs: getEnvInt("VOICEBOT_PLAYBACK_LEAD_MS", 200),
+		TransferAgentNumber:    getEnv("TRANSFER_AGENT_NUMBER", ""),
+		TransferMode:           getEnv("TRANSFER_MODE", "flow"),
+		VoicebotAMDEnabled:     getEnvBool("VOICEBOT_AMD_ENABLED", false),
+		VoicebotAMDAction:      getEnv("VOICEBOT_AMD_ACTION", "hangup"),
+		VoicebotTTSProviders:   getEnv("VOICEBOT_TTS_PROVIDERS", "openai,elevenlabs"),
+		VoicebotSTTProviders:   getEnv("VOICEBOT_STT_PROVIDERS", "deepgram,whisper"),
+		VoicebotTTSCacheHours:  getEnvInt("VOICEBOT_TTS_CACHE_TTL_HOURS", 720),
+		VoicebotRecording:      getEnvBool("VOICEBOT_RECORDING_ENABLED", true),
+		VoicebotInstanceID:     getEnv("VOICEBOT_INSTANCE_ID", ""),
 
 		DialBusinessStartHour: getEnvInt("DIAL_BUSINESS_START_HOUR", 9),
 		DialBusinessEndHour:   getEnvInt("DIAL_BUSINESS_END_HOUR", 21),
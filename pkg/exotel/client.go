@@ -16,6 +16,7 @@ type Client struct {
 	accountSID string
 	apiKey     string
 	apiToken   string
+	baseURL    string
 	httpClient *http.Client
 }
 
@@ -28,15 +29,25 @@ func normalizeSubdomain(subdomain string) string {
 }
 
 func NewClient(subdomain, accountSID, apiKey, apiToken string) *Client {
+	subdomain = normalizeSubdomain(subdomain)
 	return &Client{
-		subdomain:  normalizeSubdomain(subdomain),
+		subdomain:  subdomain,
 		accountSID: accountSID,
 		apiKey:     apiKey,
 		apiToken:   apiToken,
+		baseURL:    fmt.Sprintf("https://%s.exotel.com", subdomain),
 		httpClient: &http.Client{Timeout: 30 * time.Second},
 	}
 }
 
+// WithBaseURL points the client at a different API host (e.g. a local fake Exotel API in tests)
+func (c *Client) WithBaseURL(baseURL string) *Client {
+	if baseURL != "" {
+		c.baseURL = strings.TrimSuffix(baseURL, "/")
+	}
+	return c
+}
+
 type ConnectCallRequest struct {
 	From        string
 	To          string
@@ -60,8 +71,8 @@ type ConnectCallResponse struct {
 }
 
 func (c *Client) ConnectCall(req ConnectCallRequest) (*ConnectCallResponse, error) {
-	endpoint := fmt.Sprintf("https://%s.exotel.com/v1/Accounts/%s/Calls/connect.json",
-		c.subdomain, c.accountSID)
+	endpoint := fmt.Sprintf("%s/v1/Accounts/%s/Calls/connect.json",
+		c.baseURL, c.accountSID)
 
 	data := url.Values{}
 
@@ -230,6 +241,28 @@ func (c *Client) ConnectCall(req ConnectCallRequest) (*ConnectCallResponse, erro
 	return &result, nil
 }
 
+// ConnectAgentRequest bridges a customer to a human agent with a plain two-leg call
+type ConnectAgentRequest struct {
+	AgentNumber    string // Dialled first so the agent is on the line (and has read the summary) before the customer
+	CustomerNumber string // Connected once the agent answers
+	CallerID       string // Exophone shown on both legs
+	CallbackURL    string // Optional status callback
+}
+
+// ConnectAgent places a warm-transfer call: Exotel rings the agent, then connects the customer
+func (c *Client) ConnectAgent(req ConnectAgentRequest) (*ConnectCallResponse, error) {
+	if req.AgentNumber == "" || req.CustomerNumber == "" {
+		return nil, fmt.Errorf("agent and customer numbers are required")
+	}
+	return c.ConnectCall(ConnectCallRequest{
+		From:        req.AgentNumber,
+		To:          req.CustomerNumber,
+		CallerID:    req.CallerID,
+		CallType:    "trans",
+		CallbackURL: req.CallbackURL,
+	})
+}
+
 type CreateCampaignRequest struct {
 	Name        string   `json:"name"`
 	ContentType string   `json:"content_type"`
@@ -253,8 +286,8 @@ type CreateCampaignResponse struct {
 }
 
 func (c *Client) CreateCampaign(req CreateCampaignRequest) (*CreateCampaignResponse, error) {
-	endpoint := fmt.Sprintf("https://%s.exotel.com/v2/accounts/%s/campaigns",
-		c.subdomain, c.accountSID)
+	endpoint := fmt.Sprintf("%s/v2/accounts/%s/campaigns",
+		c.baseURL, c.accountSID)
 
 	jsonData, err := json.Marshal(req)
 	if err != nil {
@@ -293,8 +326,8 @@ func (c *Client) CreateCampaign(req CreateCampaignRequest) (*CreateCampaignRespo
 }
 
 func (c *Client) PauseCampaign(campaignID string) error {
-	endpoint := fmt.Sprintf("https://%s.exotel.com/v2/accounts/%s/campaigns/%s/pause",
-		c.subdomain, c.accountSID, campaignID)
+	endpoint := fmt.Sprintf("%s/v2/accounts/%s/campaigns/%s/pause",
+		c.baseURL, c.accountSID, campaignID)
 
 	httpReq, err := http.NewRequest("POST", endpoint, nil)
 	if err != nil {
@@ -318,8 +351,8 @@ func (c *Client) PauseCampaign(campaignID string) error {
 }
 
 func (c *Client) ResumeCampaign(campaignID string) error {
-	endpoint := fmt.Sprintf("https://%s.exotel.com/v2/accounts/%s/campaigns/%s/resume",
-		c.subdomain, c.accountSID, campaignID)
+	endpoint := fmt.Sprintf("%s/v2/accounts/%s/campaigns/%s/resume",
+		c.baseURL, c.accountSID, campaignID)
 
 	httpReq, err := http.NewRequest("POST", endpoint, nil)
 	if err != nil {
@@ -343,8 +376,8 @@ func (c *Client) ResumeCampaign(campaignID string) error {
 }
 
 func (c *Client) CancelCampaign(campaignID string) error {
-	endpoint := fmt.Sprintf("https://%s.exotel.com/v2/accounts/%s/campaigns/%s/cancel",
-		c.subdomain, c.accountSID, campaignID)
+	endpoint := fmt.Sprintf("%s/v2/accounts/%s/campaigns/%s/cancel",
+		c.baseURL, c.accountSID, campaignID)
 
 	httpReq, err := http.NewRequest("POST", endpoint, nil)
 	if err != nil {
@@ -369,8 +402,8 @@ func (c *Client) CancelCampaign(campaignID string) error {
 
 // GetCallStatus gets the status of a call from Exotel API
 func (c *Client) GetCallStatus(callSID string) (*CallStatusResponse, error) {
-	endpoint := fmt.Sprintf("https://%s.exotel.com/v1/Accounts/%s/Calls/%s.json",
-		c.subdomain, c.accountSID, callSID)
+	endpoint := fmt.Sprintf("%s/v1/Accounts/%s/Calls/%s.json",
+		c.baseURL, c.accountSID, callSID)
 
 	httpReq, err := http.NewRequest("GET", endpoint, nil)
 	if err != nil {
@@ -0,0 +1,63 @@
+package exotel
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestClient_ConnectAgent(t *testing.T) {
+	var got *http.Request
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if err := r.ParseForm(); err != nil {
+			t.Fatalf("failed to parse form: %v", err)
+		}
+		got = r
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`{"Call":{"Sid":"bridge-123","Status":"in-progress","Direction":"outbound-api"}}`))
+	}))
+	defer server.Close()
+
+	client := NewClient("api", "acct-1", "key", "token").WithBaseURL(server.URL + "/")
+
+	resp, err := client.ConnectAgent(ConnectAgentRequest{
+		AgentNumber:    "09876543210",
+		CustomerNumber: "09123456789",
+		CallerID:       "07948516111",
+	})
+	if err != nil {
+		t.Fatalf("ConnectAgent() error = %v", err)
+	}
+	if resp.Call.Sid != "bridge-123" {
+		t.Errorf("Call.Sid = %q, want %q", resp.Call.Sid, "bridge-123")
+	}
+
+	if got.URL.Path != "/v1/Accounts/acct-1/Calls/connect.json" {
+		t.Errorf("path = %q", got.URL.Path)
+	}
+	if user, pass, ok := got.BasicAuth(); !ok || user != "key" || pass != "token" {
+		t.Errorf("basic auth = %q/%q (ok=%v)", user, pass, ok)
+	}
+
+	want := map[string]string{
+		"From":     "09876543210",
+		"To":       "09123456789",
+		"CallerId": "07948516111",
+		"CallType": "trans",
+	}
+	for field, value := range want {
+		if v := got.PostForm.Get(field); v != value {
+			t.Errorf("%s = %q, want %q", field, v, value)
+		}
+	}
+	if got.PostForm.Get("Url") != "" {
+		t.Errorf("agent bridge must not route through the voicebot applet, got Url=%q", got.PostForm.Get("Url"))
+	}
+}
+
+func TestClient_ConnectAgent_RequiresNumbers(t *testing.T) {
+	client := NewClient("api", "acct-1", "key", "token")
+	if _, err := client.ConnectAgent(ConnectAgentRequest{AgentNumber: "09876543210"}); err == nil {
+		t.Error("expected error when customer number is missing")
+	}
+}
@@ -0,0 +1,136 @@
+// Package extract pulls plain text out of uploaded documents (PDF, DOCX, XLSX, CSV, HTML, text)
+// together with a map of its pages or sections and the language it is written in
+package extract
+
+import (
+	"errors"
+	"fmt"
+	"path/filepath"
+	"strings"
+	"unicode/utf8"
+)
+
+// ErrUnsupported is returned for file types that have no extractor
+var ErrUnsupported = errors.New("unsupported document format")
+
+// Document is the text of a file and where its parts start
+type Document struct {
+	Text     string
+	Language string // BCP 47 tag ("en", "hi", "hi-Latn", ...) or "und" when unknown
+	Pages    int    // Page count for paged formats, 0 otherwise
+	Sections []Section
+}
+
+// Section is a page, heading, sheet or other named part of a document
+type Section struct {
+	Title  string
+	Page   int // 1-based page for paged formats, 0 otherwise
+	Offset int // Byte offset of the section in Document.Text
+}
+
+// Formats lists the file extensions Extract understands
+var Formats = []string{".pdf", ".docx", ".xlsx", ".csv", ".html", ".htm", ".txt", ".md"}
+
+// Supported reports whether files with the extension can be extracted
+func Supported(ext string) bool {
+	ext = strings.ToLower(ext)
+	for _, f := range Formats {
+		if f == ext {
+			return true
+		}
+	}
+	return false
+}
+
+// Extract returns the text of a file, choosing the extractor by the file name's extension
+func Extract(filename string, data []byte) (*Document, error) {
+	var (
+		doc *Document
+		err error
+	)
+	switch ext := strings.ToLower(filepath.Ext(filename)); ext {
+	case ".pdf":
+		doc, err = extractPDF(data)
+	case ".docx":
+		doc, err = extractDOCX(data)
+	case ".xlsx":
+		doc, err = extractXLSX(data)
+	case ".csv":
+		doc, err = extractCSV(data)
+	case ".html", ".htm":
+		doc, err = extractHTML(data)
+	case ".txt", ".md":
+		doc, err = extractPlain(data)
+	default:
+		return nil, fmt.Errorf("%w: %s", ErrUnsupported, ext)
+	}
+	if err != nil {
+		return nil, err
+	}
+	doc.Language = DetectLanguage(doc.Text)
+	return doc, nil
+}
+
+// extractPlain reads UTF-8 (or Latin-1) text
+func extractPlain(data []byte) (*Document, error) {
+	text := string(data)
+	if !utf8.ValidString(text) {
+		text = latin1(data)
+	}
+	var b builder
+	b.add("", 0, strings.TrimPrefix(text, "\ufeff"))
+	return b.document(0), nil
+}
+
+// latin1 decodes bytes as ISO-8859-1
+func latin1(data []byte) string {
+	runes := make([]rune, len(data))
+	for i, c := range data {
+		runes[i] = rune(c)
+	}
+	return string(runes)
+}
+
+// builder joins normalised parts of a document, recording where each section starts
+type builder struct {
+	text     strings.Builder
+	sections []Section
+}
+
+// add appends a part; a non-empty title or page starts a new section there.
+// Parts without text are dropped.
+func (b *builder) add(title string, page int, text string) {
+	text = Normalize(text)
+	if text == "" {
+		return
+	}
+	if b.text.Len() > 0 {
+		b.text.WriteString("\n\n")
+	}
+	if title != "" || page > 0 {
+		b.sections = append(b.sections, Section{Title: title, Page: page, Offset: b.text.Len()})
+	}
+	b.text.WriteString(text)
+}
+
+// document returns the assembled text
+func (b *builder) document(pages int) *Document {
+	return &Document{
+		Text:     b.text.String(),
+		Pages:    pages,
+		Sections: b.sections,
+	}
+}
+
+// SectionAt returns the section containing byte offset off, or false if the text starts before any section
+func (d *Document) SectionAt(off int) (Section, bool) {
+	var found Section
+	ok := false
+	for _, s := range d.Sections {
+		if s.Offset > off {
+			break
+		}
+		found, ok = s, true
+	}
+	return found, ok
+}
@@ -0,0 +1,221 @@
+package extract
+
+import (
+	"archive/zip"
+	"bytes"
+	"compress/zlib"
+	"fmt"
+	"strings"
+	"testing"
+)
+
+// zipFiles builds an in-memory zip archive
+func zipFiles(t *testing.T, files map[string]string) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	zw := zip.NewWriter(&buf)
+	for name, content := range files {
+		w, err := zw.Create(name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err := w.Write([]byte(content)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := zw.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+// buildPDF writes a PDF with one page per content stream, using a Type0 font with a ToUnicode CMap
+// for pages whose content starts with "/F2"
+func buildPDF(t *testing.T, contents ...string) []byte {
+	t.Helper()
+	deflate := func(s string) string {
+		var buf bytes.Buffer
+		zw := zlib.NewWriter(&buf)
+		zw.Write([]byte(s))
+		zw.Close()
+		return buf.String()
+	}
+	cmap := "/CIDInit /ProcSet findresource begin\n1 begincodespacerange <0000> <FFFF> endcodespacerange\n" +
+		"2 beginbfchar <0001> <0939> <0002> <0948> endbfchar\n" +
+		"1 beginbfrange <0010> <0012> <0041> endbfrange\nendcmap"
+
+	objects := []string{
+		"<< /Type /Catalog /Pages 2 0 R >>",
+		"", // Pages, filled in below
+		"<< /Type /Font /Subtype /Type1 /BaseFont /Helvetica >>",
+		fmt.Sprintf("<< /Length %d /Filter /FlateDecode >>\nstream\n%s\nendstream", len(deflate(cmap)), deflate(cmap)),
+		"<< /Type /Font /Subtype /Type0 /BaseFont /Noto /Encoding /Identity-H /ToUnicode 4 0 R >>",
+	}
+	var kids []string
+	for _, content := range contents {
+		data := deflate(content)
+		objects = append(objects, fmt.Sprintf("<< /Length %d /Filter /FlateDecode >>\nstream\n%s\nendstream", len(data), data))
+		objects = append(objects, fmt.Sprintf("<< /Type /Page /Parent 2 0 R /Contents %d 0 R >>", len(objects)))
+		kids = append(kids, fmt.Sprintf("%d 0 R", len(objects)))
+	}
+	objects[1] = fmt.Sprintf("<< /Type /Pages /Kids [%s] /Count %d /Resources << /Font << /F1 3 0 R /F2 5 0 R >> >> >>",
+		strings.Join(kids, " "), len(kids))
+
+	var buf bytes.Buffer
+	buf.WriteString("%PDF-1.7\n")
+	offsets := make([]int, len(objects))
+	for i, obj := range objects {
+		offsets[i] = buf.Len()
+		fmt.Fprintf(&buf, "%d 0 obj\n%s\nendobj\n", i+1, obj)
+	}
+	xref := buf.Len()
+	fmt.Fprintf(&buf, "xref\n0 %d\n0000000000 65535 f \n", len(objects)+1)
+	for _, off := range offsets {
+		fmt.Fprintf(&buf, "%010d 00000 n \n", off)
+	}
+	fmt.Fprintf(&buf, "trailer\n<< /Size %d /Root 1 0 R >>\nstartxref\n%d\n%%%%EOF\n", len(objects)+1, xref)
+	return buf.Bytes()
+}
+
+func TestExtract_PDF(t *testing.T) {
+	data := buildPDF(t,
+		"BT /F1 12 Tf 72 720 Td (Premium plan costs \\(monthly\\)) Tj 0 -14 Td [(Rs 4)-10(99)-400(only)] TJ ET",
+		"BT /F2 12 Tf 72 720 Td <00010002> Tj T* <001000110012> Tj ET",
+	)
+
+	doc, err := Extract("brochure.pdf", data)
+	if err != nil {
+		t.Fatalf("Extract: %v", err)
+	}
+	want := "Premium plan costs (monthly)\nRs 499 only\n\nहै\nABC"
+	if doc.Text != want {
+		t.Errorf("Text = %q, want %q", doc.Text, want)
+	}
+	if doc.Pages != 2 || len(doc.Sections) != 2 {
+		t.Fatalf("Pages = %d, Sections = %+v", doc.Pages, doc.Sections)
+	}
+	if s, _ := doc.SectionAt(strings.Index(doc.Text, "ABC")); s.Page != 2 {
+		t.Errorf("ABC is on page %d, want 2", s.Page)
+	}
+}
+
+func TestExtract_PDFEncrypted(t *testing.T) {
+	data := bytes.Replace(buildPDF(t, "BT (x) Tj ET"), []byte("/Root 1 0 R"), []byte("/Root 1 0 R /Encrypt 9 0 R"), 1)
+	if _, err := Extract("secret.pdf", data); err != ErrEncrypted {
+		t.Errorf("err = %v, want ErrEncrypted", err)
+	}
+}
+
+func TestExtract_DOCX(t *testing.T) {
+	body := `<?xml version="1.0" encoding="UTF-8"?>
+<w:document xmlns:w="http://schemas.openxmlformats.org/wordprocessingml/2006/main"><w:body>
+<w:p><w:r><w:t>Welcome to   Acme.</w:t></w:r></w:p>
+<w:p><w:pPr><w:pStyle w:val="Heading1"/></w:pPr><w:r><w:t>Pricing</w:t></w:r></w:p>
+<w:p><w:r><w:t xml:space="preserve">Plans start </w:t></w:r><w:r><w:t>today.</w:t></w:r></w:p>
+<w:tbl>
+<w:tr><w:tc><w:p><w:r><w:t>Plan</w:t></w:r></w:p></w:tc><w:tc><w:p><w:r><w:t>Price</w:t></w:r></w:p></w:tc></w:tr>
+<w:tr><w:tc><w:p><w:r><w:t>Basic</w:t></w:r></w:p></w:tc><w:tc><w:p><w:r><w:t>499</w:t></w:r></w:p></w:tc></w:tr>
+</w:tbl>
+</w:body></w:document>`
+	data := zipFiles(t, map[string]string{"word/document.xml": body})
+
+	doc, err := Extract("guide.docx", data)
+	if err != nil {
+		t.Fatalf("Extract: %v", err)
+	}
+	want := "Welcome to Acme.\n\nPlans start today.\nPlan: Basic, Price: 499"
+	if doc.Text != want {
+		t.Errorf("Text = %q, want %q", doc.Text, want)
+	}
+	if len(doc.Sections) != 1 || doc.Sections[0].Title != "Pricing" || doc.Text[doc.Sections[0].Offset:][:5] != "Plans" {
+		t.Errorf("Sections = %+v", doc.Sections)
+	}
+}
+
+func TestExtract_XLSX(t *testing.T) {
+	data := zipFiles(t, map[string]string{
+		"xl/workbook.xml": `<workbook xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main" xmlns:r="http://schemas.openxmlformats.org/officeDocument/2006/relationships">
+<sheets><sheet name="Prices" sheetId="1" r:id="rId1"/></sheets></workbook>`,
+		"xl/_rels/workbook.xml.rels": `<Relationships xmlns="http://schemas.openxmlformats.org/package/2006/relationships">
+<Relationship Id="rId1" Target="worksheets/prices.xml"/></Relationships>`,
+		"xl/sharedStrings.xml": `<sst><si><t>Product</t></si><si><t>Price</t></si><si><r><t>Gold </t></r><r><t>Plan</t></r></si></sst>`,
+		"xl/worksheets/prices.xml": `<worksheet><sheetData>
+<row r="1"><c r="A1" t="s"><v>0</v></c><c r="B1" t="s"><v>1</v></c></row>
+<row r="2"><c r="A2" t="s"><v>2</v></c><c r="B2"><v>1299.5</v></c></row>
+<row r="3"><c r="B3" t="inlineStr"><is><t>on request</t></is></c></row>
+</sheetData></worksheet>`,
+	})
+
+	doc, err := Extract("prices.xlsx", data)
+	if err != nil {
+		t.Fatalf("Extract: %v", err)
+	}
+	want := "Product: Gold Plan, Price: 1299.5\nPrice: on request"
+	if doc.Text != want {
+		t.Errorf("Text = %q, want %q", doc.Text, want)
+	}
+	if len(doc.Sections) != 1 || doc.Sections[0].Title != "Prices" {
+		t.Errorf("Sections = %+v", doc.Sections)
+	}
+}
+
+func TestExtract_CSV(t *testing.T) {
+	doc, err := Extract("rates.csv", []byte("\ufeffCity;Rate\nPune;\"1,200\"\n\nDelhi;1500\n"))
+	if err != nil {
+		t.Fatalf("Extract: %v", err)
+	}
+	if want := "City: Pune, Rate: 1,200\nCity: Delhi, Rate: 1500"; doc.Text != want {
+		t.Errorf("Text = %q, want %q", doc.Text, want)
+	}
+}
+
+func TestExtract_HTML(t *testing.T) {
+	page := `<html><head><title>Acme FAQ</title><style>p{color:red}</style></head><body>
+<p>Intro&nbsp;text.</p><script>alert(1)</script>
+<h2>Refunds</h2><p>Within <b>7 days</b>.</p>
+<table><tr><td>Plan</td><td>Days</td></tr></table>
+</body></html>`
+
+	doc, err := Extract("faq.html", []byte(page))
+	if err != nil {
+		t.Fatalf("Extract: %v", err)
+	}
+	want := "Intro text.\n\nWithin 7 days.\n\nPlan | Days"
+	if doc.Text != want {
+		t.Errorf("Text = %q, want %q", doc.Text, want)
+	}
+	titles := []string{}
+	for _, s := range doc.Sections {
+		titles = append(titles, s.Title)
+	}
+	if strings.Join(titles, ",") != "Acme FAQ,Refunds" {
+		t.Errorf("section titles = %q", titles)
+	}
+}
+
+func TestExtract_Unsupported(t *testing.T) {
+	if _, err := Extract("logo.png", nil); err == nil {
+		t.Error("expected an error for .png")
+	}
+}
+
+func TestNormalize(t *testing.T) {
+	got := Normalize("  a\u00ad b\t\tc  \r\n\n\n\nd\u200be\n")
+	if want := "a b c\n\nde"; got != want {
+		t.Errorf("Normalize = %q, want %q", got, want)
+	}
+}
+
+func TestDetectLanguage(t *testing.T) {
+	cases := map[string]string{
+		"The premium plan includes free installation and you can cancel within seven days.": "en",
+		"प्रीमियम प्लान में मुफ्त इंस्टॉलेशन शामिल है और आप सात दिनों में रद्द कर सकते हैं।":          "hi",
+		"Aapko premium plan mein free installation milega aur aap saat din mein cancel kar sakte hai": "hi-Latn",
+		"Rs 499": "und",
+	}
+	for text, want := range cases {
+		if got := DetectLanguage(text); got != want {
+			t.Errorf("DetectLanguage(%q) = %q, want %q", text, got, want)
+		}
+	}
+}
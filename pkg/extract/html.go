@@ -0,0 +1,127 @@
+package extract
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+
+	"golang.org/x/net/html"
+	"golang.org/x/net/html/atom"
+	"golang.org/x/net/html/charset"
+)
+
+// skippedElements hold no readable text
+var skippedElements = map[atom.Atom]bool{
+	atom.Script: true, atom.Style: true, atom.Noscript: true, atom.Template: true,
+	atom.Svg: true, atom.Iframe: true, atom.Object: true, atom.Head: true,
+}
+
+// blockElements end the current line of text
+var blockElements = map[atom.Atom]bool{
+	atom.P: true, atom.Div: true, atom.Br: true, atom.Li: true, atom.Tr: true, atom.Section: true,
+	atom.Article: true, atom.Header: true, atom.Footer: true, atom.Blockquote: true, atom.Pre: true,
+	atom.H4: true, atom.H5: true, atom.H6: true, atom.Dt: true, atom.Dd: true, atom.Hr: true,
+	atom.Ul: true, atom.Ol: true, atom.Table: true, atom.Main: true, atom.Nav: true, atom.Aside: true,
+}
+
+// headingElements start a new section
+var headingElements = map[atom.Atom]bool{atom.H1: true, atom.H2: true, atom.H3: true}
+
+// extractHTML reads the visible text of a web page; h1 to h3 headings start sections
+func extractHTML(data []byte) (*Document, error) {
+	r, err := charset.NewReader(bytes.NewReader(data), "text/html")
+	if err != nil {
+		return nil, fmt.Errorf("failed to detect HTML encoding: %w", err)
+	}
+	root, err := html.Parse(r)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse HTML: %w", err)
+	}
+
+	var (
+		b       builder
+		title   string
+		section strings.Builder
+	)
+	flush := func() {
+		b.add(title, 0, trimCellSeparators(section.String()))
+		section.Reset()
+	}
+	if t := findElement(root, atom.Title); t != nil {
+		title = strings.Join(strings.Fields(nodeText(t)), " ")
+	}
+
+	var walk func(n *html.Node)
+	walk = func(n *html.Node) {
+		switch n.Type {
+		case html.TextNode:
+			section.WriteString(n.Data)
+			return
+		case html.ElementNode:
+			if skippedElements[n.DataAtom] {
+				return
+			}
+			if headingElements[n.DataAtom] {
+				if heading := strings.Join(strings.Fields(nodeText(n)), " "); heading != "" {
+					flush()
+					title = heading
+					return
+				}
+			}
+			if blockElements[n.DataAtom] {
+				section.WriteString("\n")
+			}
+		}
+
+		for c := n.FirstChild; c != nil; c = c.NextSibling {
+			walk(c)
+		}
+
+		if n.Type == html.ElementNode {
+			switch {
+			case n.DataAtom == atom.Td || n.DataAtom == atom.Th:
+				section.WriteString(" | ")
+			case blockElements[n.DataAtom]:
+				section.WriteString("\n")
+			}
+		}
+	}
+	walk(root)
+	flush()
+	return b.document(0), nil
+}
+
+// trimCellSeparators drops the separator left after the last cell of each table row
+func trimCellSeparators(text string) string {
+	lines := strings.Split(text, "\n")
+	for i, line := range lines {
+		lines[i] = strings.TrimSuffix(strings.TrimRight(line, " \t"), "|")
+	}
+	return strings.Join(lines, "\n")
+}
+
+// findElement returns the first element of the given type
+func findElement(n *html.Node, a atom.Atom) *html.Node {
+	if n.Type == html.ElementNode && n.DataAtom == a {
+		return n
+	}
+	for c := n.FirstChild; c != nil; c = c.NextSibling {
+		if found := findElement(c, a); found != nil {
+			return found
+		}
+	}
+	return nil
+}
+
+// nodeText returns the text inside a node
+func nodeText(n *html.Node) string {
+	if n.Type == html.TextNode {
+		return n.Data
+	}
+	var b strings.Builder
+	for c := n.FirstChild; c != nil; c = c.NextSibling {
+		b.WriteString(nodeText(c))
+		b.WriteString(" ")
+	}
+	return b.String()
+}
@@ -0,0 +1,89 @@
+package extract
+
+import (
+	"strings"
+	"unicode"
+)
+
+// scripts maps Unicode scripts to the language most documents in them are written in
+var scripts = []struct {
+	table *unicode.RangeTable
+	lang  string
+}{
+	{unicode.Devanagari, "hi"},
+	{unicode.Bengali, "bn"},
+	{unicode.Gurmukhi, "pa"},
+	{unicode.Gujarati, "gu"},
+	{unicode.Oriya, "or"},
+	{unicode.Tamil, "ta"},
+	{unicode.Telugu, "te"},
+	{unicode.Kannada, "kn"},
+	{unicode.Malayalam, "ml"},
+	{unicode.Arabic, "ur"},
+	{unicode.Latin, "en"},
+}
+
+// englishWords and hinglishWords are frequent function words used to tell English from romanised Hindi
+var englishWords = map[string]bool{
+	"the": true, "and": true, "is": true, "are": true, "of": true, "to": true, "in": true, "for": true,
+	"with": true, "you": true, "your": true, "this": true, "that": true, "on": true, "we": true, "our": true,
+}
+
+var hinglishWords = map[string]bool{
+	"hai": true, "hain": true, "aap": true, "aapka": true, "aapko": true, "kya": true, "ka": true, "ki": true,
+	"ke": true, "ko": true, "mein": true, "se": true, "aur": true, "nahi": true, "hum": true, "yeh": true,
+	"woh": true, "bhi": true, "kar": true, "karein": true, "liye": true, "kitna": true, "kitne": true,
+}
+
+// DetectLanguage guesses the language of text from its dominant script, and for Latin script
+// whether English or romanised Hindi (Hinglish) function words are more frequent.
+// It returns "und" when there is too little text to tell.
+func DetectLanguage(text string) string {
+	counts := make([]int, len(scripts))
+	letters := 0
+	for _, r := range text {
+		if !unicode.IsLetter(r) {
+			continue
+		}
+		letters++
+		for i, s := range scripts {
+			if unicode.Is(s.table, r) {
+				counts[i]++
+				break
+			}
+		}
+		if letters >= 20000 {
+			break // Enough of a sample
+		}
+	}
+	if letters < 20 {
+		return "und"
+	}
+
+	best := 0
+	for i := range counts {
+		if counts[i] > counts[best] {
+			best = i
+		}
+	}
+	if counts[best]*2 < letters {
+		return "und"
+	}
+	if scripts[best].lang != "en" {
+		return scripts[best].lang
+	}
+
+	english, hinglish := 0, 0
+	for _, word := range strings.FieldsFunc(strings.ToLower(text), func(r rune) bool { return !unicode.IsLetter(r) }) {
+		if englishWords[word] {
+			english++
+		}
+		if hinglishWords[word] {
+			hinglish++
+		}
+	}
+	if hinglish > english {
+		return "hi-Latn"
+	}
+	return "en"
+}
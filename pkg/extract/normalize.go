@@ -0,0 +1,59 @@
+package extract
+
+import (
+	"strings"
+	"unicode"
+)
+
+// Normalize tidies extracted text: one space between words, no blank space at line ends,
+// at most one empty line between paragraphs, and no control or zero-width characters
+func Normalize(text string) string {
+	text = strings.ReplaceAll(text, "\r\n", "\n")
+	text = strings.ReplaceAll(text, "\r", "\n")
+
+	var b strings.Builder
+	b.Grow(len(text))
+	blank := 0
+	for _, line := range strings.Split(text, "\n") {
+		line = normalizeLine(line)
+		if line == "" {
+			blank++
+			continue
+		}
+		if b.Len() > 0 {
+			if blank > 0 {
+				b.WriteString("\n\n")
+			} else {
+				b.WriteString("\n")
+			}
+		}
+		b.WriteString(line)
+		blank = 0
+	}
+	return b.String()
+}
+
+// normalizeLine collapses white space within a line and drops invisible characters
+func normalizeLine(line string) string {
+	var b strings.Builder
+	space := false
+	for _, r := range line {
+		switch {
+		case r == '\u00ad', r == '\u200b', r == '\ufeff':
+			// Soft hyphens, zero-width spaces and byte-order marks carry no text;
+			// zero-width (non-)joiners are kept because Indic scripts need them
+			continue
+		case unicode.IsSpace(r):
+			space = true
+			continue
+		case unicode.IsControl(r), r == unicode.ReplacementChar:
+			continue
+		}
+		if space && b.Len() > 0 {
+			b.WriteByte(' ')
+		}
+		space = false
+		b.WriteRune(r)
+	}
+	return b.String()
+}
@@ -0,0 +1,400 @@
+package extract
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"path"
+	"strconv"
+	"strings"
+)
+
+// maxPartSize bounds how much of one zip entry is read, guarding against zip bombs
+const maxPartSize = 64 << 20
+
+// openZip opens an OOXML package
+func openZip(data []byte) (*zip.Reader, error) {
+	zr, err := zip.NewReader(bytes.NewReader(data), int64(len(data)))
+	if err != nil {
+		return nil, fmt.Errorf("not a valid Office document: %w", err)
+	}
+	return zr, nil
+}
+
+// readPart returns the contents of a file in the package, or nil if it does not exist
+func readPart(zr *zip.Reader, name string) ([]byte, error) {
+	for _, f := range zr.File {
+		if f.Name != name {
+			continue
+		}
+		rc, err := f.Open()
+		if err != nil {
+			return nil, fmt.Errorf("failed to open %s: %w", name, err)
+		}
+		defer rc.Close()
+		data, err := io.ReadAll(io.LimitReader(rc, maxPartSize))
+		if err != nil {
+			return nil, fmt.Errorf("failed to read %s: %w", name, err)
+		}
+		return data, nil
+	}
+	return nil, nil
+}
+
+// attr returns the value of the attribute with the given local name
+func attr(el xml.StartElement, local string) string {
+	for _, a := range el.Attr {
+		if a.Name.Local == local {
+			return a.Value
+		}
+	}
+	return ""
+}
+
+// extractDOCX reads the paragraphs and tables of a Word document's body.
+// Paragraphs styled as headings or the title start a new section.
+func extractDOCX(data []byte) (*Document, error) {
+	zr, err := openZip(data)
+	if err != nil {
+		return nil, err
+	}
+	body, err := readPart(zr, "word/document.xml")
+	if err != nil {
+		return nil, err
+	}
+	if body == nil {
+		return nil, fmt.Errorf("not a Word document: word/document.xml missing")
+	}
+
+	var (
+		b         builder
+		title     string          // Heading of the current section
+		section   strings.Builder // Text of the current section
+		para      strings.Builder // Text of the current paragraph
+		heading   bool            // Current paragraph is a heading
+		tables    [][][]string    // Rows of each open table, innermost last
+		cell      []string        // Paragraphs of the current cell
+		cellStack [][]string      // Cells of enclosing tables while inside a nested one
+	)
+	flushSection := func() {
+		b.add(title, 0, section.String())
+		section.Reset()
+	}
+
+	dec := xml.NewDecoder(bytes.NewReader(body))
+	for {
+		tok, err := dec.Token()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse Word document: %w", err)
+		}
+
+		switch el := tok.(type) {
+		case xml.StartElement:
+			switch el.Name.Local {
+			case "p":
+				para.Reset()
+				heading = false
+			case "pStyle":
+				style := strings.ToLower(attr(el, "val"))
+				heading = strings.HasPrefix(style, "heading") || style == "title"
+			case "t":
+				var text string
+				if err := dec.DecodeElement(&text, &el); err != nil {
+					return nil, fmt.Errorf("failed to parse Word document: %w", err)
+				}
+				para.WriteString(text)
+			case "tab":
+				para.WriteString(" ")
+			case "br", "cr":
+				para.WriteString("\n")
+			case "tbl":
+				if len(tables) > 0 {
+					cellStack = append(cellStack, cell)
+				}
+				tables = append(tables, nil)
+			case "tr":
+				if len(tables) > 0 {
+					tables[len(tables)-1] = append(tables[len(tables)-1], []string{})
+				}
+			case "tc":
+				cell = nil
+			}
+
+		case xml.EndElement:
+			switch el.Name.Local {
+			case "p":
+				text := para.String()
+				switch {
+				case len(tables) > 0:
+					cell = append(cell, text)
+				case heading && strings.TrimSpace(text) != "":
+					flushSection()
+					title = strings.Join(strings.Fields(text), " ")
+				default:
+					section.WriteString(text)
+					section.WriteString("\n")
+				}
+			case "tc":
+				if n := len(tables); n > 0 {
+					rows := tables[n-1]
+					if len(rows) == 0 {
+						rows = append(rows, []string{})
+					}
+					rows[len(rows)-1] = append(rows[len(rows)-1], strings.Join(cell, " "))
+					tables[n-1] = rows
+				}
+			case "tbl":
+				n := len(tables)
+				if n == 0 {
+					continue
+				}
+				text := renderTable(tables[n-1])
+				tables = tables[:n-1]
+				if n > 1 {
+					// A nested table becomes text inside its enclosing cell
+					cell = append(cellStack[len(cellStack)-1], text)
+					cellStack = cellStack[:len(cellStack)-1]
+				} else {
+					section.WriteString(text)
+				}
+			}
+		}
+	}
+	flushSection()
+	return b.document(0), nil
+}
+
+// extractXLSX reads every worksheet of an Excel workbook as a table, one section per sheet
+func extractXLSX(data []byte) (*Document, error) {
+	zr, err := openZip(data)
+	if err != nil {
+		return nil, err
+	}
+
+	shared, err := sharedStrings(zr)
+	if err != nil {
+		return nil, err
+	}
+	sheets, err := workbookSheets(zr)
+	if err != nil {
+		return nil, err
+	}
+
+	var b builder
+	for _, sheet := range sheets {
+		raw, err := readPart(zr, sheet.path)
+		if err != nil {
+			return nil, err
+		}
+		if raw == nil {
+			continue
+		}
+		rows, err := sheetRows(raw, shared)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse sheet %q: %w", sheet.name, err)
+		}
+		if text := renderTable(rows); text != "" {
+			b.add(sheet.name, 0, text)
+		}
+	}
+	return b.document(0), nil
+}
+
+// sheetRef is a worksheet's name and location in the package
+type sheetRef struct {
+	name string
+	path string
+}
+
+// workbookSheets lists worksheets in workbook order
+func workbookSheets(zr *zip.Reader) ([]sheetRef, error) {
+	workbook, err := readPart(zr, "xl/workbook.xml")
+	if err != nil {
+		return nil, err
+	}
+	if workbook == nil {
+		return nil, fmt.Errorf("not an Excel workbook: xl/workbook.xml missing")
+	}
+	rels, err := readPart(zr, "xl/_rels/workbook.xml.rels")
+	if err != nil {
+		return nil, err
+	}
+
+	targets := map[string]string{}
+	var relDoc struct {
+		Rels []struct {
+			ID     string `xml:"Id,attr"`
+			Target string `xml:"Target,attr"`
+		} `xml:"Relationship"`
+	}
+	if rels != nil {
+		if err := xml.Unmarshal(rels, &relDoc); err != nil {
+			return nil, fmt.Errorf("failed to parse workbook relationships: %w", err)
+		}
+	}
+	for _, r := range relDoc.Rels {
+		target := r.Target
+		if strings.HasPrefix(target, "/") {
+			target = strings.TrimPrefix(target, "/")
+		} else {
+			target = path.Join("xl", target)
+		}
+		targets[r.ID] = target
+	}
+
+	var wb struct {
+		Sheets []struct {
+			Name  string     `xml:"name,attr"`
+			Attrs []xml.Attr `xml:",any,attr"`
+		} `xml:"sheets>sheet"`
+	}
+	if err := xml.Unmarshal(workbook, &wb); err != nil {
+		return nil, fmt.Errorf("failed to parse workbook: %w", err)
+	}
+
+	sheets := make([]sheetRef, 0, len(wb.Sheets))
+	for i, s := range wb.Sheets {
+		p := fmt.Sprintf("xl/worksheets/sheet%d.xml", i+1)
+		for _, a := range s.Attrs {
+			if a.Name.Local == "id" && targets[a.Value] != "" {
+				p = targets[a.Value]
+			}
+		}
+		sheets = append(sheets, sheetRef{name: s.Name, path: p})
+	}
+	return sheets, nil
+}
+
+// sharedStrings reads the workbook's string table
+func sharedStrings(zr *zip.Reader) ([]string, error) {
+	raw, err := readPart(zr, "xl/sharedStrings.xml")
+	if err != nil || raw == nil {
+		return nil, err
+	}
+
+	var strs []string
+	var current strings.Builder
+	inItem := false
+	dec := xml.NewDecoder(bytes.NewReader(raw))
+	for {
+		tok, err := dec.Token()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse shared strings: %w", err)
+		}
+		switch el := tok.(type) {
+		case xml.StartElement:
+			switch el.Name.Local {
+			case "si":
+				current.Reset()
+				inItem = true
+			case "t":
+				var text string
+				if err := dec.DecodeElement(&text, &el); err != nil {
+					return nil, fmt.Errorf("failed to parse shared strings: %w", err)
+				}
+				if inItem {
+					current.WriteString(text)
+				}
+			case "rPh":
+				// Phonetic hints repeat the text in another script
+				if err := dec.Skip(); err != nil {
+					return nil, fmt.Errorf("failed to parse shared strings: %w", err)
+				}
+			}
+		case xml.EndElement:
+			if el.Name.Local == "si" {
+				strs = append(strs, current.String())
+				inItem = false
+			}
+		}
+	}
+	return strs, nil
+}
+
+// sheetRows reads a worksheet's cells into rows, placing each cell in its column
+func sheetRows(raw []byte, shared []string) ([][]string, error) {
+	var sheet struct {
+		Rows []struct {
+			Cells []struct {
+				Ref    string `xml:"r,attr"`
+				Type   string `xml:"t,attr"`
+				Value  string `xml:"v"`
+				Inline struct {
+					Text string `xml:",innerxml"`
+				} `xml:"is"`
+			} `xml:"c"`
+		} `xml:"sheetData>row"`
+	}
+	if err := xml.Unmarshal(raw, &sheet); err != nil {
+		return nil, err
+	}
+
+	rows := make([][]string, 0, len(sheet.Rows))
+	for _, r := range sheet.Rows {
+		var row []string
+		for _, c := range r.Cells {
+			var value string
+			switch c.Type {
+			case "s":
+				if i, err := strconv.Atoi(strings.TrimSpace(c.Value)); err == nil && i >= 0 && i < len(shared) {
+					value = shared[i]
+				}
+			case "inlineStr":
+				value = xmlText(c.Inline.Text)
+			case "b":
+				value = map[string]string{"1": "TRUE", "0": "FALSE"}[c.Value]
+			case "e":
+				value = ""
+			default:
+				value = c.Value
+			}
+
+			col := len(row)
+			if c.Ref != "" {
+				col = columnIndex(c.Ref)
+			}
+			for len(row) < col {
+				row = append(row, "")
+			}
+			row = append(row, value)
+		}
+		rows = append(rows, row)
+	}
+	return rows, nil
+}
+
+// columnIndex converts the letters of a cell reference ("C7") to a 0-based column
+func columnIndex(ref string) int {
+	col := 0
+	for _, r := range ref {
+		if r < 'A' || r > 'Z' {
+			break
+		}
+		col = col*26 + int(r-'A'+1)
+	}
+	return col - 1
+}
+
+// xmlText returns the character data inside an XML fragment
+func xmlText(fragment string) string {
+	var b strings.Builder
+	dec := xml.NewDecoder(strings.NewReader("<x>" + fragment + "</x>"))
+	for {
+		tok, err := dec.Token()
+		if err != nil {
+			break
+		}
+		if cd, ok := tok.(xml.CharData); ok {
+			b.Write(cd)
+		}
+	}
+	return b.String()
+}
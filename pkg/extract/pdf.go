@@ -0,0 +1,485 @@
+package extract
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// ErrEncrypted is returned for password-protected PDFs
+var ErrEncrypted = errors.New("PDF is encrypted")
+
+// pdfObjectHeader finds "N G obj" markers. The file is scanned instead of trusting the xref
+// table, which is often wrong in files written by phone scanners and online converters.
+var pdfObjectHeader = regexp.MustCompile(`(?:^|[^0-9])(\d{1,10})\s+(\d{1,5})\s+obj\b`)
+
+// ligatures expands presentation forms that fonts map ligature glyphs to
+var ligatures = strings.NewReplacer("ﬀ", "ff", "ﬁ", "fi", "ﬂ", "fl", "ﬃ", "ffi", "ﬄ", "ffl")
+
+// maxFormDepth and maxTreeDepth limit how deeply form XObjects and page tree nodes may nest
+const (
+	maxFormDepth = 8
+	maxTreeDepth = 64
+)
+
+// pdfDocument holds the objects of a PDF file
+type pdfDocument struct {
+	objects  map[int]any
+	trailers []pdfDict
+	fonts    map[pdfRef]*pdfFont
+}
+
+// extractPDF reads the text layer of a PDF, one section per page.
+// Scanned pages without a text layer come out empty.
+func extractPDF(data []byte) (*Document, error) {
+	if !bytes.Contains(data[:min(len(data), 1024)], []byte("%PDF-")) {
+		return nil, fmt.Errorf("not a PDF file")
+	}
+
+	doc := parsePDF(data)
+	for _, t := range doc.trailers {
+		if t["Encrypt"] != nil {
+			return nil, ErrEncrypted
+		}
+	}
+
+	pages := doc.pages()
+	if len(pages) == 0 {
+		return nil, fmt.Errorf("PDF has no pages")
+	}
+
+	var b builder
+	for i, page := range pages {
+		b.add("", i+1, ligatures.Replace(doc.pageText(page)))
+	}
+	return b.document(len(pages)), nil
+}
+
+// parsePDF reads every object in the file, including those packed in object streams
+func parsePDF(data []byte) *pdfDocument {
+	doc := &pdfDocument{
+		objects: map[int]any{},
+		fonts:   map[pdfRef]*pdfFont{},
+	}
+
+	skipUntil := 0 // End of the last stream, so markers inside binary data are ignored
+	for _, m := range pdfObjectHeader.FindAllSubmatchIndex(data, -1) {
+		if m[0] < skipUntil {
+			continue
+		}
+		num, err := strconv.Atoi(string(data[m[2]:m[3]]))
+		if err != nil {
+			continue
+		}
+		p := &pdfParser{data: data, pos: m[1]}
+		obj, err := p.object()
+		if err != nil && err != errPDFSyntax {
+			continue
+		}
+		if d, ok := obj.(pdfDict); ok {
+			if s := streamAfter(p, d); s != nil {
+				obj = s
+				skipUntil = p.pos
+			}
+		}
+		// Later definitions win, as in incremental updates
+		doc.objects[num] = obj
+	}
+
+	// Trailers, either classic or as cross-reference streams
+	for i := 0; ; {
+		j := bytes.Index(data[i:], []byte("trailer"))
+		if j < 0 {
+			break
+		}
+		p := &pdfParser{data: data, pos: i + j + len("trailer")}
+		if obj, _ := p.object(); obj != nil {
+			if d, ok := obj.(pdfDict); ok {
+				doc.trailers = append(doc.trailers, d)
+			}
+		}
+		i += j + len("trailer")
+	}
+	for _, obj := range doc.objects {
+		if s, ok := obj.(*pdfStream); ok && s.dict["Type"] == pdfName("XRef") {
+			doc.trailers = append(doc.trailers, s.dict)
+		}
+	}
+
+	doc.expandObjectStreams()
+	return doc
+}
+
+// streamAfter reads the stream data following a dictionary, if there is any
+func streamAfter(p *pdfParser, d pdfDict) *pdfStream {
+	p.skipSpace()
+	if !bytes.HasPrefix(p.data[p.pos:], []byte("stream")) {
+		return nil
+	}
+	start := p.pos + len("stream")
+	if start < len(p.data) && p.data[start] == '\r' {
+		start++
+	}
+	if start < len(p.data) && p.data[start] == '\n' {
+		start++
+	}
+
+	// Trust /Length only when "endstream" follows it
+	if n, ok := d["Length"].(float64); ok && n >= 0 {
+		end := start + int(n)
+		if end <= len(p.data) {
+			rest := bytes.TrimLeft(p.data[end:min(len(p.data), end+32)], "\r\n \t")
+			if bytes.HasPrefix(rest, []byte("endstream")) {
+				p.pos = end
+				return &pdfStream{dict: d, raw: p.data[start:end]}
+			}
+		}
+	}
+
+	end := bytes.Index(p.data[start:], []byte("endstream"))
+	if end < 0 {
+		p.pos = len(p.data)
+		return &pdfStream{dict: d, raw: p.data[start:]}
+	}
+	p.pos = start + end
+	raw := p.data[start : start+end]
+	raw = bytes.TrimSuffix(raw, []byte("\n"))
+	raw = bytes.TrimSuffix(raw, []byte("\r"))
+	return &pdfStream{dict: d, raw: raw}
+}
+
+// expandObjectStreams adds the objects stored inside /ObjStm streams
+func (doc *pdfDocument) expandObjectStreams() {
+	var streams []*pdfStream
+	for _, obj := range doc.objects {
+		if s, ok := obj.(*pdfStream); ok && s.dict["Type"] == pdfName("ObjStm") {
+			streams = append(streams, s)
+		}
+	}
+
+	for _, s := range streams {
+		data, err := decodeStream(s, doc.resolve)
+		if err != nil {
+			continue
+		}
+		n, _ := doc.resolve(s.dict["N"]).(float64)
+		first, _ := doc.resolve(s.dict["First"]).(float64)
+		if int(first) > len(data) {
+			continue
+		}
+
+		header := &pdfParser{data: data[:int(first)]}
+		for i := 0; i < int(n); i++ {
+			num, err1 := header.object()
+			off, err2 := header.object()
+			if err1 != nil || err2 != nil {
+				break
+			}
+			objNum, ok1 := num.(float64)
+			objOff, ok2 := off.(float64)
+			if !ok1 || !ok2 || int(first)+int(objOff) >= len(data) {
+				continue
+			}
+			if _, exists := doc.objects[int(objNum)]; exists {
+				continue
+			}
+			p := &pdfParser{data: data, pos: int(first) + int(objOff)}
+			if obj, err := p.object(); err == nil {
+				doc.objects[int(objNum)] = obj
+			}
+		}
+	}
+}
+
+// resolve follows indirect references
+func (doc *pdfDocument) resolve(obj any) any {
+	for i := 0; i < 16; i++ {
+		ref, ok := obj.(pdfRef)
+		if !ok {
+			return obj
+		}
+		obj = doc.objects[ref.num]
+	}
+	return nil
+}
+
+// dict resolves obj to a dictionary, using a stream's dictionary when it is a stream
+func (doc *pdfDocument) dict(obj any) pdfDict {
+	switch v := doc.resolve(obj).(type) {
+	case pdfDict:
+		return v
+	case *pdfStream:
+		return v.dict
+	}
+	return nil
+}
+
+// pdfPage is a page's content and the resources it may use
+type pdfPage struct {
+	dict      pdfDict
+	resources pdfDict
+}
+
+// pages returns the pages in reading order
+func (doc *pdfDocument) pages() []pdfPage {
+	var root pdfDict
+	for _, t := range doc.trailers {
+		if catalog := doc.dict(t["Root"]); catalog != nil {
+			root = doc.dict(catalog["Pages"])
+			if root != nil {
+				break
+			}
+		}
+	}
+	if root == nil {
+		for _, obj := range doc.objects {
+			if d := doc.dict(obj); d != nil && d["Type"] == pdfName("Catalog") {
+				root = doc.dict(d["Pages"])
+				break
+			}
+		}
+	}
+
+	var pages []pdfPage
+	if root != nil {
+		var walk func(node pdfDict, resources pdfDict, depth int)
+		walk = func(node pdfDict, resources pdfDict, depth int) {
+			if depth > maxTreeDepth {
+				return
+			}
+			if r := doc.dict(node["Resources"]); r != nil {
+				resources = r
+			}
+			kids, ok := doc.resolve(node["Kids"]).([]any)
+			if !ok {
+				pages = append(pages, pdfPage{dict: node, resources: resources})
+				return
+			}
+			for _, kid := range kids {
+				if d := doc.dict(kid); d != nil {
+					walk(d, resources, depth+1)
+				}
+			}
+		}
+		walk(root, nil, 0)
+	}
+	if len(pages) > 0 {
+		return pages
+	}
+
+	// No usable page tree: take page objects in object-number order
+	nums := make([]int, 0, len(doc.objects))
+	for num, obj := range doc.objects {
+		if d := doc.dict(obj); d != nil && d["Type"] == pdfName("Page") {
+			nums = append(nums, num)
+		}
+	}
+	sort.Ints(nums)
+	for _, num := range nums {
+		d := doc.dict(doc.objects[num])
+		pages = append(pages, pdfPage{dict: d, resources: doc.dict(d["Resources"])})
+	}
+	return pages
+}
+
+// pageText returns the text shown by a page's content streams
+func (doc *pdfDocument) pageText(page pdfPage) string {
+	var content []byte
+	var streams []any
+	switch c := doc.resolve(page.dict["Contents"]).(type) {
+	case *pdfStream:
+		streams = []any{c}
+	case []any:
+		streams = c
+	}
+	for _, s := range streams {
+		stream, ok := doc.resolve(s).(*pdfStream)
+		if !ok {
+			continue
+		}
+		data, err := decodeStream(stream, doc.resolve)
+		if err != nil {
+			continue
+		}
+		content = append(content, data...)
+		content = append(content, '\n')
+	}
+
+	w := &textWriter{}
+	doc.runContent(content, page.resources, w, 0)
+	return w.String()
+}
+
+// textWriter collects shown text, inserting line breaks and spaces
+type textWriter struct {
+	strings.Builder
+}
+
+func (w *textWriter) newline() {
+	if s := w.String(); len(s) > 0 && s[len(s)-1] != '\n' {
+		w.WriteByte('\n')
+	}
+}
+
+func (w *textWriter) space() {
+	if s := w.String(); len(s) > 0 && s[len(s)-1] != '\n' && s[len(s)-1] != ' ' {
+		w.WriteByte(' ')
+	}
+}
+
+// runContent interprets the text operators of a content stream
+func (doc *pdfDocument) runContent(content []byte, resources pdfDict, w *textWriter, depth int) {
+	var (
+		font     *pdfFont
+		operands []any
+		lineY    float64
+	)
+	p := &pdfParser{data: content}
+	for {
+		obj, err := p.object()
+		if err == errPDFSyntax {
+			operands = operands[:0]
+			continue
+		}
+		if err != nil {
+			return
+		}
+		op, ok := obj.(pdfKeyword)
+		if !ok {
+			operands = append(operands, obj)
+			continue
+		}
+
+		switch op {
+		case "BT":
+			lineY = 0
+		case "Tf":
+			if len(operands) >= 1 {
+				if name, ok := operands[0].(pdfName); ok {
+					font = doc.font(resources, name)
+				}
+			}
+		case "Td", "TD":
+			if len(operands) >= 2 {
+				if ty, _ := operands[1].(float64); ty != 0 {
+					w.newline()
+				} else {
+					w.space()
+				}
+			}
+		case "T*":
+			w.newline()
+		case "Tm":
+			if len(operands) >= 6 {
+				y, _ := operands[5].(float64)
+				if y != lineY {
+					w.newline()
+				} else {
+					w.space()
+				}
+				lineY = y
+			}
+		case "Tj":
+			if len(operands) >= 1 {
+				w.WriteString(showText(font, operands[len(operands)-1]))
+			}
+		case "'", "\"":
+			w.newline()
+			if len(operands) >= 1 {
+				w.WriteString(showText(font, operands[len(operands)-1]))
+			}
+		case "TJ":
+			if len(operands) >= 1 {
+				arr, _ := operands[len(operands)-1].([]any)
+				for _, item := range arr {
+					switch v := item.(type) {
+					case pdfString:
+						w.WriteString(showText(font, v))
+					case float64:
+						// Large negative adjustments (in thousandths of an em) are word gaps
+						if v < -200 {
+							w.space()
+						}
+					}
+				}
+			}
+		case "ET":
+			w.space()
+		case "Do":
+			if len(operands) >= 1 && depth < maxFormDepth {
+				name, _ := operands[0].(pdfName)
+				xobjects := doc.dict(resources["XObject"])
+				if form, ok := doc.resolve(xobjects[name]).(*pdfStream); ok && form.dict["Subtype"] == pdfName("Form") {
+					if data, err := decodeStream(form, doc.resolve); err == nil {
+						formResources := doc.dict(form.dict["Resources"])
+						if formResources == nil {
+							formResources = resources
+						}
+						w.newline()
+						doc.runContent(data, formResources, w, depth+1)
+						w.newline()
+					}
+				}
+			}
+		case "BI":
+			skipInlineImage(p)
+		}
+		operands = operands[:0]
+	}
+}
+
+// showText decodes a string operand with the current font
+func showText(font *pdfFont, operand any) string {
+	s, ok := operand.(pdfString)
+	if !ok {
+		return ""
+	}
+	if font == nil {
+		return latin1([]byte(s))
+	}
+	return font.decode(s)
+}
+
+// font returns the decoder for a font in the resources, caching it per font object
+func (doc *pdfDocument) font(resources pdfDict, name pdfName) *pdfFont {
+	obj := doc.dict(resources["Font"])[name]
+	ref, isRef := obj.(pdfRef)
+	if f, ok := doc.fonts[ref]; isRef && ok {
+		return f
+	}
+	d := doc.dict(obj)
+	if d == nil {
+		return nil
+	}
+	f := newPDFFont(d, doc)
+	if isRef {
+		doc.fonts[ref] = f
+	}
+	return f
+}
+
+// skipInlineImage moves past the data of an inline image ("BI ... ID <data> EI")
+func skipInlineImage(p *pdfParser) {
+	for {
+		obj, err := p.object()
+		if err != nil && err != errPDFSyntax {
+			return
+		}
+		if obj == pdfKeyword("ID") {
+			break
+		}
+	}
+	p.pos++ // Single white space after ID
+	for i := p.pos; i+2 <= len(p.data); i++ {
+		if p.data[i] == 'E' && p.data[i+1] == 'I' && isPDFSpace(p.data[i-1]) &&
+			(i+2 == len(p.data) || isPDFDelimiter(p.data[i+2])) {
+			p.pos = i + 2
+			return
+		}
+	}
+	p.pos = len(p.data)
+}
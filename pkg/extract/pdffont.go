@@ -0,0 +1,290 @@
+package extract
+
+import (
+	"strconv"
+	"strings"
+	"unicode/utf16"
+	"unicode/utf8"
+)
+
+// pdfFont turns the bytes of a shown string into text
+type pdfFont struct {
+	toUnicode  map[string]string // Character code → text, from the ToUnicode CMap
+	codespaces []codespace       // Code ranges of the CMap, deciding how many bytes each code takes
+	encoding   *[256]rune        // Single-byte encoding for simple fonts without a usable CMap
+	composite  bool              // Type0 font: codes are two bytes unless the CMap says otherwise
+}
+
+// codespace is a range of character codes of one length
+type codespace struct {
+	lo, hi []byte
+}
+
+// newPDFFont reads what is needed to decode text from a font dictionary
+func newPDFFont(d pdfDict, doc *pdfDocument) *pdfFont {
+	f := &pdfFont{composite: doc.resolve(d["Subtype"]) == pdfName("Type0")}
+
+	if s, ok := doc.resolve(d["ToUnicode"]).(*pdfStream); ok {
+		if data, err := decodeStream(s, doc.resolve); err == nil {
+			f.parseCMap(data)
+		}
+	}
+	if f.composite {
+		return f
+	}
+
+	enc := winAnsiEncoding
+	switch e := doc.resolve(d["Encoding"]).(type) {
+	case pdfName:
+		if e == "StandardEncoding" {
+			enc = standardEncoding()
+		}
+	case pdfDict:
+		if doc.resolve(e["BaseEncoding"]) == pdfName("StandardEncoding") {
+			enc = standardEncoding()
+		}
+		if diffs, ok := doc.resolve(e["Differences"]).([]any); ok {
+			code := 0
+			for _, item := range diffs {
+				switch v := doc.resolve(item).(type) {
+				case float64:
+					code = int(v)
+				case pdfName:
+					if code >= 0 && code < 256 {
+						if r, ok := glyphRune(string(v)); ok {
+							enc[code] = r
+						}
+					}
+					code++
+				}
+			}
+		}
+	}
+	f.encoding = &enc
+	return f
+}
+
+// decode converts the bytes of a shown string to text
+func (f *pdfFont) decode(s pdfString) string {
+	var b strings.Builder
+	raw := []byte(s)
+	for i := 0; i < len(raw); {
+		n := f.codeLength(raw[i:])
+		code := string(raw[i : i+n])
+		i += n
+
+		if text, ok := f.toUnicode[code]; ok {
+			b.WriteString(text)
+			continue
+		}
+		if f.encoding != nil && n == 1 {
+			if r := f.encoding[code[0]]; r != 0 {
+				b.WriteRune(r)
+			}
+		}
+	}
+	return b.String()
+}
+
+// codeLength returns how many bytes the code at the start of raw takes
+func (f *pdfFont) codeLength(raw []byte) int {
+	for _, cs := range f.codespaces {
+		n := len(cs.lo)
+		if n == 0 || n > len(raw) {
+			continue
+		}
+		inRange := true
+		for j := 0; j < n; j++ {
+			if raw[j] < cs.lo[j] || raw[j] > cs.hi[j] {
+				inRange = false
+				break
+			}
+		}
+		if inRange {
+			return n
+		}
+	}
+	if f.composite && len(raw) >= 2 {
+		return 2
+	}
+	return 1
+}
+
+// parseCMap reads the codespace ranges and bfchar/bfrange mappings of a ToUnicode CMap
+func (f *pdfFont) parseCMap(data []byte) {
+	f.toUnicode = map[string]string{}
+	p := &pdfParser{data: data}
+	var operands []any
+	for {
+		obj, err := p.object()
+		if err != nil {
+			if err == errPDFSyntax {
+				continue
+			}
+			return
+		}
+		kw, ok := obj.(pdfKeyword)
+		if !ok {
+			operands = append(operands, obj)
+			continue
+		}
+
+		switch kw {
+		case "endcodespacerange":
+			for i := 0; i+1 < len(operands); i += 2 {
+				lo, ok1 := operands[i].(pdfString)
+				hi, ok2 := operands[i+1].(pdfString)
+				if ok1 && ok2 && len(lo) == len(hi) {
+					f.codespaces = append(f.codespaces, codespace{lo: []byte(lo), hi: []byte(hi)})
+				}
+			}
+		case "endbfchar":
+			for i := 0; i+1 < len(operands); i += 2 {
+				src, ok1 := operands[i].(pdfString)
+				dst, ok2 := operands[i+1].(pdfString)
+				if ok1 && ok2 {
+					f.toUnicode[string(src)] = utf16BE([]byte(dst))
+				}
+			}
+		case "endbfrange":
+			for i := 0; i+2 < len(operands); i += 3 {
+				lo, ok1 := operands[i].(pdfString)
+				hi, ok2 := operands[i+1].(pdfString)
+				if !ok1 || !ok2 || len(lo) != len(hi) || len(lo) == 0 || len(lo) > 4 {
+					continue
+				}
+				start, end := codeValue([]byte(lo)), codeValue([]byte(hi))
+				if end < start || end-start > 0xffff {
+					continue
+				}
+				switch dst := operands[i+2].(type) {
+				case pdfString:
+					base := []byte(dst)
+					for c := start; c <= end; c++ {
+						f.toUnicode[codeString(c, len(lo))] = utf16BE(addToLast(base, int(c-start)))
+					}
+				case []any:
+					for j, item := range dst {
+						if s, ok := item.(pdfString); ok && start+uint32(j) <= end {
+							f.toUnicode[codeString(start+uint32(j), len(lo))] = utf16BE([]byte(s))
+						}
+					}
+				}
+			}
+		}
+		operands = operands[:0]
+	}
+}
+
+// codeValue reads a big-endian character code
+func codeValue(b []byte) uint32 {
+	var v uint32
+	for _, c := range b {
+		v = v<<8 | uint32(c)
+	}
+	return v
+}
+
+// codeString writes a character code as n big-endian bytes
+func codeString(v uint32, n int) string {
+	b := make([]byte, n)
+	for i := n - 1; i >= 0; i-- {
+		b[i] = byte(v)
+		v >>= 8
+	}
+	return string(b)
+}
+
+// addToLast adds delta to the last byte of a bfrange destination, carrying into the one before
+func addToLast(base []byte, delta int) []byte {
+	out := append([]byte{}, base...)
+	if len(out) == 0 {
+		return out
+	}
+	v := int(out[len(out)-1]) + delta
+	out[len(out)-1] = byte(v)
+	if len(out) >= 2 {
+		out[len(out)-2] += byte(v >> 8)
+	}
+	return out
+}
+
+// utf16BE decodes UTF-16 big-endian text
+func utf16BE(b []byte) string {
+	if len(b) == 1 {
+		return string(rune(b[0]))
+	}
+	units := make([]uint16, len(b)/2)
+	for i := range units {
+		units[i] = uint16(b[2*i])<<8 | uint16(b[2*i+1])
+	}
+	return string(utf16.Decode(units))
+}
+
+// winAnsiEncoding is Windows-1252, the default for most simple fonts
+var winAnsiEncoding = func() [256]rune {
+	var enc [256]rune
+	for c := 0x20; c < 0x7f; c++ {
+		enc[c] = rune(c)
+	}
+	for c := 0xa0; c <= 0xff; c++ {
+		enc[c] = rune(c)
+	}
+	high := []rune{
+		'€', 0, '‚', 'ƒ', '„', '…', '†', '‡', 'ˆ', '‰', 'Š', '‹', 'Œ', 0, 'Ž', 0,
+		0, '‘', '’', '“', '”', '•', '–', '—', '˜', '™', 'š', '›', 'œ', 0, 'ž', 'Ÿ',
+	}
+	for i, r := range high {
+		enc[0x80+i] = r
+	}
+	enc['\t'], enc['\n'], enc['\r'] = ' ', '\n', '\n'
+	return enc
+}()
+
+// standardEncoding approximates Adobe StandardEncoding, which differs from WinAnsi mainly in its quotes
+func standardEncoding() [256]rune {
+	enc := winAnsiEncoding
+	enc['\''] = '’'
+	enc['`'] = '‘'
+	return enc
+}
+
+// glyphNames maps the glyph names used in Differences arrays that are not single letters
+var glyphNames = map[string]rune{
+	"space": ' ', "exclam": '!', "quotedbl": '"', "numbersign": '#', "dollar": '$', "percent": '%',
+	"ampersand": '&', "quotesingle": '\'', "parenleft": '(', "parenright": ')', "asterisk": '*',
+	"plus": '+', "comma": ',', "hyphen": '-', "period": '.', "slash": '/', "zero": '0', "one": '1',
+	"two": '2', "three": '3', "four": '4', "five": '5', "six": '6', "seven": '7', "eight": '8',
+	"nine": '9', "colon": ':', "semicolon": ';', "less": '<', "equal": '=', "greater": '>',
+	"question": '?', "at": '@', "bracketleft": '[', "backslash": '\\', "bracketright": ']',
+	"asciicircum": '^', "underscore": '_', "grave": '`', "braceleft": '{', "bar": '|',
+	"braceright": '}', "asciitilde": '~', "quoteleft": '‘', "quoteright": '’', "quotedblleft": '“',
+	"quotedblright": '”', "bullet": '•', "endash": '–', "emdash": '—', "ellipsis": '…',
+	"trademark": '™', "copyright": '©', "registered": '®', "degree": '°', "Euro": '€',
+	"rupee": '₹', "minus": '−', "multiply": '×', "divide": '÷', "nbspace": ' ',
+}
+
+// glyphRune returns the character for a glyph name
+func glyphRune(name string) (rune, bool) {
+	if r, ok := glyphNames[name]; ok {
+		return r, true
+	}
+	if base, _, found := strings.Cut(name, "."); found && base != "" {
+		name = base // Stylistic variants such as "a.sc"
+	}
+	if r, size := utf8.DecodeRuneInString(name); size == len(name) && r < 0x80 {
+		return r, true
+	}
+	if ligature := map[string]string{"fi": "ﬁ", "fl": "ﬂ", "ff": "ﬀ"}[name]; ligature != "" {
+		r, _ := utf8.DecodeRuneInString(ligature)
+		return r, true
+	}
+	for _, prefix := range []string{"uni", "u"} {
+		if strings.HasPrefix(name, prefix) && len(name) >= len(prefix)+4 {
+			if v, err := strconv.ParseUint(name[len(prefix):len(prefix)+4], 16, 32); err == nil {
+				return rune(v), true
+			}
+		}
+	}
+	return 0, false
+}
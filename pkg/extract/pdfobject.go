@@ -0,0 +1,348 @@
+package extract
+
+import (
+	"bytes"
+	"compress/flate"
+	"compress/zlib"
+	"encoding/ascii85"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+)
+
+// PDF object model. Values are nil, bool, float64, pdfName, pdfString, []any, pdfDict,
+// pdfRef, *pdfStream, or pdfKeyword for operators and other bare words.
+type (
+	pdfName    string
+	pdfString  string
+	pdfKeyword string
+	pdfDict    map[pdfName]any
+	pdfRef     struct{ num, gen int }
+	pdfStream  struct {
+		dict pdfDict
+		raw  []byte
+	}
+)
+
+// errPDFSyntax is returned when the parser meets bytes that do not form an object
+var errPDFSyntax = errors.New("malformed PDF object")
+
+// pdfParser reads PDF objects from a byte slice
+type pdfParser struct {
+	data []byte
+	pos  int
+}
+
+// isPDFSpace reports whether c is PDF white space
+func isPDFSpace(c byte) bool {
+	return c == 0 || c == '\t' || c == '\n' || c == '\f' || c == '\r' || c == ' '
+}
+
+// isPDFDelimiter reports whether c ends a bare word
+func isPDFDelimiter(c byte) bool {
+	switch c {
+	case '(', ')', '<', '>', '[', ']', '{', '}', '/', '%':
+		return true
+	}
+	return isPDFSpace(c)
+}
+
+// skipSpace moves past white space and comments
+func (p *pdfParser) skipSpace() {
+	for p.pos < len(p.data) {
+		c := p.data[p.pos]
+		if c == '%' {
+			for p.pos < len(p.data) && p.data[p.pos] != '\n' && p.data[p.pos] != '\r' {
+				p.pos++
+			}
+			continue
+		}
+		if !isPDFSpace(c) {
+			return
+		}
+		p.pos++
+	}
+}
+
+// word reads a run of regular characters
+func (p *pdfParser) word() string {
+	start := p.pos
+	for p.pos < len(p.data) && !isPDFDelimiter(p.data[p.pos]) {
+		p.pos++
+	}
+	return string(p.data[start:p.pos])
+}
+
+// object reads the next object, or returns io.EOF at the end of the data
+func (p *pdfParser) object() (any, error) {
+	p.skipSpace()
+	if p.pos >= len(p.data) {
+		return nil, io.EOF
+	}
+
+	switch c := p.data[p.pos]; {
+	case c == '/':
+		p.pos++
+		return pdfName(decodeNameEscapes(p.word())), nil
+	case c == '(':
+		p.pos++
+		return p.literalString(), nil
+	case c == '<' && p.pos+1 < len(p.data) && p.data[p.pos+1] == '<':
+		p.pos += 2
+		return p.dict()
+	case c == '<':
+		p.pos++
+		return p.hexString(), nil
+	case c == '[':
+		p.pos++
+		return p.array()
+	case c == ']' || c == '>' || c == ')' || c == '{' || c == '}':
+		p.pos++
+		return pdfKeyword(c), nil
+	case c == '+' || c == '-' || c == '.' || (c >= '0' && c <= '9'):
+		return p.number()
+	}
+
+	word := p.word()
+	switch word {
+	case "":
+		p.pos++
+		return nil, errPDFSyntax
+	case "true":
+		return true, nil
+	case "false":
+		return false, nil
+	case "null":
+		return nil, nil
+	}
+	return pdfKeyword(word), nil
+}
+
+// number reads a number, or an indirect reference "num gen R"
+func (p *pdfParser) number() (any, error) {
+	word := p.word()
+	n, err := strconv.ParseFloat(word, 64)
+	if err != nil {
+		// Some writers emit "--1" or "1.2.3"; treat them as zero like most readers do
+		return 0.0, nil
+	}
+	if n != float64(int(n)) || n < 0 {
+		return n, nil
+	}
+
+	// Look ahead for "gen R"
+	save := p.pos
+	p.skipSpace()
+	gen := p.word()
+	if g, err := strconv.Atoi(gen); err == nil {
+		p.skipSpace()
+		if p.pos < len(p.data) && p.data[p.pos] == 'R' && (p.pos+1 == len(p.data) || isPDFDelimiter(p.data[p.pos+1])) {
+			p.pos++
+			return pdfRef{num: int(n), gen: g}, nil
+		}
+	}
+	p.pos = save
+	return n, nil
+}
+
+// array reads objects up to the closing bracket
+func (p *pdfParser) array() ([]any, error) {
+	var arr []any
+	for {
+		p.skipSpace()
+		if p.pos >= len(p.data) {
+			return arr, nil
+		}
+		if p.data[p.pos] == ']' {
+			p.pos++
+			return arr, nil
+		}
+		obj, err := p.object()
+		if err != nil {
+			return arr, err
+		}
+		arr = append(arr, obj)
+	}
+}
+
+// dict reads key/value pairs up to the closing ">>"
+func (p *pdfParser) dict() (pdfDict, error) {
+	d := pdfDict{}
+	for {
+		p.skipSpace()
+		if p.pos+1 >= len(p.data) {
+			return d, nil
+		}
+		if p.data[p.pos] == '>' && p.data[p.pos+1] == '>' {
+			p.pos += 2
+			return d, nil
+		}
+		key, err := p.object()
+		if err != nil {
+			return d, err
+		}
+		name, ok := key.(pdfName)
+		if !ok {
+			continue
+		}
+		value, err := p.object()
+		if err != nil {
+			return d, err
+		}
+		d[name] = value
+	}
+}
+
+// literalString reads a "(...)" string after the opening parenthesis
+func (p *pdfParser) literalString() pdfString {
+	var b []byte
+	depth := 1
+	for p.pos < len(p.data) {
+		c := p.data[p.pos]
+		p.pos++
+		switch c {
+		case '(':
+			depth++
+		case ')':
+			depth--
+			if depth == 0 {
+				return pdfString(b)
+			}
+		case '\\':
+			if p.pos >= len(p.data) {
+				continue
+			}
+			c = p.data[p.pos]
+			p.pos++
+			switch c {
+			case 'n':
+				c = '\n'
+			case 'r':
+				c = '\r'
+			case 't':
+				c = '\t'
+			case 'b':
+				c = '\b'
+			case 'f':
+				c = '\f'
+			case '\r':
+				if p.pos < len(p.data) && p.data[p.pos] == '\n' {
+					p.pos++
+				}
+				continue
+			case '\n':
+				continue
+			default:
+				if c >= '0' && c <= '7' {
+					v := int(c - '0')
+					for i := 0; i < 2 && p.pos < len(p.data) && p.data[p.pos] >= '0' && p.data[p.pos] <= '7'; i++ {
+						v = v*8 + int(p.data[p.pos]-'0')
+						p.pos++
+					}
+					c = byte(v)
+				}
+			}
+		}
+		b = append(b, c)
+	}
+	return pdfString(b)
+}
+
+// hexString reads a "<...>" string after the opening bracket
+func (p *pdfParser) hexString() pdfString {
+	var digits []byte
+	for p.pos < len(p.data) && p.data[p.pos] != '>' {
+		if c := p.data[p.pos]; !isPDFSpace(c) {
+			digits = append(digits, c)
+		}
+		p.pos++
+	}
+	p.pos++
+	if len(digits)%2 == 1 {
+		digits = append(digits, '0')
+	}
+	out := make([]byte, len(digits)/2)
+	n, _ := hex.Decode(out, digits)
+	return pdfString(out[:n])
+}
+
+// decodeNameEscapes expands "#xx" escapes in a name
+func decodeNameEscapes(name string) string {
+	if !bytes.ContainsRune([]byte(name), '#') {
+		return name
+	}
+	var b []byte
+	for i := 0; i < len(name); i++ {
+		if name[i] == '#' && i+2 < len(name) {
+			if v, err := strconv.ParseUint(name[i+1:i+3], 16, 8); err == nil {
+				b = append(b, byte(v))
+				i += 2
+				continue
+			}
+		}
+		b = append(b, name[i])
+	}
+	return string(b)
+}
+
+// decodeStream applies a stream's filters to its raw bytes
+func decodeStream(s *pdfStream, resolve func(any) any) ([]byte, error) {
+	var filters []any
+	switch f := resolve(s.dict["Filter"]).(type) {
+	case pdfName:
+		filters = []any{f}
+	case []any:
+		filters = f
+	}
+
+	data := s.raw
+	for _, f := range filters {
+		name, _ := resolve(f).(pdfName)
+		var err error
+		switch name {
+		case "FlateDecode", "Fl":
+			data, err = inflate(data)
+		case "ASCIIHexDecode", "AHx":
+			data = []byte((&pdfParser{data: append(append([]byte{}, data...), '>')}).hexString())
+		case "ASCII85Decode", "A85":
+			data, err = decodeASCII85(data)
+		default:
+			return nil, fmt.Errorf("unsupported PDF filter %s", name)
+		}
+		if err != nil {
+			return nil, err
+		}
+	}
+	return data, nil
+}
+
+// inflate decompresses Flate data, keeping whatever could be read from truncated streams
+func inflate(data []byte) ([]byte, error) {
+	var r io.ReadCloser
+	r, err := zlib.NewReader(bytes.NewReader(data))
+	if err != nil {
+		r = flate.NewReader(bytes.NewReader(data))
+	}
+	defer r.Close()
+	out, err := io.ReadAll(io.LimitReader(r, maxPartSize))
+	if err != nil && len(out) == 0 {
+		return nil, fmt.Errorf("failed to inflate PDF stream: %w", err)
+	}
+	return out, nil
+}
+
+// decodeASCII85 decodes "<~ ... ~>" data
+func decodeASCII85(data []byte) ([]byte, error) {
+	data = bytes.TrimPrefix(bytes.TrimSpace(data), []byte("<~"))
+	if i := bytes.Index(data, []byte("~>")); i >= 0 {
+		data = data[:i]
+	}
+	out := make([]byte, len(data))
+	n, _, err := ascii85.Decode(out, data, true)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode ASCII85 stream: %w", err)
+	}
+	return out[:n], nil
+}
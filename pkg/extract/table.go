@@ -0,0 +1,120 @@
+package extract
+
+import (
+	"bytes"
+	"encoding/csv"
+	"fmt"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+)
+
+// renderTable turns rows of cells into lines of text. When the first row looks like a header,
+// every other row is written as "Header: value, Header: value" so a price or plan row makes sense
+// on its own once the table is cut into passages; otherwise cells are joined with " | ".
+func renderTable(rows [][]string) string {
+	rows = trimRows(rows)
+	if len(rows) == 0 {
+		return ""
+	}
+
+	var b strings.Builder
+	header := rows[0]
+	if len(rows) > 1 && isHeader(header) {
+		for _, row := range rows[1:] {
+			var cells []string
+			for i, cell := range row {
+				if cell == "" {
+					continue
+				}
+				if i < len(header) && header[i] != "" {
+					cells = append(cells, header[i]+": "+cell)
+				} else {
+					cells = append(cells, cell)
+				}
+			}
+			if len(cells) > 0 {
+				b.WriteString(strings.Join(cells, ", "))
+				b.WriteString("\n")
+			}
+		}
+		return b.String()
+	}
+
+	for _, row := range rows {
+		b.WriteString(strings.Join(row, " | "))
+		b.WriteString("\n")
+	}
+	return b.String()
+}
+
+// trimRows collapses white space in cells and drops empty rows and trailing empty cells
+func trimRows(rows [][]string) [][]string {
+	out := make([][]string, 0, len(rows))
+	for _, row := range rows {
+		cells := make([]string, len(row))
+		last := -1
+		for i, cell := range row {
+			cells[i] = strings.Join(strings.Fields(cell), " ")
+			if cells[i] != "" {
+				last = i
+			}
+		}
+		if last >= 0 {
+			out = append(out, cells[:last+1])
+		}
+	}
+	return out
+}
+
+// isHeader reports whether a row reads like column names: at least two cells, all filled and none numeric
+func isHeader(row []string) bool {
+	if len(row) < 2 {
+		return false
+	}
+	for _, cell := range row {
+		if cell == "" {
+			return false
+		}
+		if _, err := strconv.ParseFloat(strings.NewReplacer(",", "", "₹", "", "$", "", "%", "").Replace(cell), 64); err == nil {
+			return false
+		}
+	}
+	return true
+}
+
+// extractCSV reads comma, semicolon or tab separated values
+func extractCSV(data []byte) (*Document, error) {
+	data = bytes.TrimPrefix(data, []byte("\ufeff"))
+	if !utf8.Valid(data) {
+		data = []byte(latin1(data))
+	}
+
+	r := csv.NewReader(bytes.NewReader(data))
+	r.Comma = sniffDelimiter(data)
+	r.FieldsPerRecord = -1
+	r.LazyQuotes = true
+	rows, err := r.ReadAll()
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse CSV: %w", err)
+	}
+
+	var b builder
+	b.add("", 0, renderTable(rows))
+	return b.document(0), nil
+}
+
+// sniffDelimiter picks the separator that occurs most on the first line
+func sniffDelimiter(data []byte) rune {
+	line := data
+	if i := bytes.IndexByte(data, '\n'); i >= 0 {
+		line = data[:i]
+	}
+	best, bestCount := ',', 0
+	for _, d := range []rune{',', ';', '\t', '|'} {
+		if n := bytes.Count(line, []byte(string(d))); n > bestCount {
+			best, bestCount = d, n
+		}
+	}
+	return best
+}
@@ -0,0 +1,47 @@
+package mongo
+
+import (
+	"context"
+	"fmt"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// countersCollection holds one document per sequence: {_id: <name>, seq: <last value handed out>}
+const countersCollection = "counters"
+
+// NextSequence atomically increments the named counter and returns its new value; a new counter starts at 1
+func (c *Client) NextSequence(ctx context.Context, name string) (int64, error) {
+	var counter struct {
+		Seq int64 `bson:"seq"`
+	}
+	opts := options.FindOneAndUpdate().SetUpsert(true).SetReturnDocument(options.After)
+	update := bson.M{"$inc": bson.M{"seq": int64(1)}}
+
+	err := c.Collection(countersCollection).FindOneAndUpdate(ctx, bson.M{"_id": name}, update, opts).Decode(&counter)
+	if mongo.IsDuplicateKeyError(err) {
+		// Two first uses raced to create the counter; it exists now
+		err = c.Collection(countersCollection).FindOneAndUpdate(ctx, bson.M{"_id": name}, update, opts).Decode(&counter)
+	}
+	if err != nil {
+		return 0, fmt.Errorf("failed to advance sequence %s: %w", name, err)
+	}
+	return counter.Seq, nil
+}
+
+// RaiseSequence moves the named counter up to at least floor, e.g. past IDs assigned before the counter existed
+func (c *Client) RaiseSequence(ctx context.Context, name string, floor int64) error {
+	opts := options.Update().SetUpsert(true)
+	update := bson.M{"$max": bson.M{"seq": floor}}
+
+	_, err := c.Collection(countersCollection).UpdateOne(ctx, bson.M{"_id": name}, update, opts)
+	if mongo.IsDuplicateKeyError(err) {
+		_, err = c.Collection(countersCollection).UpdateOne(ctx, bson.M{"_id": name}, update, opts)
+	}
+	if err != nil {
+		return fmt.Errorf("failed to raise sequence %s: %w", name, err)
+	}
+	return nil
+}
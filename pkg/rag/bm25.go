@@ -0,0 +1,121 @@
+package rag
+
+import (
+	"math"
+	"sort"
+)
+
+// BM25 parameters: term-frequency saturation and document-length normalisation
+const (
+	bm25K1 = 1.2
+	bm25B  = 0.75
+)
+
+// Document is a passage to index
+type Document struct {
+	ID   string
+	Text string
+}
+
+// Hit is a search result, best first
+type Hit struct {
+	ID    string
+	Score float64
+}
+
+// posting records how often a term occurs in one document
+type posting struct {
+	doc int
+	tf  int
+}
+
+// BM25 is an in-memory Okapi BM25 index over a fixed set of passages
+type BM25 struct {
+	ids      []string
+	lengths  []int
+	avgLen   float64
+	postings map[string][]posting
+}
+
+// NewBM25 indexes docs
+func NewBM25(docs []Document) *BM25 {
+	ix := &BM25{
+		ids:      make([]string, len(docs)),
+		lengths:  make([]int, len(docs)),
+		postings: make(map[string][]posting),
+	}
+
+	total := 0
+	for i, doc := range docs {
+		terms := Tokenize(doc.Text)
+		ix.ids[i] = doc.ID
+		ix.lengths[i] = len(terms)
+		total += len(terms)
+
+		counts := make(map[string]int, len(terms))
+		for _, term := range terms {
+			counts[term]++
+		}
+		for term, tf := range counts {
+			ix.postings[term] = append(ix.postings[term], posting{doc: i, tf: tf})
+		}
+	}
+	if len(docs) > 0 {
+		ix.avgLen = float64(total) / float64(len(docs))
+	}
+	return ix
+}
+
+// Len returns the number of indexed passages
+func (ix *BM25) Len() int {
+	return len(ix.ids)
+}
+
+// Search returns up to k passages sharing at least one term with query, best first
+func (ix *BM25) Search(query string, k int) []Hit {
+	if k <= 0 || ix.avgLen == 0 {
+		return nil
+	}
+
+	n := float64(len(ix.ids))
+	scores := make(map[int]float64)
+	seen := make(map[string]bool)
+	for _, term := range Tokenize(query) {
+		if seen[term] {
+			continue
+		}
+		seen[term] = true
+
+		list := ix.postings[term]
+		if len(list) == 0 {
+			continue
+		}
+		df := float64(len(list))
+		idf := math.Log(1 + (n-df+0.5)/(df+0.5))
+		for _, p := range list {
+			tf := float64(p.tf)
+			norm := bm25K1 * (1 - bm25B + bm25B*float64(ix.lengths[p.doc])/ix.avgLen)
+			scores[p.doc] += idf * tf * (bm25K1 + 1) / (tf + norm)
+		}
+	}
+
+	hits := make([]Hit, 0, len(scores))
+	for doc, score := range scores {
+		hits = append(hits, Hit{ID: ix.ids[doc], Score: score})
+	}
+	sortHits(hits)
+	if len(hits) > k {
+		hits = hits[:k]
+	}
+	return hits
+}
+
+// sortHits orders hits by score, breaking ties by ID so results are stable
+func sortHits(hits []Hit) {
+	sort.Slice(hits, func(i, j int) bool {
+		if hits[i].Score != hits[j].Score {
+			return hits[i].Score > hits[j].Score
+		}
+		return hits[i].ID < hits[j].ID
+	})
+}
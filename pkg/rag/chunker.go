@@ -0,0 +1,124 @@
+// Package rag splits documents into passages and ranks them against a query (BM25, optionally fused with embeddings)
+package rag
+
+import (
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// Chunk is a passage of a document small enough to put in a prompt
+type Chunk struct {
+	Index int    // Position of the chunk in the document
+	Text  string // Passage with whitespace collapsed
+	Start int    // Byte offset of the passage in the source text
+	End   int    // Byte offset just past the passage
+}
+
+// segment is a sentence or line of the source text
+type segment struct {
+	start, end int
+	words      int
+}
+
+// Split cuts text into chunks of at most maxWords words, breaking between sentences and lines where it can.
+// Consecutive chunks share up to overlapWords words of whole sentences so an answer split across a boundary
+// is still found in one piece.
+func Split(text string, maxWords, overlapWords int) []Chunk {
+	if maxWords <= 0 {
+		maxWords = 120
+	}
+	if overlapWords < 0 || overlapWords >= maxWords {
+		overlapWords = 0
+	}
+
+	segs := splitLongSegments(text, sentences(text), maxWords)
+	chunks := make([]Chunk, 0, len(segs)/4+1)
+	for i := 0; i < len(segs); {
+		j, words := i, 0
+		for j < len(segs) && (j == i || words+segs[j].words <= maxWords) {
+			words += segs[j].words
+			j++
+		}
+
+		start, end := segs[i].start, segs[j-1].end
+		chunks = append(chunks, Chunk{
+			Index: len(chunks),
+			Text:  strings.Join(strings.Fields(text[start:end]), " "),
+			Start: start,
+			End:   end,
+		})
+		if j == len(segs) {
+			break
+		}
+
+		// Step back over trailing sentences for the overlap, always moving forward by at least one
+		next, overlap := j, 0
+		for next-1 > i && overlap+segs[next-1].words <= overlapWords {
+			next--
+			overlap += segs[next].words
+		}
+		i = next
+	}
+	return chunks
+}
+
+// sentences finds sentence and line boundaries; segments without words are dropped
+func sentences(text string) []segment {
+	var segs []segment
+	start := 0
+	emit := func(end int) {
+		if words := len(strings.Fields(text[start:end])); words > 0 {
+			segs = append(segs, segment{start: start, end: end, words: words})
+		}
+		start = end
+	}
+
+	for i, r := range text {
+		switch r {
+		case '\n':
+			emit(i + 1)
+		case '.', '!', '?', '।', '॥':
+			next := i + utf8.RuneLen(r)
+			if next == len(text) {
+				break
+			}
+			if after, _ := utf8.DecodeRuneInString(text[next:]); unicode.IsSpace(after) {
+				emit(next)
+			}
+		}
+	}
+	emit(len(text))
+	return segs
+}
+
+// splitLongSegments breaks segments longer than maxWords (run-on text, tables flattened to one line)
+// into word windows
+func splitLongSegments(text string, segs []segment, maxWords int) []segment {
+	out := make([]segment, 0, len(segs))
+	for _, seg := range segs {
+		if seg.words <= maxWords {
+			out = append(out, seg)
+			continue
+		}
+
+		words, inWord, start := 0, false, seg.start
+		for i, r := range text[seg.start:seg.end] {
+			pos := seg.start + i
+			if unicode.IsSpace(r) {
+				inWord = false
+				continue
+			}
+			if !inWord {
+				if words == maxWords {
+					out = append(out, segment{start: start, end: pos, words: words})
+					start, words = pos, 0
+				}
+				words++
+				inWord = true
+			}
+		}
+		out = append(out, segment{start: start, end: seg.end, words: words})
+	}
+	return out
+}
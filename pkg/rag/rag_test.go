@@ -0,0 +1,81 @@
+package rag
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestSplit_BreaksBetweenSentencesWithOverlap(t *testing.T) {
+	text := "One two three four. Five six seven eight.\nNine ten eleven twelve. Thirteen fourteen."
+	chunks := Split(text, 8, 4)
+
+	want := []string{
+		"One two three four. Five six seven eight.",
+		"Five six seven eight. Nine ten eleven twelve.",
+		"Nine ten eleven twelve. Thirteen fourteen.",
+	}
+	if len(chunks) != len(want) {
+		t.Fatalf("got %d chunks, want %d: %+v", len(chunks), len(want), chunks)
+	}
+	for i, c := range chunks {
+		if c.Text != want[i] || c.Index != i {
+			t.Errorf("chunk %d = %q, want %q", i, c.Text, want[i])
+		}
+		if got := strings.Join(strings.Fields(text[c.Start:c.End]), " "); got != c.Text {
+			t.Errorf("chunk %d offsets point at %q", i, got)
+		}
+	}
+}
+
+func TestSplit_LongSentence(t *testing.T) {
+	words := make([]string, 25)
+	for i := range words {
+		words[i] = "w"
+	}
+	chunks := Split(strings.Join(words, " "), 10, 3)
+	if len(chunks) != 3 {
+		t.Fatalf("got %d chunks, want 3", len(chunks))
+	}
+	if n := len(strings.Fields(chunks[2].Text)); n != 5 {
+		t.Errorf("last chunk has %d words, want 5", n)
+	}
+}
+
+func TestTokenize(t *testing.T) {
+	got := Tokenize("What are the EMI plans? क्या कीमत है, price 499!")
+	want := []string{"emi", "plan", "क्या", "कीमत", "है", "price", "499"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("Tokenize = %q, want %q", got, want)
+	}
+}
+
+func TestBM25_RanksRelevantPassageFirst(t *testing.T) {
+	ix := NewBM25([]Document{
+		{ID: "intro", Text: "Acme Fibre offers home broadband across Pune and Mumbai."},
+		{ID: "price", Text: "The basic plan costs 499 rupees per month. The premium plan costs 999 rupees per month."},
+		{ID: "support", Text: "Support is available 24x7 on the helpline and over email."},
+	})
+
+	hits := ix.Search("how much does the premium plan cost", 2)
+	if len(hits) == 0 || hits[0].ID != "price" {
+		t.Fatalf("hits = %+v, want price first", hits)
+	}
+	if got := ix.Search("refund", 3); len(got) != 0 {
+		t.Errorf("unmatched query returned %+v", got)
+	}
+}
+
+func TestFuse(t *testing.T) {
+	keyword := []Hit{{ID: "a", Score: 9}, {ID: "b", Score: 5}}
+	semantic := []Hit{{ID: "b", Score: 0.9}, {ID: "c", Score: 0.8}}
+	got := Fuse(keyword, semantic)
+	if got[0].ID != "b" || len(got) != 3 {
+		t.Errorf("Fuse = %+v, want b first of 3", got)
+	}
+
+	vectors := map[string][]float32{"x": {1, 0}, "y": {0.7, 0.7}, "z": {-1, 0}}
+	if hits := VectorSearch([]float32{1, 0.1}, vectors, 5); len(hits) != 2 || hits[0].ID != "x" {
+		t.Errorf("VectorSearch = %+v, want x then y", hits)
+	}
+}
@@ -0,0 +1,50 @@
+package rag
+
+import (
+	"strings"
+	"unicode"
+)
+
+// stopWords are dropped from queries and documents: English and romanised Hindi function words
+var stopWords = map[string]bool{
+	"a": true, "an": true, "and": true, "are": true, "as": true, "at": true, "be": true, "but": true,
+	"by": true, "can": true, "do": true, "does": true, "for": true, "from": true, "has": true, "have": true,
+	"how": true, "i": true, "if": true, "in": true, "is": true, "it": true, "me": true, "my": true,
+	"of": true, "on": true, "or": true, "our": true, "so": true, "that": true, "the": true, "this": true,
+	"to": true, "was": true, "we": true, "what": true, "when": true, "which": true, "will": true, "with": true,
+	"you": true, "your": true,
+	"aap": true, "aur": true, "bhi": true, "hai": true, "hain": true, "ho": true, "ka": true, "ke": true,
+	"ki": true, "ko": true, "kya": true, "main": true, "mein": true, "se": true, "toh": true, "ye": true,
+	"yeh": true, "woh": true,
+}
+
+// Tokenize lowercases text and splits it into index terms: runs of letters, marks and digits in any script
+// (so Devanagari vowel signs stay inside their word), minus stop words, with a light plural stem on Latin words
+func Tokenize(text string) []string {
+	fields := strings.FieldsFunc(strings.ToLower(text), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsMark(r) && !unicode.IsDigit(r)
+	})
+
+	terms := make([]string, 0, len(fields))
+	for _, field := range fields {
+		if stopWords[field] {
+			continue
+		}
+		terms = append(terms, stem(field))
+	}
+	return terms
+}
+
+// stem folds simple English plurals ("plans" → "plan", "policies" → "policy")
+func stem(word string) string {
+	if len(word) <= 3 || word[len(word)-1] != 's' {
+		return word
+	}
+	switch {
+	case strings.HasSuffix(word, "ies") && len(word) > 4:
+		return word[:len(word)-3] + "y"
+	case strings.HasSuffix(word, "ss"), strings.HasSuffix(word, "us"), strings.HasSuffix(word, "is"):
+		return word
+	}
+	return word[:len(word)-1]
+}
@@ -0,0 +1,55 @@
+package rag
+
+import "math"
+
+// rrfK damps the weight of top ranks in reciprocal rank fusion (the value from the original paper)
+const rrfK = 60
+
+// Cosine returns the cosine similarity of two vectors, 0 if either is empty or their lengths differ
+func Cosine(a, b []float32) float64 {
+	if len(a) == 0 || len(a) != len(b) {
+		return 0
+	}
+	var dot, na, nb float64
+	for i := range a {
+		dot += float64(a[i]) * float64(b[i])
+		na += float64(a[i]) * float64(a[i])
+		nb += float64(b[i]) * float64(b[i])
+	}
+	if na == 0 || nb == 0 {
+		return 0
+	}
+	return dot / math.Sqrt(na*nb)
+}
+
+// VectorSearch ranks vectors by cosine similarity to query and returns the best k
+func VectorSearch(query []float32, vectors map[string][]float32, k int) []Hit {
+	hits := make([]Hit, 0, len(vectors))
+	for id, v := range vectors {
+		if score := Cosine(query, v); score > 0 {
+			hits = append(hits, Hit{ID: id, Score: score})
+		}
+	}
+	sortHits(hits)
+	if len(hits) > k {
+		hits = hits[:k]
+	}
+	return hits
+}
+
+// Fuse merges ranked lists with reciprocal rank fusion, so keyword and semantic scores
+// need not be on the same scale
+func Fuse(lists ...[]Hit) []Hit {
+	scores := make(map[string]float64)
+	for _, list := range lists {
+		for rank, hit := range list {
+			scores[hit.ID] += 1 / float64(rrfK+rank+1)
+		}
+	}
+	hits := make([]Hit, 0, len(scores))
+	for id, score := range scores {
+		hits = append(hits, Hit{ID: id, Score: score})
+	}
+	sortHits(hits)
+	return hits
+}
@@ -1,17 +1,34 @@
 package storage
 
 import (
+	"errors"
 	"fmt"
 	"io"
 	"net/http"
 	"os"
+	"path"
 	"path/filepath"
 	"strings"
+	"time"
 )
 
+// ErrNotSupported is returned by drivers that cannot store files themselves
+var ErrNotSupported = errors.New("not supported by this storage driver")
+
 type Driver interface {
 	GetRecordingURL(callSID string) (string, error)
 	DownloadRecording(callSID string, exotelURL string) error
+	// SaveRecording stores a recording file under key (e.g. "voicebot/<call_sid>.wav")
+	SaveRecording(key string, data []byte) error
+	// OpenRecording opens a file stored with SaveRecording
+	OpenRecording(key string) (*Recording, error)
+}
+
+// Recording is a stored recording opened for reading; the caller must close Content
+type Recording struct {
+	Content io.ReadSeekCloser
+	Size    int64
+	ModTime time.Time
 }
 
 type ExotelProxyDriver struct {
@@ -35,6 +52,14 @@ func (d *ExotelProxyDriver) DownloadRecording(callSID string, exotelURL string)
 	return nil
 }
 
+func (d *ExotelProxyDriver) SaveRecording(key string, data []byte) error {
+	return ErrNotSupported
+}
+
+func (d *ExotelProxyDriver) OpenRecording(key string) (*Recording, error) {
+	return nil, ErrNotSupported
+}
+
 type LocalDriver struct {
 	basePath string
 }
@@ -83,6 +108,53 @@ func (d *LocalDriver) DownloadRecording(callSID string, exotelURL string) error
 	return nil
 }
 
+func (d *LocalDriver) SaveRecording(key string, data []byte) error {
+	filePath, err := d.keyPath(key)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(filePath), 0755); err != nil {
+		return fmt.Errorf("failed to create storage directory: %w", err)
+	}
+
+	// Write to a temporary file first so readers never see a partial recording
+	tmpPath := filePath + ".tmp"
+	if err := os.WriteFile(tmpPath, data, 0644); err != nil {
+		return fmt.Errorf("failed to write file: %w", err)
+	}
+	if err := os.Rename(tmpPath, filePath); err != nil {
+		os.Remove(tmpPath)
+		return fmt.Errorf("failed to write file: %w", err)
+	}
+	return nil
+}
+
+func (d *LocalDriver) OpenRecording(key string) (*Recording, error) {
+	filePath, err := d.keyPath(key)
+	if err != nil {
+		return nil, err
+	}
+	file, err := os.Open(filePath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open recording: %w", err)
+	}
+	info, err := file.Stat()
+	if err != nil {
+		file.Close()
+		return nil, fmt.Errorf("failed to stat recording: %w", err)
+	}
+	return &Recording{Content: file, Size: info.Size(), ModTime: info.ModTime()}, nil
+}
+
+// keyPath maps a storage key to a file under basePath; keys cannot escape it
+func (d *LocalDriver) keyPath(key string) (string, error) {
+	cleaned := strings.TrimPrefix(path.Clean("/"+key), "/")
+	if cleaned == "" {
+		return "", fmt.Errorf("storage key is required")
+	}
+	return filepath.Join(d.basePath, filepath.FromSlash(cleaned)), nil
+}
+
 func NewDriver(driverType string, accountSID string, localPath string) (Driver, error) {
 	switch strings.ToLower(driverType) {
 	case "exotel-proxy", "proxy":
@@ -7,6 +7,8 @@ import (
 	"fmt"
 	"io"
 	"net/http"
+	"net/url"
+	"strconv"
 	"time"
 
 	"go.uber.org/zap"
@@ -41,20 +43,27 @@ func (d *DeepgramClient) IsAvailable() bool {
 
 // STTRequest represents a Deepgram STT request
 type STTRequest struct {
-	AudioData   []byte // Raw PCM16 audio data (16kHz, mono)
-	SampleRate  int    // Sample rate (should be 16000)
-	Language    string // Optional language code (e.g., "en", "hi")
-	Model       string // Optional model (e.g., "nova-2", "base")
-	Punctuate   bool   // Add punctuation
-	Interim     bool   // Return interim results
-	Endpointing bool   // Enable endpointing
+	AudioData     []byte   // Raw PCM16 audio data (16kHz, mono)
+	SampleRate    int      // Sample rate (should be 16000)
+	Language      string   // Optional language code (e.g., "en", "hi")
+	LanguageHints []string // Restricts language detection to these codes when Language is empty
+	Model         string   // Optional model (e.g., "nova-2", "base")
+	Punctuate     bool     // Add punctuation
+	Interim       bool     // Return interim results
+	Endpointing   bool     // Enable endpointing
 }
 
 // STTResponse represents a Deepgram STT response
 type STTResponse struct {
-	Text     string
-	Language string
-	IsFinal  bool
+	Text         string
+	Language     string
+	IsFinal      bool
+	SpeechFinal  bool    // Deepgram detected the end of the speaker's utterance (streaming only)
+	UtteranceEnd bool    // Deepgram saw a gap after the last final word (streaming only)
+	FromFinalize bool    // Result was flushed by an explicit Finalize request (streaming only)
+	Confidence   float64 // Confidence of the top alternative
+	Words        []Word  // Word-level timing and confidence, when the provider reports them
+	Provider     string  // Provider that produced the transcript
 }
 
 // SpeechToText converts speech audio to text using Deepgram
@@ -80,28 +89,29 @@ func (d *DeepgramClient) SpeechToText(ctx context.Context, req *STTRequest) (*ST
 	}
 
 	// Build URL with query parameters
-	url := fmt.Sprintf("%s/listen?model=%s&language=%s&punctuate=%t&interim_results=%t&endpointing=%t",
-		d.baseURL,
-		model,
-		req.Language,
-		req.Punctuate,
-		req.Interim,
-		req.Endpointing,
-	)
-
-	if req.Language == "" {
-		// Remove language parameter if not specified (let Deepgram auto-detect)
-		url = fmt.Sprintf("%s/listen?model=%s&punctuate=%t&interim_results=%t&endpointing=%t",
-			d.baseURL,
-			model,
-			req.Punctuate,
-			req.Interim,
-			req.Endpointing,
-		)
+	q := url.Values{}
+	q.Set("encoding", "linear16")
+	q.Set("sample_rate", strconv.Itoa(sampleRate))
+	q.Set("channels", "1")
+	q.Set("model", model)
+	q.Set("punctuate", strconv.FormatBool(req.Punctuate))
+	q.Set("interim_results", strconv.FormatBool(req.Interim))
+	q.Set("endpointing", strconv.FormatBool(req.Endpointing))
+	switch {
+	case req.Language != "":
+		q.Set("language", req.Language)
+	case len(req.LanguageHints) > 0:
+		// Auto-detect among the hinted languages only
+		for _, hint := range req.LanguageHints {
+			q.Add("detect_language", hint)
+		}
+	default:
+		q.Set("detect_language", "true")
 	}
+	listenURL := d.baseURL + "/listen?" + q.Encode()
 
 	// Create HTTP request
-	httpReq, err := http.NewRequestWithContext(ctx, "POST", url, bytes.NewReader(req.AudioData))
+	httpReq, err := http.NewRequestWithContext(ctx, "POST", listenURL, bytes.NewReader(req.AudioData))
 	if err != nil {
 		return nil, fmt.Errorf("failed to create request: %w", err)
 	}
@@ -128,9 +138,11 @@ func (d *DeepgramClient) SpeechToText(ctx context.Context, req *STTRequest) (*ST
 		Results struct {
 			Channels []struct {
 				Alternatives []struct {
-					Transcript string  `json:"transcript"`
-					Confidence float64 `json:"confidence"`
+					Transcript string         `json:"transcript"`
+					Confidence float64        `json:"confidence"`
+					Words      []deepgramWord `json:"words"`
 				} `json:"alternatives"`
+				DetectedLanguage string `json:"detected_language"`
 			} `json:"channels"`
 		} `json:"results"`
 		Metadata struct {
@@ -149,13 +161,22 @@ func (d *DeepgramClient) SpeechToText(ctx context.Context, req *STTRequest) (*ST
 
 	// Extract transcript
 	text := ""
+	confidence := 0.0
+	detected := ""
+	var words []Word
 	if len(deepgramResp.Results.Channels) > 0 {
+		detected = deepgramResp.Results.Channels[0].DetectedLanguage
 		if len(deepgramResp.Results.Channels[0].Alternatives) > 0 {
 			text = deepgramResp.Results.Channels[0].Alternatives[0].Transcript
+			confidence = deepgramResp.Results.Channels[0].Alternatives[0].Confidence
+			words = convertDeepgramWords(deepgramResp.Results.Channels[0].Alternatives[0].Words)
 		}
 	}
 
 	language := deepgramResp.Metadata.Language
+	if language == "" {
+		language = detected
+	}
 	if language == "" {
 		language = req.Language
 		if language == "" {
@@ -164,9 +185,64 @@ func (d *DeepgramClient) SpeechToText(ctx context.Context, req *STTRequest) (*ST
 	}
 
 	return &STTResponse{
-		Text:     text,
-		Language: language,
-		IsFinal:  true, // Prerecorded API always returns final results
+		Text:       text,
+		Language:   language,
+		IsFinal:    true, // Prerecorded API always returns final results
+		Confidence: confidence,
+		Words:      words,
+		Provider:   d.Name(),
 	}, nil
 }
 
+// Name returns the provider name
+func (d *DeepgramClient) Name() string {
+	return "deepgram"
+}
+
+// SupportsStreaming reports that Deepgram offers live transcription
+func (d *DeepgramClient) SupportsStreaming() bool {
+	return true
+}
+
+// Transcribe implements Provider using the prerecorded API
+func (d *DeepgramClient) Transcribe(ctx context.Context, req *TranscribeRequest) (*STTResponse, error) {
+	return d.SpeechToText(ctx, &STTRequest{
+		AudioData:     req.Audio,
+		SampleRate:    req.SampleRate,
+		Language:      req.Language,
+		LanguageHints: req.LanguageHints,
+		Model:         "nova-2", // Best accuracy model
+		Punctuate:     true,
+		Endpointing:   true,
+	})
+}
+
+// deepgramWord is a word entry in a Deepgram alternative
+type deepgramWord struct {
+	Word           string  `json:"word"`
+	PunctuatedWord string  `json:"punctuated_word"`
+	Start          float64 `json:"start"`
+	End            float64 `json:"end"`
+	Confidence     float64 `json:"confidence"`
+}
+
+// convertDeepgramWords maps Deepgram words, preferring the punctuated form
+func convertDeepgramWords(in []deepgramWord) []Word {
+	if len(in) == 0 {
+		return nil
+	}
+	words := make([]Word, len(in))
+	for i, w := range in {
+		text := w.PunctuatedWord
+		if text == "" {
+			text = w.Word
+		}
+		words[i] = Word{
+			Text:       text,
+			Start:      secondsToDuration(w.Start),
+			End:        secondsToDuration(w.End),
+			Confidence: w.Confidence,
+		}
+	}
+	return words
+}
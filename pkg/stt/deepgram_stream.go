@@ -0,0 +1,306 @@
+package stt
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gorilla/websocket"
+	"go.uber.org/zap"
+)
+
+// keepAliveInterval is how often a KeepAlive is sent while no audio is flowing.
+// Deepgram closes idle live sockets after ~10 seconds.
+const keepAliveInterval = 5 * time.Second
+
+// StreamOptions configures a Deepgram live-transcription stream
+type StreamOptions struct {
+	SampleRate     int      // Sample rate of the PCM16 audio sent on the stream (default 16000)
+	Language       string   // Optional language code (e.g., "en", "hi")
+	LanguageHints  []string // Likely languages when Language is empty; live streams use the first
+	Model          string   // Optional model (default "nova-2")
+	Punctuate      bool     // Add punctuation
+	Interim        bool     // Deliver interim (non-final) transcripts
+	EndpointingMs  int      // Silence in ms before a result is marked speech_final (0 = Deepgram default)
+	UtteranceEndMs int      // Emit an utterance-end result after this many ms without words (0 = disabled)
+}
+
+// DeepgramStream is a live-transcription WebSocket session with Deepgram.
+// Audio is pushed with Send and transcripts are delivered on Results.
+type DeepgramStream struct {
+	conn      *websocket.Conn
+	logger    *zap.Logger
+	results   chan *STTResponse
+	done      chan struct{}
+	closing   chan struct{} // Closed when Close gives up waiting, so readLoop stops even if nobody drains Results
+	writeMu   sync.Mutex
+	lastSend  time.Time
+	closeOnce sync.Once
+	language  string
+}
+
+// OpenStream opens a live-transcription WebSocket to Deepgram.
+// Audio sent on the stream must be raw PCM16, mono, little-endian at opts.SampleRate.
+func (d *DeepgramClient) OpenStream(ctx context.Context, opts *StreamOptions) (Stream, error) {
+	if !d.IsAvailable() {
+		return nil, fmt.Errorf("Deepgram STT service not available. Set DEEPGRAM_API_KEY environment variable")
+	}
+	if opts == nil {
+		opts = &StreamOptions{}
+	}
+	// Live streams take a single language
+	resolved := *opts
+	resolved.Language = hintedLanguage(opts.Language, opts.LanguageHints)
+	opts = &resolved
+
+	wsURL, err := d.streamURL(opts)
+	if err != nil {
+		return nil, err
+	}
+
+	header := http.Header{}
+	header.Set("Authorization", "Token "+d.apiKey)
+
+	dialer := websocket.Dialer{HandshakeTimeout: d.timeout}
+	conn, resp, err := dialer.DialContext(ctx, wsURL, header)
+	if err != nil {
+		if resp != nil {
+			return nil, fmt.Errorf("failed to open Deepgram stream: %w (status %d)", err, resp.StatusCode)
+		}
+		return nil, fmt.Errorf("failed to open Deepgram stream: %w", err)
+	}
+
+	s := &DeepgramStream{
+		conn:     conn,
+		logger:   d.logger,
+		results:  make(chan *STTResponse, 32),
+		done:     make(chan struct{}),
+		closing:  make(chan struct{}),
+		lastSend: time.Now(),
+		language: opts.Language,
+	}
+
+	go s.readLoop()
+	go s.keepAlive()
+
+	return s, nil
+}
+
+// streamURL builds the wss:// listen URL with query parameters for the stream
+func (d *DeepgramClient) streamURL(opts *StreamOptions) (string, error) {
+	base, err := url.Parse(d.baseURL)
+	if err != nil {
+		return "", fmt.Errorf("invalid Deepgram base URL: %w", err)
+	}
+	switch base.Scheme {
+	case "https":
+		base.Scheme = "wss"
+	case "http":
+		base.Scheme = "ws"
+	}
+	base.Path = strings.TrimSuffix(base.Path, "/") + "/listen"
+
+	sampleRate := opts.SampleRate
+	if sampleRate == 0 {
+		sampleRate = 16000 // Default 16kHz
+	}
+	model := opts.Model
+	if model == "" {
+		model = "nova-2" // Best accuracy model
+	}
+
+	q := url.Values{}
+	q.Set("encoding", "linear16")
+	q.Set("sample_rate", strconv.Itoa(sampleRate))
+	q.Set("channels", "1")
+	q.Set("model", model)
+	q.Set("punctuate", strconv.FormatBool(opts.Punctuate))
+	q.Set("interim_results", strconv.FormatBool(opts.Interim))
+	if opts.Language != "" {
+		q.Set("language", opts.Language)
+	}
+	if opts.EndpointingMs > 0 {
+		q.Set("endpointing", strconv.Itoa(opts.EndpointingMs))
+	}
+	if opts.UtteranceEndMs > 0 {
+		// utterance_end_ms requires interim results
+		q.Set("utterance_end_ms", strconv.Itoa(opts.UtteranceEndMs))
+		q.Set("interim_results", "true")
+	}
+	base.RawQuery = q.Encode()
+
+	return base.String(), nil
+}
+
+// Send forwards a chunk of PCM16 audio to Deepgram
+func (s *DeepgramStream) Send(pcm []byte) error {
+	if len(pcm) == 0 {
+		return nil
+	}
+	s.writeMu.Lock()
+	defer s.writeMu.Unlock()
+
+	select {
+	case <-s.done:
+		return fmt.Errorf("Deepgram stream closed")
+	default:
+	}
+
+	s.lastSend = time.Now()
+	if err := s.conn.WriteMessage(websocket.BinaryMessage, pcm); err != nil {
+		return fmt.Errorf("failed to send audio to Deepgram: %w", err)
+	}
+	return nil
+}
+
+// Finalize asks Deepgram to flush the audio received so far as final results.
+// The flushed results arrive with FromFinalize set.
+func (s *DeepgramStream) Finalize() error {
+	select {
+	case <-s.done:
+		return fmt.Errorf("Deepgram stream closed")
+	default:
+	}
+	if err := s.sendControl("Finalize"); err != nil {
+		return fmt.Errorf("failed to finalize Deepgram stream: %w", err)
+	}
+	return nil
+}
+
+// Results returns the channel on which interim and final transcripts are delivered.
+// The channel is closed when the stream ends.
+func (s *DeepgramStream) Results() <-chan *STTResponse {
+	return s.results
+}
+
+// Done is closed once the stream has stopped receiving results
+func (s *DeepgramStream) Done() <-chan struct{} {
+	return s.done
+}
+
+// Close asks Deepgram to flush pending results and closes the socket
+func (s *DeepgramStream) Close() error {
+	var err error
+	s.closeOnce.Do(func() {
+		s.writeMu.Lock()
+		s.conn.WriteMessage(websocket.TextMessage, []byte(`{"type":"CloseStream"}`))
+		s.writeMu.Unlock()
+
+		// Give Deepgram a moment to deliver the final results before tearing down
+		select {
+		case <-s.done:
+		case <-time.After(2 * time.Second):
+		}
+		close(s.closing)
+		err = s.conn.Close()
+	})
+	return err
+}
+
+// sendControl writes a JSON control message (KeepAlive, CloseStream, ...)
+func (s *DeepgramStream) sendControl(msgType string) error {
+	s.writeMu.Lock()
+	defer s.writeMu.Unlock()
+	return s.conn.WriteMessage(websocket.TextMessage, []byte(fmt.Sprintf(`{"type":%q}`, msgType)))
+}
+
+// keepAlive prevents Deepgram from closing the socket while no audio is flowing
+func (s *DeepgramStream) keepAlive() {
+	ticker := time.NewTicker(keepAliveInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-s.done:
+			return
+		case <-ticker.C:
+			s.writeMu.Lock()
+			idle := time.Since(s.lastSend)
+			s.writeMu.Unlock()
+			if idle < keepAliveInterval {
+				continue
+			}
+			if err := s.sendControl("KeepAlive"); err != nil {
+				s.logger.Debug("Failed to send Deepgram keepalive", zap.Error(err))
+				return
+			}
+		}
+	}
+}
+
+// deepgramStreamMessage is the subset of Deepgram live messages we consume
+type deepgramStreamMessage struct {
+	Type    string `json:"type"`
+	Channel struct {
+		Alternatives []struct {
+			Transcript string         `json:"transcript"`
+			Confidence float64        `json:"confidence"`
+			Words      []deepgramWord `json:"words"`
+		} `json:"alternatives"`
+	} `json:"channel"`
+	IsFinal      bool `json:"is_final"`
+	SpeechFinal  bool `json:"speech_final"`
+	FromFinalize bool `json:"from_finalize"`
+}
+
+// readLoop decodes Deepgram messages and publishes them on the results channel
+func (s *DeepgramStream) readLoop() {
+	defer close(s.results)
+	defer close(s.done)
+
+	for {
+		_, message, err := s.conn.ReadMessage()
+		if err != nil {
+			if !websocket.IsCloseError(err, websocket.CloseNormalClosure) {
+				s.logger.Debug("Deepgram stream read ended", zap.Error(err))
+			}
+			return
+		}
+
+		var msg deepgramStreamMessage
+		if err := json.Unmarshal(message, &msg); err != nil {
+			s.logger.Warn("Failed to decode Deepgram stream message", zap.Error(err))
+			continue
+		}
+
+		var result *STTResponse
+		switch msg.Type {
+		case "Results":
+			result = &STTResponse{
+				Language:     s.language,
+				IsFinal:      msg.IsFinal,
+				SpeechFinal:  msg.SpeechFinal,
+				FromFinalize: msg.FromFinalize,
+				Provider:     "deepgram",
+			}
+			if len(msg.Channel.Alternatives) > 0 {
+				result.Text = msg.Channel.Alternatives[0].Transcript
+				result.Confidence = msg.Channel.Alternatives[0].Confidence
+				result.Words = convertDeepgramWords(msg.Channel.Alternatives[0].Words)
+			}
+		case "UtteranceEnd":
+			result = &STTResponse{
+				Language:     s.language,
+				IsFinal:      true,
+				UtteranceEnd: true,
+				Provider:     "deepgram",
+			}
+		default:
+			// Metadata, SpeechStarted, etc. are not needed by callers
+			continue
+		}
+
+		select {
+		case s.results <- result:
+		case <-s.closing:
+			// Nobody is reading results any more
+			return
+		}
+	}
+}
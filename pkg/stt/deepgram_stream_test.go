@@ -0,0 +1,248 @@
+package stt
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/gorilla/websocket"
+	"go.uber.org/zap"
+)
+
+// deepgramServer runs a fake Deepgram live endpoint; script drives the server side of each connection
+// The returned client points at the server
+func deepgramServer(t *testing.T, script func(conn *websocket.Conn, r *http.Request)) *DeepgramClient {
+	t.Helper()
+	upgrader := websocket.Upgrader{}
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		conn, err := upgrader.Upgrade(w, r, nil)
+		if err != nil {
+			t.Errorf("upgrade: %v", err)
+			return
+		}
+		defer conn.Close()
+		script(conn, r)
+	}))
+	t.Cleanup(srv.Close)
+
+	client := NewDeepgramClient("test-key", 2*time.Second, zap.NewNop())
+	client.baseURL = srv.URL + "/v1"
+	return client
+}
+
+// openTestStream opens a stream against the fake server
+func openTestStream(t *testing.T, client *DeepgramClient, opts *StreamOptions) *DeepgramStream {
+	t.Helper()
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+	defer cancel()
+	stream, err := client.OpenStream(ctx, opts)
+	if err != nil {
+		t.Fatalf("OpenStream: %v", err)
+	}
+	t.Cleanup(func() { stream.Close() })
+	return stream.(*DeepgramStream)
+}
+
+// nextResult waits for the next result on the stream
+func nextResult(t *testing.T, stream *DeepgramStream) *STTResponse {
+	t.Helper()
+	select {
+	case result, ok := <-stream.Results():
+		if !ok {
+			t.Fatal("results closed early")
+		}
+		return result
+	case <-time.After(2 * time.Second):
+		t.Fatal("no result")
+	}
+	return nil
+}
+
+func TestDeepgramStream_QueryParams(t *testing.T) {
+	client := NewDeepgramClient("test-key", time.Second, zap.NewNop())
+
+	tests := []struct {
+		name string
+		opts *StreamOptions
+		want map[string]string
+	}{
+		{"defaults", &StreamOptions{}, map[string]string{
+			"encoding": "linear16", "sample_rate": "16000", "channels": "1", "model": "nova-2",
+			"punctuate": "false", "interim_results": "false", "language": "", "endpointing": "", "utterance_end_ms": "",
+		}},
+		{"configured", &StreamOptions{SampleRate: 8000, Language: "hi", Model: "nova-3", Punctuate: true, Interim: true, EndpointingMs: 300}, map[string]string{
+			"sample_rate": "8000", "model": "nova-3", "punctuate": "true", "interim_results": "true", "language": "hi", "endpointing": "300",
+		}},
+		{"utterance end forces interim results", &StreamOptions{UtteranceEndMs: 1000}, map[string]string{
+			"interim_results": "true", "utterance_end_ms": "1000",
+		}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			raw, err := client.streamURL(tt.opts)
+			if err != nil {
+				t.Fatal(err)
+			}
+			u, _ := url.Parse(raw)
+			if u.Scheme != "wss" || u.Host != "api.deepgram.com" || u.Path != "/v1/listen" {
+				t.Errorf("url = %s", raw)
+			}
+			for key, want := range tt.want {
+				if got := u.Query().Get(key); got != want {
+					t.Errorf("%s = %q, want %q", key, got, want)
+				}
+			}
+		})
+	}
+
+	// The dialed URL carries the resolved options and the API key
+	requests := make(chan *http.Request, 1)
+	server := deepgramServer(t, func(conn *websocket.Conn, r *http.Request) {
+		requests <- r
+		conn.ReadMessage()
+	})
+	openTestStream(t, server, &StreamOptions{LanguageHints: []string{"hi", "en"}})
+	r := <-requests
+	if r.URL.Path != "/v1/listen" || r.URL.Query().Get("language") != "hi" {
+		t.Errorf("dialed %s, want /v1/listen with the first language hint", r.URL)
+	}
+	if auth := r.Header.Get("Authorization"); auth != "Token test-key" {
+		t.Errorf("Authorization = %q", auth)
+	}
+}
+
+func TestDeepgramStream_Results(t *testing.T) {
+	client := deepgramServer(t, func(conn *websocket.Conn, r *http.Request) {
+		for _, msg := range []string{
+			`{"type":"Metadata","request_id":"abc"}`,
+			`{"type":"Results","is_final":false,"channel":{"alternatives":[{"transcript":"I want","confidence":0.6}]}}`,
+			`{"type":"SpeechStarted"}`,
+			`{"type":"Results","is_final":true,"speech_final":true,"from_finalize":true,"channel":{"alternatives":[{"transcript":"I want to pay.","confidence":0.9,` +
+				`"words":[{"word":"pay","punctuated_word":"pay.","start":0.5,"end":0.8,"confidence":0.95}]}]}}`,
+			`{"type":"UtteranceEnd","last_word_end":0.8}`,
+			`not json`,
+		} {
+			conn.WriteMessage(websocket.TextMessage, []byte(msg))
+		}
+		conn.ReadMessage()
+	})
+	stream := openTestStream(t, client, &StreamOptions{Language: "en"})
+
+	interim := nextResult(t, stream)
+	if interim.IsFinal || interim.Text != "I want" || interim.Confidence != 0.6 || interim.Provider != "deepgram" || interim.Language != "en" {
+		t.Errorf("interim = %+v", interim)
+	}
+
+	final := nextResult(t, stream)
+	if !final.IsFinal || !final.SpeechFinal || !final.FromFinalize || final.Text != "I want to pay." || final.UtteranceEnd {
+		t.Errorf("final = %+v", final)
+	}
+	if len(final.Words) != 1 || final.Words[0].Text != "pay." || final.Words[0].End != 800*time.Millisecond {
+		t.Errorf("words = %+v", final.Words)
+	}
+
+	end := nextResult(t, stream)
+	if !end.UtteranceEnd || !end.IsFinal || end.Text != "" {
+		t.Errorf("utterance end = %+v", end)
+	}
+}
+
+func TestDeepgramStream_SendFinalizeClose(t *testing.T) {
+	received := make(chan string, 8)
+	client := deepgramServer(t, func(conn *websocket.Conn, r *http.Request) {
+		for {
+			kind, msg, err := conn.ReadMessage()
+			if err != nil {
+				return
+			}
+			if kind == websocket.BinaryMessage {
+				received <- fmt.Sprintf("audio:%d", len(msg))
+				continue
+			}
+			received <- string(msg)
+			if string(msg) == `{"type":"CloseStream"}` {
+				// Deepgram flushes what it has, then closes the socket
+				conn.WriteMessage(websocket.TextMessage, []byte(`{"type":"Results","is_final":true,"channel":{"alternatives":[{"transcript":"bye"}]}}`))
+				conn.WriteMessage(websocket.CloseMessage, websocket.FormatCloseMessage(websocket.CloseNormalClosure, ""))
+				return
+			}
+		}
+	})
+	stream := openTestStream(t, client, nil)
+
+	if err := stream.Send(make([]byte, 640)); err != nil {
+		t.Fatal(err)
+	}
+	if err := stream.Send(nil); err != nil {
+		t.Fatal(err)
+	}
+	if err := stream.Finalize(); err != nil {
+		t.Fatal(err)
+	}
+
+	start := time.Now()
+	if err := stream.Close(); err != nil {
+		t.Errorf("Close: %v", err)
+	}
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Errorf("Close took %v, want it to return once Deepgram closes", elapsed)
+	}
+
+	for _, want := range []string{"audio:640", `{"type":"Finalize"}`, `{"type":"CloseStream"}`} {
+		if got := <-received; got != want {
+			t.Errorf("server received %q, want %q", got, want)
+		}
+	}
+
+	// Results flushed on close are still delivered, then the channel closes
+	if final := nextResult(t, stream); final.Text != "bye" {
+		t.Errorf("flushed result = %+v", final)
+	}
+	if _, ok := <-stream.Results(); ok {
+		t.Error("results not closed after the stream ended")
+	}
+	if err := stream.Send(make([]byte, 640)); err == nil {
+		t.Error("Send succeeded on a closed stream")
+	}
+	if err := stream.Finalize(); err == nil {
+		t.Error("Finalize succeeded on a closed stream")
+	}
+}
+
+func TestDeepgramStream_CloseWithoutReader(t *testing.T) {
+	client := deepgramServer(t, func(conn *websocket.Conn, r *http.Request) {
+		// More results than the stream buffers, and the socket stays open
+		for i := 0; i < 64; i++ {
+			conn.WriteMessage(websocket.TextMessage, []byte(`{"type":"Results","is_final":false,"channel":{"alternatives":[{"transcript":"hello"}]}}`))
+		}
+		for {
+			if _, _, err := conn.ReadMessage(); err != nil {
+				return
+			}
+		}
+	})
+	stream := openTestStream(t, client, nil)
+
+	// Let the read loop fill the results buffer and block on it
+	time.Sleep(100 * time.Millisecond)
+
+	closed := make(chan struct{})
+	go func() {
+		stream.Close()
+		close(closed)
+	}()
+	select {
+	case <-closed:
+	case <-time.After(5 * time.Second):
+		t.Fatal("Close did not return")
+	}
+	select {
+	case <-stream.Done():
+	case <-time.After(time.Second):
+		t.Fatal("read loop still running after Close")
+	}
+}
@@ -0,0 +1,185 @@
+package stt
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+)
+
+// FakeProvider is a deterministic offline provider for tests and local runs.
+// Each transcription returns the next scripted transcript, cycling through the script.
+type FakeProvider struct {
+	mu          sync.Mutex
+	transcripts []string
+	next        int
+
+	// Err, when set, is returned by Transcribe and OpenStream
+	Err error
+}
+
+// NewFakeProvider creates a fake provider that returns the transcripts in order
+func NewFakeProvider(transcripts ...string) *FakeProvider {
+	return &FakeProvider{transcripts: transcripts}
+}
+
+// Name returns the provider name
+func (f *FakeProvider) Name() string {
+	return "fake"
+}
+
+// IsAvailable always returns true
+func (f *FakeProvider) IsAvailable() bool {
+	return true
+}
+
+// SupportsStreaming returns true
+func (f *FakeProvider) SupportsStreaming() bool {
+	return true
+}
+
+// Transcribe returns the next scripted transcript with words spread evenly over the audio
+func (f *FakeProvider) Transcribe(ctx context.Context, req *TranscribeRequest) (*STTResponse, error) {
+	if f.Err != nil {
+		return nil, f.Err
+	}
+	if len(req.Audio) == 0 {
+		return nil, fmt.Errorf("audio data cannot be empty")
+	}
+	return f.transcript(len(req.Audio), req.SampleRate, req.Language), nil
+}
+
+// OpenStream starts a fake live stream: each Finalize after audio has been sent yields the next transcript
+func (f *FakeProvider) OpenStream(ctx context.Context, opts *StreamOptions) (Stream, error) {
+	if f.Err != nil {
+		return nil, f.Err
+	}
+	if opts == nil {
+		opts = &StreamOptions{}
+	}
+	return &fakeStream{
+		provider:   f,
+		sampleRate: opts.SampleRate,
+		language:   hintedLanguage(opts.Language, opts.LanguageHints),
+		results:    make(chan *STTResponse, 32),
+		done:       make(chan struct{}),
+	}, nil
+}
+
+// transcript builds the next scripted result for audioBytes of PCM16
+func (f *FakeProvider) transcript(audioBytes, sampleRate int, language string) *STTResponse {
+	f.mu.Lock()
+	text := ""
+	if len(f.transcripts) > 0 {
+		text = f.transcripts[f.next%len(f.transcripts)]
+		f.next++
+	}
+	f.mu.Unlock()
+
+	if sampleRate == 0 {
+		sampleRate = 16000 // Default 16kHz
+	}
+	duration := time.Duration(audioBytes/2) * time.Second / time.Duration(sampleRate)
+
+	fields := strings.Fields(text)
+	words := make([]Word, len(fields))
+	for i, field := range fields {
+		words[i] = Word{
+			Text:       field,
+			Start:      duration * time.Duration(i) / time.Duration(len(fields)),
+			End:        duration * time.Duration(i+1) / time.Duration(len(fields)),
+			Confidence: 1,
+		}
+	}
+
+	confidence := 0.0
+	if text != "" {
+		confidence = 1
+	}
+	return &STTResponse{
+		Text:       text,
+		Language:   language,
+		IsFinal:    true,
+		Confidence: confidence,
+		Words:      words,
+		Provider:   f.Name(),
+	}
+}
+
+// fakeStream is the live stream returned by FakeProvider
+type fakeStream struct {
+	provider   *FakeProvider
+	sampleRate int
+	language   string
+
+	mu      sync.Mutex
+	pending int // Audio bytes received since the last Finalize
+	closed  bool
+	results chan *STTResponse
+	done    chan struct{}
+}
+
+// Send records the audio; the content is ignored
+func (s *fakeStream) Send(pcm []byte) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.closed {
+		return fmt.Errorf("fake stream closed")
+	}
+	s.pending += len(pcm)
+	return nil
+}
+
+// Finalize emits the next transcript as an interim result followed by a final one
+func (s *fakeStream) Finalize() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.closed {
+		return fmt.Errorf("fake stream closed")
+	}
+
+	final := &STTResponse{IsFinal: true, FromFinalize: true, Language: s.language, Provider: s.provider.Name()}
+	if s.pending > 0 {
+		final = s.provider.transcript(s.pending, s.sampleRate, s.language)
+		final.FromFinalize = true
+		s.pending = 0
+
+		interim := *final
+		interim.IsFinal = false
+		interim.FromFinalize = false
+		s.publish(&interim)
+	}
+	s.publish(final)
+	return nil
+}
+
+// publish delivers a result without blocking; results are dropped if nobody is reading
+func (s *fakeStream) publish(result *STTResponse) {
+	select {
+	case s.results <- result:
+	default:
+	}
+}
+
+// Results returns the transcript channel
+func (s *fakeStream) Results() <-chan *STTResponse {
+	return s.results
+}
+
+// Done is closed when the stream is closed
+func (s *fakeStream) Done() <-chan struct{} {
+	return s.done
+}
+
+// Close ends the stream
+func (s *fakeStream) Close() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if !s.closed {
+		s.closed = true
+		close(s.results)
+		close(s.done)
+	}
+	return nil
+}
@@ -0,0 +1,118 @@
+package stt
+
+import (
+	"context"
+	"fmt"
+
+	"go.uber.org/zap"
+
+	"github.com/troikatech/calling-agent/pkg/circuitbreaker"
+)
+
+// Manager transcribes with ordered fallback across providers.
+// Each provider sits behind its own circuit breaker so a failing backend is skipped until it recovers.
+type Manager struct {
+	providers []Provider
+	breakers  []*circuitbreaker.CircuitBreaker
+	logger    *zap.Logger
+}
+
+// NewManager creates an STT manager; providers are tried in order
+func NewManager(providers []Provider, breakerConfig circuitbreaker.Config, logger *zap.Logger) *Manager {
+	breakers := make([]*circuitbreaker.CircuitBreaker, len(providers))
+	for i := range providers {
+		breakers[i] = circuitbreaker.New(breakerConfig)
+	}
+	return &Manager{
+		providers: providers,
+		breakers:  breakers,
+		logger:    logger,
+	}
+}
+
+// Providers returns the names of the available providers in fallback order
+func (m *Manager) Providers() []string {
+	var names []string
+	for _, provider := range m.providers {
+		if provider.IsAvailable() {
+			names = append(names, provider.Name())
+		}
+	}
+	return names
+}
+
+// CanStream reports whether a streaming provider is available and not tripped
+func (m *Manager) CanStream() bool {
+	for i, provider := range m.providers {
+		if provider.IsAvailable() && provider.SupportsStreaming() && m.breakers[i].GetState() != circuitbreaker.StateOpen {
+			return true
+		}
+	}
+	return false
+}
+
+// Transcribe converts an utterance to text with the first provider that succeeds
+func (m *Manager) Transcribe(ctx context.Context, req *TranscribeRequest) (*STTResponse, error) {
+	var resp *STTResponse
+	err := m.tryProviders(ctx, false, func(provider Provider) error {
+		var err error
+		resp, err = provider.Transcribe(ctx, req)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	return resp, nil
+}
+
+// OpenStream starts live transcription with the first streaming provider that connects
+func (m *Manager) OpenStream(ctx context.Context, opts *StreamOptions) (Stream, error) {
+	var stream Stream
+	err := m.tryProviders(ctx, true, func(provider Provider) error {
+		var err error
+		stream, err = provider.OpenStream(ctx, opts)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	return stream, nil
+}
+
+// tryProviders runs fn on each available provider, through its circuit breaker, until one succeeds
+func (m *Manager) tryProviders(ctx context.Context, streaming bool, fn func(Provider) error) error {
+	var lastErr error
+	for i, provider := range m.providers {
+		if !provider.IsAvailable() || (streaming && !provider.SupportsStreaming()) {
+			continue
+		}
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+
+		called := false
+		err := m.breakers[i].Execute(ctx, func() error {
+			called = true
+			return fn(provider)
+		})
+		if err == nil {
+			return nil
+		}
+		if !called {
+			m.logger.Debug("STT provider circuit open, skipping", zap.String("provider", provider.Name()))
+			continue
+		}
+
+		lastErr = err
+		m.logger.Warn("STT provider failed, trying next",
+			zap.String("provider", provider.Name()),
+			zap.Bool("streaming", streaming),
+			zap.Error(err),
+		)
+	}
+
+	if lastErr == nil {
+		return fmt.Errorf("no STT providers available")
+	}
+	return fmt.Errorf("all STT providers failed. Last error: %w", lastErr)
+}
@@ -0,0 +1,130 @@
+package stt
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"go.uber.org/zap"
+
+	"github.com/troikatech/calling-agent/pkg/circuitbreaker"
+)
+
+// countingProvider wraps the fake provider and counts batch calls
+type countingProvider struct {
+	*FakeProvider
+	name      string
+	streaming bool
+	calls     int
+}
+
+func (c *countingProvider) Name() string            { return c.name }
+func (c *countingProvider) SupportsStreaming() bool { return c.streaming }
+
+func (c *countingProvider) Transcribe(ctx context.Context, req *TranscribeRequest) (*STTResponse, error) {
+	c.calls++
+	return c.FakeProvider.Transcribe(ctx, req)
+}
+
+func testBreaker() circuitbreaker.Config {
+	return circuitbreaker.Config{FailureThreshold: 2, SuccessThreshold: 1, Timeout: time.Hour, ResetTimeout: time.Hour}
+}
+
+func TestFakeProvider_TranscribeIsDeterministic(t *testing.T) {
+	fake := NewFakeProvider("hello there", "bye")
+	req := &TranscribeRequest{Audio: make([]byte, 32000), SampleRate: 16000}
+
+	var got []string
+	for i := 0; i < 3; i++ {
+		resp, err := fake.Transcribe(context.Background(), req)
+		if err != nil {
+			t.Fatalf("Transcribe() error = %v", err)
+		}
+		got = append(got, resp.Text)
+	}
+	if got[0] != "hello there" || got[1] != "bye" || got[2] != "hello there" {
+		t.Errorf("transcripts = %q", got)
+	}
+
+	resp, _ := NewFakeProvider("hello there").Transcribe(context.Background(), req)
+	if len(resp.Words) != 2 {
+		t.Fatalf("words = %+v", resp.Words)
+	}
+	if resp.Words[1].Start != 500*time.Millisecond || resp.Words[1].End != time.Second || resp.Words[1].Confidence != 1 {
+		t.Errorf("second word = %+v", resp.Words[1])
+	}
+}
+
+func TestFakeProvider_StreamFinalize(t *testing.T) {
+	stream, err := NewFakeProvider("yes please").OpenStream(context.Background(), &StreamOptions{SampleRate: 16000})
+	if err != nil {
+		t.Fatalf("OpenStream() error = %v", err)
+	}
+	stream.Send(make([]byte, 640))
+	stream.Finalize()
+	stream.Close()
+
+	var results []*STTResponse
+	for r := range stream.Results() {
+		results = append(results, r)
+	}
+	if len(results) != 2 {
+		t.Fatalf("got %d results, want interim + final", len(results))
+	}
+	if results[0].IsFinal || !results[1].IsFinal || !results[1].FromFinalize || results[1].Text != "yes please" {
+		t.Errorf("results = %+v, %+v", results[0], results[1])
+	}
+	if err := stream.Send([]byte{0, 0}); err == nil {
+		t.Error("Send after Close should fail")
+	}
+}
+
+func TestManager_FallbackAndCircuitBreaker(t *testing.T) {
+	failing := &countingProvider{FakeProvider: NewFakeProvider(), name: "deepgram"}
+	failing.Err = errors.New("mock error")
+	backup := &countingProvider{FakeProvider: NewFakeProvider("backup"), name: "whisper"}
+	manager := NewManager([]Provider{failing, backup}, testBreaker(), zap.NewNop())
+
+	req := &TranscribeRequest{Audio: make([]byte, 640)}
+	for i := 0; i < 4; i++ {
+		resp, err := manager.Transcribe(context.Background(), req)
+		if err != nil {
+			t.Fatalf("Transcribe() error = %v", err)
+		}
+		if resp.Provider != "fake" || resp.Text != "backup" {
+			t.Errorf("resp = %+v", resp)
+		}
+	}
+
+	// Two failures trip the breaker; the failing provider is skipped after that
+	if failing.calls != 2 {
+		t.Errorf("failing provider called %d times, want 2", failing.calls)
+	}
+	if backup.calls != 4 {
+		t.Errorf("backup provider called %d times, want 4", backup.calls)
+	}
+}
+
+func TestManager_StreamingSkipsBatchProviders(t *testing.T) {
+	batchOnly := &countingProvider{FakeProvider: NewFakeProvider(), name: "whisper"}
+	streaming := &countingProvider{FakeProvider: NewFakeProvider("hi"), name: "deepgram", streaming: true}
+	manager := NewManager([]Provider{batchOnly, streaming}, testBreaker(), zap.NewNop())
+
+	if !manager.CanStream() {
+		t.Fatal("CanStream() = false")
+	}
+	stream, err := manager.OpenStream(context.Background(), nil)
+	if err != nil {
+		t.Fatalf("OpenStream() error = %v", err)
+	}
+	stream.Close()
+
+	noStreaming := NewManager([]Provider{batchOnly}, testBreaker(), zap.NewNop())
+	if noStreaming.CanStream() {
+		t.Error("CanStream() = true with only batch providers")
+	}
+	if _, err := noStreaming.OpenStream(context.Background(), nil); err == nil {
+		t.Error("expected error with no streaming provider")
+	}
+}
@@ -0,0 +1,66 @@
+package stt
+
+import (
+	"context"
+	"errors"
+	"time"
+)
+
+// ErrStreamingUnsupported is returned by OpenStream on batch-only providers
+var ErrStreamingUnsupported = errors.New("provider does not support streaming")
+
+// Provider is a speech-to-text backend. Every provider transcribes whole utterances;
+// providers that report SupportsStreaming also transcribe live audio.
+type Provider interface {
+	Name() string
+	IsAvailable() bool
+	SupportsStreaming() bool
+	// Transcribe converts a complete utterance of PCM16 mono audio to text
+	Transcribe(ctx context.Context, req *TranscribeRequest) (*STTResponse, error)
+	// OpenStream starts live transcription; audio sent must be PCM16 mono at opts.SampleRate
+	OpenStream(ctx context.Context, opts *StreamOptions) (Stream, error)
+}
+
+// Stream is a live-transcription session. Audio is pushed with Send and transcripts are delivered on Results.
+type Stream interface {
+	// Send forwards a chunk of PCM16 audio
+	Send(pcm []byte) error
+	// Finalize flushes the audio received so far as final results, delivered with FromFinalize set
+	Finalize() error
+	// Results delivers interim and final transcripts and is closed when the stream ends
+	Results() <-chan *STTResponse
+	// Done is closed once the stream has stopped receiving results
+	Done() <-chan struct{}
+	// Close flushes pending results and ends the stream
+	Close() error
+}
+
+// TranscribeRequest is a provider-neutral batch transcription request
+type TranscribeRequest struct {
+	Audio         []byte   // Raw PCM16, mono, little-endian
+	SampleRate    int      // Sample rate of Audio (default 16000)
+	Language      string   // Language code (e.g. "en", "hi"); empty lets the provider detect it
+	LanguageHints []string // Languages the caller is likely to speak, used when Language is empty
+	Prompt        string   // Optional vocabulary/context hint (Whisper only)
+}
+
+// Word is a transcribed word with its position in the audio
+type Word struct {
+	Text       string
+	Start      time.Duration
+	End        time.Duration
+	Confidence float64 // 0 when the provider does not report per-word confidence
+}
+
+// hintedLanguage picks the language to request when only hints are given and the provider takes one language
+func hintedLanguage(language string, hints []string) string {
+	if language == "" && len(hints) > 0 {
+		return hints[0]
+	}
+	return language
+}
+
+// secondsToDuration converts provider timestamps in seconds
+func secondsToDuration(seconds float64) time.Duration {
+	return time.Duration(seconds * float64(time.Second))
+}
@@ -0,0 +1,71 @@
+package stt
+
+import (
+	"context"
+	"strings"
+
+	"github.com/troikatech/calling-agent/pkg/ai"
+	"github.com/troikatech/calling-agent/pkg/audio"
+)
+
+// WhisperProvider adapts the OpenAI Whisper service to Provider. Whisper is batch-only.
+type WhisperProvider struct {
+	service *ai.STTService
+}
+
+// NewWhisperProvider wraps an OpenAI Whisper service
+func NewWhisperProvider(service *ai.STTService) *WhisperProvider {
+	return &WhisperProvider{service: service}
+}
+
+// Name returns the provider name
+func (w *WhisperProvider) Name() string {
+	return "whisper"
+}
+
+// IsAvailable checks if the Whisper service is configured
+func (w *WhisperProvider) IsAvailable() bool {
+	return w.service != nil && w.service.IsAvailable()
+}
+
+// SupportsStreaming returns false; Whisper only transcribes complete utterances
+func (w *WhisperProvider) SupportsStreaming() bool {
+	return false
+}
+
+// Transcribe sends the utterance to Whisper as WAV with word timestamps
+func (w *WhisperProvider) Transcribe(ctx context.Context, req *TranscribeRequest) (*STTResponse, error) {
+	resp, err := w.service.SpeechToText(ctx, &ai.STTRequest{
+		AudioData:      audio.PCMToWAV(req.Audio, req.SampleRate),
+		AudioFormat:    "wav",
+		Language:       hintedLanguage(req.Language, req.LanguageHints),
+		Prompt:         req.Prompt,
+		WordTimestamps: true,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	words := make([]Word, 0, len(resp.Words))
+	for _, word := range resp.Words {
+		words = append(words, Word{
+			Text:  word.Word,
+			Start: secondsToDuration(word.Start),
+			End:   secondsToDuration(word.End),
+		})
+	}
+
+	return &STTResponse{
+		Text:       strings.TrimSpace(resp.Text),
+		Language:   resp.Language,
+		IsFinal:    true,
+		Confidence: resp.Confidence,
+		Words:      words,
+		Provider:   w.Name(),
+	}, nil
+}
+
+// OpenStream is not supported by Whisper
+func (w *WhisperProvider) OpenStream(ctx context.Context, opts *StreamOptions) (Stream, error) {
+	return nil, ErrStreamingUnsupported
+}